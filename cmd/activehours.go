@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// activeWindow is a daily time-of-day range in which posting is allowed. It
+// may span midnight (e.g. 22:00-02:00).
+type activeWindow struct {
+	start, end time.Duration // offsets from midnight
+	loc        *time.Location
+}
+
+// parseActiveHours parses an --active-hours value such as "08:00-22:00",
+// interpreted in timezone (local time when empty).
+func parseActiveHours(spec, timezone string) (activeWindow, error) {
+	w := activeWindow{loc: time.Local}
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return w, fmt.Errorf("invalid --active-hours-tz %q: %w", timezone, err)
+		}
+		w.loc = loc
+	}
+
+	from, to, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return w, fmt.Errorf("invalid --active-hours %q: expected HH:MM-HH:MM", spec)
+	}
+	var err error
+	if w.start, err = parseClock(from); err != nil {
+		return w, fmt.Errorf("invalid --active-hours %q: %w", spec, err)
+	}
+	if w.end, err = parseClock(to); err != nil {
+		return w, fmt.Errorf("invalid --active-hours %q: %w", spec, err)
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("invalid --active-hours %q: start and end are the same", spec)
+	}
+	return w, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", strings.TrimSpace(s))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains reports whether t falls inside the window.
+func (w activeWindow) contains(t time.Time) bool {
+	t = t.In(w.loc)
+	tod := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.start < w.end {
+		return tod >= w.start && tod < w.end
+	}
+	return tod >= w.start || tod < w.end
+}
+
+// nextOpen returns the next time after t at which the window opens.
+func (w activeWindow) nextOpen(t time.Time) time.Time {
+	t = t.In(w.loc)
+	h, m := int(w.start/time.Hour), int(w.start%time.Hour/time.Minute)
+	open := time.Date(t.Year(), t.Month(), t.Day(), h, m, 0, 0, w.loc)
+	if !open.After(t) {
+		open = time.Date(t.Year(), t.Month(), t.Day()+1, h, m, 0, 0, w.loc)
+	}
+	return open
+}
+
+// scheduleFor returns when a post made at now should be scheduled to keep it
+// inside the window: the zero time when now is inside it already, otherwise
+// the next time the window opens.
+func (w activeWindow) scheduleFor(now time.Time) time.Time {
+	if w.contains(now) {
+		return time.Time{}
+	}
+	return w.nextOpen(now)
+}
+
+// enforceActiveHours returns nil when now is inside the window. Otherwise it
+// fails, or with wait blocks until the window opens or ctx is cancelled.
+func enforceActiveHours(ctx context.Context, w activeWindow, now time.Time, wait, dryRun bool, out io.Writer) error {
+	if w.contains(now) {
+		return nil
+	}
+
+	open := w.nextOpen(now)
+	if !wait {
+		err := fmt.Errorf("outside active hours; the window opens at %s (use --wait-for-window to wait)", open.Format(time.RFC1123))
+		fmt.Fprintf(out, "error: %v\n", err)
+		return err
+	}
+	if dryRun {
+		fmt.Fprintf(out, "[dry-run] outside active hours; would wait until %s\n", open.Format(time.RFC1123))
+		return nil
+	}
+
+	fmt.Fprintf(out, "Outside active hours; waiting until %s\n", open.Format(time.RFC1123))
+	timer := time.NewTimer(open.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for active hours: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
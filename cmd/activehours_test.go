@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// at returns 2025-06-10 at hh:mm in UTC.
+func at(hh, mm int) time.Time {
+	return time.Date(2025, 6, 10, hh, mm, 0, 0, time.UTC)
+}
+
+func TestParseActiveHours(t *testing.T) {
+	tests := []struct {
+		spec, tz   string
+		start, end time.Duration
+		wantErr    bool
+	}{
+		{spec: "08:00-22:00", start: 8 * time.Hour, end: 22 * time.Hour},
+		{spec: " 22:30 - 06:15 ", tz: "Europe/Berlin", start: 22*time.Hour + 30*time.Minute, end: 6*time.Hour + 15*time.Minute},
+		{spec: "08:00", wantErr: true},
+		{spec: "8am-10pm", wantErr: true},
+		{spec: "09:00-09:00", wantErr: true},
+		{spec: "08:00-22:00", tz: "Mars/Olympus", wantErr: true},
+	}
+	for _, tt := range tests {
+		w, err := parseActiveHours(tt.spec, tt.tz)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (w.start != tt.start || w.end != tt.end) {
+			t.Errorf("%q: window = %s-%s, want %s-%s", tt.spec, w.start, w.end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestActiveWindow(t *testing.T) {
+	day := activeWindow{start: 8 * time.Hour, end: 22 * time.Hour, loc: time.UTC}
+	night := activeWindow{start: 22 * time.Hour, end: 2 * time.Hour, loc: time.UTC}
+	tests := []struct {
+		name     string
+		w        activeWindow
+		now      time.Time
+		contains bool
+		nextOpen time.Time
+	}{
+		{name: "day, inside", w: day, now: at(12, 0), contains: true, nextOpen: at(8, 0).AddDate(0, 0, 1)},
+		{name: "day, at the start", w: day, now: at(8, 0), contains: true, nextOpen: at(8, 0).AddDate(0, 0, 1)},
+		{name: "day, at the end", w: day, now: at(22, 0), nextOpen: at(8, 0).AddDate(0, 0, 1)},
+		{name: "day, early morning", w: day, now: at(3, 0), nextOpen: at(8, 0)},
+		{name: "midnight, late evening", w: night, now: at(23, 30), contains: true, nextOpen: at(22, 0).AddDate(0, 0, 1)},
+		{name: "midnight, after midnight", w: night, now: at(1, 59), contains: true, nextOpen: at(22, 0)},
+		{name: "midnight, closed", w: night, now: at(2, 0), nextOpen: at(22, 0)},
+		{name: "midnight, afternoon", w: night, now: at(15, 0), nextOpen: at(22, 0)},
+	}
+	for _, tt := range tests {
+		if got := tt.w.contains(tt.now); got != tt.contains {
+			t.Errorf("%s: contains = %v, want %v", tt.name, got, tt.contains)
+		}
+		if got := tt.w.nextOpen(tt.now); !got.Equal(tt.nextOpen) {
+			t.Errorf("%s: nextOpen = %s, want %s", tt.name, got, tt.nextOpen)
+		}
+		want := tt.nextOpen
+		if tt.contains {
+			want = time.Time{}
+		}
+		if got := tt.w.scheduleFor(tt.now); !got.Equal(want) {
+			t.Errorf("%s: scheduleFor = %s, want %s", tt.name, got, want)
+		}
+	}
+}
+
+func TestActiveWindowTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	w := activeWindow{start: 8 * time.Hour, end: 22 * time.Hour, loc: tokyo}
+	// 23:00 UTC is 08:00 the next day in Tokyo.
+	if !w.contains(at(23, 0)) || w.contains(at(22, 59)) {
+		t.Error("the window is not applied in its time zone")
+	}
+	if got := w.nextOpen(at(22, 0)); !got.Equal(at(23, 0)) {
+		t.Errorf("nextOpen = %s, want 23:00 UTC", got.UTC())
+	}
+}
+
+func TestEnforceActiveHours(t *testing.T) {
+	w := activeWindow{start: 8 * time.Hour, end: 22 * time.Hour, loc: time.UTC}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		now     time.Time
+		wait    bool
+		dryRun  bool
+		wantErr error
+		wantOut string
+	}{
+		{name: "in window proceeds", ctx: cancelled, now: at(12, 0)},
+		{name: "out of window fails", ctx: context.Background(), now: at(3, 0), wantErr: errors.New("outside active hours"), wantOut: "error: outside active hours"},
+		{name: "waits until cancelled", ctx: cancelled, now: at(3, 0), wait: true, wantErr: context.Canceled, wantOut: "waiting until Tue, 10 Jun 2025 08:00:00 UTC"},
+		{name: "dry run does not wait", ctx: cancelled, now: at(3, 0), wait: true, dryRun: true, wantOut: "[dry-run] outside active hours; would wait until"},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		err := enforceActiveHours(tt.ctx, w, tt.now, tt.wait, tt.dryRun, &out)
+		switch {
+		case tt.wantErr == nil && err != nil:
+			t.Errorf("%s: err = %v", tt.name, err)
+		case tt.wantErr != nil && (err == nil || !errors.Is(err, tt.wantErr) && !strings.Contains(err.Error(), tt.wantErr.Error())):
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if !strings.Contains(out.String(), tt.wantOut) {
+			t.Errorf("%s: output %q lacks %q", tt.name, out.String(), tt.wantOut)
+		}
+	}
+}
+
+func TestEnforceActiveHoursWaits(t *testing.T) {
+	w := activeWindow{start: 8 * time.Hour, end: 22 * time.Hour, loc: time.UTC}
+	// A second before the window opens.
+	if err := enforceActiveHours(context.Background(), w, at(7, 59).Add(59*time.Second), true, false, io.Discard); err != nil {
+		t.Errorf("enforceActiveHours = %v, want it to return once the window opens", err)
+	}
+}
+
+func TestScheduleToWindow(t *testing.T) {
+	w := activeWindow{start: 8 * time.Hour, end: 22 * time.Hour, loc: time.UTC}
+	validators := []xpost.Validator{newFakePoster("mastodon", 500), newFakePoster("twitter", 280)}
+
+	req := xpost.Request{Message: "good morning", ScheduledAt: w.scheduleFor(at(3, 0))}
+	requests, _, _, errs := prepareRequests(validators, req, dispatchOptions{})
+	if got := requests["mastodon"].ScheduledAt; !got.Equal(at(8, 0)) {
+		t.Errorf("mastodon ScheduledAt = %s, want the window opening", got)
+	}
+	// Targets that cannot schedule are refused rather than posting now.
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "twitter") {
+		t.Errorf("errs = %v, want twitter to be refused", errs)
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/spf13/cobra"
+)
+
+func newBoostCommand() *cobra.Command {
+	var boostDryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "boost <post-url>",
+		Short: "Repost (boost/retweet) a post on the network it was published on",
+		Long: "boost detects the network from the post URL, like reply, and reblogs it on " +
+			"Mastodon, reposts it on Bluesky, or retweets it on Twitter/X.",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+			logutil.SetVerbose(verbose)
+
+			if err := loadSecrets(ctx); err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			ref := strings.TrimSpace(args[0])
+			target, err := providerForURL(cfg, ref)
+			if err != nil {
+				return err
+			}
+
+			posters, err := buildPosters(ctx, cfg, []string{target})
+			if err != nil {
+				return err
+			}
+			defer closePosters(ctx, posters)
+			reposter, ok := posters[0].(xpost.Reposter)
+			if !ok {
+				fmt.Fprintf(out, "%s does not support reposting; nothing to do\n", styledProvider(target, out))
+				return nil
+			}
+
+			if boostDryRun {
+				fmt.Fprintf(out, "[dry-run] would repost on %s: %s\n", styledProvider(target, out), ref)
+				return nil
+			}
+			if err := reposter.Repost(ctx, ref); err != nil {
+				fmt.Fprintf(out, "error: %s: %v\n", target, err)
+				return err
+			}
+			fmt.Fprintf(out, "Reposted on %s\n", styledProvider(target, out))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&boostDryRun, "dry-run", false, "Print actions without reposting")
+
+	return cmd
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// campaignTag returns the hashtag of campaign to add to posts made at now, or
+// "" when none is configured or it has expired.
+func campaignTag(campaign config.Campaign, now time.Time) (string, error) {
+	tag := strings.TrimSpace(campaign.Tag)
+	if tag == "" {
+		return "", nil
+	}
+	tag = "#" + strings.TrimPrefix(tag, "#")
+	if tags := xpost.Hashtags(tag); len(tags) != 1 || "#"+tags[0] != tag {
+		return "", fmt.Errorf("invalid campaign tag %q: expected a single hashtag", tag)
+	}
+
+	raw := strings.TrimSpace(campaign.Expires)
+	if raw == "" {
+		return tag, nil
+	}
+	expires, err := parseCampaignExpiry(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid campaign expiry %q: %w", raw, err)
+	}
+	if !now.Before(expires) {
+		logutil.Debugf("campaign tag %s expired at %s", tag, expires.Format(time.RFC3339))
+		return "", nil
+	}
+	return tag, nil
+}
+
+// parseCampaignExpiry accepts an RFC 3339 time or a local date, which expires
+// at the end of that day.
+func parseCampaignExpiry(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation(time.DateOnly, raw, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("expected YYYY-MM-DD or an RFC 3339 time")
+	}
+	return day.AddDate(0, 0, 1), nil
+}
+
+// addCampaignTag appends the campaign hashtag to req's message unless it is
+// already there or the post would no longer validate with it.
+func addCampaignTag(v xpost.Validator, req xpost.Request, opts dispatchOptions, crosslinkURL string) xpost.Request {
+	tag := opts.campaignTag
+	if slices.ContainsFunc(xpost.Hashtags(req.Message), func(t string) bool {
+		return xpost.NormalizeHashtag(t) == xpost.NormalizeHashtag(tag)
+	}) {
+		return req
+	}
+
+	tagged := req
+	tagged.Message = strings.TrimSpace(req.Message + "\n\n" + tag)
+	if err := v.Validate(crosslinkRequest(tagged, v.Name(), opts.crosslink, crosslinkURL)); err != nil {
+		logutil.Debugf("%s: campaign tag %s does not fit; skipping it", v.Name(), tag)
+		return req
+	}
+	return tagged
+}
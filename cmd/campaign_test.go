@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestCampaignTag(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		tag     string
+		expires string
+		want    string
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "no expiry", tag: "LaunchWeek", want: "#LaunchWeek"},
+		{name: "before the date", tag: "#LaunchWeek", expires: "2025-06-10", want: "#LaunchWeek"},
+		{name: "after the date", tag: "#LaunchWeek", expires: "2025-06-09"},
+		{name: "before the time", tag: "LaunchWeek", expires: now.Add(time.Minute).Format(time.RFC3339), want: "#LaunchWeek"},
+		{name: "at the time", tag: "LaunchWeek", expires: now.Format(time.RFC3339)},
+		{name: "several tags", tag: "#a #b", wantErr: true},
+		{name: "bad expiry", tag: "LaunchWeek", expires: "next week", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := campaignTag(config.Campaign{Tag: tt.tag, Expires: tt.expires}, now)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: campaignTag = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestAddCampaignTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		limit   int
+		want    string
+	}{
+		{name: "fits", message: "Launching today", limit: 100, want: "Launching today\n\n#LaunchWeek"},
+		{name: "already tagged", message: "Launching #launchweek today", limit: 100, want: "Launching #launchweek today"},
+		{name: "does not fit", message: "Launching today", limit: 20, want: "Launching today"},
+	}
+	for _, tt := range tests {
+		got := addCampaignTag(newFakePoster("fake", tt.limit), xpost.Request{Message: tt.message}, dispatchOptions{campaignTag: "#LaunchWeek"}, "")
+		if got.Message != tt.want {
+			t.Errorf("%s: message = %q, want %q", tt.name, got.Message, tt.want)
+		}
+	}
+}
+
+func TestCampaignTagInjected(t *testing.T) {
+	campaign := config.Campaign{Tag: "LaunchWeek", Expires: "2025-06-15"}
+	validators := []xpost.Validator{newFakePoster("mastodon", 500), newFakePoster("twitter", 280)}
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{name: "before expiry", now: time.Date(2025, 6, 15, 23, 0, 0, 0, time.Local), want: "hello\n\n#LaunchWeek"},
+		{name: "after expiry", now: time.Date(2025, 6, 16, 0, 0, 0, 0, time.Local), want: "hello"},
+	}
+	for _, tt := range tests {
+		tag, err := campaignTag(campaign, tt.now)
+		if err != nil {
+			t.Fatalf("%s: campaignTag: %v", tt.name, err)
+		}
+		requests, _, _, errs := prepareRequests(validators, xpost.Request{Message: "hello"}, dispatchOptions{campaignTag: tag})
+		if len(errs) > 0 {
+			t.Fatalf("%s: prepareRequests: %v", tt.name, errs)
+		}
+		for provider, req := range requests {
+			if req.Message != tt.want {
+				t.Errorf("%s: %s message = %q, want %q", tt.name, provider, req.Message, tt.want)
+			}
+		}
+	}
+}
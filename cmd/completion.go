@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/blacktop/xpost/internal/xpost/mastodon"
+	"github.com/spf13/cobra"
+)
+
+// registerTargetCompletion completes cmd's --target flag with the registered
+// providers. Values already given before a comma are kept, and a leading "-"
+// completes the providers to exclude.
+func registerTargetCompletion(cmd *cobra.Command) {
+	cmd.RegisterFlagCompletionFunc("target", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		given, current := "", toComplete
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			given, current = toComplete[:i+1], toComplete[i+1:]
+		}
+		prefix := ""
+		if strings.HasPrefix(current, "-") {
+			prefix = "-"
+		}
+
+		names := xpost.Providers()
+		if prefix == "" {
+			names = append([]string{"all"}, names...)
+		}
+		completions := make([]string, 0, len(names))
+		for _, name := range names {
+			completions = append(completions, given+prefix+name)
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// registerVisibilityCompletion completes --visibility with the values the
+// providers accept.
+func registerVisibilityCompletion(cmd *cobra.Command) {
+	cmd.RegisterFlagCompletionFunc("visibility", cobra.FixedCompletions(mastodon.Visibilities, cobra.ShellCompDirectiveNoFileComp))
+}
@@ -0,0 +1,393 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/xpost/bluesky"
+	"github.com/blacktop/xpost/internal/xpost/discord"
+	"github.com/blacktop/xpost/internal/xpost/lemmy"
+	"github.com/blacktop/xpost/internal/xpost/mastodon"
+	"github.com/blacktop/xpost/internal/xpost/misskey"
+	"github.com/blacktop/xpost/internal/xpost/pixelfed"
+	"github.com/blacktop/xpost/internal/xpost/slack"
+	"github.com/blacktop/xpost/internal/xpost/telegram"
+	"github.com/blacktop/xpost/internal/xpost/twitter"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// wizardStep prompts for and verifies a single provider's credentials.
+type wizardStep struct {
+	name   string
+	prompt func(p *prompter, cfg *config.Config) error
+	verify func(ctx context.Context, cfg *config.Config) (string, error)
+}
+
+var wizardSteps = []wizardStep{
+	{name: "twitter", prompt: promptTwitter, verify: verifyTwitter},
+	{name: "mastodon", prompt: promptMastodon, verify: verifyMastodon},
+	{name: "bluesky", prompt: promptBluesky, verify: verifyBluesky},
+	{name: "lemmy", prompt: promptLemmy, verify: verifyLemmy},
+	{name: "pixelfed", prompt: promptPixelfed, verify: verifyPixelfed},
+	{name: "misskey", prompt: promptMisskey, verify: verifyMisskey},
+	{name: "discord", prompt: promptDiscord, verify: verifyDiscord},
+	{name: "slack", prompt: promptSlack, verify: verifySlack},
+	{name: "telegram", prompt: promptTelegram, verify: verifyTelegram},
+}
+
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the xpost config file",
+	}
+	cmd.AddCommand(newConfigInitCommand())
+	return cmd
+}
+
+func newConfigInitCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "init",
+		Short: "Interactively create or update the config file",
+		Long: "Prompts for each provider's credentials (answer n to skip a provider), " +
+			"verifies them with a read-only API call, and writes the config file. " +
+			"Leaving a prompt empty keeps the existing value.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := resolveConfigPath()
+			if err != nil {
+				return err
+			}
+			return runConfigInit(cmd.Context(), path, cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+}
+
+func runConfigInit(ctx context.Context, path string, in io.Reader, out io.Writer) error {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	p := newPrompter(in, out)
+	fmt.Fprintf(out, "Configuring xpost (%s)\n", path)
+
+	for _, step := range wizardSteps {
+		label := providerLabel(step.name)
+		configure, err := p.confirm(fmt.Sprintf("Configure %s?", label), true)
+		if err != nil {
+			return err
+		}
+		if !configure {
+			continue
+		}
+
+		draft := *cfg
+		if err := step.prompt(p, &draft); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Verifying %s credentials... ", label)
+		check := draft
+		if check.HTTPTimeout, err = requestTimeout(&draft); err != nil {
+			return err
+		}
+		handle, err := step.verify(ctx, &check)
+		if err != nil {
+			fmt.Fprintf(out, "failed: %v\n", err)
+			keep, err := p.confirm("Save these credentials anyway?", false)
+			if err != nil {
+				return err
+			}
+			if !keep {
+				continue
+			}
+		} else {
+			fmt.Fprintf(out, "ok (%s)\n", handle)
+		}
+
+		*cfg = draft
+	}
+
+	if err := config.Save(path, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Wrote %s\n", path)
+
+	return nil
+}
+
+func promptTwitter(p *prompter, cfg *config.Config) error {
+	tw := &cfg.Twitter
+	return p.fill([]promptField{
+		{label: "Consumer key", value: &tw.ConsumerKey},
+		{label: "Consumer secret", value: &tw.ConsumerSecret, secret: true},
+		{label: "Access token", value: &tw.AccessToken},
+		{label: "Access token secret", value: &tw.AccessTokenSecret, secret: true},
+	})
+}
+
+func promptMastodon(p *prompter, cfg *config.Config) error {
+	m := &cfg.Mastodon
+	return p.fill([]promptField{
+		{label: "Server URL", value: &m.Server},
+		{label: "Access token", value: &m.AccessToken, secret: true},
+		{label: "Client ID (optional)", value: &m.ClientID},
+		{label: "Client secret (optional)", value: &m.ClientSecret, secret: true},
+	})
+}
+
+func promptBluesky(p *prompter, cfg *config.Config) error {
+	b := &cfg.Bluesky
+	return p.fill([]promptField{
+		{label: "Handle", value: &b.Handle},
+		{label: "App password", value: &b.AppPassword, secret: true},
+		{label: "PDS URL (optional)", value: &b.PDSURL},
+	})
+}
+
+func promptLemmy(p *prompter, cfg *config.Config) error {
+	l := &cfg.Lemmy
+	return p.fill([]promptField{
+		{label: "Instance URL", value: &l.Instance},
+		{label: "Username", value: &l.Username},
+		{label: "Password", value: &l.Password, secret: true},
+	})
+}
+
+func promptPixelfed(p *prompter, cfg *config.Config) error {
+	pf := &cfg.Pixelfed
+	return p.fill([]promptField{
+		{label: "Server URL", value: &pf.Server},
+		{label: "Access token", value: &pf.AccessToken, secret: true},
+	})
+}
+
+func promptMisskey(p *prompter, cfg *config.Config) error {
+	mk := &cfg.Misskey
+	return p.fill([]promptField{
+		{label: "Server URL", value: &mk.Server},
+		{label: "Access token", value: &mk.Token, secret: true},
+	})
+}
+
+func promptDiscord(p *prompter, cfg *config.Config) error {
+	return p.fill([]promptField{
+		{label: "Webhook URL", value: &cfg.Discord.WebhookURL, secret: true},
+	})
+}
+
+func promptSlack(p *prompter, cfg *config.Config) error {
+	sl := &cfg.Slack
+	return p.fill([]promptField{
+		{label: "Bot token (leave empty to use a webhook)", value: &sl.BotToken, secret: true},
+		{label: "Channel (with a bot token)", value: &sl.Channel},
+		{label: "Webhook URL (without a bot token)", value: &sl.WebhookURL, secret: true},
+	})
+}
+
+func promptTelegram(p *prompter, cfg *config.Config) error {
+	tg := &cfg.Telegram
+	return p.fill([]promptField{
+		{label: "Bot token", value: &tg.BotToken, secret: true},
+		{label: "Chat ID or @channel", value: &tg.ChatID},
+	})
+}
+
+func verifyTwitter(ctx context.Context, cfg *config.Config) (string, error) {
+	client, err := twitter.NewWithConfig(ctx, twitter.Config{
+		APIKey:       cfg.Twitter.ConsumerKey,
+		APISecret:    cfg.Twitter.ConsumerSecret,
+		AccessToken:  cfg.Twitter.AccessToken,
+		AccessSecret: cfg.Twitter.AccessTokenSecret,
+		Timeout:      cfg.HTTPTimeout,
+	})
+	if err != nil {
+		return "", err
+	}
+	return client.Verify(ctx)
+}
+
+func verifyMastodon(ctx context.Context, cfg *config.Config) (string, error) {
+	client := mastodon.NewWithConfig(ctx, mastodon.Config{
+		Server:       cfg.Mastodon.Server,
+		AccessToken:  cfg.Mastodon.AccessToken,
+		ClientID:     cfg.Mastodon.ClientID,
+		ClientSecret: cfg.Mastodon.ClientSecret,
+		Timeout:      cfg.HTTPTimeout,
+	})
+	return client.Verify(ctx)
+}
+
+func verifyBluesky(ctx context.Context, cfg *config.Config) (string, error) {
+	client, err := bluesky.NewWithConfig(ctx, bluesky.Config{
+		Handle:      cfg.Bluesky.Handle,
+		AppPassword: cfg.Bluesky.AppPassword,
+		PDSURL:      cfg.Bluesky.PDSURL,
+		Timeout:     cfg.HTTPTimeout,
+	})
+	if err != nil {
+		return "", err
+	}
+	return client.Verify(ctx)
+}
+
+func verifyLemmy(ctx context.Context, cfg *config.Config) (string, error) {
+	client := lemmy.NewWithConfig(ctx, lemmy.Config{
+		Instance: cfg.Lemmy.Instance,
+		Username: cfg.Lemmy.Username,
+		Password: cfg.Lemmy.Password,
+		Timeout:  cfg.HTTPTimeout,
+	})
+	return client.Verify(ctx)
+}
+
+func verifyPixelfed(ctx context.Context, cfg *config.Config) (string, error) {
+	client := pixelfed.NewWithConfig(ctx, pixelfed.Config{
+		Server:      cfg.Pixelfed.Server,
+		AccessToken: cfg.Pixelfed.AccessToken,
+		Timeout:     cfg.HTTPTimeout,
+	})
+	return client.Verify(ctx)
+}
+
+func verifyMisskey(ctx context.Context, cfg *config.Config) (string, error) {
+	client := misskey.NewWithConfig(ctx, misskey.Config{
+		Server:  cfg.Misskey.Server,
+		Token:   cfg.Misskey.Token,
+		Timeout: cfg.HTTPTimeout,
+	})
+	return client.Verify(ctx)
+}
+
+func verifyDiscord(ctx context.Context, cfg *config.Config) (string, error) {
+	client := discord.NewWithConfig(ctx, discord.Config{WebhookURL: cfg.Discord.WebhookURL, Timeout: cfg.HTTPTimeout})
+	return client.Verify(ctx)
+}
+
+func verifySlack(ctx context.Context, cfg *config.Config) (string, error) {
+	if cfg.Slack.BotToken == "" {
+		return "", errors.New("a webhook cannot be checked without posting")
+	}
+	client := slack.NewWithConfig(ctx, slack.Config{
+		BotToken: cfg.Slack.BotToken,
+		Channel:  cfg.Slack.Channel,
+		Timeout:  cfg.HTTPTimeout,
+	})
+	return client.Verify(ctx)
+}
+
+func verifyTelegram(ctx context.Context, cfg *config.Config) (string, error) {
+	client := telegram.NewWithConfig(ctx, telegram.Config{
+		BotToken: cfg.Telegram.BotToken,
+		ChatID:   cfg.Telegram.ChatID,
+		Timeout:  cfg.HTTPTimeout,
+	})
+	return client.Verify(ctx)
+}
+
+// prompter reads answers line by line, masking secrets when attached to a terminal.
+type prompter struct {
+	in  *bufio.Reader
+	tty *os.File
+	out io.Writer
+}
+
+type promptField struct {
+	label  string
+	value  *string
+	secret bool
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	p := &prompter{in: bufio.NewReader(in), out: out}
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		p.tty = f
+	}
+	return p
+}
+
+// fill prompts for each field in turn; an empty answer keeps the current value.
+func (p *prompter) fill(fields []promptField) error {
+	for _, f := range fields {
+		var (
+			answer string
+			err    error
+		)
+		if f.secret {
+			answer, err = p.secret(f.label, *f.value != "")
+		} else {
+			answer, err = p.ask(f.label, *f.value)
+		}
+		if err != nil {
+			return err
+		}
+		if answer != "" {
+			*f.value = answer
+		}
+	}
+	return nil
+}
+
+func (p *prompter) ask(label, current string) (string, error) {
+	if current != "" {
+		fmt.Fprintf(p.out, "  %s [%s]: ", label, current)
+	} else {
+		fmt.Fprintf(p.out, "  %s: ", label)
+	}
+	return p.readLine()
+}
+
+func (p *prompter) secret(label string, hasCurrent bool) (string, error) {
+	if hasCurrent {
+		fmt.Fprintf(p.out, "  %s [keep existing]: ", label)
+	} else {
+		fmt.Fprintf(p.out, "  %s: ", label)
+	}
+	if p.tty == nil {
+		return p.readLine()
+	}
+	data, err := term.ReadPassword(int(p.tty.Fd()))
+	fmt.Fprintln(p.out)
+	if err != nil {
+		return "", fmt.Errorf("read input: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (p *prompter) confirm(label string, def bool) (bool, error) {
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	fmt.Fprintf(p.out, "%s %s ", label, hint)
+	answer, err := p.readLine()
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (p *prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		if errors.Is(err, io.EOF) {
+			return "", errors.New("read input: unexpected end of input")
+		}
+		return "", fmt.Errorf("read input: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/config"
+)
+
+// stubWizardVerify replaces every step's verification for the duration of
+// the test; failing names the providers whose check fails.
+func stubWizardVerify(t *testing.T, failing ...string) {
+	t.Helper()
+	saved := wizardSteps
+	t.Cleanup(func() { wizardSteps = saved })
+
+	steps := make([]wizardStep, len(saved))
+	copy(steps, saved)
+	for i := range steps {
+		name := steps[i].name
+		steps[i].verify = func(context.Context, *config.Config) (string, error) {
+			for _, f := range failing {
+				if f == name {
+					return "", errors.New("401 Unauthorized")
+				}
+			}
+			return "@tester", nil
+		}
+	}
+	wizardSteps = steps
+}
+
+// wizardInput answers the per-provider "Configure?" prompts in step order;
+// answers maps a provider to the lines typed after saying yes.
+func wizardInput(answers map[string][]string) string {
+	var lines []string
+	for _, step := range wizardSteps {
+		a, ok := answers[step.name]
+		if !ok {
+			lines = append(lines, "n")
+			continue
+		}
+		lines = append(lines, "y")
+		lines = append(lines, a...)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestConfigInitWritesConfig(t *testing.T) {
+	stubWizardVerify(t, "bluesky")
+	path := filepath.Join(t.TempDir(), "xpost", "config.yaml")
+
+	in := wizardInput(map[string][]string{
+		"twitter":  {"ck", "cs", "at", "ats"},
+		"mastodon": {"https://mastodon.example", "token", "", ""},
+		// Verification fails and the credentials are not kept.
+		"bluesky": {"me.example.com", "abcd-efgh-ijkl-mnop", "", "n"},
+	})
+	var out bytes.Buffer
+	if err := runConfigInit(context.Background(), path, strings.NewReader(in), &out); err != nil {
+		t.Fatalf("runConfigInit: %v\n%s", err, out.String())
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config.Twitter{ConsumerKey: "ck", ConsumerSecret: "cs", AccessToken: "at", AccessTokenSecret: "ats"}
+	if cfg.Twitter.ConsumerKey != want.ConsumerKey || cfg.Twitter.ConsumerSecret != want.ConsumerSecret ||
+		cfg.Twitter.AccessToken != want.AccessToken || cfg.Twitter.AccessTokenSecret != want.AccessTokenSecret {
+		t.Errorf("twitter = %+v, want %+v", cfg.Twitter, want)
+	}
+	if cfg.Mastodon.Server != "https://mastodon.example" || cfg.Mastodon.AccessToken != "token" || cfg.Mastodon.ClientID != "" {
+		t.Errorf("mastodon = %+v", cfg.Mastodon)
+	}
+	if cfg.Bluesky.Handle != "" || cfg.Bluesky.AppPassword != "" {
+		t.Errorf("bluesky credentials that failed verification were saved: %+v", cfg.Bluesky)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"twitter:\n", "  consumer_key: ck\n", "mastodon:\n", "  server: https://mastodon.example\n"} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("config.yaml missing %q:\n%s", s, data)
+		}
+	}
+	if strings.Contains(string(data), "bluesky") {
+		t.Errorf("config.yaml has a bluesky section:\n%s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("config.yaml mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+	if !strings.Contains(out.String(), "Verifying Bluesky credentials... failed: 401 Unauthorized") {
+		t.Errorf("output does not report the failed check:\n%s", out.String())
+	}
+}
+
+func TestConfigInitKeepsExistingValues(t *testing.T) {
+	stubWizardVerify(t, "bluesky")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	existing := &config.Config{
+		Mastodon: config.Mastodon{Server: "https://old.example", AccessToken: "old-token"},
+		Bluesky:  config.Bluesky{Handle: "old.bsky.social"},
+	}
+	if err := config.Save(path, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	in := wizardInput(map[string][]string{
+		// Empty answers keep the server and token; only the client ID changes.
+		"mastodon": {"", "", "client", ""},
+		// A failed check can still be saved when confirmed.
+		"bluesky": {"", "abcd-efgh-ijkl-mnop", "", "y"},
+	})
+	if err := runConfigInit(context.Background(), path, strings.NewReader(in), &bytes.Buffer{}); err != nil {
+		t.Fatalf("runConfigInit: %v", err)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Mastodon.Server != "https://old.example" || cfg.Mastodon.AccessToken != "old-token" || cfg.Mastodon.ClientID != "client" {
+		t.Errorf("mastodon = %+v", cfg.Mastodon)
+	}
+	if cfg.Bluesky.Handle != "old.bsky.social" || cfg.Bluesky.AppPassword != "abcd-efgh-ijkl-mnop" {
+		t.Errorf("bluesky = %+v", cfg.Bluesky)
+	}
+}
+
+func TestConfigInitTruncatedInput(t *testing.T) {
+	stubWizardVerify(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	// Input ends in the middle of the Twitter prompts.
+	err := runConfigInit(context.Background(), path, strings.NewReader("y\nck\n"), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "unexpected end of input") {
+		t.Fatalf("err = %v, want unexpected end of input", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("config written despite the aborted wizard: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/blacktop/xpost/internal/xpost"
+	"golang.org/x/term"
+)
+
+// errCancelled is returned when the user declines the --confirm prompt.
+var errCancelled = errors.New("posting cancelled")
+
+// canConfirm reports whether a --confirm prompt read from in can be
+// answered, which needs in to be a terminal rather than a pipe.
+func canConfirm(in io.Reader) bool {
+	f, ok := in.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// confirmPosting shows what will be posted where and asks for a yes on out.
+// Anything but y or yes declines, as does cancelling ctx.
+func confirmPosting(ctx context.Context, in io.Reader, out io.Writer, posters []xpost.Poster, requests map[string]xpost.Request) error {
+	for _, poster := range posters {
+		preq := requests[poster.Name()]
+		message := preq.Message
+		if preq.Link != "" {
+			message = message + "\n\n" + preq.Link
+		}
+		fmt.Fprintf(out, "%s: %q\n", styledProvider(poster.Name(), out), message)
+		if n := len(preq.Images); n > 0 {
+			fmt.Fprintf(out, "  with %d attachment(s)\n", n)
+		}
+	}
+
+	if len(posters) == 1 {
+		fmt.Fprint(out, "Post to this network? [y/N] ")
+	} else {
+		fmt.Fprintf(out, "Post to these %d networks? [y/N] ", len(posters))
+	}
+	answer := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(in).ReadString('\n')
+		answer <- line
+	}()
+	var line string
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(out)
+	case line = <-answer:
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return nil
+	}
+	fmt.Fprintln(out, "Cancelled; nothing was posted")
+	return errCancelled
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/spf13/cobra"
+)
+
+func newCountCommand() *cobra.Command {
+	var (
+		countTargets []string
+		fromFile     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "count [message]",
+		Short: "Count a message's length against each provider's limit",
+		Long: "count prints how long the message is as each provider measures it, and how much " +
+			"of the limit is left. It needs no credentials or network access, except that " +
+			"Mastodon's limit is read from the instance when its credentials are configured; " +
+			"otherwise the default is shown. A --from-file message may start with front " +
+			"matter; its link and cw are counted too.",
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			var req xpost.Request
+			switch {
+			case len(args) == 1 && fromFile != "":
+				return errors.New("provide either a message or --from-file, not both")
+			case len(args) == 1:
+				req.Message = args[0]
+			case fromFile != "":
+				data, err := os.ReadFile(fromFile)
+				if err != nil {
+					return fmt.Errorf("read message file: %w", err)
+				}
+				meta, body, err := splitFrontMatter(data)
+				if err != nil {
+					return fmt.Errorf("parse front matter in %s: %w", fromFile, err)
+				}
+				req.Message = strings.TrimSpace(string(body))
+				req.Link = meta.Link
+				req.ContentWarning = meta.CW
+			default:
+				return errors.New("a message or --from-file is required")
+			}
+
+			targets := xpost.Providers()
+			if len(countTargets) > 0 {
+				var err error
+				if targets, err = normalizeTargets(countTargets); err != nil {
+					return err
+				}
+			}
+
+			tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "PROVIDER\tLIMIT\tUSED\tREMAINING")
+			var over []string
+			for _, target := range targets {
+				validator, err := xpost.NewValidator(target)
+				if err != nil {
+					return err
+				}
+				if !countRequest(validator, liveLimit(cmd.Context(), target), req, tw) {
+					over = append(over, target)
+				}
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			if len(over) > 0 {
+				return fmt.Errorf("message too long for %s", strings.Join(over, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&countTargets, "target", nil, "Providers to count for (default all)")
+	cmd.Flags().StringVar(&fromFile, "from-file", "", "Read the message from a file")
+	registerTargetCompletion(cmd)
+
+	return cmd
+}
+
+// liveLimit reads the character limit target's server reports, using the
+// configured credentials. It returns zero when target's limit is fixed, or
+// when it cannot be read and the default applies.
+func liveLimit(ctx context.Context, target string) int {
+	if !features(target).LiveLimit {
+		return 0
+	}
+	caps, err := probeLimit(ctx, target)
+	if err != nil {
+		logutil.Debugf("%s: counting against the default limit: %v", target, err)
+		return 0
+	}
+	return caps.MaxChars
+}
+
+func probeLimit(ctx context.Context, target string) (xpost.Capabilities, error) {
+	if err := loadSecrets(ctx); err != nil {
+		return xpost.Capabilities{}, err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return xpost.Capabilities{}, err
+	}
+	poster, err := xpost.New(ctx, target, cfg)
+	if err != nil {
+		return xpost.Capabilities{}, err
+	}
+	defer xpost.Close(ctx, poster)
+
+	prober, ok := poster.(xpost.Prober)
+	if !ok {
+		return xpost.Capabilities{}, errors.New("the limit is not reported")
+	}
+	caps, err := prober.Capabilities(ctx)
+	if err == nil && (!caps.Live || caps.MaxChars <= 0) {
+		err = errors.New("the instance does not publish its limit")
+	}
+	return caps, err
+}
+
+// countRequest writes req's length as v measures it as a row of the count
+// table and reports whether it fits. live is the limit the server reported;
+// without one, a limit the server may change is labelled as the default.
+func countRequest(v xpost.Validator, live int, req xpost.Request, out io.Writer) bool {
+	label := providerLabel(v.Name())
+	counter, ok := v.(xpost.Counter)
+	if !ok {
+		fmt.Fprintf(out, "%s\tnone\t-\t-\n", label)
+		return true
+	}
+
+	count, limit, unit := counter.Count(req)
+	if live > 0 {
+		limit = live
+	}
+	limitText := fmt.Sprintf("%d %s", limit, unit)
+	if features(v.Name()).LiveLimit && live == 0 {
+		limitText += " (default)"
+	}
+	remaining := strconv.Itoa(limit - count)
+	if count > limit {
+		remaining = fmt.Sprintf("%d over", count-limit)
+	}
+	fmt.Fprintf(out, "%s\t%s\t%d\t%s\n", label, limitText, count, remaining)
+	return count <= limit
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestCountMixedMessage(t *testing.T) {
+	// A ZWJ family, CJK, a URL and a flag: each provider counts them
+	// differently.
+	const message = "Hi 👨‍👩‍👧 日本 https://example.com/a 🇯🇵!"
+	tests := []struct {
+		provider string
+		req      xpost.Request
+		want     int
+		unit     string
+	}{
+		// Emoji and CJK weigh 2, the URL counts as 23.
+		{provider: "twitter", req: xpost.Request{Message: message}, want: 38, unit: "characters"},
+		{provider: "twitter", req: xpost.Request{Message: message, Link: "https://go.dev"}, want: 38 + 2 + 23, unit: "characters"},
+		// Code points, with the content warning included.
+		{provider: "mastodon", req: xpost.Request{Message: message}, want: 37, unit: "characters"},
+		{provider: "mastodon", req: xpost.Request{Message: message, ContentWarning: "日本"}, want: 39, unit: "characters"},
+		// Graphemes.
+		{provider: "bluesky", req: xpost.Request{Message: message}, want: 32, unit: "graphemes"},
+		{provider: "bluesky", req: xpost.Request{Message: message, Link: "https://go.dev"}, want: 32 + 2 + 14, unit: "graphemes"},
+	}
+	for _, tt := range tests {
+		v, err := xpost.NewValidator(tt.provider)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _, unit := v.(xpost.Counter).Count(tt.req)
+		if got != tt.want || unit != tt.unit {
+			t.Errorf("%s: Count(%+v) = %d %s, want %d %s", tt.provider, tt.req, got, unit, tt.want, tt.unit)
+		}
+	}
+}
+
+func TestCountCommand(t *testing.T) {
+	// Without credentials, Mastodon's limit is the default.
+	oldPath := configPath
+	t.Cleanup(func() { configPath = oldPath })
+	configPath = filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("XPOST_MASTODON_SERVER", "")
+	t.Setenv("XPOST_MASTODON_ACCESS_TOKEN", "")
+
+	instance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/instance" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"configuration": {"statuses": {"max_characters": 1000}}}`)
+	}))
+	t.Cleanup(instance.Close)
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "fits everywhere",
+			args: []string{"--target", "twitter,mastodon,bluesky", "Hi 日本 https://example.com/a"},
+			want: []string{
+				"PROVIDER LIMIT USED REMAINING",
+				"Twitter/X 280 characters 31 249",
+				"Mastodon 500 characters (default) 27 473",
+				"Bluesky 300 graphemes 27 273",
+			},
+		},
+		{
+			name:    "over on twitter only",
+			args:    []string{"--target", "twitter,bluesky", strings.Repeat("日", 150)},
+			want:    []string{"Twitter/X 280 characters 300 20 over", "Bluesky 300 graphemes 150 150"},
+			wantErr: "message too long for twitter",
+		},
+		{
+			name: "live mastodon limit",
+			env:  map[string]string{"XPOST_MASTODON_SERVER": instance.URL, "XPOST_MASTODON_ACCESS_TOKEN": "token"},
+			args: []string{"--target", "mastodon", strings.Repeat("a", 600)},
+			want: []string{"Mastodon 1000 characters 600 400"},
+		},
+		{name: "no message", args: []string{"--target", "twitter"}, wantErr: "a message or --from-file is required"},
+	}
+	for _, tt := range tests {
+		for key, value := range tt.env {
+			t.Setenv(key, value)
+		}
+		var out bytes.Buffer
+		cmd := newCountCommand()
+		cmd.SetOut(&out)
+		cmd.SetArgs(tt.args)
+		err := cmd.Execute()
+		if (err == nil && tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+			t.Errorf("%s: count = %v, want error %q", tt.name, err, tt.wantErr)
+		}
+		// The columns line up; compare the rows with the padding collapsed.
+		rows := regexp.MustCompile(` {2,}`).ReplaceAllString(out.String(), " ")
+		for _, want := range tt.want {
+			if !strings.Contains(rows, want+"\n") {
+				t.Errorf("%s: output %q lacks the row %q", tt.name, out.String(), want)
+			}
+		}
+		if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) > 1 {
+			if col := strings.Index(lines[0], "LIMIT"); col < 0 || strings.Index(lines[1], strings.Fields(lines[1][col:])[0]) != col {
+				t.Errorf("%s: columns not aligned:\n%s", tt.name, out.String())
+			}
+		}
+	}
+}
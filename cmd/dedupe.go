@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/state"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// dedupeKey hashes what a target would post: its message, link, quote,
+// reply parent, images and poll.
+func dedupeKey(target, message, replyTo string, req xpost.Request) string {
+	parts := []string{target, message, req.Link, req.Quote, replyTo}
+	for _, img := range req.Images {
+		parts = append(parts, img.Path, img.Alt)
+	}
+	if req.Poll != nil {
+		parts = append(parts, req.Poll.Options...)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// skipDuplicates drops the targets that already posted the same content
+// within window, reporting each one to out.
+func skipDuplicates(targets []string, keys map[string]string, window time.Duration, now time.Time, out io.Writer) ([]string, error) {
+	path, err := state.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	st, err := state.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var remaining []string
+	for _, target := range targets {
+		last, ok := st.LastPosted(target, keys[target], now.Add(-window))
+		if !ok {
+			remaining = append(remaining, target)
+			continue
+		}
+		ago := now.Sub(last.PostedAt).Round(time.Second)
+		if last.URL != "" {
+			fmt.Fprintf(out, "Skipping %s: posted the same %s ago (%s); use --force to post again\n", styledProvider(target, out), ago, last.URL)
+		} else {
+			fmt.Fprintf(out, "Skipping %s: posted the same %s ago; use --force to post again\n", styledProvider(target, out), ago)
+		}
+	}
+	return remaining, nil
+}
+
+// recordPosted adds the successfully published posts to the state file,
+// dropping entries older than window.
+func recordPosted(outcomes []postOutcome, keys map[string]string, window time.Duration, now time.Time) error {
+	path, err := state.DefaultPath()
+	if err != nil {
+		return err
+	}
+	st, err := state.Load(path)
+	if err != nil {
+		return err
+	}
+
+	st.PrunePosted(now.Add(-window))
+	for _, o := range outcomes {
+		if !o.posted() {
+			continue
+		}
+		st.Posted = append(st.Posted, state.Posted{
+			Provider: o.provider,
+			Key:      keys[o.provider],
+			URL:      o.result.URL,
+			PostedAt: now,
+		})
+	}
+	logutil.Debugf("dedupe: %d recent posts remembered", len(st.Posted))
+	return state.Save(path, st)
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/spf13/cobra"
+)
+
+func newDeleteCommand() *cobra.Command {
+	var deleteDryRun bool
+
+	cmd := &cobra.Command{
+		Use:     "delete RESULTS_FILE",
+		Aliases: []string{"undo"},
+		Short:   "Delete the posts recorded in a --results file",
+		Long: "delete retracts a cross-post by deleting every post listed in the " +
+			"JSON file written with --results. Failed and scheduled entries are skipped.",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logutil.SetVerbose(verbose)
+
+			if err := loadSecrets(ctx); err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			return runDelete(ctx, cfg, args[0], deleteDryRun, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().BoolVar(&deleteDryRun, "dry-run", false, "List the posts without deleting them")
+
+	return cmd
+}
+
+func runDelete(ctx context.Context, cfg *config.Config, path string, dryRun bool, out io.Writer) error {
+	summary, err := readResults(path)
+	if err != nil {
+		return err
+	}
+
+	posters := map[string]xpost.Poster{}
+	defer func() { closePosters(ctx, slices.Collect(maps.Values(posters))) }()
+
+	var errs []error
+	deleted := 0
+	for _, r := range summary.Results {
+		label := r.URL
+		if label == "" {
+			label = r.ID
+		}
+		switch {
+		case !r.Success || r.ID == "":
+			continue
+		case r.Scheduled:
+			logutil.Debugf("%s: skipping scheduled post %s", r.Provider, r.ID)
+			continue
+		case dryRun:
+			fmt.Fprintf(out, "[dry-run] would delete %s post %s\n", styledProvider(r.Provider, out), label)
+			continue
+		}
+
+		deleter, err := deleterFor(ctx, cfg, posters, r.Provider)
+		if err == nil {
+			err = deleter.Delete(ctx, r.ID)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", r.Provider, label, err))
+			continue
+		}
+		fmt.Fprintf(out, "Deleted %s post %s\n", styledProvider(r.Provider, out), label)
+		deleted++
+	}
+
+	if len(errs) == 0 && deleted == 0 && !dryRun {
+		fmt.Fprintln(out, "Nothing to delete")
+	}
+	for _, err := range errs {
+		fmt.Fprintf(out, "error: %v\n", err)
+	}
+	return errors.Join(errs...)
+}
+
+// writeResults saves a JSON summary of outcomes to path for xpost delete.
+func writeResults(path string, outcomes []postOutcome) error {
+	data, err := json.MarshalIndent(summarize(outcomes), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func readResults(path string) (outcomeSummary, error) {
+	var summary outcomeSummary
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return summary, fmt.Errorf("read results: %w", err)
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return summary, fmt.Errorf("parse results %s: %w", path, err)
+	}
+	return summary, nil
+}
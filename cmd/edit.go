@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/spf13/cobra"
+)
+
+func newEditCommand() *cobra.Command {
+	var editTargets []string
+
+	cmd := &cobra.Command{
+		Use:   "edit RESULTS_FILE [message]",
+		Short: "Edit the posts recorded in a --results file",
+		Long: "edit replaces the text, and optionally the images, of every post listed in " +
+			"the JSON file written with --results, on the networks that allow editing " +
+			"(Mastodon). Images and content warnings are kept unless new ones are given.",
+		Args:          cobra.MinimumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logutil.SetVerbose(verbose)
+
+			if err := loadSecrets(ctx); err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			message, err := resolveMessage(cmd, args[1:])
+			if err != nil {
+				return err
+			}
+			images, err := buildImages(imagePaths, imageAlts, "")
+			if err != nil {
+				return err
+			}
+			applyDefaultAlt(images)
+			req := xpost.Request{
+				Message:        message,
+				Images:         images,
+				ContentWarning: strings.TrimSpace(contentWarn),
+			}
+
+			var only []string
+			if len(editTargets) > 0 {
+				if only, err = normalizeTargets(editTargets); err != nil {
+					return err
+				}
+			}
+			return runEdit(ctx, cfg, args[0], req, only, dryRun, cmd.OutOrStdout())
+		},
+		Example: `  xpost "Release shipped" --results last.json
+  xpost edit last.json "Release shipped (now with notes)"`,
+	}
+
+	cmd.Flags().StringVarP(&messageFlag, "message", "m", "", "New message text")
+	cmd.Flags().StringArrayVar(&imagePaths, "image", nil, "Replace the images with this one (repeatable)")
+	cmd.Flags().StringArrayVar(&imageAlts, "alt-text", nil, "Alternative text for the image given in the same position (repeatable)")
+	cmd.Flags().StringVar(&contentWarn, "cw", "", "Replace the content warning")
+	cmd.Flags().StringSliceVar(&editTargets, "target", nil, "Only edit the posts on these providers (default all in the file)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the posts without editing them")
+	registerTargetCompletion(cmd)
+	cmd.Flags().SortFlags = false
+
+	return cmd
+}
+
+// runEdit applies req to each successful post in the results file at path,
+// restricted to the providers in only when it is set.
+func runEdit(ctx context.Context, cfg *config.Config, path string, req xpost.Request, only []string, dryRun bool, out io.Writer) error {
+	summary, err := readResults(path)
+	if err != nil {
+		return err
+	}
+
+	posters := map[string]xpost.Poster{}
+	defer func() { closePosters(ctx, slices.Collect(maps.Values(posters))) }()
+
+	var errs []error
+	edited := 0
+	for _, r := range summary.Results {
+		label := r.URL
+		if label == "" {
+			label = r.ID
+		}
+		switch {
+		case !r.Success || r.ID == "":
+			continue
+		case only != nil && !slices.Contains(only, r.Provider):
+			continue
+		case r.Scheduled:
+			logutil.Debugf("%s: skipping scheduled post %s", r.Provider, r.ID)
+			continue
+		}
+
+		validator, err := xpost.NewValidator(r.Provider)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", r.Provider, label, err))
+			continue
+		}
+		if _, ok := validator.(xpost.Editor); !ok {
+			fmt.Fprintf(out, "%s: edit not supported; %s left as is\n", styledProvider(r.Provider, out), label)
+			continue
+		}
+		if err := validator.Validate(req); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", r.Provider, label, err))
+			continue
+		}
+		if dryRun {
+			fmt.Fprintf(out, "[dry-run] would edit %s post %s: %q\n", styledProvider(r.Provider, out), label, req.Message)
+			continue
+		}
+
+		editor, err := editorFor(ctx, cfg, posters, r.Provider)
+		if err == nil {
+			_, err = editor.Edit(ctx, r.ID, req)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", r.Provider, label, err))
+			continue
+		}
+		fmt.Fprintf(out, "Edited %s post %s\n", styledProvider(r.Provider, out), label)
+		edited++
+	}
+
+	if len(errs) == 0 && edited == 0 && !dryRun {
+		fmt.Fprintln(out, "Nothing edited")
+	}
+	for _, err := range errs {
+		fmt.Fprintf(out, "error: %v\n", err)
+	}
+	return errors.Join(errs...)
+}
+
+// editorFor builds (and caches) the poster for provider as an Editor.
+func editorFor(ctx context.Context, cfg *config.Config, cache map[string]xpost.Poster, provider string) (xpost.Editor, error) {
+	poster, ok := cache[provider]
+	if !ok {
+		posters, err := buildPosters(ctx, cfg, []string{provider})
+		if err != nil {
+			return nil, err
+		}
+		poster = posters[0]
+		cache[provider] = poster
+	}
+	editor, ok := poster.(xpost.Editor)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support editing posts", provider)
+	}
+	return editor, nil
+}
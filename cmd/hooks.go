@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/blacktop/xpost/internal/logutil"
+)
+
+const hookTimeout = 30 * time.Second
+
+// shellCommand runs command through the platform shell. On Unix, args are
+// available to it as $1, $2, ...
+func shellCommand(ctx context.Context, command string, args ...string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", append([]string{"-c", command, "xpost"}, args...)...)
+}
+
+// runHooks runs the --on-success or --on-failure command once for each
+// outcome, passing the provider and post URL as $1 and $2 and in XPOST_HOOK_*
+// variables. Hook failures are logged and never fail the run.
+func runHooks(ctx context.Context, onSuccess, onFailure string, outcomes []postOutcome) {
+	for _, o := range outcomes {
+		command := onSuccess
+		if o.err != nil {
+			command = onFailure
+		}
+		if command == "" {
+			continue
+		}
+		if err := runHook(ctx, command, o); err != nil {
+			logutil.Errorf("%s hook: %v", o.provider, err)
+		}
+	}
+}
+
+func runHook(ctx context.Context, command string, o postOutcome) error {
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	c := shellCommand(ctx, command, o.provider, o.result.URL)
+	c.Env = append(os.Environ(),
+		"XPOST_HOOK_PROVIDER="+o.provider,
+		"XPOST_HOOK_ID="+o.result.ID,
+		"XPOST_HOOK_URL="+o.result.URL,
+	)
+	if o.err != nil {
+		c.Env = append(c.Env, "XPOST_HOOK_ERROR="+o.err.Error())
+	}
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+
+	err := c.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", hookTimeout)
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks use sh")
+	}
+	log := filepath.Join(t.TempDir(), "hooks.log")
+	onSuccess := `echo "ok $1 $2 $XPOST_HOOK_ID" >> ` + log
+	onFailure := `echo "failed $1 [$2] $XPOST_HOOK_ERROR" >> ` + log
+
+	tests := []struct {
+		name      string
+		onSuccess string
+		onFailure string
+		outcomes  []postOutcome
+		want      []string
+	}{
+		{
+			name:      "success hook gets the URL",
+			onSuccess: onSuccess,
+			onFailure: onFailure,
+			outcomes:  []postOutcome{{provider: "mastodon", result: xpost.PostResult{ID: "101", URL: "https://example.social/@me/101"}}},
+			want:      []string{"ok mastodon https://example.social/@me/101 101"},
+		},
+		{
+			name:      "failure hook gets the error",
+			onSuccess: onSuccess,
+			onFailure: onFailure,
+			outcomes:  []postOutcome{{provider: "bluesky", err: errors.New("rate limited")}},
+			want:      []string{"failed bluesky [] rate limited"},
+		},
+		{
+			name:      "each provider in turn",
+			onSuccess: onSuccess,
+			onFailure: onFailure,
+			outcomes: []postOutcome{
+				{provider: "bluesky", err: errors.New("boom")},
+				{provider: "twitter", result: xpost.PostResult{ID: "1", URL: "https://x.com/i/status/1"}},
+			},
+			want: []string{"failed bluesky [] boom", "ok twitter https://x.com/i/status/1 1"},
+		},
+		{
+			name:      "only a failure hook",
+			onFailure: onFailure,
+			outcomes:  []postOutcome{{provider: "twitter", result: xpost.PostResult{ID: "1"}}},
+		},
+		{
+			// A failing hook is logged and the next one still runs.
+			name:      "failing hook",
+			onSuccess: "exit 3",
+			onFailure: onFailure,
+			outcomes: []postOutcome{
+				{provider: "twitter", result: xpost.PostResult{ID: "1"}},
+				{provider: "bluesky", err: errors.New("boom")},
+			},
+			want: []string{"failed bluesky [] boom"},
+		},
+	}
+	for _, tt := range tests {
+		os.Remove(log)
+		runHooks(context.Background(), tt.onSuccess, tt.onFailure, tt.outcomes)
+
+		data, err := os.ReadFile(log)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		var got []string
+		if s := strings.TrimSpace(string(data)); s != "" {
+			got = strings.Split(s, "\n")
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: hooks ran %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// languageTagRegex matches the BCP 47 tags Mastodon understands: a two or
+// three letter language, optionally followed by a script and a region.
+var languageTagRegex = regexp.MustCompile(`^[a-z]{2,3}(-[a-z]{4})?(-([a-z]{2}|[0-9]{3}))?$`)
+
+// normalizeLanguage validates a --lang value and returns it in canonical
+// case (e.g. "pt-BR", "zh-Hant").
+func normalizeLanguage(raw string) (string, error) {
+	tag := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(raw), "_", "-"))
+	if tag == "" {
+		return "", nil
+	}
+	if !languageTagRegex.MatchString(tag) {
+		return "", fmt.Errorf("invalid --lang %q: expected a BCP 47 code such as en, de or pt-BR", raw)
+	}
+	parts := strings.Split(tag, "-")
+	for i, part := range parts[1:] {
+		switch len(part) {
+		case 4:
+			parts[i+1] = strings.ToUpper(part[:1]) + part[1:]
+		case 2:
+			parts[i+1] = strings.ToUpper(part)
+		}
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+// scriptLanguages maps the scripts written in (practically) one language to
+// that language. Latin, Cyrillic and Arabic text is left undetected.
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	lang   string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Armenian, "hy"},
+	{unicode.Georgian, "ka"},
+	{unicode.Han, "zh"},
+}
+
+// detectLanguage guesses the message's language from the script most of its
+// letters are written in, or returns "" when that script does not identify
+// one. Han characters alongside kana are taken as Japanese.
+func detectLanguage(text string) string {
+	counts := make([]int, len(scriptLanguages))
+	letters, kana, han := 0, 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		}
+		for i, s := range scriptLanguages {
+			if unicode.Is(s.script, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	if kana > 0 && (kana+han)*2 > letters {
+		return "ja"
+	}
+	for i, s := range scriptLanguages {
+		if counts[i]*2 > letters {
+			return s.lang
+		}
+	}
+	return ""
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost/bluesky"
+	"github.com/blacktop/xpost/internal/xpost/twitter"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultTwitterRedirectURL = "http://127.0.0.1:8910/callback"
+	blueskyAppPasswordsURL    = "https://bsky.app/settings/app-passwords"
+)
+
+func newLoginCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Sign in to a provider that needs an interactive login",
+	}
+	cmd.AddCommand(newLoginBlueskyCommand())
+	cmd.AddCommand(newLoginTwitterCommand())
+	return cmd
+}
+
+func newLoginBlueskyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "bluesky",
+		Short: "Sign in to Bluesky with an app password and save it",
+		Long: "login bluesky prompts for your handle and an app password, signs in to check " +
+			"them, caches the session and writes them to the config file.",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logutil.SetVerbose(verbose)
+
+			path, err := resolveConfigPath()
+			if err != nil {
+				return err
+			}
+			return runLoginBluesky(cmd.Context(), path, cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+}
+
+func runLoginBluesky(ctx context.Context, path string, in io.Reader, out io.Writer) error {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	p := newPrompter(in, out)
+	fmt.Fprintln(out, "Sign in to Bluesky")
+	if err := promptBluesky(p, cfg); err != nil {
+		return err
+	}
+	if cfg.Bluesky.Handle == "" || cfg.Bluesky.AppPassword == "" {
+		return errors.New("a handle and app password are required")
+	}
+
+	if !bluesky.LooksLikeAppPassword(cfg.Bluesky.AppPassword) {
+		fmt.Fprintln(out, "That does not look like an app password (xxxx-xxxx-xxxx-xxxx). xpost should not be given")
+		fmt.Fprintf(out, "your account password; create an app password at %s\n", blueskyAppPasswordsURL)
+		proceed, err := p.confirm("Use it anyway?", false)
+		if err != nil {
+			return err
+		}
+		if !proceed {
+			return errors.New("login cancelled")
+		}
+	}
+
+	// XPOST_BLUESKY_SESSION_FILE may move the session cache, but the
+	// environment is not saved to the file.
+	resolved := *cfg
+	if err := resolved.ApplyEnv(os.LookupEnv); err != nil {
+		return err
+	}
+	timeout, err := requestTimeout(&resolved)
+	if err != nil {
+		return err
+	}
+	client, err := bluesky.Login(ctx, bluesky.Config{
+		Handle:      cfg.Bluesky.Handle,
+		AppPassword: cfg.Bluesky.AppPassword,
+		PDSURL:      cfg.Bluesky.PDSURL,
+		SessionFile: resolved.Bluesky.SessionFile,
+		Timeout:     timeout,
+	})
+	if err != nil {
+		return err
+	}
+	handle, err := client.Verify(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Signed in as %s\n", handle)
+
+	if err := config.Save(path, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Wrote %s\n", path)
+	return nil
+}
+
+func newLoginTwitterCommand() *cobra.Command {
+	var redirectURL string
+
+	cmd := &cobra.Command{
+		Use:   "twitter",
+		Short: "Authorize xpost on X with OAuth 2.0 and save the token",
+		Long: "login twitter runs the OAuth 2.0 authorization code flow with PKCE for the app " +
+			"in XPOST_TWITTER_CLIENT_ID or client_id under twitter in the config (and the client " +
+			"secret for confidential apps). The redirect URL must be registered with the app. " +
+			"The token is saved to XPOST_TWITTER_TOKEN_FILE or token_file (default " +
+			"~/.config/xpost/twitter-token.json), refreshed automatically, and used when " +
+			"XPOST_TWITTER_AUTH=oauth2.",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logutil.SetVerbose(verbose)
+
+			if err := loadSecrets(ctx); err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			app := twitter.Config{
+				ClientID:     strings.TrimSpace(cfg.Twitter.ClientID),
+				ClientSecret: strings.TrimSpace(cfg.Twitter.ClientSecret),
+				TokenFile:    strings.TrimSpace(cfg.Twitter.TokenFile),
+				Timeout:      cfg.HTTPTimeout,
+			}
+			return runLoginTwitter(ctx, app, redirectURL, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&redirectURL, "redirect-url", defaultTwitterRedirectURL, "Local callback URL registered with the X app")
+
+	return cmd
+}
+
+func runLoginTwitter(ctx context.Context, app twitter.Config, redirectURL string, out io.Writer) error {
+	redirect, err := url.Parse(redirectURL)
+	if err != nil || redirect.Scheme != "http" || redirect.Port() == "" {
+		return fmt.Errorf("invalid --redirect-url %q: expected a local http URL with a port", redirectURL)
+	}
+
+	login, err := twitter.NewOAuth2Login(app, redirectURL)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return fmt.Errorf("listen for the redirect: %w", err)
+	}
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	// Only the first redirect counts; later ones must not block the handler.
+	report := func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(redirect.Path, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case query.Get("state") != login.State:
+			http.Error(w, "state mismatch", http.StatusBadRequest)
+			report(errors.New("authorization failed: state mismatch"))
+		case query.Get("error") != "":
+			http.Error(w, "authorization denied", http.StatusBadRequest)
+			report(fmt.Errorf("authorization failed: %s", query.Get("error")))
+		default:
+			fmt.Fprintln(w, "xpost is authorized. You can close this window.")
+			select {
+			case codes <- query.Get("code"):
+			default:
+			}
+		}
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	fmt.Fprintf(out, "Open this URL to authorize xpost:\n\n  %s\n\nWaiting for the redirect to %s ...\n", login.AuthorizeURL(), redirectURL)
+
+	var code string
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errs:
+		return err
+	case code = <-codes:
+	}
+
+	path, err := login.Exchange(ctx, code)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Saved the token to %s. Set XPOST_TWITTER_AUTH=oauth2 (or auth: oauth2 under twitter in the config) to post with it.\n", path)
+	return nil
+}
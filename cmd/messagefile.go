@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter holds the post settings a message file may declare in a YAML
+// block delimited by "---" lines at its top. Flags given on the command line
+// take precedence.
+type frontMatter struct {
+	Link    string   `yaml:"link"`
+	Image   string   `yaml:"image"`
+	AltText string   `yaml:"alt_text"`
+	Targets []string `yaml:"targets"`
+	CW      string   `yaml:"cw"`
+}
+
+// newestMatch returns the most recently modified file matching pattern.
+func newestMatch(pattern string) (string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid --message-file-glob %q: %w", pattern, err)
+	}
+
+	var (
+		newest     string
+		newestInfo os.FileInfo
+	)
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if newestInfo == nil || info.ModTime().After(newestInfo.ModTime()) {
+			newest, newestInfo = match, info
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("no files match --message-file-glob %q", pattern)
+	}
+	return newest, nil
+}
+
+// readNewestMessageFile reads the newest file matching pattern as the
+// message.
+func readNewestMessageFile(cmd *cobra.Command, pattern string) (string, error) {
+	path, err := newestMatch(pattern)
+	if err != nil {
+		return "", err
+	}
+	return readMessageFile(cmd, path)
+}
+
+// readMessageFile reads the message file at path, applies its front matter to
+// any flags not set on the command line, and returns the body.
+func readMessageFile(cmd *cobra.Command, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read message file: %w", err)
+	}
+
+	meta, body, err := splitFrontMatter(data)
+	if err != nil {
+		return "", fmt.Errorf("parse front matter in %s: %w", path, err)
+	}
+	applyFrontMatter(cmd, meta, filepath.Dir(path))
+
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		return "", fmt.Errorf("message file %s is empty", path)
+	}
+	return message, nil
+}
+
+// splitFrontMatter separates a leading "---" delimited YAML block from the
+// rest of data. Files without one are returned unchanged.
+func splitFrontMatter(data []byte) (frontMatter, []byte, error) {
+	var meta frontMatter
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	rest, ok := bytes.CutPrefix(data, []byte("---\n"))
+	if !ok {
+		if rest, ok = bytes.CutPrefix(data, []byte("---\r\n")); !ok {
+			return meta, data, nil
+		}
+	}
+
+	block, body, found := bytes.Cut(rest, []byte("\n---"))
+	if !found {
+		return meta, nil, errors.New("missing closing ---")
+	}
+	if err := yaml.Unmarshal(block, &meta); err != nil {
+		return meta, nil, err
+	}
+	// Drop the remainder of the closing delimiter line.
+	if _, after, ok := bytes.Cut(body, []byte("\n")); ok {
+		body = after
+	} else {
+		body = nil
+	}
+	return meta, body, nil
+}
+
+func applyFrontMatter(cmd *cobra.Command, meta frontMatter, dir string) {
+	flags := cmd.Flags()
+	if meta.Link != "" && !flags.Changed("link") {
+		linkFlag = meta.Link
+	}
+	if meta.Image != "" && !flags.Changed("image") {
+		path := meta.Image
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		imagePaths = []string{path}
+	}
+	if meta.AltText != "" && !flags.Changed("alt-text") {
+		imageAlts = []string{meta.AltText}
+	}
+	if len(meta.Targets) > 0 && !flags.Changed("target") {
+		targetsFlag = meta.Targets
+	}
+	if meta.CW != "" && !flags.Changed("cw") {
+		contentWarn = meta.CW
+	}
+}
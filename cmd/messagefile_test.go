@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFiles creates each named file in dir with its contents, modified the
+// given number of hours ago.
+func writeFiles(t *testing.T, dir string, files map[string]int) {
+	t.Helper()
+	for name, age := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("notes for "+name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Now().Add(-time.Duration(age) * time.Hour)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNewestMatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]int{"v1.0.md": 48, "v1.2.md": 1, "v1.1.md": 24, "notes.txt": 0})
+	// A directory is never picked, however new.
+	if err := os.Mkdir(filepath.Join(dir, "v2.md"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{pattern: "*.md", want: "v1.2.md"},
+		{pattern: "v1.[01].md", want: "v1.1.md"},
+		{pattern: "*", want: "notes.txt"},
+		{pattern: "*.rst", wantErr: true},
+		{pattern: "v2.md", wantErr: true},
+		{pattern: "[", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := newestMatch(filepath.Join(dir, tt.pattern))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != filepath.Join(dir, tt.want) {
+			t.Errorf("%s: newestMatch = %s, want %s", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantMeta frontMatter
+		wantBody string
+		wantErr  bool
+	}{
+		{name: "none", data: "Just a message\n", wantBody: "Just a message\n"},
+		{
+			name:     "front matter",
+			data:     "---\nlink: https://example.com\ntargets: [mastodon]\ncw: spoilers\n---\nRelease notes\n",
+			wantMeta: frontMatter{Link: "https://example.com", Targets: []string{"mastodon"}, CW: "spoilers"},
+			wantBody: "Release notes\n",
+		},
+		{name: "BOM and CRLF", data: "\xef\xbb\xbf---\r\nimage: shot.png\n---\r\nHi", wantMeta: frontMatter{Image: "shot.png"}, wantBody: "Hi"},
+		{name: "unclosed", data: "---\nlink: x\nHi", wantErr: true},
+		{name: "bad yaml", data: "---\nlink: [\n---\nHi", wantErr: true},
+	}
+	for _, tt := range tests {
+		meta, body, err := splitFrontMatter([]byte(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if meta.Link != tt.wantMeta.Link || meta.Image != tt.wantMeta.Image || meta.CW != tt.wantMeta.CW || len(meta.Targets) != len(tt.wantMeta.Targets) || string(body) != tt.wantBody {
+			t.Errorf("%s: got %+v, %q; want %+v, %q", tt.name, meta, body, tt.wantMeta, tt.wantBody)
+		}
+	}
+}
+
+func TestReadNewestMessageFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]int{"old.md": 2})
+	newest := filepath.Join(dir, "new.md")
+	if err := os.WriteFile(newest, []byte("---\nimage: shot.png\n---\n\n  Shipped v2  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newRootCommand()
+	t.Cleanup(func() { imagePaths = nil })
+	got, err := readNewestMessageFile(cmd, filepath.Join(dir, "*.md"))
+	if err != nil {
+		t.Fatalf("readNewestMessageFile: %v", err)
+	}
+	if got != "Shipped v2" {
+		t.Errorf("message = %q, want the newest file's body", got)
+	}
+	// Relative front matter paths are resolved next to the file.
+	if len(imagePaths) != 1 || imagePaths[0] != filepath.Join(dir, "shot.png") {
+		t.Errorf("image = %v, want shot.png beside the file", imagePaths)
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/blacktop/xpost/internal/logutil"
+)
+
+const notifyTimeout = 10 * time.Second
+
+// outcomeSummary is the JSON body sent to --notify-webhook and written to
+// --results.
+type outcomeSummary struct {
+	Success bool            `json:"success"`
+	Results []outcomeResult `json:"results"`
+}
+
+type outcomeResult struct {
+	Provider  string `json:"provider"`
+	Success   bool   `json:"success"`
+	ID        string `json:"id,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Scheduled bool   `json:"scheduled,omitempty"`
+	Truncated string `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func summarize(outcomes []postOutcome) outcomeSummary {
+	summary := outcomeSummary{Success: true, Results: make([]outcomeResult, 0, len(outcomes))}
+	for _, o := range outcomes {
+		r := outcomeResult{
+			Provider:  o.provider,
+			Success:   o.err == nil,
+			ID:        o.result.ID,
+			URL:       o.result.URL,
+			Scheduled: o.result.Scheduled,
+			Truncated: o.truncated,
+		}
+		if o.err != nil {
+			r.Error = o.err.Error()
+			summary.Success = false
+		}
+		summary.Results = append(summary.Results, r)
+	}
+	return summary
+}
+
+// notify POSTs a summary of outcomes to url. Delivery problems are logged and
+// never fail the run, since the posts themselves have already been made.
+func notify(ctx context.Context, url string, outcomes []postOutcome) {
+	if err := sendNotification(ctx, url, outcomes); err != nil {
+		logutil.Errorf("notify webhook: %v", err)
+	}
+}
+
+func sendNotification(ctx context.Context, url string, outcomes []postOutcome) error {
+	body, err := json.Marshal(summarize(outcomes))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "xpost")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	logutil.Debugf("notify webhook delivered: status=%d", resp.StatusCode)
+	return nil
+}
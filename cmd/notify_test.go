@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestSendNotification(t *testing.T) {
+	outcomes := []postOutcome{
+		{provider: "bluesky", result: xpost.PostResult{ID: "1", URL: "https://bsky.app/1"}, truncated: " more"},
+		{provider: "mastodon", err: errors.New("boom")},
+	}
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{name: "delivered", status: http.StatusNoContent},
+		{name: "rejected", status: http.StatusInternalServerError, wantErr: "unexpected response: 500"},
+	}
+	for _, tt := range tests {
+		var got outcomeSummary
+		var contentType string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+			if r.Method != http.MethodPost {
+				t.Errorf("%s: method = %s", tt.name, r.Method)
+			}
+			json.NewDecoder(r.Body).Decode(&got)
+			w.WriteHeader(tt.status)
+		}))
+		err := sendNotification(context.Background(), srv.URL, outcomes)
+		srv.Close()
+
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if contentType != "application/json" {
+			t.Errorf("%s: Content-Type = %q", tt.name, contentType)
+		}
+		want := outcomeSummary{Results: []outcomeResult{
+			{Provider: "bluesky", Success: true, ID: "1", URL: "https://bsky.app/1", Truncated: " more"},
+			{Provider: "mastodon", Error: "boom"},
+		}}
+		if got.Success || len(got.Results) != 2 || got.Results[0] != want.Results[0] || got.Results[1] != want.Results[1] {
+			t.Errorf("%s: summary = %+v, want %+v", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// buildPoll assembles the --poll-option values into a poll, or returns nil
+// when none were given. Each provider checks the option count and duration.
+func buildPoll(options []string, duration time.Duration, durationSet bool) (*xpost.Poll, error) {
+	if len(options) == 0 {
+		if durationSet {
+			return nil, errors.New("--poll-duration requires --poll-option")
+		}
+		return nil, nil
+	}
+	poll := &xpost.Poll{Duration: duration}
+	for _, option := range options {
+		poll.Options = append(poll.Options, strings.TrimSpace(option))
+	}
+	return poll, nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestBuildPoll(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     []string
+		duration    time.Duration
+		durationSet bool
+		want        *xpost.Poll
+		wantErr     bool
+	}{
+		{name: "no poll", duration: 24 * time.Hour},
+		{name: "options trimmed", options: []string{" yes ", "no"}, duration: time.Hour, want: &xpost.Poll{Options: []string{"yes", "no"}, Duration: time.Hour}},
+		// Counts are left to each provider's limits.
+		{name: "one option", options: []string{"yes"}, duration: time.Hour, want: &xpost.Poll{Options: []string{"yes"}, Duration: time.Hour}},
+		{name: "duration without options", duration: time.Hour, durationSet: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := buildPoll(tt.options, tt.duration, tt.durationSet)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: buildPoll error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || got != nil && (!slices.Equal(got.Options, tt.want.Options) || got.Duration != tt.want.Duration) {
+			t.Errorf("%s: buildPoll = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPollTargets(t *testing.T) {
+	poll := &xpost.Poll{Options: []string{"yes", "no"}, Duration: time.Hour}
+	for _, tt := range []struct {
+		provider string
+		ok       bool
+	}{
+		{provider: "mastodon", ok: true},
+		{provider: "twitter", ok: true},
+		{provider: "bluesky"},
+		{provider: "lemmy"},
+	} {
+		poster := newFakePoster(tt.provider, 500)
+		_, err := dispatch(context.Background(), []xpost.Poster{poster}, xpost.Request{Message: "Tabs or spaces?", Poll: poll}, io.Discard, dispatchOptions{})
+		if tt.ok {
+			if err != nil || len(poster.requests()) != 1 || poster.requests()[0].Poll != poll {
+				t.Errorf("%s: dispatch = %v, want the poll posted", tt.provider, err)
+			}
+			continue
+		}
+		if !errors.As(err, new(xpost.ValidationError)) || len(poster.requests()) != 0 {
+			t.Errorf("%s: dispatch = %v, want a validation error and no post", tt.provider, err)
+		}
+	}
+}
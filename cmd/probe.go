@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/spf13/cobra"
+)
+
+func newProbeCommand() *cobra.Command {
+	var probeTargets []string
+
+	cmd := &cobra.Command{
+		Use:   "probe",
+		Short: "Show each configured provider's limits and capabilities",
+		Long: "probe prints the character limit, image limit, media types, alt text cap, and " +
+			"supported features of every configured provider. Mastodon and Pixelfed limits " +
+			"are read from the instance; the others use the networks' published defaults.",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+			logutil.SetVerbose(verbose)
+
+			if err := loadSecrets(ctx); err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			targets := xpost.Providers()
+			if len(probeTargets) > 0 {
+				var err error
+				if targets, err = normalizeTargets(probeTargets); err != nil {
+					return err
+				}
+			}
+
+			var errs []error
+			for _, target := range targets {
+				posters, err := buildPosters(ctx, cfg, []string{target})
+				if err != nil {
+					if missing := (xpost.MissingEnvError{}); errors.As(err, &missing) {
+						fmt.Fprintf(out, "%s: not configured\n\n", styledProvider(target, out))
+						continue
+					}
+					fmt.Fprintf(out, "error: %v\n\n", err)
+					errs = append(errs, err)
+					continue
+				}
+				probeProvider(ctx, posters[0], out)
+				closePosters(ctx, posters)
+			}
+			return errors.Join(errs...)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&probeTargets, "target", nil, "Providers to probe (default all configured)")
+	registerTargetCompletion(cmd)
+
+	return cmd
+}
+
+// probeProvider prints p's capabilities, plus the xpost actions it supports.
+func probeProvider(ctx context.Context, p xpost.Poster, out io.Writer) {
+	prober, ok := p.(xpost.Prober)
+	if !ok {
+		fmt.Fprintf(out, "%s: capabilities unknown\n\n", styledProvider(p.Name(), out))
+		return
+	}
+
+	caps, err := prober.Capabilities(ctx)
+	source := "defaults"
+	if err != nil {
+		fmt.Fprintf(out, "warning: %s: %v; showing defaults\n", p.Name(), err)
+	} else if caps.Live {
+		source = "live"
+	}
+
+	features := slices.Clone(caps.Features)
+	for _, f := range []struct {
+		name string
+		ok   bool
+	}{
+		{"quote", implements[xpost.Quoter](p)},
+		{"repost", implements[xpost.Reposter](p)},
+		{"react", implements[xpost.Reacter](p)},
+		{"delete", implements[xpost.Deleter](p)},
+		{"read-back", implements[xpost.ReadBacker](p)},
+		{"truncate", implements[xpost.Truncater](p)},
+	} {
+		if f.ok {
+			features = append(features, f.name)
+		}
+	}
+
+	alt := "not supported"
+	if caps.MaxAltChars > 0 {
+		alt = fmt.Sprintf("%d %s", caps.MaxAltChars, caps.Unit)
+	}
+
+	fmt.Fprintf(out, "%s (%s)\n", styledProvider(p.Name(), out), source)
+	fmt.Fprintf(out, "  length:      %d %s\n", caps.MaxChars, caps.Unit)
+	if caps.MaxImageBytes > 0 {
+		fmt.Fprintf(out, "  images:      %d per post, up to %s each\n", caps.MaxImages, formatBytes(caps.MaxImageBytes))
+	} else {
+		fmt.Fprintf(out, "  images:      %d per post\n", caps.MaxImages)
+	}
+	fmt.Fprintf(out, "  media types: %s\n", strings.Join(caps.MediaTypes, ", "))
+	fmt.Fprintf(out, "  alt text:    %s\n", alt)
+	fmt.Fprintf(out, "  features:    %s\n\n", strings.Join(features, ", "))
+}
+
+func implements[T any](p xpost.Poster) bool {
+	_, ok := p.(T)
+	return ok
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/xpost/mastodon"
+)
+
+func TestProbeProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance string // /api/v1/instance response; a 404 when empty
+		want     []string
+	}{
+		{
+			name: "instance limits",
+			instance: `{"uri":"example.social","configuration":{"statuses":{"max_characters":5000,"max_media_attachments":8},` +
+				`"media_attachments":{"image_size_limit":16777216,"description_limit":1000,"supported_mime_types":["image/png","image/avif"]}}}`,
+			want: []string{
+				"Mastodon (live)",
+				"length:      5000 characters",
+				"images:      8 per post, up to 16 MB each",
+				"media types: image/png, image/avif",
+				"alt text:    1000 characters",
+				"features:    content warnings, visibility (public, unlisted, private, direct), polls, repost, react, delete, read-back, truncate",
+			},
+		},
+		{
+			name: "instance unavailable",
+			want: []string{"warning: mastodon: get instance:", "Mastodon (defaults)", "length:      500 characters", "alt text:    1500 characters"},
+		},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/instance" || tt.instance == "" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, tt.instance)
+		}))
+		p := mastodon.NewWithConfig(context.Background(), mastodon.Config{Server: srv.URL, AccessToken: "token"})
+
+		var out strings.Builder
+		probeProvider(context.Background(), p, &out)
+		srv.Close()
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s: output %q lacks %q", tt.name, out.String(), want)
+			}
+		}
+	}
+}
+
+func TestProbeProviderUnknown(t *testing.T) {
+	var out strings.Builder
+	probeProvider(context.Background(), newFakePoster("fake", 100), &out)
+	if out.String() != defaultIcon+" fake: capabilities unknown\n\n" {
+		t.Errorf("output = %q", out.String())
+	}
+}
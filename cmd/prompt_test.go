@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeStdin makes stdin look piped or like a terminal until the test ends.
+func fakeStdin(t *testing.T, piped, terminal bool) {
+	t.Helper()
+	saved := stdinState
+	stdinState = func(io.Reader) (bool, bool, error) { return piped, terminal, nil }
+	t.Cleanup(func() { stdinState = saved })
+}
+
+func TestResolveMessageFromStdin(t *testing.T) {
+	t.Cleanup(func() { noPrompt = false })
+	tests := []struct {
+		name     string
+		piped    bool
+		terminal bool
+		noPrompt bool
+		input    string
+		want     string
+		wantOut  string
+		wantErr  string
+	}{
+		{name: "terminal prompts", terminal: true, input: "  Hello from a prompt \n", want: "Hello from a prompt", wantOut: "Message: "},
+		{name: "terminal without newline", terminal: true, input: "Hi", want: "Hi", wantOut: "Message: "},
+		{name: "terminal blank answer", terminal: true, input: "\n", wantOut: "Message: ", wantErr: "message is required"},
+		{name: "terminal with --no-prompt", terminal: true, noPrompt: true, input: "Hello\n", wantErr: "message is required"},
+		{name: "piped", piped: true, input: "Line one\nLine two\n", want: "Line one\nLine two"},
+		{name: "not a terminal", input: "Hello\n", wantErr: "message is required"},
+	}
+	for _, tt := range tests {
+		fakeStdin(t, tt.piped, tt.terminal)
+		cmd := newRootCommand()
+		noPrompt = tt.noPrompt
+		var out strings.Builder
+		cmd.SetIn(strings.NewReader(tt.input))
+		cmd.SetOut(&out)
+
+		got, err := resolveMessage(cmd, nil)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+		} else if err != nil || got != tt.want {
+			t.Errorf("%s: resolveMessage = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+		if out.String() != tt.wantOut {
+			t.Errorf("%s: output = %q, want %q", tt.name, out.String(), tt.wantOut)
+		}
+	}
+}
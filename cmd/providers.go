@@ -0,0 +1,15 @@
+package cmd
+
+// Each provider registers itself with xpost from its package init, so adding
+// one only needs an import here.
+import (
+	_ "github.com/blacktop/xpost/internal/xpost/bluesky"
+	_ "github.com/blacktop/xpost/internal/xpost/discord"
+	_ "github.com/blacktop/xpost/internal/xpost/lemmy"
+	_ "github.com/blacktop/xpost/internal/xpost/mastodon"
+	_ "github.com/blacktop/xpost/internal/xpost/misskey"
+	_ "github.com/blacktop/xpost/internal/xpost/pixelfed"
+	_ "github.com/blacktop/xpost/internal/xpost/slack"
+	_ "github.com/blacktop/xpost/internal/xpost/telegram"
+	_ "github.com/blacktop/xpost/internal/xpost/twitter"
+)
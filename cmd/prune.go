@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/state"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/spf13/cobra"
+)
+
+func newPruneCommand() *cobra.Command {
+	var pruneDryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete posts made with --expire whose time is up",
+		Long: "prune deletes every post recorded by --expire that has expired and " +
+			"removes it from the state file. Run it periodically, e.g. from cron.",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logutil.SetVerbose(verbose)
+
+			if err := loadSecrets(ctx); err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			path, err := state.DefaultPath()
+			if err != nil {
+				return err
+			}
+			return runPrune(ctx, cfg, path, time.Now(), pruneDryRun, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "List expired posts without deleting them")
+
+	return cmd
+}
+
+func runPrune(ctx context.Context, cfg *config.Config, path string, now time.Time, dryRun bool, out io.Writer) error {
+	st, err := state.Load(path)
+	if err != nil {
+		return err
+	}
+
+	due, pending := st.Expired(now)
+	if len(due) == 0 {
+		fmt.Fprintln(out, "Nothing to prune")
+		return nil
+	}
+	if dryRun {
+		for _, e := range due {
+			fmt.Fprintf(out, "[dry-run] would delete %s post %s\n", styledProvider(e.Provider, out), postLabel(e))
+		}
+		return nil
+	}
+
+	posters := map[string]xpost.Poster{}
+	defer func() { closePosters(ctx, slices.Collect(maps.Values(posters))) }()
+
+	var errs []error
+	for _, e := range due {
+		deleter, err := deleterFor(ctx, cfg, posters, e.Provider)
+		if err == nil {
+			err = deleter.Delete(ctx, e.ID)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", e.Provider, postLabel(e), err))
+			pending = append(pending, e) // retry on the next run
+			continue
+		}
+		fmt.Fprintf(out, "Deleted %s post %s\n", styledProvider(e.Provider, out), postLabel(e))
+	}
+
+	st.Expiring = pending
+	if err := state.Save(path, st); err != nil {
+		errs = append(errs, err)
+	}
+	for _, err := range errs {
+		fmt.Fprintf(out, "error: %v\n", err)
+	}
+	return errors.Join(errs...)
+}
+
+// deleterFor builds (and caches) the poster for provider as a Deleter.
+func deleterFor(ctx context.Context, cfg *config.Config, cache map[string]xpost.Poster, provider string) (xpost.Deleter, error) {
+	poster, ok := cache[provider]
+	if !ok {
+		posters, err := buildPosters(ctx, cfg, []string{provider})
+		if err != nil {
+			return nil, err
+		}
+		poster = posters[0]
+		cache[provider] = poster
+	}
+	deleter, ok := poster.(xpost.Deleter)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support deleting posts", provider)
+	}
+	return deleter, nil
+}
+
+// checkDeletable ensures every poster can later delete what it publishes.
+func checkDeletable(posters []xpost.Poster) error {
+	for _, poster := range posters {
+		if _, ok := poster.(xpost.Deleter); !ok {
+			return fmt.Errorf("--expire: %s does not support deleting posts", poster.Name())
+		}
+	}
+	return nil
+}
+
+// recordExpiring adds the successfully published posts to the state file so
+// prune can delete them once ttl has elapsed.
+func recordExpiring(outcomes []postOutcome, ttl time.Duration, now time.Time) error {
+	path, err := state.DefaultPath()
+	if err != nil {
+		return err
+	}
+	st, err := state.Load(path)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range outcomes {
+		if !o.posted() || o.result.ID == "" {
+			continue
+		}
+		st.Expiring = append(st.Expiring, state.Expiring{
+			Provider:  o.provider,
+			ID:        o.result.ID,
+			URL:       o.result.URL,
+			ExpiresAt: now.Add(ttl),
+		})
+	}
+	return state.Save(path, st)
+}
+
+func postLabel(e state.Expiring) string {
+	if e.URL != "" {
+		return e.URL
+	}
+	return e.ID
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/state"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestRecordExpiring(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	outcomes := []postOutcome{
+		{provider: "fake", result: xpost.PostResult{ID: "1", URL: "https://example.com/1"}},
+		{provider: "mastodon", err: errors.New("boom")},
+		{provider: "slack"}, // no ID to delete by
+	}
+	if err := recordExpiring(outcomes, time.Hour, now); err != nil {
+		t.Fatalf("recordExpiring: %v", err)
+	}
+	st, err := state.Load(statePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []state.Expiring{{Provider: "fake", ID: "1", URL: "https://example.com/1", ExpiresAt: now.Add(time.Hour)}}
+	if !slices.Equal(st.Expiring, want) {
+		t.Errorf("Expiring = %+v, want %+v", st.Expiring, want)
+	}
+}
+
+func TestRunPrune(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	expiring := []state.Expiring{
+		{Provider: "fake", ID: "due", ExpiresAt: now.Add(-time.Minute)},
+		{Provider: "fake", ID: "stuck", ExpiresAt: now},
+		{Provider: "fake", ID: "later", ExpiresAt: now.Add(time.Minute)},
+	}
+	tests := []struct {
+		name        string
+		dryRun      bool
+		wantDeleted []string
+		wantLeft    []string
+		wantErr     bool
+		wantOutput  string
+	}{
+		{name: "dry run", dryRun: true, wantLeft: []string{"due", "stuck", "later"}, wantOutput: "[dry-run] would delete"},
+		{name: "prune", wantDeleted: []string{"due"}, wantLeft: []string{"later", "stuck"}, wantErr: true, wantOutput: "error: fake stuck: delete stuck failed"},
+	}
+	for _, tt := range tests {
+		t.Setenv("XDG_STATE_HOME", t.TempDir())
+		path := statePath(t)
+		if err := state.Save(path, &state.State{Expiring: expiring}); err != nil {
+			t.Fatal(err)
+		}
+		poster := newFakePoster("fake", 100)
+		poster.failDelete = map[string]bool{"stuck": true}
+		useRegisteredFake(t, poster)
+
+		var out strings.Builder
+		err := runPrune(context.Background(), &config.Config{}, path, now, tt.dryRun, &out)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: runPrune = %v", tt.name, err)
+		}
+		if !strings.Contains(out.String(), tt.wantOutput) {
+			t.Errorf("%s: output %q lacks %q", tt.name, out.String(), tt.wantOutput)
+		}
+		if !slices.Equal(poster.deleted, tt.wantDeleted) {
+			t.Errorf("%s: deleted %v, want %v", tt.name, poster.deleted, tt.wantDeleted)
+		}
+		st, err := state.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var left []string
+		for _, e := range st.Expiring {
+			left = append(left, e.ID)
+		}
+		if !slices.Equal(left, tt.wantLeft) {
+			t.Errorf("%s: left %v, want %v", tt.name, left, tt.wantLeft)
+		}
+	}
+}
+
+func TestCheckDeletable(t *testing.T) {
+	if err := checkDeletable([]xpost.Poster{newFakePoster("fake", 100)}); err != nil {
+		t.Errorf("checkDeletable = %v", err)
+	}
+	if err := checkDeletable([]xpost.Poster{undeletable{newFakePoster("slack", 100)}}); err == nil || !strings.Contains(err.Error(), "slack does not support deleting") {
+		t.Errorf("checkDeletable = %v, want slack rejected", err)
+	}
+}
+
+// undeletable hides the fake poster's Delete method.
+type undeletable struct{ p *fakePoster }
+
+func (u undeletable) Name() string                   { return u.p.Name() }
+func (u undeletable) Validate(r xpost.Request) error { return u.p.Validate(r) }
+func (u undeletable) Post(ctx context.Context, r xpost.Request) (xpost.PostResult, error) {
+	return u.p.Post(ctx, r)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/spf13/cobra"
+)
+
+func newReactCommand() *cobra.Command {
+	var reactDryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "react <post-url> [emoji]",
+		Short: "Like or react to a post on the network it was published on",
+		Long: "react detects the network from the post URL, like reply, and favourites it on " +
+			"Mastodon or likes it on Bluesky and Twitter/X, where an emoji is recorded as a " +
+			"plain like. Misskey applies the emoji as a reaction.",
+		Args:          cobra.RangeArgs(1, 2),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+			logutil.SetVerbose(verbose)
+
+			if err := loadSecrets(ctx); err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			ref := strings.TrimSpace(args[0])
+			var emoji string
+			if len(args) > 1 {
+				emoji = strings.TrimSpace(args[1])
+			}
+			target, err := providerForURL(cfg, ref)
+			if err != nil {
+				return err
+			}
+
+			posters, err := buildPosters(ctx, cfg, []string{target})
+			if err != nil {
+				return err
+			}
+			defer closePosters(ctx, posters)
+			reacter, ok := posters[0].(xpost.Reacter)
+			if !ok {
+				fmt.Fprintf(out, "%s does not support reactions; nothing to do\n", styledProvider(target, out))
+				return nil
+			}
+			if emoji != "" && !features(target).EmojiReactions {
+				fmt.Fprintf(out, "note: %s has no emoji reactions; %s will be sent as a like\n", styledProvider(target, out), emoji)
+			}
+
+			if reactDryRun {
+				fmt.Fprintf(out, "[dry-run] would react on %s: %s\n", styledProvider(target, out), ref)
+				return nil
+			}
+			if err := reacter.React(ctx, ref, emoji); err != nil {
+				fmt.Fprintf(out, "error: %s: %v\n", target, err)
+				return err
+			}
+			fmt.Fprintf(out, "Reacted on %s\n", styledProvider(target, out))
+			return nil
+		},
+		Example: `  xpost react https://bsky.app/profile/alice.bsky.social/post/3kabc
+  xpost react https://mastodon.social/@bob/1123581321 🎉`,
+	}
+
+	cmd.Flags().BoolVar(&reactDryRun, "dry-run", false, "Print actions without reacting")
+
+	return cmd
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// readBack looks each successfully published post up as an anonymous reader
+// and flags posts that appear to have been filtered. Lookup failures are
+// logged and never fail the run.
+func readBack(ctx context.Context, posters []xpost.Poster, outcomes []postOutcome, out io.Writer) {
+	byName := make(map[string]xpost.Poster, len(posters))
+	for _, poster := range posters {
+		byName[poster.Name()] = poster
+	}
+
+	for _, o := range outcomes {
+		if !o.posted() {
+			continue
+		}
+		checker, ok := byName[o.provider].(xpost.ReadBacker)
+		if !ok {
+			logutil.Debugf("%s: read-back not supported", o.provider)
+			continue
+		}
+
+		rb, err := checker.ReadBack(ctx, o.result)
+		if err != nil {
+			logutil.Errorf("%s: %v", o.provider, err)
+			continue
+		}
+		fmt.Fprintln(out, readBackSummary(styledProvider(o.provider, out), rb))
+	}
+}
+
+func readBackSummary(provider string, rb xpost.ReadBack) string {
+	switch {
+	case !rb.Visible:
+		return fmt.Sprintf("Possibly filtered on %s: %s", provider, rb.Detail)
+	case rb.Detail != "":
+		return fmt.Sprintf("Visible on %s (%s)", provider, rb.Detail)
+	default:
+		return fmt.Sprintf("Visible on %s", provider)
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// readBackPoster reads every post back as rb, or fails with err.
+type readBackPoster struct {
+	*fakePoster
+	rb  xpost.ReadBack
+	err error
+}
+
+func (p readBackPoster) ReadBack(ctx context.Context, res xpost.PostResult) (xpost.ReadBack, error) {
+	return p.rb, p.err
+}
+
+func TestReadBack(t *testing.T) {
+	tests := []struct {
+		name    string
+		rb      xpost.ReadBack
+		err     error
+		postErr error
+		want    string
+	}{
+		{name: "present", rb: xpost.ReadBack{Visible: true}, want: "Visible on " + defaultIcon + " fake\n"},
+		{name: "labelled", rb: xpost.ReadBack{Visible: true, Detail: "labelled spam"}, want: "Visible on " + defaultIcon + " fake (labelled spam)\n"},
+		{name: "absent", rb: xpost.ReadBack{Detail: "not found"}, want: "Possibly filtered on " + defaultIcon + " fake: not found\n"},
+		{name: "lookup fails", err: errors.New("timeout")},
+		{name: "post failed", rb: xpost.ReadBack{Visible: true}, postErr: errors.New("post failed")},
+	}
+	for _, tt := range tests {
+		poster := readBackPoster{fakePoster: newFakePoster("fake", 100), rb: tt.rb, err: tt.err}
+		outcomes := []postOutcome{{provider: "fake", result: xpost.PostResult{ID: "1"}, err: tt.postErr}}
+
+		var out strings.Builder
+		readBack(context.Background(), []xpost.Poster{poster}, outcomes, &out)
+		if out.String() != tt.want {
+			t.Errorf("%s: output = %q, want %q", tt.name, out.String(), tt.want)
+		}
+	}
+}
+
+func TestReadBackUnsupported(t *testing.T) {
+	var out strings.Builder
+	outcomes := []postOutcome{{provider: "fake", result: xpost.PostResult{ID: "1"}}}
+	readBack(context.Background(), []xpost.Poster{newFakePoster("fake", 100)}, outcomes, &out)
+	if out.Len() != 0 {
+		t.Errorf("output = %q, want nothing for a provider without read-back", out.String())
+	}
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/spf13/cobra"
+)
+
+func newReplyCommand() *cobra.Command {
+	var replyTo string
+
+	cmd := &cobra.Command{
+		Use:   "reply --to <post-url> [message]",
+		Short: "Reply to a post on the network it was published on",
+		Long: "reply detects the network from the post URL (x.com/twitter.com, bsky.app, " +
+			"or any other host as Mastodon) and posts the message as a threaded reply there.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReply(cmd, args, replyTo)
+		},
+		Example: `  xpost reply --to https://bsky.app/profile/alice.bsky.social/post/3kabc "Thanks!"
+  xpost reply --to https://mastodon.social/@bob/1123581321 -m "Agreed"`,
+	}
+
+	cmd.Flags().StringVar(&replyTo, "to", "", "URL of the post to reply to")
+	cmd.Flags().StringVarP(&messageFlag, "message", "m", "", "Message text to post")
+	cmd.Flags().StringArrayVar(&imagePaths, "image", nil, "Path to an image to attach (repeatable)")
+	cmd.Flags().StringArrayVar(&imageAlts, "alt-text", nil, "Alternative text for the image given in the same position (repeatable)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print actions without posting")
+	cmd.MarkFlagRequired("to")
+	cmd.Flags().SortFlags = false
+
+	return cmd
+}
+
+func runReply(cmd *cobra.Command, args []string, replyTo string) error {
+	ctx := cmd.Context()
+
+	logutil.SetVerbose(verbose)
+
+	if err := loadSecrets(ctx); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	message, err := resolveMessage(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	target, err := providerForURL(cfg, replyTo)
+	if err != nil {
+		return err
+	}
+	logutil.Debugf("replying on %s to %s", target, replyTo)
+
+	images, err := buildImages(imagePaths, imageAlts, "")
+	if err != nil {
+		return err
+	}
+	applyDefaultAlt(images)
+	req := xpost.Request{
+		Message: message,
+		Images:  images,
+		ReplyTo: strings.TrimSpace(replyTo),
+	}
+
+	posters, err := buildPosters(ctx, cfg, []string{target})
+	if err != nil {
+		return err
+	}
+	defer closePosters(ctx, posters)
+
+	_, err = dispatch(ctx, posters, req, cmd.OutOrStdout(), dispatchOptions{dryRun: dryRun})
+	return err
+}
+
+// providerForURL picks the network a post URL belongs to. Links to the
+// configured Pixelfed or Misskey server go there; anything else that isn't X or
+// Bluesky is assumed to be a Mastodon-compatible server.
+func providerForURL(cfg *config.Config, raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("a post URL is required")
+	}
+	if strings.HasPrefix(raw, "at://") {
+		return "bluesky", nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", fmt.Errorf("invalid post URL %q", raw)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	switch host {
+	case "x.com", "twitter.com", "mobile.twitter.com":
+		return "twitter", nil
+	case "bsky.app":
+		return "bluesky", nil
+	}
+	if server, err := url.Parse(cfg.Pixelfed.Server); err == nil && strings.EqualFold(server.Hostname(), host) {
+		return "pixelfed", nil
+	}
+	if server, err := url.Parse(cfg.Misskey.Server); err == nil && strings.EqualFold(server.Hostname(), host) {
+		return "misskey", nil
+	}
+	return "mastodon", nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/blacktop/xpost/internal/config"
+)
+
+func TestProviderForURL(t *testing.T) {
+	cfg := &config.Config{
+		Pixelfed: config.Pixelfed{Server: "https://pixelfed.example"},
+		Misskey:  config.Misskey{Server: "https://misskey.example/"},
+	}
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{url: "https://x.com/jack/status/20", want: "twitter"},
+		{url: "https://www.twitter.com/jack/status/20", want: "twitter"},
+		{url: "https://mobile.twitter.com/jack/status/20", want: "twitter"},
+		{url: "https://bsky.app/profile/alice.test/post/3kabc", want: "bluesky"},
+		{url: "at://did:plc:abc/app.bsky.feed.post/3kabc", want: "bluesky"},
+		{url: "https://PixelFed.example/p/me/1", want: "pixelfed"},
+		{url: "https://misskey.example/notes/9abc", want: "misskey"},
+		{url: "https://mastodon.social/@bob/1123581321", want: "mastodon"},
+		{url: "", wantErr: true},
+		{url: "mastodon.social/@bob/1", wantErr: true},
+		{url: "ftp://x.com/jack/status/20", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := providerForURL(cfg, tt.url)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("providerForURL(%q) = %q, %v; want %q, error %v", tt.url, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/state"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/spf13/cobra"
+)
+
+func newResumeCommand() *cobra.Command {
+	var resumeRetries int
+
+	cmd := &cobra.Command{
+		Use:   "resume THREAD_ID",
+		Short: "Finish posting a --thread that failed part way",
+		Long: "resume posts the rest of a thread that stopped after a failure, " +
+			"replying to the last post that was published. The thread ID is " +
+			"printed when the failure happens.",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logutil.SetVerbose(verbose)
+			if resumeRetries < 0 {
+				return errors.New("--retries must not be negative")
+			}
+
+			if err := loadSecrets(ctx); err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			path, err := state.DefaultPath()
+			if err != nil {
+				return err
+			}
+			build := func(ctx context.Context, targets []string) ([]xpost.Poster, error) {
+				return buildPosters(ctx, cfg, targets)
+			}
+			return runResume(ctx, path, args[0], build, resumeRetries, time.Now(), cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().IntVar(&resumeRetries, "retries", 0, "Retry a post this many times when a provider reports a rate limit, server error or connection timeout")
+
+	return cmd
+}
+
+// threadError reports a thread that failed after some of its follow-ups may
+// have been published.
+type threadError struct {
+	posted    []string // IDs of the follow-ups published, in order
+	remaining []string // the parts not posted, starting with the one that failed
+	err       error
+}
+
+func (e *threadError) Error() string { return e.err.Error() }
+func (e *threadError) Unwrap() error { return e.err }
+
+// unfinishedThread returns what 'xpost resume' needs to finish a thread
+// whose first post, first, was published as res before err, or nil when err
+// did not stop a thread part way.
+func unfinishedThread(provider string, first xpost.Request, res xpost.PostResult, err error) *state.Thread {
+	var te *threadError
+	if !errors.As(err, &te) || res.ID == "" {
+		return nil
+	}
+	return &state.Thread{
+		Provider:       provider,
+		URL:            res.URL,
+		Posted:         append([]string{res.ID}, te.posted...),
+		Remaining:      te.remaining,
+		ContentWarning: first.ContentWarning,
+		Visibility:     first.Visibility,
+		Community:      first.Community,
+		Language:       first.Language,
+	}
+}
+
+// recordThreads saves the threads that failed part way so they can be
+// resumed, returning them with their new IDs.
+func recordThreads(outcomes []postOutcome, now time.Time) ([]state.Thread, error) {
+	var threads []state.Thread
+	for _, o := range outcomes {
+		if o.thread == nil {
+			continue
+		}
+		t := *o.thread
+		t.ID = newThreadID()
+		t.FailedAt = now
+		threads = append(threads, t)
+	}
+	if len(threads) == 0 {
+		return nil, nil
+	}
+
+	path, err := state.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	st, err := state.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range threads {
+		st.PutThread(t)
+	}
+	return threads, state.Save(path, st)
+}
+
+// newThreadID returns a short random ID to resume a thread by.
+func newThreadID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// runResume posts the remaining parts of the thread with id. The state is
+// updated with whatever progress is made, and the thread is forgotten once
+// it is complete. Each post is retried up to retries times.
+func runResume(ctx context.Context, path, id string, build func(context.Context, []string) ([]xpost.Poster, error), retries int, now time.Time, out io.Writer) error {
+	st, err := state.Load(path)
+	if err != nil {
+		return err
+	}
+	t, ok := st.Thread(id)
+	if !ok {
+		return fmt.Errorf("no unfinished thread %q", id)
+	}
+
+	posters, err := build(ctx, []string{t.Provider})
+	if err != nil {
+		return err
+	}
+	defer closePosters(ctx, posters)
+
+	first := xpost.Request{ContentWarning: t.ContentWarning, Visibility: t.Visibility, Community: t.Community, Language: t.Language}
+	postErr := postThread(ctx, posters[0], first, xpost.PostResult{ID: t.Parent(), URL: t.URL}, t.Remaining, len(t.Posted), retries)
+	var te *threadError
+	if errors.As(postErr, &te) {
+		t.Posted = append(t.Posted, te.posted...)
+		t.Remaining = te.remaining
+		t.FailedAt = now
+		st.PutThread(t)
+	} else if postErr == nil {
+		st.DropThread(id)
+	}
+	if err := state.Save(path, st); err != nil {
+		logutil.Errorf("record thread progress: %v", err)
+	}
+
+	if postErr != nil {
+		fmt.Fprintf(out, "error: %s: %v\n", t.Provider, postErr)
+		fmt.Fprintf(out, "Resume the %s thread again with: xpost resume %s\n", styledProvider(t.Provider, out), id)
+		return fmt.Errorf("%s: %w", t.Provider, postErr)
+	}
+	fmt.Fprintf(out, "Finished the %s thread: %s\n", styledProvider(t.Provider, out), t.URL)
+	return nil
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/blacktop/xpost/internal/state"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// failThread posts a 5-part unlisted Japanese thread that fails at the third post and
+// records it, returning the thread saved to the state file.
+func failThread(t *testing.T, poster *fakePoster) state.Thread {
+	t.Helper()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	poster.failAt = 3
+	outcomes, err := dispatch(context.Background(), []xpost.Poster{poster}, xpost.Request{Message: words(40), Visibility: "unlisted", Language: "ja"}, io.Discard, dispatchOptions{thread: true})
+	if err == nil {
+		t.Fatal("dispatch succeeded despite the failing thread post")
+	}
+	threads, err := recordThreads(outcomes, time.Now())
+	if err != nil {
+		t.Fatalf("recordThreads: %v", err)
+	}
+	if len(threads) != 1 {
+		t.Fatalf("recorded %d threads, want 1", len(threads))
+	}
+
+	st, err := state.Load(statePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, ok := st.Thread(threads[0].ID)
+	if !ok {
+		t.Fatalf("thread %s not in the state file", threads[0].ID)
+	}
+	return saved
+}
+
+func statePath(t *testing.T) string {
+	t.Helper()
+	path, err := state.DefaultPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFailedThreadWritesState(t *testing.T) {
+	poster := newFakePoster("mastodon", 50)
+	saved := failThread(t, poster)
+
+	if want := []string{"mastodon-1", "mastodon-2"}; !slices.Equal(saved.Posted, want) {
+		t.Errorf("Posted = %v, want %v", saved.Posted, want)
+	}
+	if saved.Parent() != "mastodon-2" {
+		t.Errorf("Parent = %q, want mastodon-2", saved.Parent())
+	}
+	if len(saved.Remaining) != 3 {
+		t.Fatalf("Remaining = %q, want the 3 unposted parts", saved.Remaining)
+	}
+	if saved.Provider != "mastodon" || saved.URL != "https://example.com/mastodon-1" || saved.Visibility != "unlisted" || saved.Language != "ja" {
+		t.Errorf("thread = %+v", saved)
+	}
+	for i, req := range poster.requests() {
+		if req.Language != "ja" {
+			t.Errorf("part %d language = %q, want ja", i+1, req.Language)
+		}
+	}
+}
+
+func TestResumeContinuesThread(t *testing.T) {
+	tests := []struct {
+		name        string
+		failAt      int // call of the resumed Post to fail; 0 for none
+		wantParents []string
+		wantPosted  []string // the thread's IDs left in the state; nil when done
+	}{
+		{name: "completes", wantParents: []string{"mastodon-2", "mastodon-3", "mastodon-4"}},
+		{name: "fails again", failAt: 2, wantParents: []string{"mastodon-2"}, wantPosted: []string{"mastodon-1", "mastodon-2", "mastodon-3"}},
+	}
+	for _, tt := range tests {
+		poster := newFakePoster("mastodon", 50)
+		saved := failThread(t, poster)
+		poster.calls, poster.failAt = 0, tt.failAt
+
+		build := func(ctx context.Context, targets []string) ([]xpost.Poster, error) {
+			if !slices.Equal(targets, []string{"mastodon"}) {
+				t.Errorf("%s: built %v, want the thread's provider", tt.name, targets)
+			}
+			return []xpost.Poster{poster}, nil
+		}
+		err := runResume(context.Background(), statePath(t), saved.ID, build, 0, time.Now(), io.Discard)
+		if (err != nil) != (tt.failAt > 0) {
+			t.Errorf("%s: runResume = %v", tt.name, err)
+		}
+
+		resumed := poster.requests()[2:]
+		if len(resumed) != len(tt.wantParents) {
+			t.Fatalf("%s: resumed %d posts, want %d", tt.name, len(resumed), len(tt.wantParents))
+		}
+		for i, req := range resumed {
+			if req.Message != saved.Remaining[i] {
+				t.Errorf("%s: resumed post %d = %q, want %q", tt.name, i, req.Message, saved.Remaining[i])
+			}
+			if req.ReplyTo != tt.wantParents[i] {
+				t.Errorf("%s: resumed post %d replies to %q, want %q", tt.name, i, req.ReplyTo, tt.wantParents[i])
+			}
+			if req.Visibility != "unlisted" || req.Language != "ja" {
+				t.Errorf("%s: resumed post %d visibility = %q, language = %q", tt.name, i, req.Visibility, req.Language)
+			}
+		}
+
+		st, err := state.Load(statePath(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		left, ok := st.Thread(saved.ID)
+		if tt.wantPosted == nil {
+			if ok {
+				t.Errorf("%s: finished thread still in the state: %+v", tt.name, left)
+			}
+			continue
+		}
+		if !slices.Equal(left.Posted, tt.wantPosted) || len(left.Remaining) != 2 {
+			t.Errorf("%s: state = %+v, want Posted %v and 2 parts left", tt.name, left, tt.wantPosted)
+		}
+	}
+}
+
+func TestThreadPartsRetried(t *testing.T) {
+	busy := xpost.RetryableError{Err: errors.New("rate limited"), RetryAfter: time.Millisecond}
+	tests := []struct {
+		name    string
+		retries int
+		posted  int
+	}{
+		{name: "no retries", posted: 2},
+		{name: "retried", retries: 1, posted: 5},
+	}
+	for _, tt := range tests {
+		poster := newFakePoster("mastodon", 50)
+		poster.failAt, poster.failErr = 3, busy
+		_, err := dispatch(context.Background(), []xpost.Poster{poster}, xpost.Request{Message: words(40)}, io.Discard, dispatchOptions{thread: true, retries: tt.retries})
+		if (err == nil) != (tt.retries > 0) {
+			t.Errorf("%s: dispatch = %v", tt.name, err)
+		}
+		if got := len(poster.requests()); got != tt.posted {
+			t.Errorf("%s: posted %d parts, want %d", tt.name, got, tt.posted)
+		}
+	}
+}
+
+func TestResumeRetries(t *testing.T) {
+	poster := newFakePoster("mastodon", 50)
+	saved := failThread(t, poster)
+	poster.calls, poster.failAt = 0, 1
+	poster.failErr = xpost.RetryableError{Err: errors.New("rate limited"), RetryAfter: time.Millisecond}
+
+	build := func(context.Context, []string) ([]xpost.Poster, error) { return []xpost.Poster{poster}, nil }
+	if err := runResume(context.Background(), statePath(t), saved.ID, build, 1, time.Now(), io.Discard); err != nil {
+		t.Fatalf("runResume = %v, want the failed part retried", err)
+	}
+	if got := len(poster.requests()); got != 5 {
+		t.Errorf("posted %d parts in all, want 5", got)
+	}
+}
+
+func TestResumeUnknownThread(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	build := func(context.Context, []string) ([]xpost.Poster, error) {
+		t.Error("built a poster for an unknown thread")
+		return nil, nil
+	}
+	if err := runResume(context.Background(), statePath(t), "nope", build, 0, time.Now(), io.Discard); err == nil {
+		t.Error("runResume succeeded for an unknown thread")
+	}
+}
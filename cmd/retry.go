@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+const (
+	retryBaseDelay = 2 * time.Second
+	// maxRetryWait caps how long a provider's Retry-After may hold up a run.
+	maxRetryWait = 2 * time.Minute
+)
+
+// postWithRetry posts req, trying again up to retries times when the
+// provider reports a transient failure with xpost.RetryableError. It waits
+// as long as the provider asks, or backs off exponentially.
+func postWithRetry(ctx context.Context, poster xpost.Poster, req xpost.Request, retries int) (xpost.PostResult, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := poster.Post(ctx, req)
+		var retryable xpost.RetryableError
+		if err == nil || attempt >= retries || !errors.As(err, &retryable) || ctx.Err() != nil {
+			return res, err
+		}
+
+		wait := retryable.RetryAfter
+		if wait == 0 {
+			wait = retryBaseDelay << attempt
+		}
+		if wait > maxRetryWait {
+			logutil.Warnf("%s: not retrying; asked to wait %s: %v", poster.Name(), wait.Round(time.Second), err)
+			return res, err
+		}
+		logutil.Warnf("%s: retrying in %s (%d/%d): %v", poster.Name(), wait.Round(time.Second), attempt+1, retries, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestPostWithRetry(t *testing.T) {
+	timeout := func(op string) error {
+		return xpost.Retryable(&url.Error{Op: "Post", URL: "https://example.com", Err: &net.OpError{Op: op, Net: "tcp", Err: os.ErrDeadlineExceeded}}, 0, 0)
+	}
+	tests := []struct {
+		name      string
+		err       error
+		retries   int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "rate limit retried", err: xpost.Retryable(errors.New("slow down"), http.StatusTooManyRequests, time.Millisecond), retries: 1, wantCalls: 2},
+		{name: "no retries", err: xpost.Retryable(errors.New("slow down"), http.StatusTooManyRequests, time.Millisecond), wantCalls: 1, wantErr: true},
+		{name: "permanent error", err: errors.New("invalid"), retries: 2, wantCalls: 1, wantErr: true},
+		// A dial timeout means nothing reached the server.
+		{name: "connection timeout retried", err: withRetryAfter(timeout("dial")), retries: 1, wantCalls: 2},
+		// The post may have been published before the response timed out.
+		{name: "response timeout not retried", err: timeout("read"), retries: 2, wantCalls: 1, wantErr: true},
+		// The post exists; only a follow-up such as a description reply failed.
+		{name: "failure after the post not retried", err: xpost.Partial(withRetryAfter(timeout("dial"))), retries: 2, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		poster := newFakePoster("mastodon", 500)
+		poster.failAt, poster.failErr = 1, tt.err
+		_, err := postWithRetry(context.Background(), poster, xpost.Request{Message: "hi"}, tt.retries)
+		if (err != nil) != tt.wantErr || poster.calls != tt.wantCalls {
+			t.Errorf("%s: postWithRetry = %v after %d calls; want error %v after %d", tt.name, err, poster.calls, tt.wantErr, tt.wantCalls)
+		}
+	}
+}
+
+func TestDispatchPartialFailure(t *testing.T) {
+	poster := newFakePoster("mastodon", 500)
+	poster.failAt = 1
+	poster.failErr = xpost.Partial(xpost.Retryable(errors.New("post image description reply: slow down"), http.StatusTooManyRequests, time.Millisecond))
+	var out bytes.Buffer
+	outcomes, err := dispatch(context.Background(), []xpost.Poster{poster}, xpost.Request{Message: "hi"}, &out, dispatchOptions{retries: 2})
+	if err == nil || poster.calls != 1 || len(poster.requests()) != 1 {
+		t.Fatalf("dispatch = %v after %d calls; want an error and no retry", err, poster.calls)
+	}
+	if len(outcomes) != 1 || !outcomes[0].posted() || outcomes[0].result.URL != "https://example.com/mastodon-1" {
+		t.Errorf("outcomes = %+v, want the post's result kept", outcomes)
+	}
+	for _, want := range []string{"https://example.com/mastodon-1", "error: mastodon: post image description reply: slow down"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output %q lacks %q", out.String(), want)
+		}
+	}
+}
+
+// withRetryAfter shortens the backoff of a retryable err for the tests.
+func withRetryAfter(err error) error {
+	var retryable xpost.RetryableError
+	if !errors.As(err, &retryable) {
+		return err
+	}
+	retryable.RetryAfter = time.Millisecond
+	return retryable
+}
@@ -22,46 +22,116 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/imageprep"
 	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/secrets"
+	"github.com/blacktop/xpost/internal/state"
 	"github.com/blacktop/xpost/internal/xpost"
-	"github.com/blacktop/xpost/internal/xpost/bluesky"
-	"github.com/blacktop/xpost/internal/xpost/mastodon"
-	"github.com/blacktop/xpost/internal/xpost/twitter"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
 
 var (
-	messageFlag string
-	linkFlag    string
-	imagePath   string
-	imageAlt    string
-	targetsFlag []string
-	dryRun      bool
-	verbose     bool
-)
+	messageFlag   string
+	linkFlag      string
+	imagePaths    []string
+	imageAlts     []string
+	altAsReply    bool
+	altOverflow   bool
+	requireAlt    bool
+	community     string
+	mediaType     string
+	crosslinkFlag string
+	strict        bool
+	imageCredit   string
+	imageCreditIn string
+	notifyWebhook string
+	resultsPath   string
+	bestEffort    bool
+	preferFormats []string
+	autoResize    bool
+	truncate      bool
+	thread        bool
+	maxThread     int
+	limitCheck    bool
+	appendDate    string
+	dateTimezone  string
+	expireAfter   time.Duration
+	dedupeWindow  time.Duration
+	pollOptions   []string
+	pollDuration  time.Duration
+	language      string
+	force         bool
+	appendCommand string
+	appendTimeout time.Duration
+	altTexts      = map[string]*string{}
+	messageFlags  = map[string]*string{}
+	replyToFlags  = map[string]*string{}
+	quoteURL      string
+	contentWarn   string
+	visibility    string
+	linkPreview   bool
+	confirmVis    bool
+	confirmPost   bool
+	messageGlob   string
+	messagePath   string
+	noPrompt      bool
+	activeHours   string
+	activeHoursTZ string
+	waitForWindow bool
+	toWindow      bool
+	scheduleFlag  string
+	scheduleWait  bool
+	onSuccess     string
+	onFailure     string
+	plainPunct    []string
+	stripTags     []string
+	targetsFlag   []string
+	dryRun        bool
+	verbose       bool
+	configPath    string
+	account       string
+	logFile       string
+	logFormat     string
+	httpTimeout   time.Duration
+	deadline      time.Duration
+	retries       int
+	colorMode     string
+	noColor       bool
 
-var supportedTargets = map[string]struct{}{
-	"bluesky":  {},
-	"mastodon": {},
-	"twitter":  {},
-}
+	// closeLog closes the --log-file once the command has finished.
+	closeLog = func() error { return nil }
+)
 
 const (
-	defaultAltText       = "Image attached via xpost"
-	defaultBlueskyPDSURL = "https://bsky.social"
+	defaultAltText    = "Image attached via xpost"
+	defaultDateFormat = "2006-01-02 15:04 MST"
+	defaultMaxThread  = 25
+	appendOutputLimit = 64 << 10 // bytes of --append-command output kept
+	appendWaitDelay   = time.Second
 )
 
 // Execute runs the root command.
 func Execute() error {
+	defer func() { closeLog() }()
 	return newRootCommand().Execute()
 }
 
@@ -73,20 +143,138 @@ func newRootCommand() *cobra.Command {
 			"Provide your message as an argument or with --message and optional --image.",
 		SilenceUsage:  true,
 		SilenceErrors: true,
-		RunE:          runRoot,
+		// Subcommands make cobra reject unknown positional args by default;
+		// the root command takes the message as free-form arguments.
+		Args: cobra.ArbitraryArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			closeFn, err := logutil.Configure(logutil.Options{Path: logFile, Format: logFormat, Verbose: verbose})
+			if err != nil {
+				return err
+			}
+			closeLog = closeFn
+
+			if noColor {
+				if cmd.Flags().Changed("color") && colorMode != "never" {
+					return errors.New("--no-color conflicts with --color=" + colorMode)
+				}
+				colorMode = "never"
+			}
+			if !slices.Contains([]string{"auto", "always", "never"}, colorMode) {
+				return fmt.Errorf("invalid --color %q: use auto, always, or never", colorMode)
+			}
+
+			if cmd.Flags().Changed("timeout") && httpTimeout <= 0 {
+				return errors.New("--timeout must be positive")
+			}
+			return nil
+		},
+		RunE: runRoot,
 		Example: `  xpost --message "hello world" --image ./shot.png
+  xpost "Before and after" --image a.png --alt-text "Before" --image b.png --alt-text "After"
   xpost "Ship it!" --target twitter --target mastodon
   echo "Release shipped" | xpost --targets all`,
 	}
 
 	cmd.Flags().StringVarP(&messageFlag, "message", "m", "", "Message text to post")
+	cmd.Flags().BoolVar(&noPrompt, "no-prompt", false, "Fail instead of prompting for the message when none is given on a terminal")
+	cmd.Flags().StringVarP(&messagePath, "file", "f", "", "Read the message from this file (YAML front matter may set link, image, alt_text, targets, cw)")
+	cmd.Flags().StringVar(&messageGlob, "message-file-glob", "", "Use the newest file matching this glob as the message (YAML front matter may set link, image, alt_text, targets, cw)")
 	cmd.Flags().StringVarP(&linkFlag, "link", "l", "", "URL to append to message (formatted with newlines)")
-	cmd.Flags().StringVar(&imagePath, "image", "", "Path to an image to attach")
-	cmd.Flags().StringVar(&imageAlt, "alt-text", "", "Alternative text to describe the image")
-	cmd.Flags().StringSliceVar(&targetsFlag, "target", []string{"twitter", "mastodon", "bluesky"}, "Targets to post to (twitter, mastodon, bluesky, or all)")
+	cmd.Flags().StringVar(&appendCommand, "append-command", "", "Run a shell command and append its trimmed stdout to the message")
+	cmd.Flags().DurationVar(&appendTimeout, "append-command-timeout", 10*time.Second, "Maximum time to wait for --append-command")
+	cmd.Flags().StringVar(&appendDate, "append-date", "", "Append the current date/time, optionally with a Go time layout (default \""+defaultDateFormat+"\")")
+	cmd.Flags().Lookup("append-date").NoOptDefVal = defaultDateFormat
+	cmd.Flags().StringVar(&dateTimezone, "date-timezone", "", "IANA time zone for --append-date (default local time)")
+	cmd.Flags().StringVar(&activeHours, "active-hours", "", "Only post within this daily window, e.g. 08:00-22:00")
+	cmd.Flags().StringVar(&activeHoursTZ, "active-hours-tz", "", "IANA time zone for --active-hours (default local time)")
+	cmd.Flags().BoolVar(&waitForWindow, "wait-for-window", false, "Wait for --active-hours to open instead of failing")
+	cmd.Flags().BoolVar(&toWindow, "schedule-to-window", false, "Outside --active-hours, schedule the post for when the window opens (targets that schedule natively)")
+	cmd.Flags().StringVar(&scheduleFlag, "schedule", "", "Publish at this RFC 3339 time or after this duration (e.g. 2h); Mastodon schedules natively")
+	cmd.Flags().BoolVar(&scheduleWait, "schedule-wait", false, "Wait in the foreground until --schedule and post everywhere then, for targets that cannot schedule")
+	cmd.Flags().StringArrayVar(&pollOptions, "poll-option", nil, "Add a poll with this option on Twitter/X and Mastodon (repeat for 2 to 4 options)")
+	cmd.Flags().DurationVar(&pollDuration, "poll-duration", 24*time.Hour, "How long the poll stays open")
+	cmd.Flags().StringArrayVar(&imagePaths, "image", nil, "Path to an image, or an MP4/MOV video on Twitter and Mastodon, to attach (repeatable)")
+	cmd.Flags().StringArrayVar(&imageAlts, "alt-text", nil, "Alternative text for the image given in the same position (repeatable)")
+	for _, target := range xpost.Providers() {
+		alt := new(string)
+		altTexts[target] = alt
+		cmd.Flags().StringVar(alt, "alt-text-"+target, "", fmt.Sprintf("Alternative text for the first image on %s (overrides --alt-text)", providerLabel(target)))
+	}
+	for _, target := range xpost.Providers() {
+		msg := new(string)
+		messageFlags[target] = msg
+		cmd.Flags().StringVar(msg, "message-"+target, "", fmt.Sprintf("Message to post on %s instead of the base message", providerLabel(target)))
+	}
+	for _, target := range xpost.Providers() {
+		parent := new(string)
+		replyToFlags[target] = parent
+		cmd.Flags().StringVar(parent, "reply-to-"+target, "", fmt.Sprintf("URL or ID of the %s post to reply to", providerLabel(target)))
+	}
+	cmd.Flags().StringVar(&mediaType, "media-type", "", "Force the images' MIME type (e.g. image/png) instead of detecting it")
+	cmd.Flags().StringVar(&imageCredit, "image-credit", "", "Attribution for the image (e.g. \"Photo: Jane Doe\")")
+	cmd.Flags().StringVar(&imageCreditIn, "image-credit-in", "message", "Where to add the image credit (message or alt)")
+	cmd.Flags().StringSliceVar(&plainPunct, "plain-punctuation", nil, "Providers to post with ASCII quotes, dashes, and ellipses (e.g. twitter,lemmy)")
+	cmd.Flags().StringSliceVar(&stripTags, "strip-hashtags", nil, "Providers to post without hashtags: trailing tags are dropped, inline ones lose the # (e.g. twitter)")
+	cmd.Flags().StringSliceVar(&preferFormats, "prefer-format", nil, "Transcode the image for a provider when beneficial (<provider>=jpeg|png, e.g. bluesky=jpeg)")
+	cmd.Flags().BoolVar(&autoResize, "auto-resize", false, "Shrink images over a provider's size limit before uploading")
+	cmd.Flags().BoolVar(&altAsReply, "alt-as-reply", false, "Also post the image description as a reply for clients that hide alt text")
+	cmd.Flags().BoolVar(&requireAlt, "require-alt", false, "Fail validation when an image has no alt text instead of using a placeholder")
+	cmd.Flags().BoolVar(&altOverflow, "alt-overflow-reply", false, "Shorten image descriptions over a network's cap and post the full text as a reply")
+	cmd.Flags().StringVar(&community, "community", "", "Lemmy community to post to")
+	cmd.Flags().StringSliceVar(&targetsFlag, "target", []string{"twitter", "mastodon", "bluesky"}, fmt.Sprintf("Targets to post to (%s, or all for twitter, mastodon and bluesky; prefix a name with - to skip it)", strings.Join(xpost.Providers(), ", ")))
+	cmd.Flags().IntVar(&retries, "retries", 0, "Retry a post this many times when a provider reports a rate limit, server error or connection timeout")
+	cmd.Flags().DurationVar(&deadline, "deadline", 0, "Give up on posts still in flight after this long (e.g. 5m); 0 waits indefinitely")
+	cmd.Flags().BoolVar(&bestEffort, "skip-unconfigured", false, "Skip targets that fail to set up (e.g. missing credentials) and post to the rest")
+	cmd.Flags().StringVar(&language, "lang", "", "Language of the message as a BCP 47 code, e.g. en or pt-BR (Mastodon; detected from the script when unset)")
+	cmd.Flags().StringVar(&contentWarn, "cw", "", "Content warning (spoiler text) for Mastodon, Pixelfed and Misskey")
+	cmd.Flags().StringVar(&visibility, "visibility", "", "Visibility for Mastodon, Pixelfed and Misskey (public, unlisted, private or direct)")
+	cmd.Flags().BoolVar(&linkPreview, "link-preview", false, "Attach a link card for --link (or the first URL) on Bluesky posts without images")
+	cmd.Flags().StringVar(&quoteURL, "quote", "", "URL of a post to quote (embedded where the provider supports it, linked elsewhere; rejected by Mastodon)")
+	cmd.Flags().StringVar(&crosslinkFlag, "crosslink", "", "Post to one provider first and link to it from the others (primary=<provider>)")
+	cmd.Flags().DurationVar(&expireAfter, "expire", 0, "Schedule the posts for deletion by 'xpost prune' after this long (e.g. 24h)")
+	cmd.Flags().DurationVar(&dedupeWindow, "dedupe-window", 0, "Skip targets that posted the same content within this long (e.g. 10m)")
+	cmd.Flags().BoolVar(&force, "force", false, "Post even if --dedupe-window finds the same post")
+	cmd.Flags().StringVar(&onSuccess, "on-success", "", "Shell command to run for each provider that posted ($1=provider, $2=URL)")
+	cmd.Flags().StringVar(&onFailure, "on-failure", "", "Shell command to run for each provider that failed ($1=provider; error in $XPOST_HOOK_ERROR)")
+	cmd.Flags().StringVar(&notifyWebhook, "notify-webhook", "", "POST a JSON summary of the results to this URL after posting")
+	cmd.Flags().StringVar(&resultsPath, "results", "", "Write a JSON summary of the results, including post IDs, to this file (see xpost delete)")
+	cmd.Flags().BoolVar(&truncate, "truncate", false, "Shorten the message with an ellipsis where it exceeds a provider's limit")
+	cmd.Flags().BoolVar(&thread, "thread", false, "Split a message that exceeds a provider's limit into a numbered thread of replies")
+	cmd.Flags().IntVar(&maxThread, "max-thread-posts", defaultMaxThread, "Fail instead of posting a --thread longer than this many posts")
+	cmd.MarkFlagsMutuallyExclusive("truncate", "thread")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print actions without posting")
+	cmd.Flags().BoolVar(&confirmPost, "confirm", false, "Show the posts and ask before sending them (skipped when stdin is not a terminal)")
+	cmd.Flags().BoolVar(&confirmVis, "confirm-visibility", false, "After posting, look each post up as a logged-out reader to flag possible filtering (best-effort)")
+	cmd.Flags().BoolVar(&limitCheck, "limit-check-only", false, "Only check the message fits each target's limits; needs no credentials or network")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Treat all warnings (e.g. banned hashtags) as errors and post nothing")
 	cmd.PersistentFlags().BoolVarP(&verbose, "verbose", "V", false, "Enable verbose logging")
+	cmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Append logs to this file instead of stderr")
+	cmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format (text or json)")
+	cmd.PersistentFlags().DurationVar(&httpTimeout, "timeout", 0, "Timeout for each API request, and each upload chunk (default 30s, or set XPOST_HTTP_TIMEOUT)")
+	cmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file (default ~/.config/xpost/config.yaml)")
+	cmd.PersistentFlags().StringVar(&colorMode, "color", "auto", "Color provider names: auto (terminals without NO_COLOR), always, or never")
+	cmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Never color output (same as --color=never)")
+	cmd.PersistentFlags().StringVar(&account, "account", "", "Post from this named account in the config file (default credentials where a provider lacks it)")
 	cmd.Flags().SortFlags = false
+	registerTargetCompletion(cmd)
+	registerVisibilityCompletion(cmd)
+	cmd.Version, _, _ = buildVersion()
+	cmd.SetVersionTemplate(versionText())
+	cmd.RegisterFlagCompletionFunc("color", cobra.FixedCompletions([]string{"auto", "always", "never"}, cobra.ShellCompDirectiveNoFileComp))
+
+	cmd.AddCommand(newConfigCommand())
+	cmd.AddCommand(newReplyCommand())
+	cmd.AddCommand(newPruneCommand())
+	cmd.AddCommand(newResumeCommand())
+	cmd.AddCommand(newBoostCommand())
+	cmd.AddCommand(newReactCommand())
+	cmd.AddCommand(newProbeCommand())
+	cmd.AddCommand(newCountCommand())
+	cmd.AddCommand(newVerifyCommand())
+	cmd.AddCommand(newDeleteCommand())
+	cmd.AddCommand(newEditCommand())
+	cmd.AddCommand(newLoginCommand())
+	cmd.AddCommand(newVersionCommand())
 
 	return cmd
 }
@@ -96,32 +284,613 @@ func runRoot(cmd *cobra.Command, args []string) error {
 
 	logutil.SetVerbose(verbose)
 
+	// Secret backends are remote, so the credential-free limit check skips them.
+	if !limitCheck {
+		if err := loadSecrets(ctx); err != nil {
+			return err
+		}
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
 	message, err := resolveMessage(cmd, args)
 	if err != nil {
 		return err
 	}
+	messages := resolveMessageOverrides()
+	replyTos := resolveReplyTos()
+	var appendOutput string
+	if appendCommand != "" {
+		if appendOutput, err = runAppendCommand(ctx, appendCommand, appendTimeout); err != nil {
+			return err
+		}
+	}
+	if appendDate == "" && dateTimezone != "" {
+		return errors.New("--date-timezone requires --append-date")
+	}
+	now := time.Now()
+	if message, err = decorateMessage(message, appendOutput, now); err != nil {
+		return err
+	}
+	for target, m := range messages {
+		if messages[target], err = decorateMessage(m, appendOutput, now); err != nil {
+			return err
+		}
+	}
 
 	resolvedTargets, err := normalizeTargets(targetsFlag)
 	if err != nil {
 		return err
 	}
+	campaign, err := campaignTag(cfg.Campaign, time.Now())
+	if err != nil {
+		return err
+	}
+	provOpts, err := providerOptions(cfg)
+	if err != nil {
+		return err
+	}
 
+	images, err := buildImages(imagePaths, imageAlts, strings.ToLower(strings.TrimSpace(mediaType)))
+	if err != nil {
+		return err
+	}
 	req := xpost.Request{
-		Message:   message,
-		Link:      strings.TrimSpace(linkFlag),
-		ImagePath: imagePath,
-		ImageAlt:  strings.TrimSpace(imageAlt),
+		Message:        message,
+		Link:           strings.TrimSpace(linkFlag),
+		Images:         images,
+		AltAsReply:     altAsReply,
+		Community:      strings.TrimSpace(community),
+		Quote:          strings.TrimSpace(quoteURL),
+		ContentWarning: strings.TrimSpace(contentWarn),
+		Visibility:     strings.ToLower(strings.TrimSpace(visibility)),
+		LinkPreview:    linkPreview,
+	}
+	if req.Poll, err = buildPoll(pollOptions, pollDuration, cmd.Flags().Changed("poll-duration")); err != nil {
+		return err
+	}
+	if req.LinkPreview {
+		debugUnsupported("--link-preview", resolvedTargets, func(f xpost.Features) bool { return f.LinkPreview })
+	}
+	var postAt time.Time
+	if scheduleFlag != "" {
+		if postAt, err = parseSchedule(scheduleFlag, time.Now()); err != nil {
+			return err
+		}
+	} else if scheduleWait {
+		return errors.New("--schedule-wait requires --schedule")
+	}
+	var window activeWindow
+	if activeHours != "" {
+		if window, err = parseActiveHours(activeHours, activeHoursTZ); err != nil {
+			return err
+		}
+	} else if activeHoursTZ != "" || waitForWindow || toWindow {
+		return errors.New("--active-hours-tz, --wait-for-window and --schedule-to-window require --active-hours")
+	}
+	if toWindow {
+		if scheduleFlag != "" || waitForWindow {
+			return errors.New("--schedule-to-window cannot be combined with --schedule or --wait-for-window")
+		}
+		postAt = window.scheduleFor(time.Now())
+	}
+	if !postAt.IsZero() && !scheduleWait {
+		// Scheduled posts do not exist yet, so nothing can reply to, link
+		// to, delete or look up the post.
+		if thread || crosslinkFlag != "" || expireAfter > 0 || confirmVis {
+			return errors.New("--schedule cannot be combined with --thread, --crosslink, --expire or --confirm-visibility (use --schedule-wait)")
+		}
+		req.ScheduledAt = postAt
+	}
+	if req.Visibility != "" {
+		debugUnsupported("--visibility", resolvedTargets, func(f xpost.Features) bool { return f.Visibility })
+	}
+	if req.Language, err = normalizeLanguage(language); err != nil {
+		return err
+	}
+	if req.Language != "" {
+		debugUnsupported("--lang", resolvedTargets, func(f xpost.Features) bool { return f.Language })
+	}
+	if req.ContentWarning != "" {
+		debugUnsupported("--cw", resolvedTargets, func(f xpost.Features) bool { return f.ContentWarning })
+	}
+	if req.AltAsReply && slices.ContainsFunc(req.Images, func(img xpost.ImageAttachment) bool { return img.Alt == "" }) {
+		return errors.New("--alt-as-reply requires --alt-text for every image")
+	}
+	if err := applyImageCredit(&req, messages, strings.TrimSpace(imageCredit), imageCreditIn); err != nil {
+		return err
+	}
+	applyDefaultAlt(req.Images)
+	// messageFor is the text each target posts before per-provider changes.
+	messageFor := func(target string) string {
+		if m, ok := messages[target]; ok {
+			return m
+		}
+		return req.Message
+	}
+	if req.Language == "" && slices.Contains(resolvedTargets, "mastodon") {
+		if req.Language = detectLanguage(messageFor("mastodon")); req.Language != "" {
+			logutil.Debugf("mastodon: detected message language: %s", req.Language)
+		}
+	}
+
+	altOverrides, err := resolveAltOverrides(len(req.Images) > 0, strings.TrimSpace(imageCredit), imageCreditIn)
+	if err != nil {
+		return err
+	}
+
+	var warnings warningSet
+	for _, target := range resolvedTargets {
+		checkBannedHashtags(&warnings, cfg.BannedHashtags, messageFor(target), []string{target})
+	}
+	if err := warnings.enforce(strict, cmd.OutOrStdout()); err != nil {
+		return err
+	}
+
+	// An auto-applied content warning is a notice about a fix, not a problem,
+	// so it is not subject to --strict.
+	var autoCW map[string]string
+	if req.ContentWarning == "" && slices.Contains(resolvedTargets, "mastodon") {
+		if cw, tag := matchCWHashtag(cfg.Mastodon.CWHashtags, messageFor("mastodon")); cw != "" {
+			autoCW = map[string]string{"mastodon": cw}
+			logutil.Warnf("mastodon: content warning %q auto-applied because of #%s", cw, tag)
+		}
+	}
+
+	var dedupeKeys map[string]string
+	if dedupeWindow < 0 {
+		return errors.New("--dedupe-window must be positive")
+	}
+	if dedupeWindow > 0 && !limitCheck {
+		dedupeKeys = make(map[string]string, len(resolvedTargets))
+		for _, target := range resolvedTargets {
+			dedupeKeys[target] = dedupeKey(target, messageFor(target), replyTos[target], req)
+		}
+		if !force {
+			if resolvedTargets, err = skipDuplicates(resolvedTargets, dedupeKeys, dedupeWindow, time.Now(), cmd.OutOrStdout()); err != nil {
+				return err
+			}
+			if len(resolvedTargets) == 0 {
+				return nil
+			}
+		}
+	}
+
+	primary, err := parseCrosslink(crosslinkFlag, resolvedTargets)
+	if err != nil {
+		return err
+	}
+	if maxThread < 1 {
+		return errors.New("--max-thread-posts must be at least 1")
+	}
+
+	if limitCheck {
+		return checkLimits(resolvedTargets, req, cmd.OutOrStdout(), dispatchOptions{
+			truncate:        truncate,
+			thread:          thread,
+			maxThreadPosts:  maxThread,
+			altOverflow:     altOverflow,
+			requireAlt:      requireAlt,
+			campaignTag:     campaign,
+			crosslink:       primary,
+			messages:        messages,
+			replyTos:        replyTos,
+			altTexts:        altOverrides,
+			contentWarns:    autoCW,
+			providerOptions: provOpts,
+		})
+	}
+
+	imageFormats, err := parsePreferFormats(preferFormats)
+	if err != nil {
+		return err
+	}
+	if len(imageFormats) > 0 && len(req.Images) == 0 {
+		return errors.New("--prefer-format requires --image")
+	}
+
+	if expireAfter < 0 {
+		return errors.New("--expire must be positive")
+	}
+
+	// --schedule-to-window has already moved a post made outside the window.
+	if activeHours != "" && !toWindow {
+		if err := enforceActiveHours(ctx, window, time.Now(), waitForWindow, dryRun, cmd.OutOrStdout()); err != nil {
+			return err
+		}
+	}
+	if scheduleWait {
+		if err := waitForSchedule(ctx, postAt, dryRun, cmd.OutOrStdout()); err != nil {
+			return err
+		}
+	}
+
+	build := buildPosters
+	if bestEffort {
+		build = buildAvailablePosters
+	}
+	if deadline < 0 {
+		return errors.New("--deadline must be positive")
+	}
+	if retries < 0 {
+		return errors.New("--retries must not be negative")
+	}
+	// The deadline covers logging in and posting, but not the follow-up
+	// read-back, hooks and notifications.
+	runCtx := ctx
+	if deadline > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+
+	posters, err := build(runCtx, cfg, resolvedTargets)
+	if err != nil {
+		return err
+	}
+	defer closePosters(ctx, posters)
+	if primary != "" && !slices.ContainsFunc(posters, func(p xpost.Poster) bool { return p.Name() == primary }) {
+		return fmt.Errorf("--crosslink: primary %s was skipped", primary)
+	}
+	if expireAfter > 0 {
+		if err := checkDeletable(posters); err != nil {
+			return err
+		}
+	}
+
+	// Ctrl-C while posting aborts every post still in flight.
+	postCtx, stop := signal.NotifyContext(runCtx, os.Interrupt)
+	defer stop()
+	outcomes, err := dispatch(postCtx, posters, req, cmd.OutOrStdout(), dispatchOptions{
+		dryRun:          dryRun,
+		truncate:        truncate,
+		thread:          thread,
+		maxThreadPosts:  maxThread,
+		altOverflow:     altOverflow,
+		requireAlt:      requireAlt,
+		campaignTag:     campaign,
+		crosslink:       primary,
+		imageFormats:    imageFormats,
+		autoResize:      autoResize,
+		confirm:         confirmPost && canConfirm(cmd.InOrStdin()),
+		confirmIn:       cmd.InOrStdin(),
+		confirmOut:      cmd.ErrOrStderr(),
+		retries:         retries,
+		messages:        messages,
+		replyTos:        replyTos,
+		altTexts:        altOverrides,
+		contentWarns:    autoCW,
+		providerOptions: provOpts,
+	})
+	if confirmVis && len(outcomes) > 0 {
+		readBack(ctx, posters, outcomes, cmd.OutOrStdout())
+	}
+	if expireAfter > 0 && dryRun && err == nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "[dry-run] would schedule deletion after %s\n", expireAfter)
+	}
+	if expireAfter > 0 && len(outcomes) > 0 {
+		if recErr := recordExpiring(outcomes, expireAfter, time.Now()); recErr != nil {
+			logutil.Errorf("record expiring posts: %v", recErr)
+		}
+	}
+	if threads, recErr := recordThreads(outcomes, time.Now()); recErr != nil {
+		logutil.Errorf("record unfinished threads: %v", recErr)
+	} else {
+		for _, t := range threads {
+			fmt.Fprintf(cmd.OutOrStdout(), "Resume the %s thread with: xpost resume %s\n", styledProvider(t.Provider, cmd.OutOrStdout()), t.ID)
+		}
+	}
+	if dedupeWindow > 0 && !dryRun && len(outcomes) > 0 {
+		if recErr := recordPosted(outcomes, dedupeKeys, dedupeWindow, time.Now()); recErr != nil {
+			logutil.Errorf("record posts for --dedupe-window: %v", recErr)
+		}
+	}
+	if (onSuccess != "" || onFailure != "") && len(outcomes) > 0 {
+		runHooks(ctx, onSuccess, onFailure, outcomes)
+	}
+	if notifyWebhook != "" && len(outcomes) > 0 {
+		notify(ctx, notifyWebhook, outcomes)
+	}
+	if resultsPath != "" && len(outcomes) > 0 {
+		if wErr := writeResults(resultsPath, outcomes); wErr != nil {
+			logutil.Errorf("write results: %v", wErr)
+		}
+	}
+	return err
+}
+
+// features returns the optional request fields the provider registered under
+// name supports; unknown providers support none.
+func features(name string) xpost.Features {
+	info, _ := xpost.Describe(name)
+	return info.Features
+}
+
+// debugUnsupported notes the targets that will drop the value of flag.
+func debugUnsupported(flag string, targets []string, supported func(xpost.Features) bool) {
+	for _, target := range targets {
+		if !supported(features(target)) {
+			logutil.Debugf("%s: %s is not supported and will be ignored", target, flag)
+		}
+	}
+}
+
+// buildImages pairs each --image with the --alt-text in the same position.
+// Alt text given without any image is ignored.
+func buildImages(paths, alts []string, mediaType string) ([]xpost.ImageAttachment, error) {
+	if len(paths) == 0 {
+		if mediaType != "" {
+			return nil, errors.New("--media-type requires --image")
+		}
+		return nil, nil
 	}
-	if req.ImageAlt == "" && req.ImagePath != "" {
-		req.ImageAlt = defaultAltText
+	if len(alts) > len(paths) {
+		return nil, fmt.Errorf("%d --alt-text values for %d images", len(alts), len(paths))
+	}
+
+	images := make([]xpost.ImageAttachment, len(paths))
+	for i, path := range paths {
+		images[i] = xpost.ImageAttachment{Path: path, MediaType: mediaType}
+		if i < len(alts) {
+			images[i].Alt = strings.TrimSpace(alts[i])
+		}
 	}
+	return images, nil
+}
+
+// applyDefaultAlt gives every image without a description the default one.
+func applyDefaultAlt(images []xpost.ImageAttachment) {
+	for i := range images {
+		if images[i].Alt == "" {
+			images[i].Alt = defaultAltText
+		}
+	}
+}
+
+// checkAltPresent reports the first image whose alt text is missing or the
+// default placeholder, which tells screen-reader users nothing.
+func checkAltPresent(provider string, images []xpost.ImageAttachment) error {
+	for _, img := range images {
+		if alt := strings.TrimSpace(img.Alt); alt == "" || alt == defaultAltText {
+			return xpost.ValidationError{Provider: provider, Reason: fmt.Sprintf("image %s has no alt text (--require-alt)", img.Path)}
+		}
+	}
+	return nil
+}
+
+// applyImageCredit adds an attribution line to the messages or the alt text.
+// It runs before validation so the credit counts against each provider's limit.
+func applyImageCredit(req *xpost.Request, messages map[string]string, credit, placement string) error {
+	if credit == "" {
+		return nil
+	}
+	if len(req.Images) == 0 {
+		return errors.New("--image-credit requires --image")
+	}
+	switch strings.ToLower(strings.TrimSpace(placement)) {
+	case "", "message":
+		req.Message = req.Message + "\n\n" + credit
+		for target, m := range messages {
+			messages[target] = m + "\n\n" + credit
+		}
+	case "alt":
+		for i := range req.Images {
+			req.Images[i].Alt = creditAlt(req.Images[i].Alt, credit)
+		}
+	default:
+		return fmt.Errorf("invalid --image-credit-in %q: expected message or alt", placement)
+	}
+	return nil
+}
+
+// runAppendCommand runs command through the shell and returns its trimmed
+// stdout. It runs before validation so the output counts against each
+// provider's limit.
+func runAppendCommand(ctx context.Context, command string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	posters, err := buildPosters(ctx, resolvedTargets)
+	c := shellCommand(ctx, command)
+	c.Stderr = os.Stderr
+	// A child the shell started can keep stdout open after the shell is
+	// killed; stop waiting for it shortly after the timeout.
+	c.WaitDelay = appendWaitDelay
+
+	output, err := c.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("--append-command timed out after %s", timeout)
+	}
 	if err != nil {
+		return "", fmt.Errorf("--append-command: %w", err)
+	}
+	if len(output) > appendOutputLimit {
+		return "", fmt.Errorf("--append-command: output exceeds %d bytes", appendOutputLimit)
+	}
+
+	logutil.Debugf("append-command output: %q", output)
+	return strings.TrimSpace(string(output)), nil
+}
+
+// appendCurrentDate adds now, formatted with layout in the named time zone, to
+// the end of message. It runs before validation so the date counts against
+// each provider's limit.
+func appendCurrentDate(message, layout, timezone string, now time.Time) (string, error) {
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return "", fmt.Errorf("invalid --date-timezone %q: %w", timezone, err)
+		}
+		now = now.In(loc)
+	}
+	return message + "\n\n" + now.Format(layout), nil
+}
+
+// decorateMessage adds the --append-command output and --append-date stamp
+// to message.
+func decorateMessage(message, appendOutput string, now time.Time) (string, error) {
+	if appendOutput != "" {
+		message = message + "\n\n" + appendOutput
+	}
+	if appendDate == "" {
+		return message, nil
+	}
+	return appendCurrentDate(message, appendDate, dateTimezone, now)
+}
+
+// resolveMessageOverrides collects the --message-<provider> values that were
+// set.
+func resolveMessageOverrides() map[string]string {
+	overrides := map[string]string{}
+	for target, msg := range messageFlags {
+		if value := strings.TrimSpace(*msg); value != "" {
+			overrides[target] = value
+		}
+	}
+	return overrides
+}
+
+// resolveReplyTos collects the --reply-to-<provider> values that were set.
+func resolveReplyTos() map[string]string {
+	replyTos := map[string]string{}
+	for target, parent := range replyToFlags {
+		if value := strings.TrimSpace(*parent); value != "" {
+			replyTos[target] = value
+		}
+	}
+	return replyTos
+}
+
+// creditAlt appends an image credit to alt as its own sentence.
+func creditAlt(alt, credit string) string {
+	alt = strings.TrimSpace(alt)
+	if alt != "" && !strings.HasSuffix(alt, ".") && !strings.HasSuffix(alt, "!") && !strings.HasSuffix(alt, "?") {
+		alt += "."
+	}
+	return strings.TrimSpace(alt + " " + credit)
+}
+
+// resolveAltOverrides collects the --alt-text-<provider> values that were set,
+// adding the image credit when it goes in the alt text.
+func resolveAltOverrides(hasImage bool, credit, placement string) (map[string]string, error) {
+	overrides := map[string]string{}
+	for target, alt := range altTexts {
+		if value := strings.TrimSpace(*alt); value != "" {
+			overrides[target] = value
+		}
+	}
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+	if !hasImage {
+		return nil, errors.New("--alt-text-<provider> requires --image")
+	}
+	if credit != "" && strings.EqualFold(strings.TrimSpace(placement), "alt") {
+		for target, alt := range overrides {
+			overrides[target] = creditAlt(alt, credit)
+		}
+	}
+	return overrides, nil
+}
+
+// loadSecrets exports credentials from an external secret store, if one is
+// configured, ahead of the config file.
+func loadSecrets(ctx context.Context) error {
+	backend, err := secrets.FromEnv()
+	if err != nil || backend == nil {
 		return err
 	}
+	logutil.Debugf("loading credentials from %s", backend.Name())
+	return secrets.Apply(ctx, backend)
+}
 
-	return dispatch(ctx, posters, req, cmd.OutOrStdout(), dryRun)
+// loadConfig reads the config file and applies the XPOST_* variables set in
+// the environment over it. The values an --account sets win over both.
+func loadConfig() (*config.Config, error) {
+	path, err := resolveConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if account != "" {
+		switched, err := cfg.UseAccount(account)
+		if err != nil {
+			return nil, err
+		}
+		logutil.Debugf("using account %q for %s", account, strings.Join(switched, ", "))
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if httpTimeout > 0 {
+		cfg.HTTPTimeout = httpTimeout
+	}
+	return cfg, nil
+}
+
+// requestTimeout returns the per-request timeout for commands that read the
+// config file without the environment: --timeout, then XPOST_HTTP_TIMEOUT,
+// then the file's http_timeout.
+func requestTimeout(cfg *config.Config) (time.Duration, error) {
+	if httpTimeout > 0 {
+		return httpTimeout, nil
+	}
+	d, err := config.LookupHTTPTimeout(os.LookupEnv)
+	if err != nil || d > 0 {
+		return d, err
+	}
+	return cfg.HTTPTimeout, nil
+}
+
+// checkBannedHashtags warns about hashtags in message that the config marks
+// as reach-limiting for one of the targets.
+func checkBannedHashtags(warnings *warningSet, banned map[string][]string, message string, targets []string) {
+	tags := xpost.Hashtags(message)
+	if len(tags) == 0 {
+		return
+	}
+
+	for _, target := range targets {
+		for _, tag := range tags {
+			if !slices.ContainsFunc(banned[target], func(b string) bool {
+				return xpost.NormalizeHashtag(b) == xpost.NormalizeHashtag(tag)
+			}) {
+				continue
+			}
+			warnings.add(target, "hashtag #%s is on the banned list and may limit reach", tag)
+		}
+	}
+}
+
+// matchCWHashtag returns the content warning configured for the first hashtag
+// in message that has one, along with that hashtag.
+func matchCWHashtag(rules map[string]string, message string) (string, string) {
+	if len(rules) == 0 {
+		return "", ""
+	}
+	for _, tag := range xpost.Hashtags(message) {
+		for flagged, cw := range rules {
+			if xpost.NormalizeHashtag(flagged) != xpost.NormalizeHashtag(tag) {
+				continue
+			}
+			if cw = strings.TrimSpace(cw); cw == "" {
+				cw = strings.TrimPrefix(strings.TrimSpace(flagged), "#")
+			}
+			return cw, tag
+		}
+	}
+	return "", ""
+}
+
+func resolveConfigPath() (string, error) {
+	if configPath != "" {
+		return configPath, nil
+	}
+	return config.DefaultPath()
 }
 
 func resolveMessage(cmd *cobra.Command, args []string) (string, error) {
@@ -138,22 +907,37 @@ func resolveMessage(cmd *cobra.Command, args []string) (string, error) {
 		message = strings.Join(args, " ")
 	}
 
+	if messagePath != "" {
+		if message != "" || messageGlob != "" {
+			return "", errors.New("provide the message either with --file or as text, not both")
+		}
+		return readMessageFile(cmd, messagePath)
+	}
+	if messageGlob != "" {
+		if message != "" {
+			return "", errors.New("provide the message either with --message-file-glob or as text, not both")
+		}
+		return readNewestMessageFile(cmd, messageGlob)
+	}
+
 	if message != "" {
 		return strings.TrimSpace(message), nil
 	}
 
 	stdin := cmd.InOrStdin()
-	if file, ok := stdin.(*os.File); ok {
-		info, err := file.Stat()
+	piped, terminal, err := stdinState(stdin)
+	if err != nil {
+		return "", fmt.Errorf("read stdin: %w", err)
+	}
+	if piped {
+		data, err := io.ReadAll(stdin)
 		if err != nil {
 			return "", fmt.Errorf("read stdin: %w", err)
 		}
-		if (info.Mode() & os.ModeCharDevice) == 0 {
-			data, err := io.ReadAll(stdin)
-			if err != nil {
-				return "", fmt.Errorf("read stdin: %w", err)
-			}
-			message = strings.TrimSpace(string(data))
+		message = strings.TrimSpace(string(data))
+	} else if terminal && !noPrompt {
+		if message, err = promptMessage(stdin, cmd.OutOrStdout()); err != nil {
+			return "", err
 		}
 	}
 
@@ -164,32 +948,86 @@ func resolveMessage(cmd *cobra.Command, args []string) (string, error) {
 	return message, nil
 }
 
+// stdinState reports whether in is piped input to read the message from, or
+// a terminal to prompt on. It is a variable so tests can fake a terminal.
+var stdinState = func(in io.Reader) (piped, terminal bool, err error) {
+	file, ok := in.(*os.File)
+	if !ok {
+		return false, false, nil
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false, false, err
+	}
+	if info.Mode()&os.ModeCharDevice == 0 {
+		return true, false, nil
+	}
+	return false, term.IsTerminal(int(file.Fd())), nil
+}
+
+// promptMessage asks for the message when xpost is run interactively without
+// one.
+func promptMessage(in io.Reader, out io.Writer) (string, error) {
+	fmt.Fprint(out, "Message: ")
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("read message: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// normalizeTargets resolves the --target values. "all" selects the default
+// targets, and a name prefixed with "-" is removed from the selection; when
+// only exclusions are given they apply to the defaults.
 func normalizeTargets(values []string) ([]string, error) {
+	defaults := []string{"twitter", "mastodon", "bluesky"}
 	if len(values) == 0 {
-		return sortedTargets([]string{"twitter", "mastodon", "bluesky"}), nil
+		return sortedTargets(defaults), nil
 	}
 
-	result := make([]string, 0, len(values))
-	seen := map[string]struct{}{}
+	var include []string
+	exclude := map[string]struct{}{}
 	for _, raw := range values {
 		raw = strings.TrimSpace(strings.ToLower(raw))
-		if raw == "" {
+		name, excluded := strings.CutPrefix(raw, "-")
+		if name == "" {
 			continue
 		}
-		if raw == "all" {
-			return sortedTargets([]string{"twitter", "mastodon", "bluesky"}), nil
+		if name != "all" && !xpost.Registered(name) {
+			return nil, fmt.Errorf("unsupported target %q", name)
 		}
-		if _, ok := supportedTargets[raw]; !ok {
-			return nil, fmt.Errorf("unsupported target %q", raw)
+		switch {
+		case excluded && name == "all":
+			return nil, errors.New(`cannot exclude "all"`)
+		case excluded:
+			exclude[name] = struct{}{}
+		case name == "all":
+			include = append(include, defaults...)
+		default:
+			include = append(include, name)
 		}
-		if _, ok := seen[raw]; ok {
+	}
+	if len(include) == 0 && len(exclude) > 0 {
+		include = defaults
+	}
+
+	result := make([]string, 0, len(include))
+	seen := map[string]struct{}{}
+	for _, name := range include {
+		if _, ok := exclude[name]; ok {
+			continue
+		}
+		if _, ok := seen[name]; ok {
 			continue
 		}
-		seen[raw] = struct{}{}
-		result = append(result, raw)
+		seen[name] = struct{}{}
+		result = append(result, name)
 	}
 
 	if len(result) == 0 {
+		if len(exclude) > 0 {
+			return nil, errors.New("no targets selected: every target is excluded")
+		}
 		return nil, errors.New("no targets selected")
 	}
 
@@ -202,28 +1040,11 @@ func sortedTargets(targets []string) []string {
 	return out
 }
 
-func buildPosters(ctx context.Context, targets []string) ([]xpost.Poster, error) {
-	constructors := map[string]func(context.Context) (xpost.Poster, error){
-		"bluesky": func(ctx context.Context) (xpost.Poster, error) {
-			return bluesky.New(ctx, bluesky.Config{PDSURL: defaultBlueskyPDSURL})
-		},
-		"mastodon": func(ctx context.Context) (xpost.Poster, error) {
-			return mastodon.New(ctx)
-		},
-		"twitter": func(ctx context.Context) (xpost.Poster, error) {
-			return twitter.New(ctx)
-		},
-	}
-
+func buildPosters(ctx context.Context, cfg *config.Config, targets []string) ([]xpost.Poster, error) {
 	posters := make([]xpost.Poster, 0, len(targets))
 	var errs []error
 	for _, target := range targets {
-		constructor, ok := constructors[target]
-		if !ok {
-			errs = append(errs, fmt.Errorf("target %q is not implemented", target))
-			continue
-		}
-		poster, err := constructor(ctx)
+		poster, err := xpost.New(ctx, target, cfg)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("%s: %w", target, err))
 			continue
@@ -232,6 +1053,7 @@ func buildPosters(ctx context.Context, targets []string) ([]xpost.Poster, error)
 	}
 
 	if len(errs) > 0 {
+		closePosters(ctx, posters)
 		return nil, errors.Join(errs...)
 	}
 	if len(posters) == 0 {
@@ -240,83 +1062,837 @@ func buildPosters(ctx context.Context, targets []string) ([]xpost.Poster, error)
 	return posters, nil
 }
 
-func dispatch(ctx context.Context, posters []xpost.Poster, req xpost.Request, out io.Writer, simulate bool) error {
-	// Validate all platforms BEFORE posting to any
-	var validationErrs []error
+// buildAvailablePosters is buildPosters for --skip-unconfigured: targets that
+// fail to construct are logged and dropped, and it only fails when none remain.
+func buildAvailablePosters(ctx context.Context, cfg *config.Config, targets []string) ([]xpost.Poster, error) {
+	posters := make([]xpost.Poster, 0, len(targets))
+	for _, target := range targets {
+		built, err := buildPosters(ctx, cfg, []string{target})
+		if err != nil {
+			logutil.Warnf("skipping %v", err)
+			continue
+		}
+		posters = append(posters, built...)
+	}
+	if len(posters) == 0 {
+		return nil, errors.New("no targets available: every target failed to set up")
+	}
+	return posters, nil
+}
+
+// closePosters releases every poster's resources. Failures are only logged,
+// as the posting outcome has already been decided.
+func closePosters(ctx context.Context, posters []xpost.Poster) {
 	for _, poster := range posters {
-		if err := poster.Validate(req); err != nil {
-			validationErrs = append(validationErrs, fmt.Errorf("%s: %w", poster.Name(), err))
+		if err := xpost.Close(ctx, poster); err != nil {
+			logutil.Debugf("%s: close: %v", poster.Name(), err)
 		}
 	}
+}
+
+// dispatchOptions controls how a validated request is published.
+type dispatchOptions struct {
+	dryRun bool
+	// truncate shortens messages that exceed a provider's limit instead of
+	// failing validation.
+	truncate bool
+	// thread splits messages that exceed a provider's limit into a chain of
+	// replies instead of failing validation.
+	thread bool
+	// maxThreadPosts fails validation for a thread of more posts than this;
+	// 0 means no cap.
+	maxThreadPosts int
+	// altOverflow shortens image descriptions that exceed a provider's cap
+	// and posts the full text as a reply instead of failing validation.
+	altOverflow bool
+	// requireAlt fails validation for images whose alt text is missing or
+	// the default placeholder.
+	requireAlt bool
+	// campaignTag is a hashtag added to every message it fits in.
+	campaignTag string
+	// crosslink names the provider that posts first; every other provider
+	// appends the resulting post URL to its message.
+	crosslink string
+	// messages maps a provider to a message that replaces the base one.
+	messages map[string]string
+	// retries is how many times a transient post failure is retried.
+	retries int
+	// confirm asks on the terminal before anything is posted.
+	confirm bool
+	// confirmIn and confirmOut are where the --confirm prompt reads the
+	// answer and asks the question.
+	confirmIn  io.Reader
+	confirmOut io.Writer
+	// replyTos maps a provider to the post it replies to.
+	replyTos map[string]string
+	// altTexts maps a provider to alt text that replaces the first image's.
+	altTexts map[string]string
+	// contentWarns maps a provider to a content warning applied when the
+	// request has none.
+	contentWarns map[string]string
+	// providerOptions holds the per-provider defaults from the config file.
+	providerOptions map[string]config.Options
+	// imageFormats maps a provider to the image format it prefers uploads in.
+	imageFormats map[string]string
+	// autoResize shrinks images that exceed a provider's size limit.
+	autoResize bool
+}
+
+// crosslinkURLBudget is the length reserved for the primary post's URL when
+// validating the other providers' messages before it is known.
+const crosslinkURLBudget = 80
+
+// crosslinkPlaceholder stands in for the primary post's URL while measuring.
+var crosslinkPlaceholder = "https://" + strings.Repeat("x", crosslinkURLBudget-len("https://"))
+
+// postOutcome records what happened when posting to one provider.
+type postOutcome struct {
+	provider  string
+	result    xpost.PostResult
+	truncated string // text removed from the message by --truncate
+	err       error
+	// thread is set when a --thread failed after its first post, to resume.
+	thread *state.Thread
+}
+
+// posted reports whether the post was published, even if err reports that a
+// follow-up to it, such as an image description reply, failed.
+func (o postOutcome) posted() bool {
+	return o.err == nil || (o.result.ID != "" && errors.As(o.err, new(xpost.PartialError)))
+}
+
+// dispatch validates req against every poster and then publishes it,
+// returning an outcome for each provider that was attempted.
+func dispatch(ctx context.Context, posters []xpost.Poster, req xpost.Request, out io.Writer, opts dispatchOptions) ([]postOutcome, error) {
+	posters = orderPosters(posters, opts.crosslink)
+
+	// Validate all platforms BEFORE posting to any
+	validators := make([]xpost.Validator, 0, len(posters))
+	for _, poster := range posters {
+		validators = append(validators, poster)
+	}
+	requests, truncated, threads, validationErrs := prepareRequests(validators, req, opts)
 	if len(validationErrs) > 0 {
 		fmt.Fprintln(out, "Validation failed - no posts were sent:")
 		for _, err := range validationErrs {
 			fmt.Fprintf(out, "  • %v\n", err)
 		}
-		return errors.Join(validationErrs...)
+		return nil, errors.Join(validationErrs...)
 	}
 
-	if simulate {
-		message := req.Message
-		if req.Link != "" {
-			message = message + "\n\n" + req.Link
-		}
+	if opts.dryRun {
 		for _, poster := range posters {
+			preq := crosslinkRequest(requests[poster.Name()], poster.Name(), opts.crosslink, fmt.Sprintf("<%s post URL>", opts.crosslink))
+			message := preq.Message
+			if preq.Link != "" {
+				message = message + "\n\n" + preq.Link
+			}
 			fmt.Fprintf(out, "[dry-run] would post to %s: %q\n", styledProvider(poster.Name(), out), message)
+			if c, ok := poster.(xpost.Counter); ok {
+				// Measure with the URL budget validation reserved, not the label.
+				count, limit, unit := c.Count(crosslinkRequest(requests[poster.Name()], poster.Name(), opts.crosslink, crosslinkPlaceholder))
+				fmt.Fprintf(out, "[dry-run] %s length: %d/%d %s\n", styledProvider(poster.Name(), out), count, limit, unit)
+			}
+			if p, ok := poster.(xpost.Previewer); ok {
+				for _, line := range p.Preview(preq) {
+					fmt.Fprintf(out, "[dry-run]   %s\n", line)
+				}
+			}
+			if preq.Quote != "" {
+				fmt.Fprintf(out, "[dry-run] %s quoting: %s\n", styledProvider(poster.Name(), out), preq.Quote)
+			}
+			if len(preq.Images) == 0 && len(req.Images) > 0 {
+				fmt.Fprintf(out, "[dry-run] %s text only: images skipped\n", styledProvider(poster.Name(), out))
+			}
+			if preq.ContentWarning != "" {
+				fmt.Fprintf(out, "[dry-run] %s content warning: %q\n", styledProvider(poster.Name(), out), preq.ContentWarning)
+			}
+			if preq.Visibility != "" {
+				fmt.Fprintf(out, "[dry-run] %s visibility: %s\n", styledProvider(poster.Name(), out), preq.Visibility)
+			}
+			if !preq.ScheduledAt.IsZero() {
+				fmt.Fprintf(out, "[dry-run] %s scheduled for %s\n", styledProvider(poster.Name(), out), preq.ScheduledAt.Format(time.RFC1123))
+			}
+			if preq.Poll != nil {
+				fmt.Fprintf(out, "[dry-run] %s poll open for %s: %s\n", styledProvider(poster.Name(), out), preq.Poll.Duration, strings.Join(preq.Poll.Options, " / "))
+			}
+			if removed := truncated[poster.Name()]; removed != "" {
+				fmt.Fprintf(out, "[dry-run] %s %s\n", styledProvider(poster.Name(), out), truncationNote(removed))
+			}
+			for _, part := range threads[poster.Name()] {
+				fmt.Fprintf(out, "[dry-run] %s then reply: %q\n", styledProvider(poster.Name(), out), part)
+			}
+			for _, img := range preq.Images {
+				if img.FullAlt != "" {
+					fmt.Fprintf(out, "[dry-run] %s alt for %s shortened to %q; full description goes in a reply\n", styledProvider(poster.Name(), out), img.Path, img.Alt)
+				}
+			}
+			if format := opts.imageFormats[poster.Name()]; format != "" && len(req.Images) > 0 {
+				fmt.Fprintf(out, "[dry-run] would transcode images to %s for %s when beneficial\n", format, styledProvider(poster.Name(), out))
+			}
+			if opts.autoResize {
+				limit := imageByteLimit(ctx, poster)
+				for _, img := range preq.Images {
+					if info, err := os.Stat(img.Path); err == nil && limit > 0 && !img.IsVideo() && info.Size() > int64(limit) {
+						fmt.Fprintf(out, "[dry-run] would shrink %s (%s) to fit %s's %s limit\n", img.Path, formatBytes(int(info.Size())), styledProvider(poster.Name(), out), formatBytes(limit))
+					}
+				}
+			}
 		}
-		if req.ImagePath != "" {
-			fmt.Fprintf(out, "[dry-run] image: %s (alt: %q)\n", req.ImagePath, req.ImageAlt)
+		for _, img := range req.Images {
+			fmt.Fprintf(out, "[dry-run] image: %s (%s, alt: %q)\n", img.Path, detectMediaType(img), img.Alt)
 		}
-		return nil
+		if len(req.Images) > 0 {
+			for _, poster := range posters {
+				if alt, ok := opts.altTexts[poster.Name()]; ok {
+					fmt.Fprintf(out, "[dry-run] %s alt for %s: %q\n", styledProvider(poster.Name(), out), req.Images[0].Path, alt)
+				}
+			}
+		}
+		if req.ReplyTo != "" {
+			fmt.Fprintf(out, "[dry-run] in reply to: %s\n", req.ReplyTo)
+		}
+		for _, poster := range posters {
+			if parent, ok := opts.replyTos[poster.Name()]; ok {
+				fmt.Fprintf(out, "[dry-run] %s in reply to: %s\n", styledProvider(poster.Name(), out), parent)
+			}
+		}
+		if reply := req.AltReply(); reply != "" {
+			fmt.Fprintf(out, "[dry-run] would reply with image description: %q\n", reply)
+		}
+		return nil, nil
 	}
 
+	if opts.confirm {
+		if err := confirmPosting(ctx, opts.confirmIn, opts.confirmOut, posters, requests); err != nil {
+			return nil, err
+		}
+	}
+
+	post := func(poster xpost.Poster, primaryURL string) postOutcome {
+		preq, cleanup, err := preferImageFormat(requests[poster.Name()], opts.imageFormats[poster.Name()])
+		defer cleanup()
+		if err == nil && opts.autoResize {
+			var shrunk func()
+			preq, shrunk, err = fitImages(preq, imageByteLimit(ctx, poster))
+			defer shrunk()
+		}
+		var res xpost.PostResult
+		if err == nil {
+			res, err = postWithRetry(ctx, poster, crosslinkRequest(preq, poster.Name(), opts.crosslink, primaryURL), opts.retries)
+		}
+		if err == nil || (res.ID != "" && errors.As(err, new(xpost.PartialError))) {
+			// The post exists even if a follow-up failed, so its thread goes on.
+			if threadErr := postThread(ctx, poster, preq, res, threads[poster.Name()], 1, opts.retries); threadErr != nil {
+				err = errors.Join(err, threadErr)
+			}
+		}
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out at --deadline: %w", err)
+		}
+		return postOutcome{
+			provider:  poster.Name(),
+			result:    res,
+			truncated: truncated[poster.Name()],
+			err:       err,
+			thread:    unfinishedThread(poster.Name(), preq, res, err),
+		}
+	}
+
+	var (
+		errs       []error
+		outcomes   []postOutcome
+		primaryURL string
+	)
+	// The crosslink primary goes first since the others link to it.
+	if opts.crosslink != "" {
+		primary := post(posters[0], "")
+		if primary.posted() && primary.result.URL == "" {
+			primary.err = errors.New("no post URL returned to crosslink")
+		}
+		outcomes = append(outcomes, primary)
+		if !primary.posted() {
+			fmt.Fprintf(out, "error: %s: %v\n", primary.provider, primary.err)
+			fmt.Fprintln(out, "error: crosslink primary failed; remaining targets were not posted")
+			return outcomes, fmt.Errorf("%s: %w", primary.provider, primary.err)
+		}
+		primaryURL = primary.result.URL
+		printPosted(out, primary)
+		if primary.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", primary.provider, primary.err))
+		}
+		posters = posters[1:]
+	}
+
+	// The rest post concurrently so a slow provider does not hold up the others.
+	results := make([]postOutcome, len(posters))
+	var wg sync.WaitGroup
+	for i, poster := range posters {
+		wg.Go(func() {
+			results[i] = post(poster, primaryURL)
+		})
+	}
+	wg.Wait()
+
+	slices.SortFunc(results, func(a, b postOutcome) int { return strings.Compare(a.provider, b.provider) })
+	for _, o := range results {
+		if o.posted() {
+			printPosted(out, o)
+		}
+		if o.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", o.provider, o.err))
+		}
+	}
+	outcomes = append(outcomes, results...)
+
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(out, "error: %v\n", err)
+		}
+		return outcomes, errors.Join(errs...)
+	}
+	return outcomes, nil
+}
+
+// printPosted reports a successful post, with its URL when there is one.
+func printPosted(out io.Writer, o postOutcome) {
+	if o.result.Scheduled {
+		fmt.Fprintf(out, "Scheduled on %s (id %s)\n", styledProvider(o.provider, out), o.result.ID)
+		return
+	}
+	if o.result.URL != "" {
+		fmt.Fprintf(out, "Posted to %s: %s\n", styledProvider(o.provider, out), o.result.URL)
+		return
+	}
+	fmt.Fprintf(out, "Posted to %s\n", styledProvider(o.provider, out))
+}
+
+// postThread posts the follow-ups of a thread, each replying to the one
+// before, under res, the latest post published. done counts the posts of
+// the thread already published. Each post is retried like the first, up to
+// retries times. A failure is a *threadError recording what is left to post.
+func postThread(ctx context.Context, poster xpost.Poster, first xpost.Request, res xpost.PostResult, rest []string, done, retries int) error {
+	parent := res.ID
+	var ids []string
+	for i, part := range rest {
+		posted, err := postWithRetry(ctx, poster, threadPart(first, part, parent), retries)
+		if err != nil {
+			return &threadError{
+				posted:    ids,
+				remaining: rest[i:],
+				err:       fmt.Errorf("thread post %d of %d (first post %s): %w", done+i+1, done+len(rest), res.URL, err),
+			}
+		}
+		ids = append(ids, posted.ID)
+		parent = posted.ID
+	}
+	return nil
+}
+
+// prepareRequests builds each provider's request, applying alt text overrides,
+// --truncate and --thread, and validates it. It returns the requests, removed
+// text and follow-up thread posts keyed by provider.
+func prepareRequests(validators []xpost.Validator, req xpost.Request, opts dispatchOptions) (map[string]xpost.Request, map[string]string, map[string][]string, []error) {
+	placeholder := crosslinkPlaceholder
+	requests := make(map[string]xpost.Request, len(validators))
+	truncated := map[string]string{}
+	threads := map[string][]string{}
 	var errs []error
-	for _, poster := range posters {
-		if err := poster.Post(ctx, req); err != nil {
-			errs = append(errs, fmt.Errorf("%s: %w", poster.Name(), err))
+	for _, v := range validators {
+		preq := req
+		preq.Images = slices.Clone(req.Images)
+		if m, ok := opts.messages[v.Name()]; ok {
+			preq.Message = m
+		}
+		if parent, ok := opts.replyTos[v.Name()]; ok {
+			preq.ReplyTo = parent
+		}
+		if alt, ok := opts.altTexts[v.Name()]; ok {
+			preq.Images[0].Alt = alt
+			// An override is written for this provider, so one that is
+			// over its cap is cut to fit rather than failing the post.
+			if t, ok := v.(xpost.AltTruncater); ok && !opts.altOverflow {
+				if short, removed := t.TruncateAlt(alt); removed != "" {
+					preq.Images[0].Alt = short
+					logutil.Warnf("%s: --alt-text-%s %s (use --alt-overflow-reply to post it in full)", v.Name(), v.Name(), truncationNote(removed))
+				}
+			}
+		}
+		if cw, ok := opts.contentWarns[v.Name()]; ok && preq.ContentWarning == "" {
+			preq.ContentWarning = cw
+		}
+		preq = applyProviderOptions(preq, opts.providerOptions[v.Name()])
+		if opts.requireAlt {
+			if err := checkAltPresent(v.Name(), preq.Images); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", v.Name(), err))
+			}
+		}
+		supports := features(v.Name())
+		if !supports.Visibility {
+			preq.Visibility = ""
+		}
+		if !supports.ContentWarning {
+			preq.ContentWarning = ""
+		}
+		if !supports.Poll && preq.Poll != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name(), xpost.ValidationError{
+				Provider: v.Name(),
+				Reason:   "polls are not supported",
+			}))
+		}
+		if !supports.Schedule && !preq.ScheduledAt.IsZero() {
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name(), xpost.ValidationError{
+				Provider: v.Name(),
+				Reason:   "scheduled posting is not supported (use --schedule-wait to wait and post then)",
+			}))
+		}
+		if t, ok := v.(xpost.AltTruncater); ok && opts.altOverflow {
+			for i, img := range preq.Images {
+				if short, removed := t.TruncateAlt(img.Alt); removed != "" {
+					preq.Images[i].FullAlt = img.Alt
+					preq.Images[i].Alt = short
+					logutil.Debugf("%s: description of %s shortened; full text goes in a reply", v.Name(), img.Path)
+				}
+			}
+		}
+		var quoteLink string
+		if supports.NoQuote && preq.Quote != "" {
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name(), xpost.ValidationError{
+				Provider: v.Name(),
+				Reason:   "quote posts are not supported (link to the post in the message instead)",
+			}))
+		} else if q, ok := v.(xpost.Quoter); preq.Quote != "" && (!ok || !q.CanQuote(preq.Quote)) {
+			// Providers that cannot embed the quoted post link to it instead.
+			quoteLink = preq.Quote
+			preq.Message = preq.Message + "\n\n" + quoteLink
+			preq.Quote = ""
+		}
+		preq, removed := truncateRequest(v, preq, opts, placeholder)
+		if quoteLink != "" && !strings.HasSuffix(preq.Message, quoteLink) {
+			// Truncation would cut the link the quote fell back to.
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name(), xpost.ValidationError{
+				Provider: v.Name(),
+				Reason:   "the message is too long to link to the quoted post (shorten it)",
+			}))
 			continue
 		}
-		fmt.Fprintf(out, "Posted to %s\n", styledProvider(poster.Name(), out))
+		preq, rest := threadRequest(v, preq, opts, placeholder)
+		if opts.maxThreadPosts > 0 && len(rest)+1 > opts.maxThreadPosts {
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name(), xpost.ValidationError{
+				Provider: v.Name(),
+				Reason:   fmt.Sprintf("the message splits into a thread of %d posts (max %d; raise --max-thread-posts to allow it)", len(rest)+1, opts.maxThreadPosts),
+			}))
+			continue
+		}
+		if opts.campaignTag != "" && !opts.providerOptions[v.Name()].StripHashtags {
+			preq = addCampaignTag(v, preq, opts, placeholder)
+		}
+		requests[v.Name()] = preq
+		if removed != "" {
+			truncated[v.Name()] = removed
+			logutil.Debugf("%s: %s", v.Name(), truncationNote(removed))
+		}
+		if err := v.Validate(crosslinkRequest(preq, v.Name(), opts.crosslink, placeholder)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name(), err))
+		}
+		if len(rest) > 0 {
+			threads[v.Name()] = rest
+			logutil.Debugf("%s: split into a thread of %d posts", v.Name(), len(rest)+1)
+			for _, part := range rest {
+				if err := v.Validate(threadPart(preq, part, threadReplyPlaceholder)); err != nil {
+					errs = append(errs, fmt.Errorf("%s: thread: %w", v.Name(), err))
+					break
+				}
+			}
+		}
+	}
+	return requests, truncated, threads, errs
+}
+
+// checkLimits validates req for each target without credentials or network
+// access, for use in pre-commit hooks.
+func checkLimits(targets []string, req xpost.Request, out io.Writer, opts dispatchOptions) error {
+	validators := make([]xpost.Validator, 0, len(targets))
+	for _, target := range targets {
+		validator, err := xpost.NewValidator(target)
+		if err != nil {
+			return err
+		}
+		validators = append(validators, validator)
 	}
 
+	_, truncated, threads, errs := prepareRequests(validators, req, opts)
 	if len(errs) > 0 {
+		fmt.Fprintln(out, "Limit check failed:")
 		for _, err := range errs {
-			fmt.Fprintf(out, "error: %v\n", err)
+			fmt.Fprintf(out, "  • %v\n", err)
 		}
 		return errors.Join(errs...)
 	}
+	for _, v := range validators {
+		if parts := threads[v.Name()]; len(parts) > 0 {
+			fmt.Fprintf(out, "%s fits as a thread of %d posts\n", styledProvider(v.Name(), out), len(parts)+1)
+			continue
+		}
+		if removed := truncated[v.Name()]; removed != "" {
+			fmt.Fprintf(out, "%s fits after truncation (%s)\n", styledProvider(v.Name(), out), truncationNote(removed))
+			continue
+		}
+		fmt.Fprintf(out, "%s fits\n", styledProvider(v.Name(), out))
+	}
 	return nil
 }
 
-type providerStyle struct {
-	icon  string
-	label string
-	color string
+// applyProviderOptions merges a provider's config-file defaults into req.
+// Explicit flags win over the defaults.
+func applyProviderOptions(req xpost.Request, o config.Options) xpost.Request {
+	if o.TextOnly {
+		req.Images = nil
+	}
+	if req.ContentWarning == "" {
+		req.ContentWarning = strings.TrimSpace(o.ContentWarning)
+	}
+	if req.Visibility == "" {
+		req.Visibility = strings.ToLower(strings.TrimSpace(o.DefaultVisibility))
+	}
+	if o.StripHashtags {
+		req.Message = xpost.StripHashtags(req.Message)
+	}
+	if o.PlainPunctuation {
+		req.Message = xpost.PlainPunctuation(req.Message)
+		req.ContentWarning = xpost.PlainPunctuation(req.ContentWarning)
+		// The images are shared with the other providers' requests.
+		req.Images = slices.Clone(req.Images)
+		for i := range req.Images {
+			req.Images[i].Alt = xpost.PlainPunctuation(req.Images[i].Alt)
+		}
+	}
+	return req
+}
+
+// providerOptions merges flags that set per-provider options into the config
+// file's defaults.
+func providerOptions(cfg *config.Config) (map[string]config.Options, error) {
+	options := cfg.ProviderOptions()
+	for _, raw := range plainPunct {
+		provider := strings.ToLower(strings.TrimSpace(raw))
+		if !xpost.Registered(provider) {
+			return nil, fmt.Errorf("invalid --plain-punctuation %q: unsupported provider", raw)
+		}
+		o := options[provider]
+		o.PlainPunctuation = true
+		options[provider] = o
+	}
+	for _, raw := range stripTags {
+		provider := strings.ToLower(strings.TrimSpace(raw))
+		if !xpost.Registered(provider) {
+			return nil, fmt.Errorf("invalid --strip-hashtags %q: unsupported provider", raw)
+		}
+		o := options[provider]
+		o.StripHashtags = true
+		options[provider] = o
+	}
+	return options, nil
+}
+
+// truncateRequest shortens req's message to fit poster when --truncate is set
+// and the provider supports it, returning the removed text. Room is kept for
+// the crosslink URL, which is appended after the message.
+func truncateRequest(v xpost.Validator, req xpost.Request, opts dispatchOptions, crosslinkURL string) (xpost.Request, string) {
+	t, ok := v.(xpost.Truncater)
+	if !opts.truncate || !ok {
+		return req, ""
+	}
+
+	probe := req
+	if opts.crosslink != "" && v.Name() != opts.crosslink {
+		probe.Link = strings.TrimSpace(crosslinkURL + "\n\n" + req.Link)
+	}
+	shortened, removed := t.Truncate(probe)
+	req.Message = shortened.Message
+	return req, removed
+}
+
+// threadReplyPlaceholder stands in for the previous thread post when
+// validating follow-ups before it exists.
+const threadReplyPlaceholder = "1"
+
+// threadRequest splits req's message into a thread when --thread is set and
+// it exceeds the provider's limit. It returns req carrying the first post and
+// the text of the rest; the link is folded into the text so it is counted.
+// Every post keeps room for the crosslink URL, which follows the first.
+func threadRequest(v xpost.Validator, req xpost.Request, opts dispatchOptions, crosslinkURL string) (xpost.Request, []string) {
+	counter, ok := v.(xpost.Counter)
+	if !opts.thread || !ok {
+		return req, nil
+	}
+	n, limit, _ := counter.Count(req)
+	if n <= limit {
+		return req, nil
+	}
+
+	text := req.Message
+	if req.Link != "" {
+		text = text + "\n\n" + req.Link
+	}
+	measure := func(r xpost.Request) int {
+		n, _, _ := counter.Count(r)
+		return n
+	}
+	count := func(s string) int { return measure(xpost.Request{Message: s}) }
+	// The content warning repeats on every post.
+	limit -= measure(xpost.Request{ContentWarning: req.ContentWarning})
+	if opts.crosslink != "" && v.Name() != opts.crosslink {
+		limit -= count("\n\n" + crosslinkURL)
+	}
+	parts := xpost.SplitThread(text, limit, count)
+	req.Message, req.Link = parts[0], ""
+	return req, parts[1:]
+}
+
+// threadPart returns the request for a follow-up thread post replying to
+// parent. It keeps first's content warning, visibility and community.
+func threadPart(first xpost.Request, text, parent string) xpost.Request {
+	return xpost.Request{
+		Message:        text,
+		ReplyTo:        parent,
+		ContentWarning: first.ContentWarning,
+		Visibility:     first.Visibility,
+		Community:      first.Community,
+		Language:       first.Language,
+	}
+}
+
+// truncationNote describes text removed by --truncate.
+func truncationNote(removed string) string {
+	return fmt.Sprintf("truncated %d chars: '%s'", utf8.RuneCountInString(removed), strings.TrimSpace(removed))
+}
+
+// preferImageFormat swaps the request's images for copies transcoded to
+// format when imageprep judges it worthwhile. The returned cleanup removes
+// those copies.
+func preferImageFormat(req xpost.Request, format string) (xpost.Request, func(), error) {
+	var copies []string
+	cleanup := func() {
+		for _, path := range copies {
+			os.Remove(path)
+		}
+	}
+	if format == "" || len(req.Images) == 0 {
+		return req, cleanup, nil
+	}
+
+	req.Images = slices.Clone(req.Images)
+	for i, img := range req.Images {
+		if img.IsVideo() {
+			continue
+		}
+		prepared, err := imageprep.Prefer(img.Path, format)
+		if err != nil {
+			cleanup()
+			return req, func() {}, fmt.Errorf("prepare image: %w", err)
+		}
+		if !prepared.Transcoded {
+			logutil.Debugf("keeping %s as-is (no benefit from %s)", img.Path, format)
+			continue
+		}
+
+		logutil.Debugf("transcoded %s to %s", img.Path, format)
+		copies = append(copies, prepared.Path)
+		req.Images[i].Path = prepared.Path
+		req.Images[i].MediaType = prepared.MediaType
+	}
+	return req, cleanup, nil
+}
+
+// fitImages swaps images larger than maxBytes for smaller re-encoded copies.
+// The returned cleanup removes those copies. Videos, and every image when
+// maxBytes is 0, are left alone.
+func fitImages(req xpost.Request, maxBytes int) (xpost.Request, func(), error) {
+	var copies []string
+	cleanup := func() {
+		for _, path := range copies {
+			os.Remove(path)
+		}
+	}
+	if maxBytes <= 0 || len(req.Images) == 0 {
+		return req, cleanup, nil
+	}
+
+	req.Images = slices.Clone(req.Images)
+	for i, img := range req.Images {
+		if img.IsVideo() {
+			continue
+		}
+		fitted, err := imageprep.FitFile(img.Path, maxBytes)
+		if err != nil {
+			cleanup()
+			return req, func() {}, fmt.Errorf("resize image: %w", err)
+		}
+		if !fitted.Transcoded {
+			continue
+		}
+
+		logutil.Debugf("shrank %s to fit %s", img.Path, formatBytes(maxBytes))
+		copies = append(copies, fitted.Path)
+		req.Images[i].Path = fitted.Path
+		req.Images[i].MediaType = fitted.MediaType
+	}
+	return req, cleanup, nil
+}
+
+// imageByteLimit returns the largest image poster accepts, or 0 when it does
+// not say.
+func imageByteLimit(ctx context.Context, poster xpost.Poster) int {
+	prober, ok := poster.(xpost.Prober)
+	if !ok {
+		return 0
+	}
+	// Capabilities falls back to the defaults on error, which still apply.
+	caps, _ := prober.Capabilities(ctx)
+	return caps.MaxImageBytes
+}
+
+// formatBytes renders n as a human-readable size such as "5 MB".
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.3g MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.3g KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
+
+// detectMediaType returns the media type an attachment will be uploaded as:
+// the override, else the type sniffed from its contents or extension.
+func detectMediaType(img xpost.ImageAttachment) string {
+	if img.MediaType != "" {
+		return img.MediaType
+	}
+	f, err := os.Open(img.Path)
+	if err != nil {
+		return "unreadable"
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	detected := http.DetectContentType(head[:n])
+	if detected == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(filepath.Ext(img.Path)); byExt != "" {
+			return byExt
+		}
+	}
+	return detected
+}
+
+// parsePreferFormats parses repeated "<provider>=<format>" values.
+func parsePreferFormats(values []string) (map[string]string, error) {
+	formats := map[string]string{}
+	for _, raw := range values {
+		provider, name, ok := strings.Cut(raw, "=")
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if !ok || provider == "" {
+			return nil, fmt.Errorf("invalid --prefer-format %q: expected <provider>=<format>", raw)
+		}
+		if !xpost.Registered(provider) {
+			return nil, fmt.Errorf("invalid --prefer-format %q: unsupported provider %q", raw, provider)
+		}
+		format, err := imageprep.ParseFormat(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --prefer-format %q: %w", raw, err)
+		}
+		formats[provider] = format
+	}
+	return formats, nil
+}
+
+// orderPosters moves the crosslink primary to the front so its URL is known
+// before the others post.
+func orderPosters(posters []xpost.Poster, primary string) []xpost.Poster {
+	if primary == "" {
+		return posters
+	}
+	ordered := make([]xpost.Poster, 0, len(posters))
+	for _, poster := range posters {
+		if poster.Name() == primary {
+			ordered = append(ordered, poster)
+		}
+	}
+	for _, poster := range posters {
+		if poster.Name() != primary {
+			ordered = append(ordered, poster)
+		}
+	}
+	return ordered
+}
+
+// crosslinkRequest appends the primary post's URL to the message of every
+// provider other than the primary.
+func crosslinkRequest(req xpost.Request, provider, primary, primaryURL string) xpost.Request {
+	if primary == "" || provider == primary {
+		return req
+	}
+	req.Message = req.Message + "\n\n" + primaryURL
+	return req
+}
+
+// parseCrosslink accepts "primary=<provider>" (or just "<provider>") and
+// checks the provider is one of the selected targets.
+func parseCrosslink(value string, targets []string) (string, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if value == "" {
+		return "", nil
+	}
+	if key, primary, ok := strings.Cut(value, "="); ok {
+		if strings.TrimSpace(key) != "primary" {
+			return "", fmt.Errorf("invalid --crosslink %q: expected primary=<provider>", value)
+		}
+		value = strings.TrimSpace(primary)
+	}
+	if !slices.Contains(targets, value) {
+		return "", fmt.Errorf("invalid --crosslink: %q is not a selected target", value)
+	}
+	if len(targets) < 2 {
+		return "", errors.New("--crosslink needs at least two targets")
+	}
+	return value, nil
 }
 
 const (
-	colorReset    = "\033[0m"
-	twitterColor  = "\033[38;5;39m"
-	mastodonColor = "\033[38;5;63m"
-	blueskyColor  = "\033[38;5;45m"
-	iconTwitter   = "\uf099"
-	iconMastodon  = "\uedc0"
-	iconBluesky   = "\ue28e" // butterfly as a playful Bluesky glyph
+	colorReset = "\033[0m"
+	// defaultIcon marks providers that registered no icon of their own.
+	defaultIcon = "\uf0ac"
 )
 
-var providerStyles = map[string]providerStyle{
-	"twitter":  {icon: iconTwitter, label: "Twitter/X", color: twitterColor},
-	"mastodon": {icon: iconMastodon, label: "Mastodon", color: mastodonColor},
-	"bluesky":  {icon: iconBluesky, label: "Bluesky", color: blueskyColor},
+// providerInfo returns how the provider registered under name is shown,
+// falling back to a globe and the bare name for those that registered none.
+func providerInfo(name string) xpost.Info {
+	info, _ := xpost.Describe(name)
+	if info.Label == "" {
+		info.Label = name
+	}
+	if info.Icon == "" {
+		info.Icon = defaultIcon
+	}
+	return info
+}
+
+// providerLabel returns the provider's display name, without the icon and
+// color styledProvider adds, for aligned tables.
+func providerLabel(name string) string {
+	return providerInfo(name).Label
 }
 
 func styledProvider(name string, out io.Writer) string {
-	style, ok := providerStyles[name]
-	if !ok {
-		return name
+	info := providerInfo(name)
+	text := fmt.Sprintf("%s %s", info.Icon, info.Label)
+	if info.Color == 0 {
+		return text
 	}
-	text := fmt.Sprintf("%s %s", style.icon, style.label)
-	return colorize(out, text, style.color)
+	return colorize(out, text, fmt.Sprintf("\033[38;5;%dm", info.Color))
 }
 
 func colorize(out io.Writer, text, color string) string {
@@ -326,7 +1902,15 @@ func colorize(out io.Writer, text, color string) string {
 	return color + text + colorReset
 }
 
+// supportsColor reports whether to color output written to w. --color
+// always and never override the NO_COLOR and terminal checks.
 func supportsColor(w io.Writer) bool {
+	switch colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
 	if os.Getenv("NO_COLOR") != "" {
 		return false
 	}
@@ -0,0 +1,1114 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// fakePoster records what it is asked to post. Messages are measured in
+// runes against limit.
+type fakePoster struct {
+	name  string
+	limit int
+	// altLimit caps image descriptions in runes; 0 means no cap.
+	altLimit int
+	// failAt makes the nth call to Post (counting from 1) fail with failErr.
+	failAt  int
+	failErr error
+
+	mu      sync.Mutex
+	posted  []xpost.Request
+	calls   int
+	deleted []string
+	// failDelete lists IDs whose deletion fails.
+	failDelete map[string]bool
+	closed     int
+}
+
+// registeredFake is what the "fake" provider registered for the tests
+// builds, for code that constructs posters by name. The "unconfigured"
+// provider always fails to construct.
+var registeredFake *fakePoster
+
+func init() {
+	xpost.Register("fake", func(context.Context, *config.Config) (xpost.Poster, error) {
+		if registeredFake == nil {
+			return nil, errors.New("no fake poster set up")
+		}
+		return registeredFake, nil
+	}, xpost.Info{})
+	xpost.Register("unconfigured", func(context.Context, *config.Config) (xpost.Poster, error) {
+		return nil, xpost.MissingEnvError{Provider: "unconfigured", Variables: []string{"XPOST_UNCONFIGURED_TOKEN"}}
+	}, xpost.Info{})
+}
+
+// useRegisteredFake makes the "fake" provider build p until the test ends.
+func useRegisteredFake(t *testing.T, p *fakePoster) {
+	t.Helper()
+	registeredFake = p
+	t.Cleanup(func() { registeredFake = nil })
+}
+
+func newFakePoster(name string, limit int) *fakePoster {
+	return &fakePoster{name: name, limit: limit}
+}
+
+func (p *fakePoster) Name() string { return p.name }
+
+func (p *fakePoster) Count(req xpost.Request) (int, int, string) {
+	text := req.Message
+	if req.Link != "" {
+		text += "\n\n" + req.Link
+	}
+	return utf8.RuneCountInString(text) + utf8.RuneCountInString(req.ContentWarning), p.limit, "characters"
+}
+
+func (p *fakePoster) Truncate(req xpost.Request) (xpost.Request, string) {
+	limit := p.limit
+	if req.Link != "" {
+		limit -= utf8.RuneCountInString("\n\n" + req.Link)
+	}
+	var removed string
+	req.Message, removed = xpost.TruncateText(req.Message, limit, utf8.RuneCountInString)
+	return req, removed
+}
+
+func (p *fakePoster) TruncateAlt(alt string) (string, string) {
+	if p.altLimit == 0 {
+		return alt, ""
+	}
+	return xpost.TruncateText(alt, p.altLimit, utf8.RuneCountInString)
+}
+
+func (p *fakePoster) Validate(req xpost.Request) error {
+	if n, limit, _ := p.Count(req); n > limit {
+		return xpost.ValidationError{Provider: p.name, Reason: fmt.Sprintf("message too long: %d characters (max %d)", n, limit)}
+	}
+	return nil
+}
+
+func (p *fakePoster) Post(ctx context.Context, req xpost.Request) (xpost.PostResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	var err error
+	if p.calls == p.failAt {
+		err = p.failErr
+		if err == nil {
+			err = fmt.Errorf("post %d failed", p.calls)
+		}
+		// A partial failure comes after the post was published.
+		if !errors.As(err, new(xpost.PartialError)) {
+			return xpost.PostResult{}, err
+		}
+	}
+	p.posted = append(p.posted, req)
+	id := fmt.Sprintf("%s-%d", p.name, len(p.posted))
+	return xpost.PostResult{Provider: p.name, ID: id, URL: "https://example.com/" + id}, err
+}
+
+func (p *fakePoster) Delete(ctx context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failDelete[id] {
+		return fmt.Errorf("delete %s failed", id)
+	}
+	p.deleted = append(p.deleted, id)
+	return nil
+}
+
+func (p *fakePoster) Close(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed++
+	return nil
+}
+
+// requests returns a copy of the requests posted so far.
+func (p *fakePoster) requests() []xpost.Request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]xpost.Request(nil), p.posted...)
+}
+
+// words returns n space-separated words of five letters.
+func words(n int) string {
+	return strings.TrimSpace(strings.Repeat("word ", n))
+}
+
+func TestMaxThreadPosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int
+		wantErr bool
+	}{
+		{name: "over the cap", max: 3, wantErr: true},
+		{name: "at the cap", max: 5},
+		{name: "no cap", max: 0},
+	}
+	for _, tt := range tests {
+		poster := newFakePoster("fake", 50)
+		// 40 words of 6 characters take 5 posts of at most 50.
+		req := xpost.Request{Message: words(40)}
+		outcomes, err := dispatch(context.Background(), []xpost.Poster{poster}, req, io.Discard, dispatchOptions{
+			thread:         true,
+			maxThreadPosts: tt.max,
+		})
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "thread of 5 posts (max 3") {
+				t.Errorf("%s: err = %v, want the thread cap error", tt.name, err)
+			}
+			if len(outcomes) != 0 || len(poster.requests()) != 0 {
+				t.Errorf("%s: posted %d times despite the cap", tt.name, len(poster.requests()))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: dispatch: %v", tt.name, err)
+			continue
+		}
+		if got := len(poster.requests()); got != 5 {
+			t.Errorf("%s: posted %d times, want a thread of 5", tt.name, got)
+		}
+	}
+}
+
+func TestMaxThreadPostsLimitCheck(t *testing.T) {
+	validators := []xpost.Validator{newFakePoster("fake", 50)}
+	_, _, _, errs := prepareRequests(validators, xpost.Request{Message: words(40)}, dispatchOptions{thread: true, maxThreadPosts: 2})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "raise --max-thread-posts") {
+		t.Errorf("errs = %v, want the thread cap error", errs)
+	}
+}
+
+func TestCrosslink(t *testing.T) {
+	tests := []struct {
+		name        string
+		primaryFail bool
+		message     string
+		wantErr     string
+	}{
+		{name: "links to the primary", message: "hello"},
+		{name: "primary fails", message: "hello", primaryFail: true, wantErr: "bluesky: post 1 failed"},
+		// The secondary reserves room for the URL even though the message fits.
+		{name: "no room for the link", message: strings.Repeat("x", 30), wantErr: "message too long"},
+	}
+	for _, tt := range tests {
+		primary := newFakePoster("bluesky", 300)
+		secondary := newFakePoster("mastodon", 100)
+		if tt.primaryFail {
+			primary.failAt = 1
+		}
+		_, err := dispatch(context.Background(), []xpost.Poster{secondary, primary}, xpost.Request{Message: tt.message}, io.Discard, dispatchOptions{crosslink: "bluesky"})
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			if n := len(secondary.requests()); n != 0 {
+				t.Errorf("%s: secondary posted %d times", tt.name, n)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: dispatch: %v", tt.name, err)
+			continue
+		}
+		if got := primary.requests()[0].Message; got != tt.message {
+			t.Errorf("%s: primary message = %q, want it unchanged", tt.name, got)
+		}
+		if got, want := secondary.requests()[0].Message, tt.message+"\n\nhttps://example.com/bluesky-1"; got != want {
+			t.Errorf("%s: secondary message = %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestParseCrosslink(t *testing.T) {
+	targets := []string{"bluesky", "mastodon"}
+	tests := []struct {
+		value   string
+		targets []string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ""},
+		{value: "primary=Bluesky", want: "bluesky"},
+		{value: " mastodon ", want: "mastodon"},
+		{value: "first=bluesky", wantErr: true},
+		{value: "twitter", wantErr: true},
+		{value: "bluesky", targets: []string{"bluesky"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if tt.targets == nil {
+			tt.targets = targets
+		}
+		got, err := parseCrosslink(tt.value, tt.targets)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseCrosslink(%q) = %q, %v; want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestApplyImageCredit(t *testing.T) {
+	img := []xpost.ImageAttachment{{Path: "a.png", Alt: "A cat"}, {Path: "b.png"}}
+	tests := []struct {
+		name      string
+		images    []xpost.ImageAttachment
+		placement string
+		wantMsg   string
+		wantOver  string
+		wantAlts  []string
+		wantErr   string
+	}{
+		{name: "message by default", images: img, wantMsg: "Hi\n\nPhoto: Jo", wantOver: "Hey\n\nPhoto: Jo", wantAlts: []string{"A cat", ""}},
+		{name: "alt", images: img, placement: " ALT ", wantMsg: "Hi", wantOver: "Hey", wantAlts: []string{"A cat. Photo: Jo", "Photo: Jo"}},
+		{name: "no image", wantErr: "requires --image"},
+		{name: "bad placement", images: img, placement: "footer", wantErr: "expected message or alt"},
+	}
+	for _, tt := range tests {
+		req := xpost.Request{Message: "Hi", Images: slices.Clone(tt.images)}
+		messages := map[string]string{"mastodon": "Hey"}
+		err := applyImageCredit(&req, messages, "Photo: Jo", tt.placement)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if req.Message != tt.wantMsg || messages["mastodon"] != tt.wantOver {
+			t.Errorf("%s: messages = %q, %q; want %q, %q", tt.name, req.Message, messages["mastodon"], tt.wantMsg, tt.wantOver)
+		}
+		for i, want := range tt.wantAlts {
+			if req.Images[i].Alt != want {
+				t.Errorf("%s: image %d alt = %q, want %q", tt.name, i, req.Images[i].Alt, want)
+			}
+		}
+	}
+}
+
+func TestCreditAlt(t *testing.T) {
+	tests := []struct{ alt, want string }{
+		{alt: "", want: "© Jo"},
+		{alt: "A cat ", want: "A cat. © Jo"},
+		{alt: "A cat!", want: "A cat! © Jo"},
+		{alt: "Is it a cat?", want: "Is it a cat? © Jo"},
+		{alt: "A cat.", want: "A cat. © Jo"},
+	}
+	for _, tt := range tests {
+		if got := creditAlt(tt.alt, "© Jo"); got != tt.want {
+			t.Errorf("creditAlt(%q) = %q, want %q", tt.alt, got, tt.want)
+		}
+	}
+}
+
+func TestParsePreferFormats(t *testing.T) {
+	tests := []struct {
+		values  []string
+		want    map[string]string
+		wantErr string
+	}{
+		{values: []string{"Bluesky=jpg", "mastodon = PNG"}, want: map[string]string{"bluesky": "jpeg", "mastodon": "png"}},
+		{values: []string{"jpeg"}, wantErr: "expected <provider>=<format>"},
+		{values: []string{"myspace=jpeg"}, wantErr: `unsupported provider "myspace"`},
+		{values: []string{"bluesky=webp"}, wantErr: `unsupported image format "webp"`},
+	}
+	for _, tt := range tests {
+		got, err := parsePreferFormats(tt.values)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parsePreferFormats(%q) err = %v, want %q", tt.values, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !maps.Equal(got, tt.want) {
+			t.Errorf("parsePreferFormats(%q) = %v, %v; want %v", tt.values, got, err, tt.want)
+		}
+	}
+}
+
+func TestTruncateReportsRemovedText(t *testing.T) {
+	long := newFakePoster("twitter", 12)
+	roomy := newFakePoster("mastodon", 500)
+	outcomes, err := dispatch(context.Background(), []xpost.Poster{long, roomy}, xpost.Request{Message: "hello there, world"}, io.Discard, dispatchOptions{truncate: true})
+	if err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	removed := map[string]string{}
+	for _, o := range outcomes {
+		removed[o.provider] = o.truncated
+	}
+	if want := map[string]string{"twitter": ", world", "mastodon": ""}; !maps.Equal(removed, want) {
+		t.Errorf("truncated = %q, want %q", removed, want)
+	}
+	if got := long.requests()[0].Message; got != "hello there…" {
+		t.Errorf("twitter message = %q", got)
+	}
+	if got := roomy.requests()[0].Message; got != "hello there, world" {
+		t.Errorf("mastodon message = %q, want it untouched", got)
+	}
+
+	var out strings.Builder
+	if _, err := dispatch(context.Background(), []xpost.Poster{newFakePoster("twitter", 12)}, xpost.Request{Message: "hello there, world"}, &out, dispatchOptions{truncate: true, dryRun: true}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !strings.Contains(out.String(), "truncated 7 chars: ', world'") {
+		t.Errorf("dry-run output = %q, want the truncation note", out.String())
+	}
+}
+
+func TestAltTextOverrides(t *testing.T) {
+	twitter := newFakePoster("twitter", 280)
+	twitter.altLimit = 10
+	validators := []xpost.Validator{twitter, newFakePoster("mastodon", 500), newFakePoster("bluesky", 300)}
+	req := xpost.Request{Message: "hi", Images: []xpost.ImageAttachment{{Path: "a.png", Alt: "A cat"}, {Path: "b.png", Alt: "A dog"}}}
+
+	requests, _, _, errs := prepareRequests(validators, req, dispatchOptions{altTexts: map[string]string{
+		"twitter":  "A very long description",
+		"mastodon": "A tabby cat",
+	}})
+	if len(errs) > 0 {
+		t.Fatalf("prepareRequests: %v", errs)
+	}
+	want := map[string][]string{
+		"twitter":  {"A very lo…", "A dog"}, // cut to the provider's cap
+		"mastodon": {"A tabby cat", "A dog"},
+		"bluesky":  {"A cat", "A dog"},
+	}
+	for provider, alts := range want {
+		for i, alt := range alts {
+			if got := requests[provider].Images[i].Alt; got != alt {
+				t.Errorf("%s image %d alt = %q, want %q", provider, i, got, alt)
+			}
+		}
+	}
+	if req.Images[0].Alt != "A cat" {
+		t.Errorf("the shared request was modified: %q", req.Images[0].Alt)
+	}
+}
+
+func TestAltOverflowReply(t *testing.T) {
+	twitter := newFakePoster("twitter", 280)
+	twitter.altLimit = 10
+	validators := []xpost.Validator{twitter, newFakePoster("mastodon", 500)}
+	full := "A tabby cat asleep on a windowsill"
+	req := xpost.Request{Message: "hi", Images: []xpost.ImageAttachment{{Path: "a.png", Alt: full}, {Path: "b.png", Alt: "A dog"}}}
+
+	tests := []struct {
+		name     string
+		provider string
+		overflow bool
+		want     []xpost.ImageAttachment
+		reply    string
+	}{
+		{
+			name:     "over the cap",
+			provider: "twitter",
+			overflow: true,
+			want:     []xpost.ImageAttachment{{Path: "a.png", Alt: "A tabby c…", FullAlt: full}, {Path: "b.png", Alt: "A dog"}},
+			reply:    "Image 1 description: " + full,
+		},
+		{
+			name:     "within the cap",
+			provider: "mastodon",
+			overflow: true,
+			want:     req.Images,
+		},
+		{
+			name:     "flag unset",
+			provider: "twitter",
+			want:     req.Images,
+		},
+	}
+	for _, tt := range tests {
+		requests, _, _, errs := prepareRequests(validators, req, dispatchOptions{altOverflow: tt.overflow})
+		if len(errs) > 0 {
+			t.Fatalf("%s: prepareRequests: %v", tt.name, errs)
+		}
+		got := requests[tt.provider]
+		if !slices.Equal(got.Images, tt.want) {
+			t.Errorf("%s: images = %+v, want %+v", tt.name, got.Images, tt.want)
+		}
+		if reply := got.AltReply(); reply != tt.reply {
+			t.Errorf("%s: AltReply = %q, want %q", tt.name, reply, tt.reply)
+		}
+	}
+	if req.Images[0].FullAlt != "" {
+		t.Error("the shared request was modified")
+	}
+}
+
+func TestResolveAltOverrides(t *testing.T) {
+	saved := altTexts
+	t.Cleanup(func() { altTexts = saved })
+	override, blank := " Photo of a cat ", "  "
+	altTexts = map[string]*string{"mastodon": &override, "twitter": &blank}
+
+	got, err := resolveAltOverrides(true, "© Jo", "alt")
+	if err != nil || !maps.Equal(got, map[string]string{"mastodon": "Photo of a cat. © Jo"}) {
+		t.Errorf("resolveAltOverrides = %v, %v", got, err)
+	}
+	if _, err := resolveAltOverrides(false, "", ""); err == nil {
+		t.Error("an override without --image was accepted")
+	}
+	altTexts = map[string]*string{"twitter": &blank}
+	if got, err := resolveAltOverrides(false, "", ""); got != nil || err != nil {
+		t.Errorf("blank overrides = %v, %v; want none", got, err)
+	}
+}
+
+func TestCheckLimitsNeedsNoCredentials(t *testing.T) {
+	for _, env := range []string{"XPOST_TWITTER_API_KEY", "XPOST_MASTODON_SERVER", "XPOST_BLUESKY_HANDLE", "XPOST_BLUESKY_APP_PASSWORD"} {
+		t.Setenv(env, "")
+	}
+	targets := []string{"bluesky", "mastodon", "twitter"}
+	long := strings.Repeat("word ", 62) // 309 characters
+	tests := []struct {
+		name    string
+		message string
+		opts    dispatchOptions
+		want    []string
+		wantErr string
+	}{
+		{name: "fits", message: "hello", want: []string{"Bluesky fits", "Mastodon fits", "Twitter/X fits"}},
+		{name: "too long", message: long, wantErr: "message too long", want: []string{"Limit check failed"}},
+		{name: "truncate", message: long, opts: dispatchOptions{truncate: true}, want: []string{"Bluesky fits after truncation", "Mastodon fits\n", "Twitter/X fits after truncation"}},
+		{name: "thread", message: long, opts: dispatchOptions{thread: true}, want: []string{"Bluesky fits as a thread of 2 posts", "Twitter/X fits as a thread of 2 posts"}},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		err := checkLimits(targets, xpost.Request{Message: tt.message}, &out, tt.opts)
+		if (tt.wantErr == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s: output %q lacks %q", tt.name, out.String(), want)
+			}
+		}
+	}
+}
+
+func TestAppendCurrentDate(t *testing.T) {
+	now := time.Date(2025, 3, 9, 6, 30, 0, 0, time.UTC)
+	tests := []struct {
+		layout   string
+		timezone string
+		want     string
+		wantErr  bool
+	}{
+		{layout: defaultDateFormat, want: "hi\n\n2025-03-09 06:30 UTC"},
+		{layout: "Jan 2", timezone: "Asia/Tokyo", want: "hi\n\nMar 9"},
+		{layout: "15:04 MST", timezone: " America/New_York ", want: "hi\n\n01:30 EST"},
+		{layout: defaultDateFormat, timezone: "Mars/Olympus", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := appendCurrentDate("hi", tt.layout, tt.timezone, now)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("appendCurrentDate(%q, %q) = %q, %v; want %q, error %v", tt.layout, tt.timezone, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestDecorateMessage(t *testing.T) {
+	savedDate, savedTZ := appendDate, dateTimezone
+	t.Cleanup(func() { appendDate, dateTimezone = savedDate, savedTZ })
+	now := time.Date(2025, 3, 9, 6, 30, 0, 0, time.UTC)
+
+	appendDate, dateTimezone = "", ""
+	if got, _ := decorateMessage("hi", "build #42", now); got != "hi\n\nbuild #42" {
+		t.Errorf("without --append-date = %q", got)
+	}
+	appendDate = "2006-01-02"
+	if got, _ := decorateMessage("hi", "build #42", now); got != "hi\n\nbuild #42\n\n2025-03-09" {
+		t.Errorf("with --append-date = %q, want the command output before the date", got)
+	}
+}
+
+func TestRunAppendCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands use sh")
+	}
+	tests := []struct {
+		command string
+		timeout time.Duration
+		want    string
+		wantErr string
+	}{
+		{command: "printf '  build 42\\n\\n'", want: "build 42"},
+		{command: "exit 3", wantErr: "--append-command: exit status 3"},
+		{command: "sleep 2", timeout: 50 * time.Millisecond, wantErr: "timed out after 50ms"},
+		{command: fmt.Sprintf("head -c %d /dev/zero", appendOutputLimit+1), wantErr: "output exceeds"},
+	}
+	for _, tt := range tests {
+		if tt.timeout == 0 {
+			tt.timeout = 5 * time.Second
+		}
+		got, err := runAppendCommand(context.Background(), tt.command, tt.timeout)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%q: err = %v, want %q", tt.command, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q = %q, %v; want %q", tt.command, got, err, tt.want)
+		}
+	}
+}
+
+func TestAutoContentWarning(t *testing.T) {
+	validators := []xpost.Validator{newFakePoster("mastodon", 500), newFakePoster("twitter", 280)}
+	opts := dispatchOptions{contentWarns: map[string]string{"mastodon": "politics"}}
+
+	requests, _, _, errs := prepareRequests(validators, xpost.Request{Message: "#politics"}, opts)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if got := requests["mastodon"].ContentWarning; got != "politics" {
+		t.Errorf("mastodon CW = %q, want the tag's warning", got)
+	}
+	if got := requests["twitter"].ContentWarning; got != "" {
+		t.Errorf("twitter CW = %q, want none", got)
+	}
+
+	// An explicit --cw wins.
+	requests, _, _, _ = prepareRequests(validators, xpost.Request{Message: "#politics", ContentWarning: "mine"}, opts)
+	if got := requests["mastodon"].ContentWarning; got != "mine" {
+		t.Errorf("mastodon CW = %q, want the explicit one", got)
+	}
+}
+
+func TestApplyProviderOptions(t *testing.T) {
+	image := []xpost.ImageAttachment{{Path: "a.png", Alt: "“Dusk”"}}
+	tests := []struct {
+		name string
+		req  xpost.Request
+		opts config.Options
+		want xpost.Request
+	}{
+		{
+			name: "none",
+			req:  xpost.Request{Message: "hi", Images: image},
+			want: xpost.Request{Message: "hi", Images: image},
+		},
+		{
+			name: "text only",
+			req:  xpost.Request{Message: "hi", Images: image},
+			opts: config.Options{TextOnly: true},
+			want: xpost.Request{Message: "hi"},
+		},
+		{
+			name: "defaults fill unset fields",
+			req:  xpost.Request{Message: "hi"},
+			opts: config.Options{ContentWarning: " spoilers ", DefaultVisibility: " Unlisted "},
+			want: xpost.Request{Message: "hi", ContentWarning: "spoilers", Visibility: "unlisted"},
+		},
+		{
+			name: "flags win",
+			req:  xpost.Request{Message: "hi", ContentWarning: "mine", Visibility: "private"},
+			opts: config.Options{ContentWarning: "spoilers", DefaultVisibility: "unlisted"},
+			want: xpost.Request{Message: "hi", ContentWarning: "mine", Visibility: "private"},
+		},
+		{
+			name: "strip hashtags",
+			req:  xpost.Request{Message: "Shipped it #golang #release"},
+			opts: config.Options{StripHashtags: true},
+			want: xpost.Request{Message: "Shipped it"},
+		},
+		{
+			name: "plain punctuation",
+			req:  xpost.Request{Message: "It’s done…", ContentWarning: "“new”", Images: image},
+			opts: config.Options{PlainPunctuation: true},
+			want: xpost.Request{Message: "It's done...", ContentWarning: `"new"`, Images: []xpost.ImageAttachment{{Path: "a.png", Alt: `"Dusk"`}}},
+		},
+	}
+	for _, tt := range tests {
+		got := applyProviderOptions(tt.req, tt.opts)
+		if got.Message != tt.want.Message || got.ContentWarning != tt.want.ContentWarning || got.Visibility != tt.want.Visibility || !slices.Equal(got.Images, tt.want.Images) {
+			t.Errorf("%s: applyProviderOptions = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	if image[0].Alt != "“Dusk”" {
+		t.Errorf("applyProviderOptions changed the caller's images: %q", image[0].Alt)
+	}
+}
+
+func TestPostersClosedAfterDispatch(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tests := []struct {
+		name    string
+		failAt  int
+		wantErr bool
+	}{
+		{name: "success"},
+		{name: "post fails", failAt: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		poster := newFakePoster("fake", 100)
+		poster.failAt = tt.failAt
+		useRegisteredFake(t, poster)
+
+		cmd := newRootCommand()
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs([]string{"--config", t.TempDir() + "/config.yaml", "--target", "fake", "hello"})
+		if err := cmd.Execute(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Execute = %v", tt.name, err)
+		}
+		if got := len(poster.requests()); got != 1-min(tt.failAt, 1) {
+			t.Errorf("%s: posted %d times", tt.name, got)
+		}
+		if poster.closed != 1 {
+			t.Errorf("%s: Close called %d times, want 1", tt.name, poster.closed)
+		}
+	}
+}
+
+func TestBuildImagesKeepsOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		alts    []string
+		want    []xpost.ImageAttachment
+		wantErr bool
+	}{
+		{name: "none"},
+		{
+			name:  "paired in order",
+			paths: []string{"a.png", "b.png", "c.png"},
+			alts:  []string{" before ", "", "after"},
+			want:  []xpost.ImageAttachment{{Path: "a.png", Alt: "before"}, {Path: "b.png"}, {Path: "c.png", Alt: "after"}},
+		},
+		{name: "fewer alts", paths: []string{"a.png", "b.png"}, alts: []string{"a"}, want: []xpost.ImageAttachment{{Path: "a.png", Alt: "a"}, {Path: "b.png"}}},
+		{name: "more alts", paths: []string{"a.png"}, alts: []string{"a", "b"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := buildImages(tt.paths, tt.alts, "")
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("%s: buildImages = %+v, %v; want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestProviderOptionsFlags(t *testing.T) {
+	defer func(p, s []string) { plainPunct, stripTags = p, s }(plainPunct, stripTags)
+
+	tests := []struct {
+		name  string
+		plain []string
+		strip []string
+		cfg   config.Config
+		want  map[string]config.Options
+		err   bool
+	}{
+		{
+			name:  "only the targeted providers",
+			plain: []string{"twitter", " Lemmy "},
+			want: map[string]config.Options{
+				"twitter": {PlainPunctuation: true},
+				"lemmy":   {PlainPunctuation: true},
+			},
+		},
+		{
+			name:  "merged with the config file",
+			plain: []string{"mastodon"},
+			strip: []string{"mastodon"},
+			cfg:   config.Config{Mastodon: config.Mastodon{Options: config.Options{TextOnly: true}}},
+			want: map[string]config.Options{
+				"mastodon": {TextOnly: true, PlainPunctuation: true, StripHashtags: true},
+			},
+		},
+		{name: "unknown provider", plain: []string{"myspace"}, err: true},
+	}
+	for _, tt := range tests {
+		plainPunct, stripTags = tt.plain, tt.strip
+		got, err := providerOptions(&tt.cfg)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: providerOptions error = %v, want error %v", tt.name, err, tt.err)
+			continue
+		}
+		for provider, o := range got {
+			if o != tt.want[provider] {
+				t.Errorf("%s: options[%s] = %+v, want %+v", tt.name, provider, o, tt.want[provider])
+			}
+		}
+	}
+}
+
+// TestDryRunOutput checks what a dry run reports per provider; %s in want
+// stands for the styled provider name.
+func TestDryRunOutput(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "shot")
+	if err := os.WriteFile(image, []byte("\x89PNG\r\n\x1a\nnot really"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		req  xpost.Request
+		opts dispatchOptions
+		want []string
+	}{
+		{
+			name: "link and content warning",
+			req:  xpost.Request{Message: "Hello", Link: "https://go.dev", ContentWarning: "cw"},
+			want: []string{
+				`[dry-run] would post to %s: "Hello\n\nhttps://go.dev"`,
+				"[dry-run] %s length: 23/500 characters",
+				`[dry-run] %s content warning: "cw"`,
+			},
+		},
+		{
+			name: "image media type",
+			req:  xpost.Request{Message: "Look", Images: []xpost.ImageAttachment{{Path: image, Alt: "a chart"}}},
+			want: []string{`[dry-run] image: ` + image + ` (image/png, alt: "a chart")`},
+		},
+		{
+			name: "truncation",
+			req:  xpost.Request{Message: words(120)},
+			opts: dispatchOptions{truncate: true},
+			want: []string{"[dry-run] %s length: 500/500 characters", "[dry-run] %s truncated"},
+		},
+		{
+			name: "thread",
+			req:  xpost.Request{Message: words(120)},
+			opts: dispatchOptions{thread: true},
+			want: []string{"[dry-run] %s then reply: "},
+		},
+	}
+	for _, tt := range tests {
+		poster := newFakePoster("mastodon", 500)
+		var out strings.Builder
+		tt.opts.dryRun = true
+		if _, err := dispatch(context.Background(), []xpost.Poster{poster}, tt.req, &out, tt.opts); err != nil {
+			t.Errorf("%s: dispatch: %v", tt.name, err)
+			continue
+		}
+		for _, want := range tt.want {
+			want = strings.ReplaceAll(want, "%s", styledProvider("mastodon", &out))
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s: output = %q, want it to contain %q", tt.name, out.String(), want)
+			}
+		}
+		if got := poster.requests(); len(got) != 0 {
+			t.Errorf("%s: a dry run posted %v", tt.name, got)
+		}
+	}
+}
+
+func TestReplyToPerProvider(t *testing.T) {
+	cmd := newRootCommand()
+	if err := cmd.ParseFlags([]string{
+		"--reply-to-mastodon", " https://mastodon.social/@bob/1 ",
+		"--reply-to-bluesky", "https://bsky.app/profile/alice.test/post/3kabc",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	replyTos := resolveReplyTos()
+	want := map[string]string{
+		"mastodon": "https://mastodon.social/@bob/1",
+		"bluesky":  "https://bsky.app/profile/alice.test/post/3kabc",
+	}
+	if !maps.Equal(replyTos, want) {
+		t.Fatalf("resolveReplyTos = %v, want %v", replyTos, want)
+	}
+
+	posters := map[string]*fakePoster{}
+	var list []xpost.Poster
+	for _, name := range []string{"mastodon", "bluesky", "twitter"} {
+		posters[name] = newFakePoster(name, 500)
+		list = append(list, posters[name])
+	}
+	req := xpost.Request{Message: "Agreed", ReplyTo: "https://x.com/jack/status/20"}
+	if _, err := dispatch(context.Background(), list, req, io.Discard, dispatchOptions{replyTos: replyTos}); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	for name, want := range map[string]string{
+		"mastodon": want["mastodon"],
+		"bluesky":  want["bluesky"],
+		// Providers without their own flag keep the request's reply target.
+		"twitter": req.ReplyTo,
+	} {
+		if got := posters[name].requests(); len(got) != 1 || got[0].ReplyTo != want {
+			t.Errorf("%s posted %v, want a reply to %s", name, got, want)
+		}
+	}
+}
+
+func TestQuoteFallback(t *testing.T) {
+	const quote = "https://bsky.app/profile/alice.test/post/3kabc"
+	tests := []struct {
+		provider string
+		wantErr  bool
+		want     string
+	}{
+		{provider: "mastodon", wantErr: true},
+		{provider: "misskey", want: "Worth a read\n\n" + quote},
+	}
+	for _, tt := range tests {
+		poster := newFakePoster(tt.provider, 500)
+		_, err := dispatch(context.Background(), []xpost.Poster{poster}, xpost.Request{Message: "Worth a read", Quote: quote}, io.Discard, dispatchOptions{})
+		if tt.wantErr {
+			if !errors.As(err, new(xpost.ValidationError)) || len(poster.requests()) != 0 {
+				t.Errorf("%s: dispatch = %v, posted %v; want a validation error and no post", tt.provider, err, poster.requests())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: dispatch: %v", tt.provider, err)
+			continue
+		}
+		if got := poster.requests(); len(got) != 1 || got[0].Message != tt.want || got[0].Quote != "" {
+			t.Errorf("%s: posted %v, want the quote linked in the message", tt.provider, got)
+		}
+	}
+
+	// A rejected quote is not linked in the message either.
+	requests, _, _, errs := prepareRequests([]xpost.Validator{newFakePoster("mastodon", 500)}, xpost.Request{Message: "Worth a read", Quote: quote}, dispatchOptions{})
+	if len(errs) != 1 || requests["mastodon"].Message != "Worth a read" {
+		t.Errorf("mastodon: message %q, errors %v; want it unchanged and one error", requests["mastodon"].Message, errs)
+	}
+
+	// The linked quote counts toward the limit: it may not be truncated away.
+	long := strings.Repeat("word ", 20)
+	lengths := []struct {
+		name    string
+		opts    dispatchOptions
+		wantErr bool
+	}{
+		{name: "too long", wantErr: true},
+		{name: "truncated", opts: dispatchOptions{truncate: true}, wantErr: true},
+		{name: "threaded", opts: dispatchOptions{thread: true}},
+	}
+	for _, tt := range lengths {
+		poster := newFakePoster("misskey", 100)
+		_, err := dispatch(context.Background(), []xpost.Poster{poster}, xpost.Request{Message: long, Quote: quote}, io.Discard, tt.opts)
+		if tt.wantErr {
+			if !errors.As(err, new(xpost.ValidationError)) || len(poster.requests()) != 0 {
+				t.Errorf("%s: dispatch = %v, posted %v; want a validation error and no post", tt.name, err, poster.requests())
+			}
+			continue
+		}
+		got := poster.requests()
+		if err != nil || len(got) == 0 || !strings.Contains(got[len(got)-1].Message, quote) {
+			t.Errorf("%s: dispatch = %v, posted %v; want the quote linked in the last post", tt.name, err, got)
+		}
+	}
+}
+
+func TestSkipUnconfigured(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(context.Context, *config.Config, []string) ([]xpost.Poster, error)
+		targets []string
+		want    []string
+		wantErr string
+		// wantClosed is how often the fake was closed after a failure.
+		wantClosed int
+	}{
+		{name: "strict", build: buildPosters, targets: []string{"fake", "unconfigured"}, wantErr: "missing XPOST_UNCONFIGURED_TOKEN", wantClosed: 1},
+		{name: "skip", build: buildAvailablePosters, targets: []string{"fake", "unconfigured"}, want: []string{"fake"}},
+		{name: "skip all", build: buildAvailablePosters, targets: []string{"unconfigured"}, wantErr: "no targets available"},
+	}
+	for _, tt := range tests {
+		fake := newFakePoster("fake", 500)
+		useRegisteredFake(t, fake)
+		posters, err := tt.build(context.Background(), &config.Config{}, tt.targets)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			if posters != nil || fake.closed != tt.wantClosed {
+				t.Errorf("%s: posters = %v, closed %d; want none, closed %d", tt.name, posters, fake.closed, tt.wantClosed)
+			}
+			continue
+		}
+		var names []string
+		for _, p := range posters {
+			names = append(names, p.Name())
+		}
+		if err != nil || !slices.Equal(names, tt.want) {
+			t.Errorf("%s: posters = %v, %v; want %v", tt.name, names, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    []string
+		wantErr string
+	}{
+		{name: "defaults", want: []string{"bluesky", "mastodon", "twitter"}},
+		{name: "all", values: []string{"all"}, want: []string{"bluesky", "mastodon", "twitter"}},
+		{name: "explicit", values: []string{" Mastodon ", "lemmy"}, want: []string{"lemmy", "mastodon"}},
+		{name: "all minus one", values: []string{"all", "-twitter"}, want: []string{"bluesky", "mastodon"}},
+		{name: "exclusion alone starts from all", values: []string{"-bluesky"}, want: []string{"mastodon", "twitter"}},
+		{name: "all plus extra", values: []string{"all", "lemmy", "-mastodon"}, want: []string{"bluesky", "lemmy", "twitter"}},
+		{name: "exclusion wins over include", values: []string{"lemmy", "-lemmy", "bluesky"}, want: []string{"bluesky"}},
+		{name: "duplicates", values: []string{"twitter", "all"}, want: []string{"bluesky", "mastodon", "twitter"}},
+		{name: "every target excluded", values: []string{"all", "-twitter", "-mastodon", "-bluesky"}, wantErr: "every target is excluded"},
+		{name: "exclude all", values: []string{"-all"}, wantErr: `cannot exclude "all"`},
+		{name: "unknown exclusion", values: []string{"all", "-myspace"}, wantErr: `unsupported target "myspace"`},
+		{name: "only blanks", values: []string{" ", "-"}, wantErr: "no targets selected"},
+	}
+	for _, tt := range tests {
+		got, err := normalizeTargets(tt.values)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: normalizeTargets(%q) = %v, %v; want error %q", tt.name, tt.values, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("%s: normalizeTargets(%q) = %v, %v; want %v", tt.name, tt.values, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("http_timeout: 90s\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	oldPath, oldTimeout := configPath, httpTimeout
+	t.Cleanup(func() { configPath, httpTimeout = oldPath, oldTimeout })
+	configPath = path
+
+	tests := []struct {
+		name string
+		flag time.Duration
+		env  string
+		want time.Duration
+	}{
+		{name: "file", want: 90 * time.Second},
+		{name: "environment", env: "2m", want: 2 * time.Minute},
+		{name: "flag wins", flag: 45 * time.Second, env: "2m", want: 45 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Setenv("XPOST_HTTP_TIMEOUT", tt.env)
+		httpTimeout = tt.flag
+		cfg, err := loadConfig()
+		if err != nil {
+			t.Fatalf("%s: loadConfig: %v", tt.name, err)
+		}
+		if cfg.HTTPTimeout != tt.want {
+			t.Errorf("%s: timeout = %v, want %v", tt.name, cfg.HTTPTimeout, tt.want)
+		}
+		// The flag must not leak into hook subprocesses through the environment.
+		if got := os.Getenv("XPOST_HTTP_TIMEOUT"); got != tt.env {
+			t.Errorf("%s: XPOST_HTTP_TIMEOUT = %q, want %q", tt.name, got, tt.env)
+		}
+	}
+}
+
+func TestLoadConfigProviderSections(t *testing.T) {
+	oldPath := configPath
+	t.Cleanup(func() { configPath = oldPath })
+	configPath = filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("XPOST_BLUESKY_PDS_URL", "https://pds.example")
+	t.Setenv("XPOST_TELEGRAM_CHAT_ID", "@news")
+	t.Setenv("XPOST_TWITTER_DEBUG", "1")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	// Each provider registers the variables of its own section.
+	if cfg.Bluesky.PDSURL != "https://pds.example" || cfg.Telegram.ChatID != "@news" || !cfg.Twitter.Debug {
+		t.Errorf("environment not applied: bluesky %+v, telegram %+v, twitter %+v", cfg.Bluesky, cfg.Telegram, cfg.Twitter)
+	}
+	options := cfg.ProviderOptions()
+	for _, name := range xpost.Providers() {
+		if _, ok := options[name]; !ok && name != "fake" && name != "unconfigured" {
+			t.Errorf("%s registered no config section", name)
+		}
+	}
+}
+
+func TestStyledProvider(t *testing.T) {
+	oldMode := colorMode
+	t.Cleanup(func() { colorMode = oldMode })
+	colorMode = "always"
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "mastodon", want: "\033[38;5;63m\uedc0 Mastodon" + colorReset},
+		// Providers that registered no style get a plain default one.
+		{name: "fake", want: defaultIcon + " fake"},
+	}
+	for _, tt := range tests {
+		if got := styledProvider(tt.name, io.Discard); got != tt.want {
+			t.Errorf("%s: styledProvider = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := providerLabel("twitter"); got != "Twitter/X" {
+		t.Errorf("twitter label = %q", got)
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	tests := []struct {
+		name string
+		want xpost.Features
+	}{
+		{name: "bluesky", want: xpost.Features{LinkPreview: true}},
+		{name: "misskey", want: xpost.Features{Visibility: true, ContentWarning: true, EmojiReactions: true}},
+		{name: "lemmy"},
+		{name: "fake"},
+		{name: "unregistered"},
+	}
+	for _, tt := range tests {
+		if got := features(tt.name); got != tt.want {
+			t.Errorf("%s: features = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDispatchConfirm(t *testing.T) {
+	tests := []struct {
+		answer   string
+		wantPost bool
+	}{
+		{answer: "y\n", wantPost: true},
+		{answer: "YES\n", wantPost: true},
+		{answer: "n\n"},
+		{answer: ""},
+	}
+	for _, tt := range tests {
+		poster := newFakePoster("mastodon", 500)
+		var prompt bytes.Buffer
+		_, err := dispatch(context.Background(), []xpost.Poster{poster}, xpost.Request{Message: "hello"}, io.Discard, dispatchOptions{
+			confirm:    true,
+			confirmIn:  strings.NewReader(tt.answer),
+			confirmOut: &prompt,
+		})
+		if !strings.Contains(prompt.String(), "Post to this network? [y/N]") {
+			t.Errorf("%q: prompt = %q, want the question on the confirm writer", tt.answer, prompt.String())
+		}
+		if posted := len(poster.requests()) == 1; posted != tt.wantPost || (err == nil) != tt.wantPost {
+			t.Errorf("%q: dispatch = %v, posted %v; want posted %v", tt.answer, err, posted, tt.wantPost)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// parseSchedule parses a --schedule value: an RFC 3339 timestamp or a
+// duration from now such as 2h. The time must be in the future.
+func parseSchedule(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		d, derr := time.ParseDuration(value)
+		if derr != nil {
+			return time.Time{}, fmt.Errorf("invalid --schedule %q: expected an RFC 3339 time or a duration like 2h", value)
+		}
+		at = now.Add(d)
+	}
+	if !at.After(now) {
+		return time.Time{}, fmt.Errorf("invalid --schedule %q: the time is not in the future", value)
+	}
+	return at, nil
+}
+
+// waitForSchedule blocks until at or until ctx is cancelled.
+func waitForSchedule(ctx context.Context, at time.Time, dryRun bool, out io.Writer) error {
+	if dryRun {
+		fmt.Fprintf(out, "[dry-run] would wait until %s before posting\n", at.Format(time.RFC1123))
+		return nil
+	}
+
+	fmt.Fprintf(out, "Waiting until %s to post\n", at.Format(time.RFC1123))
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for --schedule: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
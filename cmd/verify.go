@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/spf13/cobra"
+)
+
+func newVerifyCommand() *cobra.Command {
+	var verifyTargets []string
+
+	cmd := &cobra.Command{
+		Use:     "verify",
+		Aliases: []string{"doctor"},
+		Short:   "Check each provider's credentials without posting",
+		Long: "verify signs in to every configured provider with a lightweight authenticated " +
+			"call and prints the account it authenticated as. Nothing is posted. It exits " +
+			"non-zero if any provider fails, or if a provider named with --target is not configured.",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+			logutil.SetVerbose(verbose)
+
+			if err := loadSecrets(ctx); err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			targets := xpost.Providers()
+			if len(verifyTargets) > 0 {
+				var err error
+				if targets, err = normalizeTargets(verifyTargets); err != nil {
+					return err
+				}
+			}
+
+			var errs []error
+			for _, target := range targets {
+				posters, err := buildPosters(ctx, cfg, []string{target})
+				if err != nil {
+					if missing := (xpost.MissingEnvError{}); errors.As(err, &missing) && len(verifyTargets) == 0 {
+						fmt.Fprintf(out, "%s: not configured\n", styledProvider(target, out))
+						continue
+					}
+					fmt.Fprintf(out, "error: %v\n", err)
+					errs = append(errs, err)
+					continue
+				}
+
+				verifier, ok := posters[0].(xpost.Verifier)
+				if !ok {
+					fmt.Fprintf(out, "%s: cannot verify without posting\n", styledProvider(target, out))
+				} else if handle, err := verifier.Verify(ctx); err != nil {
+					fmt.Fprintf(out, "%s: error: %v\n", styledProvider(target, out), err)
+					errs = append(errs, fmt.Errorf("%s: %w", target, err))
+				} else {
+					fmt.Fprintf(out, "%s: OK as %s\n", styledProvider(target, out), handle)
+				}
+				closePosters(ctx, posters)
+			}
+			return errors.Join(errs...)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&verifyTargets, "target", nil, "Providers to verify (default all configured)")
+	registerTargetCompletion(cmd)
+
+	return cmd
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Build metadata, set with -ldflags "-X github.com/blacktop/xpost/cmd.Version=..."
+// by the release build. Unset values fall back to what the Go toolchain
+// recorded in the binary.
+var (
+	Version = ""
+	Commit  = ""
+	Date    = ""
+)
+
+// versionModules are the upstream API libraries worth knowing in a bug report.
+var versionModules = []string{
+	"github.com/michimani/gotwi",
+	"github.com/bluesky-social/indigo",
+	"github.com/mattn/go-mastodon",
+}
+
+func newVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the xpost version and build details",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Fprint(cmd.OutOrStdout(), versionText())
+			return nil
+		},
+	}
+}
+
+// buildVersion returns the version, commit and build date, filling gaps from
+// the binary's build info (e.g. for go install builds).
+func buildVersion() (version, commit, date string) {
+	version, commit, date = Version, Commit, Date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if version == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && commit == "":
+				commit = s.Value
+			case s.Key == "vcs.time" && date == "":
+				date = s.Value
+			}
+		}
+	}
+	if version == "" {
+		version = "dev"
+	}
+	return version, commit, date
+}
+
+// versionText describes the build for `xpost version` and --version.
+func versionText() string {
+	out := &strings.Builder{}
+	version, commit, date := buildVersion()
+	fmt.Fprintf(out, "xpost %s\n", version)
+	if commit != "" {
+		fmt.Fprintf(out, "  commit:  %s\n", commit)
+	}
+	if date != "" {
+		fmt.Fprintf(out, "  built:   %s\n", date)
+	}
+	fmt.Fprintf(out, "  go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return out.String()
+	}
+	for _, path := range versionModules {
+		for _, dep := range info.Deps {
+			if dep.Path != path {
+				continue
+			}
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			fmt.Fprintf(out, "  %s %s\n", path, dep.Version)
+		}
+	}
+	return out.String()
+}
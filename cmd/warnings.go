@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// warningSet collects pre-flight warnings so that --strict can fail the run
+// once every check has had a chance to report.
+type warningSet struct {
+	items []xpost.ValidationError
+}
+
+// add logs a warning about provider and records it.
+func (w *warningSet) add(provider, format string, args ...any) {
+	reason := fmt.Sprintf(format, args...)
+	logutil.Warnf("%s: %s", provider, reason)
+	w.items = append(w.items, xpost.ValidationError{Provider: provider, Reason: reason})
+}
+
+// enforce returns the collected warnings as errors when strict is set.
+func (w *warningSet) enforce(strict bool, out io.Writer) error {
+	if !strict || len(w.items) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(out, "Strict mode: %d warning(s) treated as errors - no posts were sent:\n", len(w.items))
+	errs := make([]error, 0, len(w.items))
+	for _, item := range w.items {
+		fmt.Fprintf(out, "  • %s: %s\n", item.Provider, item.Reason)
+		errs = append(errs, item)
+	}
+	return errors.Join(errs...)
+}
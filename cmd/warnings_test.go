@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCheckBannedHashtags(t *testing.T) {
+	banned := map[string][]string{
+		"twitter":  {"#Crypto"},
+		"mastodon": {"nsfw", "crypto"},
+	}
+	tests := []struct {
+		name    string
+		message string
+		want    []string // provider: reason prefix, in order
+	}{
+		{name: "no tags", message: "hello"},
+		{name: "case insensitive", message: "buy #CRYPTO now", want: []string{"twitter: hashtag #CRYPTO", "mastodon: hashtag #CRYPTO"}},
+		{name: "one provider", message: "#nsfw art", want: []string{"mastodon: hashtag #nsfw"}},
+		{name: "url fragment", message: "https://example.com/#crypto"},
+	}
+	for _, tt := range tests {
+		var w warningSet
+		checkBannedHashtags(&w, banned, tt.message, []string{"twitter", "mastodon", "bluesky"})
+		if len(w.items) != len(tt.want) {
+			t.Errorf("%s: got %d warnings %v, want %d", tt.name, len(w.items), w.items, len(tt.want))
+			continue
+		}
+		for i, item := range w.items {
+			if got := item.Provider + ": " + item.Reason; !strings.HasPrefix(got, tt.want[i]) {
+				t.Errorf("%s: warning %d = %q, want %q...", tt.name, i, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestMatchCWHashtag(t *testing.T) {
+	rules := map[string]string{"#Politics": "politics, elections", "nsfw": " "}
+	tests := []struct {
+		message string
+		wantCW  string
+		wantTag string
+	}{
+		{message: "hello"},
+		{message: "vote! #politics", wantCW: "politics, elections", wantTag: "politics"},
+		// An empty warning falls back to the tag itself.
+		{message: "art #NSFW", wantCW: "nsfw", wantTag: "NSFW"},
+		{message: "https://example.com/#politics"},
+	}
+	for _, tt := range tests {
+		cw, tag := matchCWHashtag(rules, tt.message)
+		if cw != tt.wantCW || tag != tt.wantTag {
+			t.Errorf("matchCWHashtag(%q) = %q, %q; want %q, %q", tt.message, cw, tag, tt.wantCW, tt.wantTag)
+		}
+	}
+}
+
+func TestWarningSetEnforce(t *testing.T) {
+	var w warningSet
+	if err := w.enforce(true, io.Discard); err != nil {
+		t.Errorf("enforce with no warnings = %v", err)
+	}
+	w.add("twitter", "hashtag #%s is banned", "x")
+	if err := w.enforce(false, io.Discard); err != nil {
+		t.Errorf("enforce without --strict = %v", err)
+	}
+	var out strings.Builder
+	err := w.enforce(true, &out)
+	if err == nil || !strings.Contains(err.Error(), "hashtag #x is banned") {
+		t.Errorf("enforce under --strict = %v, want the warning as an error", err)
+	}
+	if !strings.Contains(out.String(), "1 warning(s) treated as errors") {
+		t.Errorf("output = %q", out.String())
+	}
+}
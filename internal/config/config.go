@@ -0,0 +1,432 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const fileName = "config.yaml"
+
+// Config is the on-disk xpost configuration.
+type Config struct {
+	Twitter  Twitter  `yaml:"twitter,omitempty"`
+	Mastodon Mastodon `yaml:"mastodon,omitempty"`
+	Bluesky  Bluesky  `yaml:"bluesky,omitempty"`
+	Lemmy    Lemmy    `yaml:"lemmy,omitempty"`
+	Pixelfed Pixelfed `yaml:"pixelfed,omitempty"`
+	Misskey  Misskey  `yaml:"misskey,omitempty"`
+	Discord  Discord  `yaml:"discord,omitempty"`
+	Slack    Slack    `yaml:"slack,omitempty"`
+	Telegram Telegram `yaml:"telegram,omitempty"`
+
+	Campaign Campaign `yaml:"campaign,omitempty"`
+
+	// HTTPTimeout is how long each provider API request, including every
+	// chunk of an upload, may take; zero means the providers' default.
+	HTTPTimeout time.Duration `yaml:"http_timeout,omitempty"`
+
+	// BannedHashtags lists, per provider, hashtags known to limit reach there.
+	BannedHashtags map[string][]string `yaml:"banned_hashtags,omitempty"`
+
+	// pinned are the XPOST_* variables whose values an --account set, so
+	// the environment does not override them.
+	pinned map[string]bool
+}
+
+// Options are per-provider posting defaults merged into every request.
+type Options struct {
+	// TextOnly drops any image when posting to the provider.
+	TextOnly bool `yaml:"text_only,omitempty"`
+	// ContentWarning is applied when the request has none (Mastodon, Pixelfed, Misskey).
+	ContentWarning string `yaml:"content_warning,omitempty"`
+	// DefaultVisibility sets the post visibility (Mastodon, Pixelfed, Misskey), e.g. unlisted.
+	DefaultVisibility string `yaml:"default_visibility,omitempty"`
+	// PlainPunctuation replaces curly quotes, dashes, and ellipses with ASCII.
+	PlainPunctuation bool `yaml:"plain_punctuation,omitempty"`
+	// StripHashtags removes hashtags from the message.
+	StripHashtags bool `yaml:"strip_hashtags,omitempty"`
+}
+
+// merge returns o with the options that over sets applied on top.
+func (o Options) merge(over Options) Options {
+	o.TextOnly = o.TextOnly || over.TextOnly
+	if over.ContentWarning != "" {
+		o.ContentWarning = over.ContentWarning
+	}
+	if over.DefaultVisibility != "" {
+		o.DefaultVisibility = over.DefaultVisibility
+	}
+	o.PlainPunctuation = o.PlainPunctuation || over.PlainPunctuation
+	o.StripHashtags = o.StripHashtags || over.StripHashtags
+	return o
+}
+
+// Campaign is a hashtag added to every post until it expires.
+type Campaign struct {
+	Tag string `yaml:"tag,omitempty"`
+	// Expires is the date (YYYY-MM-DD) or RFC 3339 time after which the tag
+	// is no longer added. Empty means it never expires.
+	Expires string `yaml:"expires,omitempty"`
+}
+
+// Twitter holds OAuth 1.0a user-context credentials, or the OAuth 2.0 app
+// used with auth: oauth2.
+type Twitter struct {
+	Options `yaml:",inline"`
+
+	ConsumerKey       string `yaml:"consumer_key,omitempty"`
+	ConsumerSecret    string `yaml:"consumer_secret,omitempty"`
+	AccessToken       string `yaml:"access_token,omitempty"`
+	AccessTokenSecret string `yaml:"access_token_secret,omitempty"`
+
+	// Auth is "oauth2" to use a token from xpost login twitter instead of
+	// the OAuth 1.0a keys above.
+	Auth         string `yaml:"auth,omitempty"`
+	ClientID     string `yaml:"client_id,omitempty"`
+	ClientSecret string `yaml:"client_secret,omitempty"`
+	// TokenFile is where the OAuth 2.0 token is saved; empty means
+	// twitter-token.json in the user's xpost config dir.
+	TokenFile string `yaml:"token_file,omitempty"`
+	// Debug logs every X API request and response.
+	Debug bool `yaml:"debug,omitempty"`
+
+	// Accounts are alternative sections selected with --account.
+	Accounts map[string]Twitter `yaml:"accounts,omitempty"`
+}
+
+// Mastodon holds the server and application credentials.
+type Mastodon struct {
+	Options `yaml:",inline"`
+
+	Server       string `yaml:"server,omitempty"`
+	AccessToken  string `yaml:"access_token,omitempty"`
+	ClientID     string `yaml:"client_id,omitempty"`
+	ClientSecret string `yaml:"client_secret,omitempty"`
+	// CWHashtags maps hashtags the instance expects a content warning for to
+	// the warning text to apply (the tag itself when empty).
+	CWHashtags map[string]string `yaml:"cw_hashtags,omitempty"`
+
+	// Accounts are alternative sections selected with --account.
+	Accounts map[string]Mastodon `yaml:"accounts,omitempty"`
+}
+
+// Bluesky holds the handle and app password used to create a session.
+type Bluesky struct {
+	Options `yaml:",inline"`
+
+	Handle      string `yaml:"handle,omitempty"`
+	AppPassword string `yaml:"app_password,omitempty"`
+	PDSURL      string `yaml:"pds_url,omitempty"`
+	// SessionFile caches the login between runs; empty means
+	// bluesky-session.json in the user's xpost config dir.
+	SessionFile string `yaml:"session_file,omitempty"`
+
+	// Accounts are alternative sections selected with --account.
+	Accounts map[string]Bluesky `yaml:"accounts,omitempty"`
+}
+
+// Lemmy holds the instance and account used to log in.
+type Lemmy struct {
+	Options `yaml:",inline"`
+
+	Instance string `yaml:"instance,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+
+	// Accounts are alternative sections selected with --account.
+	Accounts map[string]Lemmy `yaml:"accounts,omitempty"`
+}
+
+// Pixelfed holds the server and access token.
+type Pixelfed struct {
+	Options `yaml:",inline"`
+
+	Server      string `yaml:"server,omitempty"`
+	AccessToken string `yaml:"access_token,omitempty"`
+
+	// Accounts are alternative sections selected with --account.
+	Accounts map[string]Pixelfed `yaml:"accounts,omitempty"`
+}
+
+// Misskey holds the server and access token.
+type Misskey struct {
+	Options `yaml:",inline"`
+
+	Server string `yaml:"server,omitempty"`
+	Token  string `yaml:"token,omitempty"`
+
+	// Accounts are alternative sections selected with --account.
+	Accounts map[string]Misskey `yaml:"accounts,omitempty"`
+}
+
+// Discord holds the webhook messages are sent through.
+type Discord struct {
+	Options `yaml:",inline"`
+
+	WebhookURL string `yaml:"webhook_url,omitempty"`
+
+	// Accounts are alternative sections selected with --account.
+	Accounts map[string]Discord `yaml:"accounts,omitempty"`
+}
+
+// Slack holds an incoming webhook, or a bot token and the channel to post to.
+type Slack struct {
+	Options `yaml:",inline"`
+
+	WebhookURL string `yaml:"webhook_url,omitempty"`
+	BotToken   string `yaml:"bot_token,omitempty"`
+	Channel    string `yaml:"channel,omitempty"`
+
+	// Accounts are alternative sections selected with --account.
+	Accounts map[string]Slack `yaml:"accounts,omitempty"`
+}
+
+// Telegram holds the bot token and the chat or channel to post to.
+type Telegram struct {
+	Options `yaml:",inline"`
+
+	BotToken string `yaml:"bot_token,omitempty"`
+	ChatID   string `yaml:"chat_id,omitempty"`
+
+	// Accounts are alternative sections selected with --account.
+	Accounts map[string]Telegram `yaml:"accounts,omitempty"`
+}
+
+// DefaultPath returns the config file location under the user's config dir.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locate config dir: %w", err)
+	}
+	return filepath.Join(dir, "xpost", fileName), nil
+}
+
+// Load reads the config file at path. A missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if cfg.HTTPTimeout < 0 {
+		return nil, fmt.Errorf("parse config %s: http_timeout must be positive", path)
+	}
+
+	return cfg, nil
+}
+
+// Save writes cfg to path, readable only by the current user.
+func Save(path string, cfg *Config) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	data := buf.Bytes()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+
+	// Write to a temp file first so a failed write never truncates the old config.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write config: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+
+	return nil
+}
+
+// Section describes a provider's section of the config to the code that
+// applies the environment and --account. Providers register theirs with
+// RegisterSection.
+type Section struct {
+	// Options points at the section's posting options in c.
+	Options func(c *Config) *Options
+	// Vars maps the XPOST_* variables that override the section's values
+	// onto their fields in c.
+	Vars func(c *Config) map[string]*string
+	// Flags maps the XPOST_* variables that turn a setting on with "1" onto
+	// their fields in c; nil when the section has none.
+	Flags func(c *Config) map[string]*bool
+	// Account copies the section's named account from c into acct and
+	// reports whether it is defined. It merges into c any values Options,
+	// Vars and Flags do not cover.
+	Account func(c, acct *Config, name string) bool
+}
+
+var (
+	sectionsMu sync.RWMutex
+	sections   = map[string]Section{}
+)
+
+// RegisterSection makes the environment and --account apply to the
+// provider section registered under name. Providers call it from their
+// package init; registering a name twice panics.
+func RegisterSection(name string, s Section) {
+	sectionsMu.Lock()
+	defer sectionsMu.Unlock()
+	if s.Options == nil || s.Vars == nil || s.Account == nil {
+		panic("config: RegisterSection section is incomplete for " + name)
+	}
+	if _, dup := sections[name]; dup {
+		panic("config: RegisterSection called twice for " + name)
+	}
+	sections[name] = s
+}
+
+// registeredSections returns the registered sections by provider name, and
+// the names sorted.
+func registeredSections() (map[string]Section, []string) {
+	sectionsMu.RLock()
+	defer sectionsMu.RUnlock()
+	return maps.Clone(sections), slices.Sorted(maps.Keys(sections))
+}
+
+// ProviderOptions returns the posting options of every registered provider
+// section.
+func (c *Config) ProviderOptions() map[string]Options {
+	all, _ := registeredSections()
+	options := make(map[string]Options, len(all))
+	for name, s := range all {
+		options[name] = *s.Options(c)
+	}
+	return options
+}
+
+// UseAccount merges the named account over every provider section that
+// defines it: the values the account sets win, even over the environment, and
+// the rest, such as the posting options, come from the section. Providers
+// without the account keep their default credentials. It returns the
+// providers switched.
+func (c *Config) UseAccount(name string) ([]string, error) {
+	if c.pinned == nil {
+		c.pinned = map[string]bool{}
+	}
+	all, names := registeredSections()
+	var acct Config
+	var switched []string
+	for _, provider := range names {
+		s := all[provider]
+		if !s.Account(c, &acct, name) {
+			continue
+		}
+		switched = append(switched, provider)
+		options := s.Options(c)
+		*options = options.merge(*s.Options(&acct))
+
+		set := s.Vars(&acct)
+		for key, value := range s.Vars(c) {
+			if v := *set[key]; v != "" {
+				*value = v
+				c.pinned[key] = true
+			}
+		}
+		if s.Flags == nil {
+			continue
+		}
+		on := s.Flags(&acct)
+		for key, value := range s.Flags(c) {
+			if *on[key] {
+				*value = true
+				c.pinned[key] = true
+			}
+		}
+	}
+	if len(switched) == 0 {
+		return nil, fmt.Errorf("account %q is not configured for any provider", name)
+	}
+	return switched, nil
+}
+
+// ApplyEnv overrides the configured values with the XPOST_* variables that
+// lookup finds set, so the environment takes precedence over the file. The
+// values set by the --account chosen with UseAccount are left alone: the
+// account always wins. It fails if XPOST_HTTP_TIMEOUT is not a duration.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	set := func(value *string, key string) {
+		if c.pinned[key] {
+			return
+		}
+		if v, ok := lookup(key); ok && strings.TrimSpace(v) != "" {
+			*value = v
+		}
+	}
+
+	all, names := registeredSections()
+	for _, provider := range names {
+		s := all[provider]
+		for key, value := range s.Vars(c) {
+			set(value, key)
+		}
+		if s.Flags == nil {
+			continue
+		}
+		for key, value := range s.Flags(c) {
+			if v, ok := lookup(key); ok && !c.pinned[key] {
+				*value = v == "1"
+			}
+		}
+	}
+
+	set(&c.Campaign.Tag, "XPOST_CAMPAIGN_TAG")
+	set(&c.Campaign.Expires, "XPOST_CAMPAIGN_EXPIRES")
+
+	timeout, err := LookupHTTPTimeout(lookup)
+	if err != nil {
+		return err
+	}
+	if timeout > 0 {
+		c.HTTPTimeout = timeout
+	}
+	return nil
+}
+
+// LookupHTTPTimeout parses the XPOST_HTTP_TIMEOUT that lookup finds set, such
+// as 90s or 2m. It returns zero when the variable is unset or blank.
+func LookupHTTPTimeout(lookup func(string) (string, bool)) (time.Duration, error) {
+	v, ok := lookup("XPOST_HTTP_TIMEOUT")
+	if !ok || strings.TrimSpace(v) == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid XPOST_HTTP_TIMEOUT %q: expected a positive duration like 90s", v)
+	}
+	return d, nil
+}
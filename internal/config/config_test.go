@@ -0,0 +1,395 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeConfig writes data to a config file in a temp dir and returns its path.
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), fileName)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// The providers register their sections from their own packages, which
+// import this one; these stand in for them.
+func init() {
+	RegisterSection("twitter", Section{
+		Options: func(c *Config) *Options { return &c.Twitter.Options },
+		Vars: func(c *Config) map[string]*string {
+			return map[string]*string{"XPOST_TWITTER_CONSUMER_KEY": &c.Twitter.ConsumerKey}
+		},
+		Flags: func(c *Config) map[string]*bool {
+			return map[string]*bool{"XPOST_TWITTER_DEBUG": &c.Twitter.Debug}
+		},
+		Account: func(c, acct *Config, name string) bool {
+			a, ok := c.Twitter.Accounts[name]
+			acct.Twitter = a
+			return ok
+		},
+	})
+	RegisterSection("mastodon", Section{
+		Options: func(c *Config) *Options { return &c.Mastodon.Options },
+		Vars: func(c *Config) map[string]*string {
+			return map[string]*string{
+				"XPOST_MASTODON_SERVER":       &c.Mastodon.Server,
+				"XPOST_MASTODON_ACCESS_TOKEN": &c.Mastodon.AccessToken,
+				"XPOST_MASTODON_CLIENT_ID":    &c.Mastodon.ClientID,
+			}
+		},
+		Account: func(c, acct *Config, name string) bool {
+			a, ok := c.Mastodon.Accounts[name]
+			acct.Mastodon = a
+			if ok && len(a.CWHashtags) > 0 {
+				tags := maps.Clone(c.Mastodon.CWHashtags)
+				if tags == nil {
+					tags = map[string]string{}
+				}
+				maps.Copy(tags, a.CWHashtags)
+				c.Mastodon.CWHashtags = tags
+			}
+			return ok
+		},
+	})
+	RegisterSection("bluesky", Section{
+		Options: func(c *Config) *Options { return &c.Bluesky.Options },
+		Vars: func(c *Config) map[string]*string {
+			return map[string]*string{
+				"XPOST_BLUESKY_HANDLE":       &c.Bluesky.Handle,
+				"XPOST_BLUESKY_APP_PASSWORD": &c.Bluesky.AppPassword,
+				"XPOST_BLUESKY_PDS_URL":      &c.Bluesky.PDSURL,
+			}
+		},
+		Account: func(c, acct *Config, name string) bool {
+			a, ok := c.Bluesky.Accounts[name]
+			acct.Bluesky = a
+			return ok
+		},
+	})
+	RegisterSection("lemmy", Section{
+		Options: func(c *Config) *Options { return &c.Lemmy.Options },
+		Vars: func(c *Config) map[string]*string {
+			return map[string]*string{"XPOST_LEMMY_PASSWORD": &c.Lemmy.Password}
+		},
+		Account: func(c, acct *Config, name string) bool {
+			a, ok := c.Lemmy.Accounts[name]
+			acct.Lemmy = a
+			return ok
+		},
+	})
+}
+
+func TestRegisterSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		section Section
+	}{
+		{name: "twitter", section: sections["twitter"]},
+		{name: "incomplete", section: Section{Options: func(c *Config) *Options { return &c.Slack.Options }}},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: RegisterSection did not panic", tt.name)
+				}
+			}()
+			RegisterSection(tt.name, tt.section)
+		}()
+	}
+}
+
+func TestProviderOptions(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `
+twitter:
+  text_only: true
+  consumer_key: key
+mastodon:
+  server: https://example.social
+  content_warning: spoilers
+  default_visibility: unlisted
+  plain_punctuation: true
+bluesky:
+  strip_hashtags: true
+`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		provider string
+		want     Options
+	}{
+		{provider: "twitter", want: Options{TextOnly: true}},
+		{provider: "mastodon", want: Options{ContentWarning: "spoilers", DefaultVisibility: "unlisted", PlainPunctuation: true}},
+		{provider: "bluesky", want: Options{StripHashtags: true}},
+		{provider: "lemmy"},
+		{provider: "telegram"},
+	}
+	options := cfg.ProviderOptions()
+	for _, tt := range tests {
+		if got := options[tt.provider]; got != tt.want {
+			t.Errorf("%s options = %+v, want %+v", tt.provider, got, tt.want)
+		}
+	}
+	// The inline options must not swallow the credentials beside them.
+	if cfg.Twitter.ConsumerKey != "key" || cfg.Mastodon.Server != "https://example.social" {
+		t.Errorf("credentials lost: twitter %+v, mastodon %+v", cfg.Twitter, cfg.Mastodon)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), fileName))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for provider, o := range cfg.ProviderOptions() {
+		if o != (Options{}) {
+			t.Errorf("%s options = %+v, want none", provider, o)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"XPOST_MASTODON_ACCESS_TOKEN": "env-token",
+		"XPOST_BLUESKY_HANDLE":        "env.handle",
+		"XPOST_LEMMY_PASSWORD":        "  ",
+		"XPOST_TWITTER_DEBUG":         "1",
+		"XPOST_CAMPAIGN_TAG":          "EnvTag",
+		"XPOST_HTTP_TIMEOUT":          "2m",
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	file := func() *Config {
+		return &Config{
+			Mastodon: Mastodon{Server: "https://file.social", AccessToken: "file-token"},
+			Bluesky:  Bluesky{Handle: "file.handle"},
+			Lemmy:    Lemmy{Password: "file-password"},
+			Campaign: Campaign{Tag: "FileTag", Expires: "2030-01-01"},
+		}
+	}
+
+	tests := []struct {
+		name string
+		want func(*Config) bool
+	}{
+		{
+			name: "environment wins",
+			want: func(c *Config) bool {
+				return c.Mastodon.AccessToken == "env-token" && c.Bluesky.Handle == "env.handle" && c.Twitter.Debug
+			},
+		},
+		{
+			name: "unset and blank variables keep the file",
+			want: func(c *Config) bool {
+				return c.Mastodon.Server == "https://file.social" && c.Lemmy.Password == "file-password"
+			},
+		},
+		{
+			name: "campaign",
+			want: func(c *Config) bool { return c.Campaign.Tag == "EnvTag" && c.Campaign.Expires == "2030-01-01" },
+		},
+		{
+			name: "timeout",
+			want: func(c *Config) bool { return c.HTTPTimeout == 2*time.Minute },
+		},
+	}
+	for _, tt := range tests {
+		cfg := file()
+		if err := cfg.ApplyEnv(lookup); err != nil {
+			t.Fatalf("%s: ApplyEnv: %v", tt.name, err)
+		}
+		if !tt.want(cfg) {
+			t.Errorf("%s: config = %+v", tt.name, cfg)
+		}
+	}
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "file", file: "http_timeout: 90s\n", want: 90 * time.Second},
+		{name: "environment wins", file: "http_timeout: 90s\n", env: "2m", want: 2 * time.Minute},
+		{name: "blank environment keeps the file", file: "http_timeout: 90s\n", env: " ", want: 90 * time.Second},
+		{name: "invalid environment", env: "soon", wantErr: true},
+		{name: "negative environment", env: "-5s", wantErr: true},
+		{name: "negative file", file: "http_timeout: -5s\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		lookup := func(key string) (string, bool) {
+			return tt.env, key == "XPOST_HTTP_TIMEOUT" && tt.env != ""
+		}
+		cfg, err := Load(writeConfig(t, tt.file))
+		if err == nil {
+			err = cfg.ApplyEnv(lookup)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && cfg.HTTPTimeout != tt.want {
+			t.Errorf("%s: timeout = %v, want %v", tt.name, cfg.HTTPTimeout, tt.want)
+		}
+	}
+
+	// The timeout is saved as a duration, not as nanoseconds.
+	path := filepath.Join(t.TempDir(), fileName)
+	if err := Save(path, &Config{HTTPTimeout: 2 * time.Minute}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "http_timeout: 2m0s\n" {
+		t.Errorf("saved %q, want http_timeout: 2m0s", got)
+	}
+}
+
+func TestUseAccount(t *testing.T) {
+	load := func() *Config {
+		cfg, err := Load(writeConfig(t, `
+mastodon:
+  server: https://mastodon.social
+  access_token: personal
+  client_id: app
+  default_visibility: unlisted
+  cw_hashtags:
+    politics: ""
+  accounts:
+    work:
+      access_token: work
+      content_warning: work stuff
+      cw_hashtags:
+        meta: about us
+bluesky:
+  handle: me.bsky.social
+  app_password: personal
+  accounts:
+    work:
+      handle: work.example
+      app_password: work
+      pds_url: https://pds.example
+twitter:
+  consumer_key: key
+`))
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		return cfg
+	}
+
+	cfg := load()
+	switched, err := cfg.UseAccount("work")
+	if err != nil {
+		t.Fatalf("UseAccount: %v", err)
+	}
+	if want := []string{"bluesky", "mastodon"}; !slices.Equal(switched, want) {
+		t.Errorf("switched = %v, want %v", switched, want)
+	}
+
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{name: "account token", got: cfg.Mastodon.AccessToken, want: "work"},
+		{name: "unrepeated server", got: cfg.Mastodon.Server, want: "https://mastodon.social"},
+		{name: "unrepeated client", got: cfg.Mastodon.ClientID, want: "app"},
+		{name: "merged options", got: cfg.Mastodon.Options, want: Options{ContentWarning: "work stuff", DefaultVisibility: "unlisted"}},
+		{name: "merged cw hashtags", got: fmt.Sprint(cfg.Mastodon.CWHashtags), want: "map[meta:about us politics:]"},
+		{name: "bluesky handle", got: cfg.Bluesky.Handle, want: "work.example"},
+		{name: "bluesky pds", got: cfg.Bluesky.PDSURL, want: "https://pds.example"},
+		{name: "provider without the account", got: cfg.Twitter.ConsumerKey, want: "key"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// The default section is left as it was when the account is not used.
+	if cfg := load(); cfg.Mastodon.AccessToken != "personal" || len(cfg.Mastodon.CWHashtags) != 1 {
+		t.Errorf("default section = %+v", cfg.Mastodon)
+	}
+	if _, err := load().UseAccount("nope"); err == nil {
+		t.Error("an unknown account was accepted")
+	}
+}
+
+func TestUseAccountWithEnv(t *testing.T) {
+	env := map[string]string{
+		"XPOST_MASTODON_SERVER":       "https://env.social",
+		"XPOST_MASTODON_ACCESS_TOKEN": "env-token",
+		"XPOST_BLUESKY_HANDLE":        "env.handle",
+		"XPOST_TWITTER_DEBUG":         "0",
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	cfg, err := Load(writeConfig(t, `
+mastodon:
+  server: https://mastodon.social
+  access_token: personal
+  client_id: app
+  accounts:
+    work:
+      access_token: work
+bluesky:
+  handle: me.bsky.social
+  app_password: personal
+  accounts:
+    work:
+      app_password: work
+twitter:
+  accounts:
+    work:
+      debug: true
+`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, err := cfg.UseAccount("work"); err != nil {
+		t.Fatalf("UseAccount: %v", err)
+	}
+	if err := cfg.ApplyEnv(lookup); err != nil {
+		t.Fatalf("ApplyEnv: %v", err)
+	}
+
+	// The account's values win, the environment fills the fields it leaves
+	// empty, and the section fills the rest.
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{name: "account token", got: cfg.Mastodon.AccessToken, want: "work"},
+		{name: "environment server", got: cfg.Mastodon.Server, want: "https://env.social"},
+		{name: "section client", got: cfg.Mastodon.ClientID, want: "app"},
+		{name: "account password", got: cfg.Bluesky.AppPassword, want: "work"},
+		{name: "environment handle", got: cfg.Bluesky.Handle, want: "env.handle"},
+		{name: "account debug", got: cfg.Twitter.Debug, want: true},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package imageprep
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"os"
+)
+
+const (
+	// scaleStep shrinks each side by this factor when lowering the quality
+	// alone is not enough.
+	scaleStep = 0.75
+	// minSide is the shortest side fitImage will scale an image down to.
+	minSide = 64
+)
+
+// fitQualities are the JPEG qualities tried, best first, at each size.
+var fitQualities = []int{jpegQuality, 80, 70, 60}
+
+// FitFile re-encodes the image at path so it takes at most maxBytes. Images
+// already within the limit, animated GIFs, which would keep only their first
+// frame, and files the standard library cannot decode are returned unchanged
+// for the provider to accept or reject.
+func FitFile(path string, maxBytes int) (Result, error) {
+	original := Result{Path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("read image: %w", err)
+	}
+	if maxBytes <= 0 || len(data) <= maxBytes {
+		return original, nil
+	}
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format == "gif" && isAnimated(data) {
+		return original, nil
+	}
+
+	fitted, err := fitImage(data, maxBytes)
+	if err != nil {
+		return Result{}, fmt.Errorf("fit %s: %w", path, err)
+	}
+	_, format, err = image.DecodeConfig(bytes.NewReader(fitted))
+	if err != nil {
+		return Result{}, fmt.Errorf("fit %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp("", "xpost-*."+format)
+	if err != nil {
+		return Result{}, fmt.Errorf("write image: %w", err)
+	}
+	if _, err := tmp.Write(fitted); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return Result{}, fmt.Errorf("write image: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return Result{}, fmt.Errorf("write image: %w", err)
+	}
+
+	return Result{Path: tmp.Name(), MediaType: "image/" + format, Transcoded: true}, nil
+}
+
+// isAnimated reports whether the GIF in data has more than one frame.
+func isAnimated(data []byte) bool {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	return err == nil && len(g.Image) > 1
+}
+
+// fitImage re-encodes data to at most maxBytes. Opaque images become JPEG,
+// stepping the quality down before shrinking the dimensions; images with
+// transparency stay PNG and are only shrunk.
+func fitImage(data []byte, maxBytes int) ([]byte, error) {
+	if len(data) <= maxBytes {
+		return data, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+
+	opaque := isOpaque(img)
+	var buf bytes.Buffer
+	for scaled := img; ; {
+		if opaque {
+			for _, quality := range fitQualities {
+				buf.Reset()
+				if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: quality}); err != nil {
+					return nil, fmt.Errorf("encode %s: %w", JPEG, err)
+				}
+				if buf.Len() <= maxBytes {
+					return buf.Bytes(), nil
+				}
+			}
+		} else {
+			buf.Reset()
+			if err := encode(&buf, scaled, PNG); err != nil {
+				return nil, fmt.Errorf("encode %s: %w", PNG, err)
+			}
+			if buf.Len() <= maxBytes {
+				return buf.Bytes(), nil
+			}
+		}
+
+		bounds := scaled.Bounds()
+		w, h := int(float64(bounds.Dx())*scaleStep), int(float64(bounds.Dy())*scaleStep)
+		if min(w, h) < minSide {
+			return nil, fmt.Errorf("cannot fit image in %d bytes", maxBytes)
+		}
+		scaled = downscale(scaled, w, h)
+	}
+}
+
+// downscale shrinks img to w x h, averaging the source pixels each
+// destination pixel covers.
+func downscale(img image.Image, w, h int) *image.NRGBA {
+	src := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		y0 := src.Min.Y + y*src.Dy()/h
+		y1 := max(src.Min.Y+(y+1)*src.Dy()/h, y0+1)
+		for x := range w {
+			x0 := src.Min.X + x*src.Dx()/w
+			x1 := max(src.Min.X+(x+1)*src.Dx()/w, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			if a == 0 {
+				dst.SetNRGBA(x, y, color.NRGBA{})
+				continue
+			}
+			// Average premultiplied values, then un-premultiply.
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r * 0xff / a),
+				G: uint8(g * 0xff / a),
+				B: uint8(b * 0xff / a),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
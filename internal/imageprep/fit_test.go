@@ -0,0 +1,103 @@
+package imageprep
+
+import (
+	"bytes"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeGIF writes a GIF of frames copies of img to a file named name.
+func writeGIF(t *testing.T, name string, img image.Image, frames int) string {
+	t.Helper()
+	anim := &gif.GIF{}
+	for range frames {
+		frame := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.Draw(frame, frame.Bounds(), img, image.Point{}, draw.Src)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFitFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		maxBytes int
+		wantType string // "" when the original is kept
+	}{
+		{name: "opaque jpeg", path: writeImage(t, "photo.jpg", noise(512, 512, false), JPEG), maxBytes: 60_000, wantType: "image/jpeg"},
+		{name: "opaque png becomes jpeg", path: writeImage(t, "photo.png", noise(256, 256, false), PNG), maxBytes: 40_000, wantType: "image/jpeg"},
+		{name: "png with alpha stays png", path: writeImage(t, "alpha.png", noise(256, 256, true), PNG), maxBytes: 100_000, wantType: "image/png"},
+		{name: "still gif", path: writeGIF(t, "still.gif", noise(256, 256, false), 1), maxBytes: 20_000, wantType: "image/jpeg"},
+		{name: "within the limit", path: writeImage(t, "small.png", flat(64, 64), PNG), maxBytes: 1 << 20},
+		{name: "animated gif", path: writeGIF(t, "anim.gif", noise(128, 128, false), 2), maxBytes: 1_000},
+		{name: "undecodable", path: writeImage(t, "a.webp", nil, "RIFF....WEBP"), maxBytes: 4},
+		{name: "no limit", path: writeImage(t, "big.png", noise(128, 128, false), PNG)},
+	}
+	for _, tt := range tests {
+		res, err := FitFile(tt.path, tt.maxBytes)
+		if err != nil {
+			t.Errorf("%s: FitFile: %v", tt.name, err)
+			continue
+		}
+		if tt.wantType == "" {
+			if res != (Result{Path: tt.path}) {
+				t.Errorf("%s: got %+v, want the original", tt.name, res)
+			}
+			continue
+		}
+		defer os.Remove(res.Path)
+		if !res.Transcoded || res.MediaType != tt.wantType || res.Path == tt.path {
+			t.Errorf("%s: got %+v, want a %s copy", tt.name, res, tt.wantType)
+			continue
+		}
+		info, err := os.Stat(res.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > int64(tt.maxBytes) {
+			t.Errorf("%s: copy is %d bytes, want at most %d", tt.name, info.Size(), tt.maxBytes)
+		}
+		if tt.wantType == "image/png" && !hasAlpha(t, res.Path) {
+			t.Errorf("%s: the copy lost its transparency", tt.name)
+		}
+	}
+}
+
+// hasAlpha reports whether the image at path has a pixel that is not opaque.
+func hasAlpha(t *testing.T, path string) bool {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return !isOpaque(img)
+}
+
+func TestFitImageTooSmall(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encode(&buf, noise(128, 128, false), PNG); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fitImage(buf.Bytes(), 100); err == nil {
+		t.Error("fitImage fitted a photo into 100 bytes")
+	}
+}
@@ -0,0 +1,152 @@
+// Package imageprep adapts images to the upload format a provider handles best.
+package imageprep
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strings"
+
+	// Register GIF decoding so animated or palette images are recognised.
+	_ "image/gif"
+)
+
+// Supported output formats.
+const (
+	JPEG = "jpeg"
+	PNG  = "png"
+)
+
+const (
+	jpegQuality = 90
+	sampleGrid  = 64 // sample at most sampleGrid x sampleGrid pixels
+	// photoColorRatio is the share of distinct colors among the sampled pixels
+	// above which an image is treated as a photograph. Screenshots and
+	// illustrations reuse a small palette and stay well below it.
+	photoColorRatio = 0.3
+)
+
+// Result describes the image to upload.
+type Result struct {
+	Path      string
+	MediaType string
+	// Transcoded reports whether Path is a temporary file the caller must remove.
+	Transcoded bool
+}
+
+// ParseFormat normalizes a format name such as "jpg" or "PNG".
+func ParseFormat(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "jpeg", "jpg":
+		return JPEG, nil
+	case "png":
+		return PNG, nil
+	default:
+		return "", fmt.Errorf("unsupported image format %q (expected jpeg or png)", name)
+	}
+}
+
+// Prefer re-encodes the image at path into format when that is beneficial:
+// photographs become JPEG and flat graphics become PNG. The original file is
+// returned unchanged when it already has the format, cannot be decoded, has
+// transparency that JPEG would drop, or would not get any smaller.
+func Prefer(path, format string) (Result, error) {
+	original := Result{Path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("read image: %w", err)
+	}
+
+	img, current, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		// Formats the standard library cannot decode (e.g. WebP) are left as-is.
+		return original, nil
+	}
+	if current == format {
+		return original, nil
+	}
+
+	photo := IsPhotographic(img)
+	switch format {
+	case JPEG:
+		if !photo || !isOpaque(img) {
+			return original, nil
+		}
+	case PNG:
+		if photo {
+			return original, nil
+		}
+	default:
+		return Result{}, fmt.Errorf("unsupported image format %q", format)
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, img, format); err != nil {
+		return Result{}, fmt.Errorf("encode %s: %w", format, err)
+	}
+	if buf.Len() >= len(data) {
+		return original, nil
+	}
+
+	tmp, err := os.CreateTemp("", "xpost-*."+format)
+	if err != nil {
+		return Result{}, fmt.Errorf("write image: %w", err)
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return Result{}, fmt.Errorf("write image: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return Result{}, fmt.Errorf("write image: %w", err)
+	}
+
+	return Result{Path: tmp.Name(), MediaType: "image/" + format, Transcoded: true}, nil
+}
+
+// IsPhotographic reports whether img looks like a photograph rather than a
+// screenshot or illustration, judged by how many distinct colors a grid of
+// sampled pixels contains.
+func IsPhotographic(img image.Image) bool {
+	bounds := img.Bounds()
+	stepX := max(bounds.Dx()/sampleGrid, 1)
+	stepY := max(bounds.Dy()/sampleGrid, 1)
+
+	colors := map[[3]uint32]struct{}{}
+	samples := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			colors[[3]uint32{r >> 8, g >> 8, b >> 8}] = struct{}{}
+			samples++
+		}
+	}
+	if samples == 0 {
+		return false
+	}
+	return float64(len(colors))/float64(samples) > photoColorRatio
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+func encode(buf *bytes.Buffer, img image.Image, format string) error {
+	switch format {
+	case JPEG:
+		return jpeg.Encode(buf, img, &jpeg.Options{Quality: jpegQuality})
+	case PNG:
+		return png.Encode(buf, img)
+	default:
+		return errors.New("unknown format")
+	}
+}
@@ -0,0 +1,149 @@
+package imageprep
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// noise returns a w x h image of pseudo-random colors, like a photograph.
+// With alpha set, every pixel is half transparent.
+func noise(w, h int, alpha bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	seed := uint32(1)
+	for y := range h {
+		for x := range w {
+			seed = seed*1664525 + 1013904223
+			a := uint8(0xff)
+			if alpha {
+				a = 0x80
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(seed >> 24), G: uint8(seed >> 16), B: uint8(seed >> 8), A: a})
+		}
+	}
+	return img
+}
+
+// flat returns a w x h image in two colors, like a screenshot. The 8px
+// checks line up with JPEG blocks so a JPEG copy keeps the two colors.
+func flat(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			c := color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			if (x/8+y/8)%2 == 0 {
+				c = color.NRGBA{R: 0x20, G: 0x40, B: 0x80, A: 0xff}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// writeImage encodes img in format to a file named name and returns its path.
+func writeImage(t *testing.T, name string, img image.Image, format string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case JPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100})
+	case PNG:
+		err = png.Encode(&buf, img)
+	default:
+		buf.WriteString(format)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "jpg", want: JPEG},
+		{name: " JPEG ", want: JPEG},
+		{name: "Png", want: PNG},
+		{name: "webp", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestIsPhotographic(t *testing.T) {
+	if !IsPhotographic(noise(128, 128, false)) {
+		t.Error("noise is not treated as a photograph")
+	}
+	if IsPhotographic(flat(128, 128)) {
+		t.Error("a two-color graphic is treated as a photograph")
+	}
+	if IsPhotographic(image.NewNRGBA(image.Rect(0, 0, 0, 0))) {
+		t.Error("an empty image is treated as a photograph")
+	}
+}
+
+func TestPrefer(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		format    string
+		wantType  string // "" when the original is kept
+		wantError bool
+	}{
+		{name: "photo png to jpeg", path: writeImage(t, "photo.png", noise(128, 128, false), PNG), format: JPEG, wantType: "image/jpeg"},
+		{name: "transparent photo stays png", path: writeImage(t, "alpha.png", noise(128, 128, true), PNG), format: JPEG},
+		{name: "graphic stays png", path: writeImage(t, "flat.png", flat(128, 128), PNG), format: JPEG},
+		{name: "graphic jpeg to png", path: writeImage(t, "flat.jpg", flat(256, 256), JPEG), format: PNG, wantType: "image/png"},
+		{name: "photo stays jpeg", path: writeImage(t, "photo.jpg", noise(128, 128, false), JPEG), format: PNG},
+		{name: "already the format", path: writeImage(t, "photo2.jpg", noise(128, 128, false), JPEG), format: JPEG},
+		{name: "undecodable", path: writeImage(t, "a.webp", nil, "RIFF....WEBP"), format: JPEG},
+		{name: "unknown format", path: writeImage(t, "photo3.png", noise(128, 128, false), PNG), format: "gif", wantError: true},
+	}
+	for _, tt := range tests {
+		res, err := Prefer(tt.path, tt.format)
+		if tt.wantError {
+			if err == nil {
+				t.Errorf("%s: Prefer succeeded", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Prefer: %v", tt.name, err)
+			continue
+		}
+		if tt.wantType == "" {
+			if res != (Result{Path: tt.path}) {
+				t.Errorf("%s: got %+v, want the original", tt.name, res)
+			}
+			continue
+		}
+		defer os.Remove(res.Path)
+		if !res.Transcoded || res.MediaType != tt.wantType || res.Path == tt.path {
+			t.Errorf("%s: got %+v, want a %s copy", tt.name, res, tt.wantType)
+			continue
+		}
+		before, _ := os.Stat(tt.path)
+		after, err := os.Stat(res.Path)
+		if err != nil || after.Size() >= before.Size() {
+			t.Errorf("%s: copy is not smaller (%v): %d >= %d bytes", tt.name, err, after.Size(), before.Size())
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package logutil
 
 import (
+	"fmt"
 	"os"
 	"sync"
 
@@ -8,41 +9,99 @@ import (
 )
 
 var (
-	logger  = log.NewWithOptions(os.Stderr, log.Options{Prefix: "xpost", ReportTimestamp: true, Level: log.InfoLevel})
-	verbose bool
-	mu      sync.RWMutex
+	logger = log.NewWithOptions(os.Stderr, log.Options{Prefix: "xpost", ReportTimestamp: true, Level: log.InfoLevel})
+	mu     sync.RWMutex
 )
 
-// SetVerbose adjusts the global logging level.
-func SetVerbose(enable bool) {
+// SetLogger replaces the logger used by xpost, letting library consumers
+// route its output through their own. A nil logger is ignored.
+func SetLogger(l *log.Logger) {
+	if l == nil {
+		return
+	}
 	mu.Lock()
 	defer mu.Unlock()
-	verbose = enable
+	logger = l
+}
+
+// Options configures the logger's destination, format and level.
+type Options struct {
+	// Path is a file logs are appended to; empty logs to stderr.
+	Path string
+	// Format is "text" (the default) or "json".
+	Format string
+	// Verbose enables debug logging.
+	Verbose bool
+}
+
+// Configure replaces the logger according to opts. The returned function
+// closes the log file, if one was opened.
+func Configure(opts Options) (func() error, error) {
+	formatter := log.TextFormatter
+	switch opts.Format {
+	case "", "text":
+	case "json":
+		formatter = log.JSONFormatter
+	default:
+		return nil, fmt.Errorf("invalid log format %q: expected text or json", opts.Format)
+	}
+
+	out, closeFn := os.Stderr, func() error { return nil }
+	if opts.Path != "" {
+		f, err := os.OpenFile(opts.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("open log file: %w", err)
+		}
+		out, closeFn = f, f.Close
+	}
+
+	SetLogger(log.NewWithOptions(out, log.Options{Prefix: "xpost", ReportTimestamp: true, Level: log.InfoLevel, Formatter: formatter}))
+	SetVerbose(opts.Verbose)
+	return closeFn, nil
+}
+
+// Logger returns the logger currently in use.
+func Logger() *log.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return logger
+}
+
+// SetVerbose adjusts the global logging level.
+func SetVerbose(enable bool) {
 	if enable {
-		logger.SetLevel(log.DebugLevel)
+		Logger().SetLevel(log.DebugLevel)
 	} else {
-		logger.SetLevel(log.InfoLevel)
+		Logger().SetLevel(log.InfoLevel)
 	}
 }
 
+// Level returns the current logging level.
+func Level() log.Level {
+	return Logger().GetLevel()
+}
+
 // Verbose reports whether verbose logging is enabled.
 func Verbose() bool {
-	mu.RLock()
-	defer mu.RUnlock()
-	return verbose
+	return Level() <= log.DebugLevel
 }
 
 // Debugf logs a debug message when verbose logging is enabled.
 func Debugf(format string, args ...any) {
-	logger.Debugf(format, args...)
+	Logger().Debugf(format, args...)
 }
 
 // Infof logs an informational message.
 func Infof(format string, args ...any) {
-	logger.Infof(format, args...)
+	Logger().Infof(format, args...)
+}
+
+// Warnf logs a warning message.
+func Warnf(format string, args ...any) {
+	Logger().Warnf(format, args...)
 }
 
 // Errorf logs an error message.
 func Errorf(format string, args ...any) {
-	logger.Errorf(format, args...)
+	Logger().Errorf(format, args...)
 }
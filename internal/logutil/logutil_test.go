@@ -0,0 +1,86 @@
+package logutil
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/charmbracelet/log"
+)
+
+// useLogger swaps in a logger writing to w for the duration of the test.
+func useLogger(t *testing.T, w io.Writer) {
+	t.Helper()
+	saved := Logger()
+	t.Cleanup(func() { SetLogger(saved) })
+	SetLogger(log.NewWithOptions(w, log.Options{Level: log.InfoLevel}))
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writers.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// TestConcurrentUse is meant for go test -race: toggling verbosity and
+// swapping the logger while others log must not race.
+func TestConcurrentUse(t *testing.T) {
+	out := &syncBuffer{}
+	useLogger(t, out)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			for j := range 100 {
+				switch (i + j) % 4 {
+				case 0:
+					SetVerbose(j%2 == 0)
+				case 1:
+					SetLogger(log.NewWithOptions(out, log.Options{Level: Level()}))
+				case 2:
+					Debugf("debug %d/%d", i, j)
+				default:
+					_ = Level()
+					_ = Verbose()
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestSetVerbose(t *testing.T) {
+	var out bytes.Buffer
+	useLogger(t, &out)
+
+	Debugf("hidden")
+	SetVerbose(true)
+	if !Verbose() || Level() != log.DebugLevel {
+		t.Errorf("after SetVerbose(true): level %v", Level())
+	}
+	Debugf("shown")
+	SetVerbose(false)
+	Debugf("hidden again")
+
+	if got := out.String(); strings.Contains(got, "hidden") || !strings.Contains(got, "shown") {
+		t.Errorf("output = %q, want only the verbose message", got)
+	}
+}
+
+func TestSetLoggerIgnoresNil(t *testing.T) {
+	var out bytes.Buffer
+	useLogger(t, &out)
+	SetLogger(nil)
+	Infof("still here")
+	if !strings.Contains(out.String(), "still here") {
+		t.Errorf("output = %q; a nil logger replaced the current one", out.String())
+	}
+}
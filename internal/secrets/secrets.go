@@ -0,0 +1,45 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Backend supplies provider credentials from an external secret store as
+// XPOST_* variable names mapped to their values.
+type Backend interface {
+	Name() string
+	Load(ctx context.Context) (map[string]string, error)
+}
+
+// FromEnv returns the backend configured through the environment, or nil when
+// none is configured.
+func FromEnv() (Backend, error) {
+	if os.Getenv(envVaultAddr) == "" {
+		return nil, nil
+	}
+	vault, err := newVaultFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return vault, nil
+}
+
+// Apply loads credentials from backend and exports any XPOST_* variable that
+// is not already set, so the environment always takes precedence.
+func Apply(ctx context.Context, backend Backend) error {
+	values, err := backend.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", backend.Name(), err)
+	}
+	for key, value := range values {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("%s: set %s: %w", backend.Name(), key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+const (
+	envVaultAddr  = "XPOST_VAULT_ADDR"
+	envVaultToken = "XPOST_VAULT_TOKEN"
+	envVaultPath  = "XPOST_VAULT_PATH"
+
+	vaultTimeout = 10 * time.Second
+)
+
+// Vault reads credentials from a HashiCorp Vault KV secret. Keys may be given
+// as full variable names (XPOST_TWITTER_ACCESS_TOKEN) or without the prefix in
+// any case (twitter_access_token).
+type Vault struct {
+	Addr  string
+	Token string
+	Path  string // e.g. secret/data/xpost (KV v2) or secret/xpost (KV v1)
+}
+
+func newVaultFromEnv() (*Vault, error) {
+	v := &Vault{
+		Addr:  strings.TrimRight(strings.TrimSpace(os.Getenv(envVaultAddr)), "/"),
+		Token: strings.TrimSpace(os.Getenv(envVaultToken)),
+		Path:  strings.Trim(strings.TrimSpace(os.Getenv(envVaultPath)), "/"),
+	}
+
+	var missing []string
+	if v.Token == "" {
+		missing = append(missing, envVaultToken)
+	}
+	if v.Path == "" {
+		missing = append(missing, envVaultPath)
+	}
+	if len(missing) > 0 {
+		return nil, xpost.MissingEnvError{Provider: "vault", Variables: missing}
+	}
+
+	return v, nil
+}
+
+// Name identifies the backend.
+func (v *Vault) Name() string { return "vault" }
+
+// Load reads the secret at Path and maps its keys to XPOST_* variables.
+func (v *Vault) Load(ctx context.Context) (map[string]string, error) {
+	client := &http.Client{Timeout: vaultTimeout}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.Addr+"/v1/"+v.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", v.Path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", v.Path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(body, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return nil, fmt.Errorf("read %s: %s (HTTP %d)", v.Path, strings.Join(vaultErr.Errors, "; "), resp.StatusCode)
+		}
+		return nil, fmt.Errorf("read %s: HTTP %d", v.Path, resp.StatusCode)
+	}
+
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", v.Path, err)
+	}
+
+	// KV v2 nests the key/value pairs under data.data alongside data.metadata.
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	values := map[string]string{}
+	for key, raw := range data {
+		value, ok := raw.(string)
+		if !ok || value == "" {
+			continue
+		}
+		name := strings.ToUpper(strings.TrimSpace(key))
+		if !strings.HasPrefix(name, "XPOST_") {
+			name = "XPOST_" + name
+		}
+		values[name] = value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestVaultLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		status  int
+		body    string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "kv v2",
+			path: "secret/data/xpost",
+			body: `{"data":{"data":{"twitter_api_key":"k","XPOST_BLUESKY_HANDLE":"me.test","empty":"","port":8080},"metadata":{"version":3}}}`,
+			want: map[string]string{"XPOST_TWITTER_API_KEY": "k", "XPOST_BLUESKY_HANDLE": "me.test"},
+		},
+		{
+			name: "kv v1",
+			path: "secret/xpost",
+			body: `{"data":{"mastodon_server":"https://example.social"}}`,
+			want: map[string]string{"XPOST_MASTODON_SERVER": "https://example.social"},
+		},
+		{
+			// Only a data/metadata pair marks KV v2; a v1 key named data stays.
+			name: "kv v1 key named data",
+			path: "secret/xpost",
+			body: `{"data":{"data":"x"}}`,
+			want: map[string]string{"XPOST_DATA": "x"},
+		},
+		{
+			name:    "vault error",
+			path:    "secret/data/xpost",
+			status:  http.StatusForbidden,
+			body:    `{"errors":["permission denied"]}`,
+			wantErr: "permission denied (HTTP 403)",
+		},
+		{
+			name:    "bare status",
+			path:    "secret/data/xpost",
+			status:  http.StatusBadGateway,
+			body:    `<html>`,
+			wantErr: "HTTP 502",
+		},
+	}
+	for _, tt := range tests {
+		var gotPath, gotToken string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath, gotToken = r.URL.Path, r.Header.Get("X-Vault-Token")
+			if tt.status != 0 {
+				w.WriteHeader(tt.status)
+			}
+			w.Write([]byte(tt.body))
+		}))
+
+		v := &Vault{Addr: srv.URL, Token: "s.token", Path: tt.path}
+		got, err := v.Load(context.Background())
+		srv.Close()
+
+		if gotPath != "/v1/"+tt.path || gotToken != "s.token" {
+			t.Errorf("%s: requested %s with token %q", tt.name, gotPath, gotToken)
+		}
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Load: %v", tt.name, err)
+			continue
+		}
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("%s: Load = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(envVaultAddr, "")
+	if b, err := FromEnv(); b != nil || err != nil {
+		t.Errorf("FromEnv without an address = %v, %v; want no backend", b, err)
+	}
+
+	t.Setenv(envVaultAddr, "https://vault.example/")
+	t.Setenv(envVaultToken, "")
+	t.Setenv(envVaultPath, "/secret/data/xpost/")
+	var missing xpost.MissingEnvError
+	if _, err := FromEnv(); !errors.As(err, &missing) || len(missing.Variables) != 1 || missing.Variables[0] != envVaultToken {
+		t.Errorf("FromEnv without a token: err = %v, want %s missing", err, envVaultToken)
+	}
+
+	t.Setenv(envVaultToken, "s.token")
+	b, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv: %v", err)
+	}
+	v := b.(*Vault)
+	if v.Addr != "https://vault.example" || v.Path != "secret/data/xpost" {
+		t.Errorf("FromEnv = %+v, want trimmed address and path", v)
+	}
+}
+
+type staticBackend map[string]string
+
+func (b staticBackend) Name() string                                    { return "static" }
+func (b staticBackend) Load(context.Context) (map[string]string, error) { return b, nil }
+
+func TestApplyKeepsEnvironment(t *testing.T) {
+	t.Setenv("XPOST_TEST_SET", "from env")
+	t.Setenv("XPOST_TEST_UNSET", "")
+	os.Unsetenv("XPOST_TEST_UNSET")
+
+	if err := Apply(context.Background(), staticBackend{"XPOST_TEST_SET": "from vault", "XPOST_TEST_UNSET": "from vault"}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if got := os.Getenv("XPOST_TEST_SET"); got != "from env" {
+		t.Errorf("XPOST_TEST_SET = %q, want the environment to win", got)
+	}
+	if got := os.Getenv("XPOST_TEST_UNSET"); got != "from vault" {
+		t.Errorf("XPOST_TEST_UNSET = %q, want the backend value", got)
+	}
+}
@@ -0,0 +1,186 @@
+// Package state persists what xpost needs to remember between runs.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const fileName = "state.json"
+
+// State is the on-disk xpost state.
+type State struct {
+	// Expiring lists posts to delete once they expire (see --expire).
+	Expiring []Expiring `json:"expiring,omitempty"`
+	// Posted lists recent posts, to skip repeats (see --dedupe-window).
+	Posted []Posted `json:"posted,omitempty"`
+	// Threads lists threads that failed part way (see 'xpost resume').
+	Threads []Thread `json:"threads,omitempty"`
+}
+
+// Expiring is a published post scheduled for deletion.
+type Expiring struct {
+	Provider  string    `json:"provider"`
+	ID        string    `json:"id"`
+	URL       string    `json:"url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Posted is a recently published post, identified by a hash of its content.
+type Posted struct {
+	Provider string    `json:"provider"`
+	Key      string    `json:"key"`
+	URL      string    `json:"url,omitempty"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+// Thread is a thread that stopped after some of its posts were published.
+type Thread struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+	// URL is the thread's first post.
+	URL string `json:"url,omitempty"`
+	// Posted holds the IDs published so far, in order; the last is the
+	// parent of the next part.
+	Posted []string `json:"posted"`
+	// Remaining holds the parts still to post, starting with the one that failed.
+	Remaining []string `json:"remaining"`
+
+	ContentWarning string `json:"content_warning,omitempty"`
+	Visibility     string `json:"visibility,omitempty"`
+	Community      string `json:"community,omitempty"`
+	Language       string `json:"language,omitempty"`
+
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Parent returns the ID the next part of t replies to.
+func (t Thread) Parent() string {
+	if len(t.Posted) == 0 {
+		return ""
+	}
+	return t.Posted[len(t.Posted)-1]
+}
+
+// DefaultPath returns the state file location, honouring XDG_STATE_HOME.
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("locate state dir: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "xpost", fileName), nil
+}
+
+// Load reads the state file at path. A missing file yields an empty state.
+func Load(path string) (*State, error) {
+	st := &State{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return st, nil
+		}
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("parse state %s: %w", path, err)
+	}
+
+	return st, nil
+}
+
+// Save writes st to path, readable only by the current user.
+func Save(path string, st *State) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+
+	// Write to a temp file first so a failed write never truncates the old state.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write state: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+
+	return nil
+}
+
+// Expired splits the scheduled deletions into those due at now and the rest.
+func (s *State) Expired(now time.Time) (due, pending []Expiring) {
+	for _, e := range s.Expiring {
+		if now.Before(e.ExpiresAt) {
+			pending = append(pending, e)
+		} else {
+			due = append(due, e)
+		}
+	}
+	return due, pending
+}
+
+// PrunePosted drops the recent posts made before since.
+func (s *State) PrunePosted(since time.Time) {
+	s.Posted = slices.DeleteFunc(s.Posted, func(p Posted) bool { return p.PostedAt.Before(since) })
+}
+
+// LastPosted returns the latest post recorded for provider with key at or
+// after since.
+func (s *State) LastPosted(provider, key string, since time.Time) (Posted, bool) {
+	var last Posted
+	found := false
+	for _, p := range s.Posted {
+		if p.Provider == provider && p.Key == key && !p.PostedAt.Before(since) && (!found || p.PostedAt.After(last.PostedAt)) {
+			last, found = p, true
+		}
+	}
+	return last, found
+}
+
+// Thread returns the unfinished thread with id.
+func (s *State) Thread(id string) (Thread, bool) {
+	i := slices.IndexFunc(s.Threads, func(t Thread) bool { return t.ID == id })
+	if i < 0 {
+		return Thread{}, false
+	}
+	return s.Threads[i], true
+}
+
+// PutThread records t, replacing any thread with the same ID.
+func (s *State) PutThread(t Thread) {
+	s.DropThread(t.ID)
+	s.Threads = append(s.Threads, t)
+}
+
+// DropThread forgets the thread with id.
+func (s *State) DropThread(id string) {
+	s.Threads = slices.DeleteFunc(s.Threads, func(t Thread) bool { return t.ID == id })
+}
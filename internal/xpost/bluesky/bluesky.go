@@ -9,9 +9,12 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
 	"github.com/blacktop/xpost/internal/xpost"
 	"github.com/bluesky-social/indigo/api/atproto"
 	"github.com/bluesky-social/indigo/api/bsky"
@@ -24,33 +27,142 @@ const (
 	envHandle      = "XPOST_BLUESKY_HANDLE"
 	envAppPassword = "XPOST_BLUESKY_APP_PASSWORD"
 	envPDSURL      = "XPOST_BLUESKY_PDS_URL"
+	envSessionFile = "XPOST_BLUESKY_SESSION_FILE"
 
-	providerName   = "bluesky"
-	requestTimeout = 30 * time.Second
-	maxGraphemes   = 300 // Bluesky's post character limit in graphemes
+	providerName    = "bluesky"
+	maxGraphemes    = 300  // Bluesky's post character limit in graphemes
+	maxAltGraphemes = 2000 // Bluesky's image description limit in graphemes
+	maxTextBytes    = 3000 // Bluesky's post text limit in UTF-8 bytes
+	maxImages       = 4    // Bluesky's images per post
+
+	maxImageBytes = 1_000_000 // Bluesky's image blob size limit
+
+	publicAppViewURL = "https://public.api.bsky.app"
+	// fallbackPDSURL is used when the handle's PDS cannot be resolved from
+	// its DID document.
+	fallbackPDSURL = "https://bsky.social"
 )
 
+// imageTypes are the MIME types accepted as an explicit media type override.
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
 // urlRegex matches URLs in text for creating link facets
 var urlRegex = regexp.MustCompile(`https?://[^\s]+`)
 
-// Config allows the caller to supply defaults prior to reading environment variables.
+// mentionRegex matches @handle.domain mentions at the start of the text or
+// after whitespace or an opening parenthesis; group 1 is the mention.
+var mentionRegex = regexp.MustCompile(`(?:^|[\s(])(@[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)+)`)
+
+// Config holds the credentials used to create a session.
 type Config struct {
+	Handle      string
+	AppPassword string
+	// PDSURL is the account's PDS; empty means the one in the handle's DID
+	// document.
 	PDSURL string
+	// SessionFile caches the session between runs; empty means the default
+	// location.
+	SessionFile string
+
+	// Timeout bounds each request; zero means xpost.DefaultHTTPTimeout.
+	Timeout time.Duration
 }
 
 // Client implements the xpost.Poster interface for Bluesky.
 type Client struct {
 	client *xrpc.Client
+	// sessionFile caches the session between runs; empty when the session
+	// is not cached.
+	sessionFile string
+	// appViewURL is the AppView ReadBack looks posts up in; empty means
+	// the public one.
+	appViewURL string
 }
 
-// New constructs a Bluesky poster.
-func New(ctx context.Context, base Config) (xpost.Poster, error) {
-	cfg, err := loadConfig(base)
+func init() {
+	xpost.Register(providerName, New, xpost.Info{
+		Label:    "Bluesky",
+		Icon:     "\ue28e", // butterfly as a playful Bluesky glyph
+		Color:    45,
+		Features: xpost.Features{LinkPreview: true},
+	})
+	xpost.RegisterValidator(providerName, NewValidator)
+	config.RegisterSection(providerName, config.Section{
+		Options: func(c *config.Config) *config.Options { return &c.Bluesky.Options },
+		Vars: func(c *config.Config) map[string]*string {
+			return map[string]*string{
+				envHandle:      &c.Bluesky.Handle,
+				envAppPassword: &c.Bluesky.AppPassword,
+				envPDSURL:      &c.Bluesky.PDSURL,
+				envSessionFile: &c.Bluesky.SessionFile,
+			}
+		},
+		Account: func(c, acct *config.Config, name string) bool {
+			a, ok := c.Bluesky.Accounts[name]
+			acct.Bluesky = a
+			return ok
+		},
+	})
+}
+
+// New constructs a Bluesky poster from its section of the resolved config. It
+// reuses the session cached by earlier runs, refreshing it if needed, and only
+// logs in when neither works.
+func New(ctx context.Context, settings *config.Config) (xpost.Poster, error) {
+	cfg, err := loadConfig(settings.Bluesky)
 	if err != nil {
 		return nil, err
 	}
+	cfg.Timeout = settings.HTTPTimeout
+
+	sessionFile, err := sessionPath(cfg.SessionFile)
+	if err != nil {
+		logutil.Debugf("bluesky: not caching the session: %v", err)
+	}
+	client, err := newClient(ctx, cfg, sessionFile)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewWithConfig constructs a Bluesky client from explicit credentials. It
+// always logs in, so the credentials are checked.
+func NewWithConfig(ctx context.Context, cfg Config) (*Client, error) {
+	return newClient(ctx, cfg, "")
+}
+
+func newClient(ctx context.Context, cfg Config, sessionFile string) (*Client, error) {
+	httpClient := &http.Client{Timeout: xpost.HTTPTimeout(cfg.Timeout)}
+
+	var cached *cachedSession
+	if sessionFile != "" {
+		var err error
+		if cached, err = loadSession(sessionFile); err != nil {
+			logutil.Debugf("bluesky: ignoring cached session: %v", err)
+		}
+	}
+	if cached != nil && (cached.Identifier != cfg.Handle || (cfg.PDSURL != "" && cfg.PDSURL != cached.PDSURL)) {
+		cached = nil
+	}
+	if cached != nil && cfg.PDSURL == "" {
+		cfg.PDSURL = cached.PDSURL
+	}
+
+	// Handles on custom domains are often hosted on a PDS other than the
+	// default, so prefer the endpoint advertised in the account's DID document
+	// unless one was set explicitly.
+	if cfg.PDSURL == "" {
+		pdsURL, err := resolvePDS(ctx, httpClient, cfg.Handle)
+		if err != nil {
+			logutil.Debugf("bluesky: PDS resolution failed, using %s: %v", fallbackPDSURL, err)
+			pdsURL = fallbackPDSURL
+		} else {
+			logutil.Debugf("bluesky: resolved PDS for %s: %s", cfg.Handle, pdsURL)
+		}
+		cfg.PDSURL = pdsURL
+	}
 
-	httpClient := &http.Client{Timeout: requestTimeout}
 	userAgent := "xpost/1"
 	xrpcClient := &xrpc.Client{
 		Client:    httpClient,
@@ -58,45 +170,139 @@ func New(ctx context.Context, base Config) (xpost.Poster, error) {
 		UserAgent: &userAgent,
 	}
 
-	session, err := atproto.ServerCreateSession(ctx, xrpcClient, &atproto.ServerCreateSession_Input{
-		Identifier: cfg.Handle,
-		Password:   cfg.AppPassword,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("login: %w", err)
+	if cached == nil || !resumeSession(ctx, xrpcClient, cached) {
+		session, err := atproto.ServerCreateSession(ctx, xrpcClient, &atproto.ServerCreateSession_Input{
+			Identifier: cfg.Handle,
+			Password:   cfg.AppPassword,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("login: %w", unwrapXRPCError(err))
+		}
+
+		xrpcClient.Auth = &xrpc.AuthInfo{
+			AccessJwt:  session.AccessJwt,
+			RefreshJwt: session.RefreshJwt,
+			Handle:     session.Handle,
+			Did:        session.Did,
+		}
 	}
 
-	xrpcClient.Auth = &xrpc.AuthInfo{
-		AccessJwt:  session.AccessJwt,
-		RefreshJwt: session.RefreshJwt,
-		Handle:     session.Handle,
-		Did:        session.Did,
+	c := &Client{client: xrpcClient, sessionFile: sessionFile}
+	if sessionFile != "" {
+		if err := saveClientSession(sessionFile, cfg.Handle, xrpcClient); err != nil {
+			logutil.Debugf("bluesky: cache session: %v", err)
+		}
 	}
+	return c, nil
+}
 
-	return &Client{client: xrpcClient}, nil
+// NewValidator returns a Bluesky validator that needs no credentials.
+func NewValidator() xpost.Validator {
+	return &Client{}
 }
 
 // Name identifies the provider.
 func (c *Client) Name() string { return providerName }
 
+// Verify checks the session without posting and returns the account handle.
+func (c *Client) Verify(ctx context.Context) (string, error) {
+	session, err := atproto.ServerGetSession(ctx, c.client)
+	if err != nil {
+		return "", fmt.Errorf("get session: %w", unwrapXRPCError(err))
+	}
+	return "@" + session.Handle, nil
+}
+
+// Truncate shortens the message so the post, including any link, fits within
+// the grapheme limit.
+func (c *Client) Truncate(req xpost.Request) (xpost.Request, string) {
+	limit := maxGraphemes
+	if req.Link != "" {
+		limit -= uniseg.GraphemeClusterCount("\n\n" + req.Link)
+	}
+	var removed string
+	req.Message, removed = xpost.TruncateText(req.Message, limit, uniseg.GraphemeClusterCount)
+	return req, removed
+}
+
+// TruncateAlt shortens the image description to Bluesky's limit.
+func (c *Client) TruncateAlt(alt string) (string, string) {
+	return xpost.TruncateText(alt, maxAltGraphemes, uniseg.GraphemeClusterCount)
+}
+
+// Capabilities returns Bluesky's limits, which are fixed by the app.bsky
+// lexicon.
+func (c *Client) Capabilities(ctx context.Context) (xpost.Capabilities, error) {
+	return xpost.Capabilities{
+		MaxChars:      maxGraphemes,
+		Unit:          "graphemes",
+		MaxImages:     maxImages,
+		MaxImageBytes: maxImageBytes,
+		MediaTypes:    imageTypes,
+		MaxAltChars:   maxAltGraphemes,
+		Features:      []string{"link, mention and hashtag facets"},
+	}, nil
+}
+
+// CanQuote reports whether url is a Bluesky post that can be embedded.
+func (c *Client) CanQuote(url string) bool {
+	_, err := parsePostRef(url)
+	return err == nil
+}
+
+// Count measures the post and link in graphemes, as Bluesky does.
+func (c *Client) Count(req xpost.Request) (int, int, string) {
+	text := req.Message
+	if req.Link != "" {
+		text = text + "\n\n" + req.Link
+	}
+	return uniseg.GraphemeClusterCount(text), maxGraphemes, "graphemes"
+}
+
 // Validate checks if the request meets Bluesky's constraints.
 func (c *Client) Validate(req xpost.Request) error {
+	if len(req.Images) > maxImages {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("too many images: %d (max %d)", len(req.Images), maxImages)}
+	}
+	for _, img := range req.Images {
+		// Posts only embed images (app.bsky.embed.images); there is no video
+		// embed for a video or its --alt-text to go in, so videos are refused
+		// here rather than uploaded as a broken image.
+		if img.MediaType == "" && img.IsVideo() {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("video %s is not supported", img.Path)}
+		}
+		if img.MediaType != "" && !slices.Contains(imageTypes, img.MediaType) {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type %q", img.MediaType)}
+		}
+		if count := uniseg.GraphemeClusterCount(img.Alt); count > maxAltGraphemes {
+			return xpost.ValidationError{
+				Provider: providerName,
+				Reason:   fmt.Sprintf("image description too long: %d graphemes (max %d)", count, maxAltGraphemes),
+			}
+		}
+	}
+	if count, _, _ := c.Count(req); count > maxGraphemes {
+		return xpost.ValidationError{
+			Provider: providerName,
+			Reason:   fmt.Sprintf("message too long: %d graphemes (max %d)", count, maxGraphemes),
+		}
+	}
+	// Long emoji sequences can fit the grapheme limit yet exceed the byte one.
 	text := req.Message
 	if req.Link != "" {
 		text = text + "\n\n" + req.Link
 	}
-	count := uniseg.GraphemeClusterCount(text)
-	if count > maxGraphemes {
+	if size := len(text); size > maxTextBytes {
 		return xpost.ValidationError{
 			Provider: providerName,
-			Reason:   fmt.Sprintf("message too long: %d graphemes (max %d)", count, maxGraphemes),
+			Reason:   fmt.Sprintf("message too long: %d bytes (max %d)", size, maxTextBytes),
 		}
 	}
 	return nil
 }
 
 // Post creates a new Bluesky post with an optional image embed.
-func (c *Client) Post(ctx context.Context, req xpost.Request) error {
+func (c *Client) Post(ctx context.Context, req xpost.Request) (xpost.PostResult, error) {
 	// Build the text, appending link if provided
 	text := req.Message
 	if req.Link != "" {
@@ -106,41 +312,200 @@ func (c *Client) Post(ctx context.Context, req xpost.Request) error {
 	post := &bsky.FeedPost{
 		CreatedAt: time.Now().UTC().Format(time.RFC3339),
 		Text:      text,
-		Facets:    extractLinkFacets(text),
+		Facets:    append(extractLinkFacets(text), extractTagFacets(text)...),
 	}
+	post.Facets = append(post.Facets, c.mentionFacets(ctx, text)...)
 
-	if req.ImagePath != "" {
-		blob, err := c.uploadImage(ctx, req.ImagePath)
+	if req.ReplyTo != "" {
+		ref, err := c.replyRef(ctx, req.ReplyTo)
 		if err != nil {
-			return err
-		}
-		post.Embed = &bsky.FeedPost_Embed{
-			EmbedImages: &bsky.EmbedImages{
-				Images: []*bsky.EmbedImages_Image{
-					{
-						Alt:   req.ImageAlt,
-						Image: blob,
-					},
-				},
-			},
+			return xpost.PostResult{}, fmt.Errorf("resolve reply target: %w", err)
+		}
+		post.Reply = ref
+	}
+
+	if len(req.Images) > 0 {
+		images := make([]*bsky.EmbedImages_Image, 0, len(req.Images))
+		for _, img := range req.Images {
+			blob, err := c.uploadImage(ctx, img.Path, img.MediaType)
+			if err != nil {
+				return xpost.PostResult{}, err
+			}
+			images = append(images, &bsky.EmbedImages_Image{Alt: img.Alt, Image: blob})
+		}
+		post.Embed = &bsky.FeedPost_Embed{EmbedImages: &bsky.EmbedImages{Images: images}}
+	} else if req.LinkPreview {
+		// A card only shows without images, for the link or else the first URL.
+		link := req.Link
+		if link == "" {
+			link = urlRegex.FindString(req.Message)
 		}
+		if link != "" {
+			post.Embed = &bsky.FeedPost_Embed{EmbedExternal: c.linkCard(ctx, link)}
+		}
+	}
+
+	if req.Quote != "" {
+		quoted, _, err := c.resolvePost(ctx, req.Quote)
+		if err != nil {
+			return xpost.PostResult{}, fmt.Errorf("resolve quoted post: %w", err)
+		}
+		post.Embed = quoteEmbed(quoted, post.Embed)
+	}
+
+	created, err := c.createPost(ctx, post)
+	if err != nil {
+		return xpost.PostResult{}, err
+	}
+	result := xpost.PostResult{Provider: providerName, ID: created.Uri, URL: c.postURL(created.Uri)}
+
+	if reply := req.AltReply(); reply != "" && post.Embed != nil {
+		// Long descriptions continue across a chain of replies.
+		parent, parentPost := &atproto.RepoStrongRef{Uri: created.Uri, Cid: created.Cid}, post
+		for _, part := range xpost.SplitText(reply, maxGraphemes, uniseg.GraphemeClusterCount) {
+			replyPost := &bsky.FeedPost{
+				CreatedAt: time.Now().UTC().Format(time.RFC3339),
+				Text:      part,
+				Reply:     threadReply(parent, parentPost),
+			}
+			posted, err := c.createPost(ctx, replyPost)
+			if err != nil {
+				return result, xpost.Partial(fmt.Errorf("post image description reply: %w", err))
+			}
+			parent, parentPost = &atproto.RepoStrongRef{Uri: posted.Uri, Cid: posted.Cid}, replyPost
+		}
+	}
+
+	return result, nil
+}
+
+// Close ends the session so its tokens stop being valid, unless it is cached
+// for the next run.
+func (c *Client) Close(ctx context.Context) error {
+	if c.client == nil || c.client.Auth == nil || c.sessionFile != "" {
+		return nil
+	}
+	// deleteSession authenticates with the refresh token rather than the
+	// access token.
+	refresh := *c.client
+	refresh.Auth = &xrpc.AuthInfo{AccessJwt: c.client.Auth.RefreshJwt}
+	if err := atproto.ServerDeleteSession(ctx, &refresh); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	c.client.Auth = nil
+	return nil
+}
+
+// ReadBack looks the post up through the public AppView, which omits posts
+// that were taken down, and reports any moderation labels on it.
+func (c *Client) ReadBack(ctx context.Context, res xpost.PostResult) (xpost.ReadBack, error) {
+	host := c.appViewURL
+	if host == "" {
+		host = publicAppViewURL
+	}
+	appView := &xrpc.Client{Client: c.client.Client, Host: host}
+	out, err := bsky.FeedGetPosts(ctx, appView, []string{res.ID})
+	if err != nil {
+		return xpost.ReadBack{}, fmt.Errorf("read back post: %w", err)
+	}
+	if len(out.Posts) == 0 {
+		return xpost.ReadBack{Detail: "post is missing from the public AppView"}, nil
+	}
+
+	var labels []string
+	for _, label := range out.Posts[0].Labels {
+		labels = append(labels, label.Val)
+	}
+	if len(labels) > 0 {
+		return xpost.ReadBack{Visible: true, Detail: "labelled " + strings.Join(labels, ", ")}, nil
+	}
+	return xpost.ReadBack{Visible: true}, nil
+}
+
+// Repost creates an app.bsky.feed.repost record for the post at ref.
+func (c *Client) Repost(ctx context.Context, ref string) error {
+	subject, _, err := c.resolvePost(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("resolve post: %w", err)
+	}
+	if _, err := atproto.RepoCreateRecord(ctx, c.client, &atproto.RepoCreateRecord_Input{
+		Collection: repostCollection,
+		Repo:       c.client.Auth.Did,
+		Record: &util.LexiconTypeDecoder{Val: &bsky.FeedRepost{
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+			Subject:   subject,
+		}},
+	}); err != nil {
+		return fmt.Errorf("create repost: %w", unwrapXRPCError(err))
+	}
+	return nil
+}
+
+// React likes the post with the given bsky.app URL or at:// URI. Bluesky has
+// no emoji reactions, so emoji is ignored.
+func (c *Client) React(ctx context.Context, ref, emoji string) error {
+	subject, _, err := c.resolvePost(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("resolve post: %w", err)
 	}
+	if _, err := atproto.RepoCreateRecord(ctx, c.client, &atproto.RepoCreateRecord_Input{
+		Collection: likeCollection,
+		Repo:       c.client.Auth.Did,
+		Record: &util.LexiconTypeDecoder{Val: &bsky.FeedLike{
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+			Subject:   subject,
+		}},
+	}); err != nil {
+		return fmt.Errorf("create like: %w", unwrapXRPCError(err))
+	}
+	return nil
+}
 
-	_, err := atproto.RepoCreateRecord(ctx, c.client, &atproto.RepoCreateRecord_Input{
-		Collection: "app.bsky.feed.post",
+// Delete removes the post with the given at:// URI.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	ref, err := parsePostRef(id)
+	if err != nil {
+		return err
+	}
+	if _, err := atproto.RepoDeleteRecord(ctx, c.client, &atproto.RepoDeleteRecord_Input{
+		Collection: postCollection,
+		Repo:       ref.repo,
+		Rkey:       ref.rkey,
+	}); err != nil {
+		return fmt.Errorf("delete record: %w", unwrapXRPCError(err))
+	}
+	return nil
+}
+
+// postURL converts an at:// record URI into its bsky.app permalink.
+func (c *Client) postURL(uri string) string {
+	// at://<did>/app.bsky.feed.post/<rkey>
+	parts := strings.Split(strings.TrimPrefix(uri, "at://"), "/")
+	if len(parts) != 3 {
+		return ""
+	}
+	actor := parts[0]
+	if c.client.Auth != nil && c.client.Auth.Did == actor && c.client.Auth.Handle != "" {
+		actor = c.client.Auth.Handle
+	}
+	return "https://bsky.app/profile/" + actor + "/post/" + parts[2]
+}
+
+func (c *Client) createPost(ctx context.Context, post *bsky.FeedPost) (*atproto.RepoCreateRecord_Output, error) {
+	out, err := atproto.RepoCreateRecord(ctx, c.client, &atproto.RepoCreateRecord_Input{
+		Collection: postCollection,
 		Repo:       c.client.Auth.Did,
 		Record: &util.LexiconTypeDecoder{
 			Val: post,
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("create record: %w", err)
+		return nil, fmt.Errorf("create record: %w", unwrapXRPCError(err))
 	}
-
-	return nil
+	return out, nil
 }
 
-func (c *Client) uploadImage(ctx context.Context, path string) (*util.LexBlob, error) {
+func (c *Client) uploadImage(ctx context.Context, path, mediaType string) (*util.LexBlob, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -154,10 +519,19 @@ func (c *Client) uploadImage(ctx context.Context, path string) (*util.LexBlob, e
 	if _, err := io.Copy(buf, file); err != nil {
 		return nil, fmt.Errorf("read image: %w", err)
 	}
+	return c.uploadBlob(ctx, buf.Bytes(), mediaType)
+}
 
-	resp, err := atproto.RepoUploadBlob(ctx, c.client, bytes.NewReader(buf.Bytes()))
-	if err != nil {
-		return nil, fmt.Errorf("upload blob: %w", err)
+// uploadBlob uploads data to the PDS and returns the blob reference.
+func (c *Client) uploadBlob(ctx context.Context, data []byte, mediaType string) (*util.LexBlob, error) {
+	// The PDS sniffs the blob when no type is declared; an override is sent as
+	// the request's Content-Type instead.
+	if mediaType == "" {
+		mediaType = "*/*"
+	}
+	var resp atproto.RepoUploadBlob_Output
+	if err := c.client.LexDo(ctx, util.Procedure, mediaType, "com.atproto.repo.uploadBlob", nil, bytes.NewReader(data), &resp); err != nil {
+		return nil, fmt.Errorf("upload blob: %w", unwrapXRPCError(err))
 	}
 
 	if resp.Blob == nil {
@@ -167,25 +541,14 @@ func (c *Client) uploadImage(ctx context.Context, path string) (*util.LexBlob, e
 	return resp.Blob, nil
 }
 
-// ProviderConfig merges defaults with environment-defined values.
-type ProviderConfig struct {
-	Handle      string
-	AppPassword string
-	PDSURL      string
-}
-
-func loadConfig(base Config) (ProviderConfig, error) {
-	cfg := ProviderConfig{
-		Handle:      strings.TrimSpace(os.Getenv(envHandle)),
-		AppPassword: strings.TrimSpace(os.Getenv(envAppPassword)),
-		PDSURL:      strings.TrimSpace(os.Getenv(envPDSURL)),
-	}
-
-	if cfg.PDSURL == "" {
-		cfg.PDSURL = strings.TrimSpace(base.PDSURL)
-	}
-	if cfg.PDSURL == "" {
-		cfg.PDSURL = "https://bsky.social"
+// loadConfig checks that the Bluesky section has the credentials needed to
+// post.
+func loadConfig(section config.Bluesky) (Config, error) {
+	cfg := Config{
+		Handle:      strings.TrimSpace(section.Handle),
+		AppPassword: strings.TrimSpace(section.AppPassword),
+		PDSURL:      strings.TrimSpace(section.PDSURL),
+		SessionFile: strings.TrimSpace(section.SessionFile),
 	}
 
 	var missing []string
@@ -195,12 +558,9 @@ func loadConfig(base Config) (ProviderConfig, error) {
 	if cfg.AppPassword == "" {
 		missing = append(missing, envAppPassword)
 	}
-	if cfg.PDSURL == "" {
-		missing = append(missing, envPDSURL)
-	}
 
 	if len(missing) > 0 {
-		return ProviderConfig{}, xpost.MissingEnvError{Provider: providerName, Variables: missing}
+		return Config{}, xpost.MissingEnvError{Provider: providerName, Variables: missing}
 	}
 
 	return cfg, nil
@@ -237,3 +597,66 @@ func extractLinkFacets(text string) []*bsky.RichtextFacet {
 
 	return facets
 }
+
+// mentionFacets creates mention facets for the @handle.domain mentions in
+// text, resolving each handle to its DID. Handles that do not resolve are
+// left as plain text.
+func (c *Client) mentionFacets(ctx context.Context, text string) []*bsky.RichtextFacet {
+	var facets []*bsky.RichtextFacet
+	for _, match := range mentionRegex.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[2], match[3]
+		handle := text[start+1 : end]
+
+		resolved, err := atproto.IdentityResolveHandle(ctx, c.client, handle)
+		if err != nil {
+			logutil.Debugf("bluesky: not linking @%s: %v", handle, err)
+			continue
+		}
+
+		facets = append(facets, &bsky.RichtextFacet{
+			Index: &bsky.RichtextFacet_ByteSlice{
+				ByteStart: int64(start),
+				ByteEnd:   int64(end),
+			},
+			Features: []*bsky.RichtextFacet_Features_Elem{
+				{
+					RichtextFacet_Mention: &bsky.RichtextFacet_Mention{
+						LexiconTypeID: "app.bsky.richtext.facet#mention",
+						Did:           resolved.Did,
+					},
+				},
+			},
+		})
+	}
+
+	return facets
+}
+
+// extractTagFacets creates tag facets for the #hashtags in text so they link
+// to the tag's feed in the Bluesky UI.
+func extractTagFacets(text string) []*bsky.RichtextFacet {
+	spans := xpost.HashtagSpans(text)
+	if len(spans) == 0 {
+		return nil
+	}
+
+	facets := make([]*bsky.RichtextFacet, 0, len(spans))
+	for _, span := range spans {
+		facets = append(facets, &bsky.RichtextFacet{
+			Index: &bsky.RichtextFacet_ByteSlice{
+				ByteStart: int64(span[0]),
+				ByteEnd:   int64(span[1]),
+			},
+			Features: []*bsky.RichtextFacet_Features_Elem{
+				{
+					RichtextFacet_Tag: &bsky.RichtextFacet_Tag{
+						LexiconTypeID: "app.bsky.richtext.facet#tag",
+						Tag:           text[span[0]+1 : span[1]],
+					},
+				},
+			},
+		})
+	}
+
+	return facets
+}
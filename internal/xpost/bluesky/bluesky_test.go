@@ -0,0 +1,356 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+const (
+	// testBlobCID and testPostCID are valid CIDs for the fake PDS to return.
+	testBlobCID = "bafkreic7eypwgulp7ywiuulx6sfmwynu57r5ez7ftjedh5gthplio26tqe"
+	testPostCID = "bafyreidsemiehpaya7tpoqfsgxvxkepmwmzfljvdovbvmmiznxuks5injm"
+	testDID     = "did:plc:me"
+)
+
+// fakePDS is a minimal PDS that records the blobs uploaded and the records
+// created.
+type fakePDS struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	blobTypes   []string         // Content-Type of each blob upload
+	records     []map[string]any // each created record, decoded from JSON
+	collections []string         // the collection of each created record
+	// failReplies is the status code returned for records that reply to a
+	// post; zero means they are created.
+	failReplies int
+}
+
+func newFakePDS(t *testing.T) *fakePDS {
+	t.Helper()
+	f := &fakePDS{}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakePDS) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/xrpc/com.atproto.repo.uploadBlob":
+		data, _ := io.ReadAll(r.Body)
+		f.blobTypes = append(f.blobTypes, r.Header.Get("Content-Type"))
+		fmt.Fprintf(w, `{"blob":{"$type":"blob","ref":{"$link":%q},"mimeType":"image/png","size":%d}}`, testBlobCID, len(data))
+	case "/xrpc/com.atproto.repo.createRecord":
+		var in struct {
+			Collection string         `json:"collection"`
+			Record     map[string]any `json:"record"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := in.Record["reply"]; ok && f.failReplies != 0 {
+			w.WriteHeader(f.failReplies)
+			fmt.Fprint(w, `{"error":"InternalServerError","message":"simulated"}`)
+			return
+		}
+		f.records = append(f.records, in.Record)
+		f.collections = append(f.collections, in.Collection)
+		fmt.Fprintf(w, `{"uri":"at://%s/%s/rec%d","cid":%q}`, testDID, in.Collection, len(f.records), testPostCID)
+	case "/xrpc/com.atproto.repo.getRecord":
+		q := r.URL.Query()
+		fmt.Fprintf(w, `{"uri":"at://%s/%s/%s","cid":%q,"value":{"$type":"app.bsky.feed.post","text":"quoted","createdAt":"2025-01-01T00:00:00Z"}}`,
+			q.Get("repo"), q.Get("collection"), q.Get("rkey"), testPostCID)
+	case "/xrpc/com.atproto.identity.resolveHandle":
+		if handle := r.URL.Query().Get("handle"); strings.HasSuffix(handle, ".invalid") {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"InvalidRequest","message":"Unable to resolve handle"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"did":"did:plc:%s"}`, strings.ReplaceAll(r.URL.Query().Get("handle"), ".", "-"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"MethodNotImplemented","message":"not faked"}`)
+	}
+}
+
+// client returns a logged-in client for the fake PDS.
+func (f *fakePDS) client() *Client {
+	return &Client{client: &xrpc.Client{
+		Client: f.Client(),
+		Host:   f.URL,
+		Auth:   &xrpc.AuthInfo{AccessJwt: "access", RefreshJwt: "refresh", Handle: "me.test", Did: testDID},
+	}}
+}
+
+// writeImage creates a small file named name to upload.
+func writeImage(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n"+name), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUploadMediaTypeOverride(t *testing.T) {
+	srv := newFakePDS(t)
+	c := srv.client()
+
+	_, err := c.Post(context.Background(), xpost.Request{
+		Message: "two images",
+		Images: []xpost.ImageAttachment{
+			{Path: writeImage(t, "detected.bin"), Alt: "a"},
+			{Path: writeImage(t, "forced.bin"), Alt: "b", MediaType: "image/webp"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	// Without an override the PDS sniffs the blob itself.
+	want := []string{"*/*", "image/webp"}
+	if len(srv.blobTypes) != len(want) {
+		t.Fatalf("uploaded %d blobs, want %d", len(srv.blobTypes), len(want))
+	}
+	for i := range want {
+		if srv.blobTypes[i] != want[i] {
+			t.Errorf("blob %d Content-Type = %q, want %q", i, srv.blobTypes[i], want[i])
+		}
+	}
+}
+
+func TestValidateMediaType(t *testing.T) {
+	c := NewValidator()
+	tests := []struct {
+		name    string
+		img     xpost.ImageAttachment
+		wantErr string
+	}{
+		{name: "supported override", img: xpost.ImageAttachment{Path: "a.bin", MediaType: "image/png"}},
+		{name: "unsupported override", img: xpost.ImageAttachment{Path: "a.png", MediaType: "image/tiff"}, wantErr: `unsupported media type "image/tiff"`},
+		{name: "video override", img: xpost.ImageAttachment{Path: "a.png", MediaType: "video/mp4"}, wantErr: `unsupported media type "video/mp4"`},
+		// Videos are refused, with or without a description.
+		{name: "video", img: xpost.ImageAttachment{Path: "clip.mp4", Alt: "A cat"}, wantErr: "video clip.mp4 is not supported"},
+		// An override vouches for the file whatever its extension says.
+		{name: "override beats extension", img: xpost.ImageAttachment{Path: "a.mp4", MediaType: "image/jpeg"}},
+	}
+	for _, tt := range tests {
+		err := c.Validate(xpost.Request{Message: "hi", Images: []xpost.ImageAttachment{tt.img}})
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPostKeepsMediaOrder(t *testing.T) {
+	srv := newFakePDS(t)
+	alts := []string{"a", "b", "c"}
+	var images []xpost.ImageAttachment
+	for _, alt := range alts {
+		images = append(images, xpost.ImageAttachment{Path: writeImage(t, alt+".png"), Alt: alt})
+	}
+	if _, err := srv.client().Post(context.Background(), xpost.Request{Message: "before and after", Images: images}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	embed, _ := srv.records[0]["embed"].(map[string]any)
+	got, _ := embed["images"].([]any)
+	if len(got) != len(alts) {
+		t.Fatalf("embed = %v, want %d images", embed, len(alts))
+	}
+	for i, img := range got {
+		if alt := img.(map[string]any)["alt"]; alt != alts[i] {
+			t.Errorf("image %d alt = %v, want %s", i, alt, alts[i])
+		}
+	}
+}
+
+func TestPostAltReplyFails(t *testing.T) {
+	srv := newFakePDS(t)
+	srv.failReplies = http.StatusInternalServerError
+	res, err := srv.client().Post(context.Background(), xpost.Request{
+		Message:    "Sunset",
+		Images:     []xpost.ImageAttachment{{Path: writeImage(t, "a.png"), Alt: "The sun setting over the sea"}},
+		AltAsReply: true,
+	})
+	// The post exists, so it is returned and must not be retried.
+	if !errors.As(err, new(xpost.PartialError)) || errors.As(err, new(xpost.RetryableError)) {
+		t.Errorf("Post = %v, want a partial failure that is not retryable", err)
+	}
+	if want := "at://" + testDID + "/app.bsky.feed.post/rec1"; res.ID != want || res.URL == "" {
+		t.Errorf("Post = %+v, want the post's result with ID %s", res, want)
+	}
+}
+
+func TestReadBack(t *testing.T) {
+	const post = `{"uri":"at://did:plc:me/app.bsky.feed.post/rec1","cid":%q,"author":{"did":"did:plc:me","handle":"me.test"},` +
+		`"record":{"$type":"app.bsky.feed.post","text":"hi","createdAt":"2025-01-01T00:00:00Z"},"indexedAt":"2025-01-01T00:00:00Z","labels":[%s]}`
+	const label = `{"src":"did:plc:mod","uri":"at://did:plc:me/app.bsky.feed.post/rec1","val":"spam","cts":"2025-01-01T00:00:00Z"}`
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    xpost.ReadBack
+		wantErr bool
+	}{
+		{name: "present", body: `{"posts":[` + fmt.Sprintf(post, testPostCID, "") + `]}`, want: xpost.ReadBack{Visible: true}},
+		{name: "labelled", body: `{"posts":[` + fmt.Sprintf(post, testPostCID, label) + `]}`, want: xpost.ReadBack{Visible: true, Detail: "labelled spam"}},
+		{name: "absent", body: `{"posts":[]}`, want: xpost.ReadBack{Detail: "post is missing from the public AppView"}},
+		{name: "lookup fails", status: http.StatusBadGateway, body: `{"error":"UpstreamFailure"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var gotURI string
+		appView := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotURI = r.URL.Query().Get("uris")
+			w.Header().Set("Content-Type", "application/json")
+			if tt.status != 0 {
+				w.WriteHeader(tt.status)
+			}
+			fmt.Fprint(w, tt.body)
+		}))
+		c := newFakePDS(t).client()
+		c.appViewURL = appView.URL
+
+		got, err := c.ReadBack(context.Background(), xpost.PostResult{ID: "at://did:plc:me/app.bsky.feed.post/rec1"})
+		appView.Close()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: ReadBack = %+v, %v; want %+v", tt.name, got, err, tt.want)
+		}
+		if gotURI != "at://did:plc:me/app.bsky.feed.post/rec1" {
+			t.Errorf("%s: looked up %q", tt.name, gotURI)
+		}
+	}
+}
+
+func TestPostFacets(t *testing.T) {
+	type facet struct {
+		start, end int
+		feature    string // the link URI, mention DID or tag
+	}
+	tests := []struct {
+		name string
+		req  xpost.Request
+		want []facet
+	}{
+		{
+			name: "ascii",
+			req:  xpost.Request{Message: "See https://example.com"},
+			want: []facet{{4, 23, "https://example.com"}},
+		},
+		{
+			name: "multibyte before a link",
+			req:  xpost.Request{Message: "日本語 https://example.com/a"},
+			want: []facet{{10, 31, "https://example.com/a"}},
+		},
+		{
+			name: "emoji before a link",
+			req:  xpost.Request{Message: "🚀 https://example.com"},
+			want: []facet{{5, 24, "https://example.com"}},
+		},
+		{
+			name: "appended link",
+			req:  xpost.Request{Message: "é", Link: "https://example.com"},
+			want: []facet{{4, 23, "https://example.com"}},
+		},
+		{
+			name: "link, tag and mention",
+			req:  xpost.Request{Message: "café #golang @alice.test https://go.dev"},
+			want: []facet{{26, 40, "https://go.dev"}, {6, 13, "golang"}, {14, 25, "did:plc:alice-test"}},
+		},
+		{
+			name: "unresolved mention",
+			req:  xpost.Request{Message: "hi @ghost.invalid"},
+		},
+		{
+			name: "email is not a mention",
+			req:  xpost.Request{Message: "mail me@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		srv := newFakePDS(t)
+		if _, err := srv.client().Post(context.Background(), tt.req); err != nil {
+			t.Errorf("%s: Post: %v", tt.name, err)
+			continue
+		}
+
+		text, _ := srv.records[0]["text"].(string)
+		raw, _ := srv.records[0]["facets"].([]any)
+		var got []facet
+		for _, r := range raw {
+			f, _ := r.(map[string]any)
+			index, _ := f["index"].(map[string]any)
+			features, _ := f["features"].([]any)
+			feature, _ := features[0].(map[string]any)
+			var value string
+			for _, key := range []string{"uri", "did", "tag"} {
+				if v, ok := feature[key].(string); ok {
+					value = v
+				}
+			}
+			start, end := int(index["byteStart"].(float64)), int(index["byteEnd"].(float64))
+			got = append(got, facet{start, end, value})
+
+			// The byte range must cover exactly the link, #tag or @mention.
+			if span := text[start:end]; span != value && span != "#"+value && !strings.HasPrefix(span, "@") {
+				t.Errorf("%s: text[%d:%d] = %q, want %q", tt.name, start, end, span, value)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: facets = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPostLink(t *testing.T) {
+	tests := []struct {
+		name string
+		req  xpost.Request
+		want string
+	}{
+		{name: "no link", req: xpost.Request{Message: "Hello"}, want: "Hello"},
+		{name: "link", req: xpost.Request{Message: "Hello", Link: "https://example.com"}, want: "Hello\n\nhttps://example.com"},
+	}
+	for _, tt := range tests {
+		srv := newFakePDS(t)
+		if _, err := srv.client().Post(context.Background(), tt.req); err != nil {
+			t.Errorf("%s: Post: %v", tt.name, err)
+			continue
+		}
+		if text := srv.records[0]["text"]; text != tt.want {
+			t.Errorf("%s: text = %q, want %q", tt.name, text, tt.want)
+		}
+		// The appended link is made clickable, but no card is fetched unless
+		// asked for.
+		if _, ok := srv.records[0]["embed"]; ok {
+			t.Errorf("%s: embed = %v, want none", tt.name, srv.records[0]["embed"])
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package bluesky
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const maxThumbBytes = 1_000_000 // Bluesky's link card thumbnail limit
+
+var (
+	metaTagRegex  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	metaAttrRegex = regexp.MustCompile(`(?is)([a-z:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// openGraph is the subset of a page's OpenGraph tags used for a link card.
+type openGraph struct {
+	Title       string
+	Description string
+	Image       string
+}
+
+// parseOpenGraph reads the og:title, og:description and og:image meta tags
+// from page. Missing tags are left empty.
+func parseOpenGraph(page []byte) openGraph {
+	var og openGraph
+	for _, tag := range metaTagRegex.FindAll(page, -1) {
+		attrs := map[string]string{}
+		for _, attr := range metaAttrRegex.FindAllSubmatch(tag, -1) {
+			attrs[strings.ToLower(string(attr[1]))] = string(attr[2]) + string(attr[3])
+		}
+		property := attrs["property"]
+		if property == "" {
+			property = attrs["name"]
+		}
+		content := strings.TrimSpace(html.UnescapeString(attrs["content"]))
+
+		switch strings.ToLower(property) {
+		case "og:title":
+			og.Title = content
+		case "og:description":
+			og.Description = content
+		case "og:image":
+			og.Image = content
+		}
+	}
+	return og
+}
+
+// linkCard builds an external embed for link from the page's OpenGraph tags,
+// uploading og:image as the thumbnail. A page that cannot be read or has no
+// tags still gets a card titled with the URL and no thumbnail.
+func (c *Client) linkCard(ctx context.Context, link string) *bsky.EmbedExternal {
+	external := &bsky.EmbedExternal_External{Uri: link, Title: link}
+	card := &bsky.EmbedExternal{LexiconTypeID: "app.bsky.embed.external", External: external}
+
+	page, err := fetch(ctx, c.client.Client, link)
+	if err != nil {
+		logutil.Debugf("bluesky: link card for %s: %v", link, err)
+		return card
+	}
+
+	og := parseOpenGraph(page)
+	if og.Title != "" {
+		external.Title = og.Title
+	}
+	external.Description = og.Description
+	if og.Image != "" {
+		thumb, err := c.uploadThumb(ctx, link, og.Image)
+		if err != nil {
+			logutil.Debugf("bluesky: link card thumbnail for %s: %v", link, err)
+		} else {
+			external.Thumb = thumb
+		}
+	}
+	return card
+}
+
+// uploadThumb downloads image, resolved against the page URL, and uploads
+// it as a blob.
+func (c *Client) uploadThumb(ctx context.Context, page, image string) (*util.LexBlob, error) {
+	base, err := url.Parse(page)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := fetch(ctx, c.client.Client, base.ResolveReference(ref).String())
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxThumbBytes {
+		return nil, fmt.Errorf("thumbnail too large: %d bytes (max %d)", len(data), maxThumbBytes)
+	}
+	return c.uploadBlob(ctx, data, "")
+}
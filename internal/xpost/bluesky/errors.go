@@ -0,0 +1,69 @@
+package bluesky
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+// APIError is a failed XRPC call, with the error name and message the PDS
+// returned so callers can tell an expired token from a rate limit from a
+// malformed record.
+type APIError struct {
+	StatusCode int
+	Name       string    // XRPC error name, e.g. RateLimitExceeded or InvalidToken
+	Message    string    // human-readable detail
+	Reset      time.Time // when a rate limit lifts; zero if not throttled
+
+	err error
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("HTTP %d", e.StatusCode)
+	switch {
+	case e.Name != "" && e.Message != "":
+		msg = fmt.Sprintf("%s: %s (%s)", e.Name, e.Message, msg)
+	case e.Name != "":
+		msg = fmt.Sprintf("%s (%s)", e.Name, msg)
+	case e.Message != "":
+		msg = fmt.Sprintf("%s (%s)", e.Message, msg)
+	}
+	if !e.Reset.IsZero() {
+		msg += fmt.Sprintf("; rate limited until %s", e.Reset.Local().Format(time.Kitchen))
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error { return e.err }
+
+// Throttled reports whether the PDS rejected the call for exceeding a rate
+// limit.
+func (e *APIError) Throttled() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.Name == "RateLimitExceeded"
+}
+
+// unwrapXRPCError turns an *xrpc.Error into an *APIError, and marks transient
+// failures as retryable.
+func unwrapXRPCError(err error) error {
+	var xe *xrpc.Error
+	if !errors.As(err, &xe) || xe == nil {
+		return xpost.Retryable(err, 0, 0)
+	}
+	apiErr := &APIError{StatusCode: xe.StatusCode, err: err}
+	var body *xrpc.XRPCError
+	if errors.As(xe.Wrapped, &body) && body != nil {
+		apiErr.Name, apiErr.Message = body.ErrStr, body.Message
+	}
+	if xe.IsThrottled() && xe.Ratelimit != nil {
+		apiErr.Reset = xe.Ratelimit.Reset
+	}
+	var wait time.Duration
+	if !apiErr.Reset.IsZero() {
+		wait = time.Until(apiErr.Reset)
+	}
+	return xpost.Retryable(apiErr, apiErr.StatusCode, wait)
+}
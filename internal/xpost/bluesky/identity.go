@@ -0,0 +1,158 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	plcDirectoryURL = "https://plc.directory"
+	pdsServiceID    = "#atproto_pds"
+	pdsServiceType  = "AtprotoPersonalDataServer"
+)
+
+// didDocument is the subset of a DID document needed to locate a PDS.
+type didDocument struct {
+	ID      string       `json:"id"`
+	Service []didService `json:"service"`
+}
+
+type didService struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	ServiceEndpoint string `json:"serviceEndpoint"`
+}
+
+// resolvePDS finds the PDS hosting handle by resolving it to a DID and
+// reading the atproto_pds service endpoint from the DID document.
+func resolvePDS(ctx context.Context, httpClient *http.Client, handle string) (string, error) {
+	did, err := resolveHandle(ctx, httpClient, handle)
+	if err != nil {
+		return "", err
+	}
+
+	doc, err := resolveDID(ctx, httpClient, did)
+	if err != nil {
+		return "", err
+	}
+
+	return pdsEndpoint(doc)
+}
+
+// resolveHandle maps a handle to its DID using the DNS TXT record first and
+// the well-known HTTPS endpoint as a fallback.
+func resolveHandle(ctx context.Context, httpClient *http.Client, handle string) (string, error) {
+	handle = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+	// DIDs are case-sensitive (did:web escapes a port as %3A); handles are not.
+	if strings.HasPrefix(handle, "did:") {
+		return handle, nil
+	}
+	handle = strings.ToLower(handle)
+	if handle == "" {
+		return "", errors.New("resolve handle: empty handle")
+	}
+
+	var resolver net.Resolver
+	if records, err := resolver.LookupTXT(ctx, "_atproto."+handle); err == nil {
+		for _, record := range records {
+			if did, ok := strings.CutPrefix(strings.TrimSpace(record), "did="); ok && did != "" {
+				return did, nil
+			}
+		}
+	}
+
+	body, err := fetch(ctx, httpClient, "https://"+handle+"/.well-known/atproto-did")
+	if err != nil {
+		return "", fmt.Errorf("resolve handle %q: %w", handle, err)
+	}
+	did := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(did, "did:") {
+		return "", fmt.Errorf("resolve handle %q: invalid DID %q", handle, did)
+	}
+	return did, nil
+}
+
+// resolveDID fetches the DID document for a did:plc or did:web identifier.
+func resolveDID(ctx context.Context, httpClient *http.Client, did string) (didDocument, error) {
+	docURL, err := didDocumentURL(did)
+	if err != nil {
+		return didDocument{}, err
+	}
+
+	body, err := fetch(ctx, httpClient, docURL)
+	if err != nil {
+		return didDocument{}, fmt.Errorf("resolve DID %q: %w", did, err)
+	}
+
+	var doc didDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return didDocument{}, fmt.Errorf("decode DID document for %q: %w", did, err)
+	}
+	if doc.ID != "" && doc.ID != did {
+		return didDocument{}, fmt.Errorf("DID document id %q does not match %q", doc.ID, did)
+	}
+	doc.ID = did
+
+	return doc, nil
+}
+
+func didDocumentURL(did string) (string, error) {
+	switch {
+	case strings.HasPrefix(did, "did:plc:"):
+		return plcDirectoryURL + "/" + did, nil
+	case strings.HasPrefix(did, "did:web:"):
+		// did:web encodes an optional port as %3A; paths are not allowed in atproto.
+		encoded := strings.TrimPrefix(did, "did:web:")
+		host, err := url.PathUnescape(encoded)
+		if err != nil || host == "" || strings.Contains(encoded, ":") || strings.Contains(host, "/") {
+			return "", fmt.Errorf("invalid did:web identifier %q", did)
+		}
+		return "https://" + host + "/.well-known/did.json", nil
+	}
+	return "", fmt.Errorf("unsupported DID method in %q", did)
+}
+
+// pdsEndpoint selects the atproto PDS service endpoint from a DID document.
+func pdsEndpoint(doc didDocument) (string, error) {
+	for _, svc := range doc.Service {
+		if svc.ID != pdsServiceID && svc.ID != doc.ID+pdsServiceID {
+			continue
+		}
+		if svc.Type != pdsServiceType {
+			continue
+		}
+		endpoint := strings.TrimRight(strings.TrimSpace(svc.ServiceEndpoint), "/")
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return "", fmt.Errorf("invalid PDS endpoint %q for %s", svc.ServiceEndpoint, doc.ID)
+		}
+		return endpoint, nil
+	}
+	return "", fmt.Errorf("no PDS service found for %s", doc.ID)
+}
+
+func fetch(ctx context.Context, httpClient *http.Client, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", target, resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
@@ -0,0 +1,113 @@
+package bluesky
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestResolveDIDWebSelectsPDS(t *testing.T) {
+	var did string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/did.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// The labeler and a mistyped PDS entry must not be chosen.
+		w.Write([]byte(`{
+			"id": "` + did + `",
+			"service": [
+				{"id": "#atproto_labeler", "type": "AtprotoLabeler", "serviceEndpoint": "https://labeler.example.com"},
+				{"id": "#atproto_pds", "type": "SomethingElse", "serviceEndpoint": "https://wrong.example.com"},
+				{"id": "` + did + `#atproto_pds", "type": "AtprotoPersonalDataServer", "serviceEndpoint": "https://pds.example.com/"}
+			]
+		}`))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	did = "did:web:" + strings.ReplaceAll(u.Host, ":", "%3A")
+
+	doc, err := resolveDID(context.Background(), srv.Client(), did)
+	if err != nil {
+		t.Fatalf("resolveDID: %v", err)
+	}
+	endpoint, err := pdsEndpoint(doc)
+	if err != nil {
+		t.Fatalf("pdsEndpoint: %v", err)
+	}
+	if endpoint != "https://pds.example.com" {
+		t.Errorf("endpoint = %q, want https://pds.example.com", endpoint)
+	}
+
+	// resolvePDS skips handle resolution when given a DID.
+	endpoint, err = resolvePDS(context.Background(), srv.Client(), did)
+	if err != nil {
+		t.Fatalf("resolvePDS: %v", err)
+	}
+	if endpoint != "https://pds.example.com" {
+		t.Errorf("resolvePDS = %q, want https://pds.example.com", endpoint)
+	}
+}
+
+func TestResolveDIDWebMismatchedID(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "did:web:someone.else", "service": []}`))
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	did := "did:web:" + strings.ReplaceAll(u.Host, ":", "%3A")
+	if _, err := resolveDID(context.Background(), srv.Client(), did); err == nil {
+		t.Fatal("expected an error for a document with another DID")
+	}
+}
+
+func TestDIDDocumentURL(t *testing.T) {
+	tests := []struct {
+		did     string
+		want    string
+		wantErr bool
+	}{
+		{did: "did:plc:abc123", want: "https://plc.directory/did:plc:abc123"},
+		{did: "did:web:example.com", want: "https://example.com/.well-known/did.json"},
+		{did: "did:web:localhost%3A8443", want: "https://localhost:8443/.well-known/did.json"},
+		{did: "did:web:example.com:user", wantErr: true},
+		{did: "did:web:", wantErr: true},
+		{did: "did:key:z6Mk", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := didDocumentURL(tt.did)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("didDocumentURL(%q) error = %v, wantErr %v", tt.did, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("didDocumentURL(%q) = %q, want %q", tt.did, got, tt.want)
+		}
+	}
+}
+
+func TestPDSEndpointRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  didDocument
+	}{
+		{name: "no service", doc: didDocument{ID: "did:plc:x"}},
+		{name: "bad scheme", doc: didDocument{ID: "did:plc:x", Service: []didService{
+			{ID: "#atproto_pds", Type: pdsServiceType, ServiceEndpoint: "ftp://pds.example.com"},
+		}}},
+	}
+	for _, tt := range tests {
+		if got, err := pdsEndpoint(tt.doc); err == nil {
+			t.Errorf("%s: pdsEndpoint = %q, want error", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package bluesky
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+)
+
+const (
+	postCollection   = "app.bsky.feed.post"
+	repostCollection = "app.bsky.feed.repost"
+	likeCollection   = "app.bsky.feed.like"
+)
+
+// postRef identifies a post by its repo (a DID or handle) and record key.
+type postRef struct {
+	repo string
+	rkey string
+}
+
+// parsePostRef accepts a bsky.app post URL
+// (https://bsky.app/profile/<actor>/post/<rkey>) or an at:// URI.
+func parsePostRef(raw string) (postRef, error) {
+	raw = strings.TrimSpace(raw)
+
+	if rest, ok := strings.CutPrefix(raw, "at://"); ok {
+		parts := strings.Split(rest, "/")
+		if len(parts) != 3 || parts[1] != postCollection || parts[0] == "" || parts[2] == "" {
+			return postRef{}, fmt.Errorf("invalid post URI %q", raw)
+		}
+		return postRef{repo: parts[0], rkey: parts[2]}, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return postRef{}, fmt.Errorf("invalid post URL %q", raw)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "profile" || parts[2] != "post" || parts[1] == "" || parts[3] == "" {
+		return postRef{}, fmt.Errorf("invalid post URL %q: expected /profile/<actor>/post/<rkey>", raw)
+	}
+	return postRef{repo: parts[1], rkey: parts[3]}, nil
+}
+
+// resolvePost looks up the current record for raw and returns a strong
+// reference to it (URI + CID) together with the post itself.
+func (c *Client) resolvePost(ctx context.Context, raw string) (*atproto.RepoStrongRef, *bsky.FeedPost, error) {
+	ref, err := parsePostRef(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	did := ref.repo
+	if !strings.HasPrefix(did, "did:") {
+		res, err := atproto.IdentityResolveHandle(ctx, c.client, ref.repo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("resolve handle %q: %w", ref.repo, err)
+		}
+		did = res.Did
+	}
+
+	rec, err := atproto.RepoGetRecord(ctx, c.client, "", postCollection, did, ref.rkey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get post %s: %w", raw, err)
+	}
+	if rec.Cid == nil || *rec.Cid == "" {
+		return nil, nil, fmt.Errorf("get post %s: record has no CID", raw)
+	}
+
+	post, _ := rec.Value.Val.(*bsky.FeedPost)
+	if post == nil {
+		return nil, nil, fmt.Errorf("get post %s: record is not a post", raw)
+	}
+
+	return &atproto.RepoStrongRef{Uri: rec.Uri, Cid: *rec.Cid}, post, nil
+}
+
+// replyRef builds the reply reference for answering raw. The root is
+// inherited from the parent when the parent is itself a reply.
+func (c *Client) replyRef(ctx context.Context, raw string) (*bsky.FeedPost_ReplyRef, error) {
+	parent, post, err := c.resolvePost(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	return threadReply(parent, post), nil
+}
+
+// quoteEmbed embeds the quoted post, keeping any images or link card already
+// attached.
+func quoteEmbed(quoted *atproto.RepoStrongRef, embed *bsky.FeedPost_Embed) *bsky.FeedPost_Embed {
+	record := &bsky.EmbedRecord{LexiconTypeID: "app.bsky.embed.record", Record: quoted}
+	if embed == nil || (embed.EmbedImages == nil && embed.EmbedExternal == nil) {
+		return &bsky.FeedPost_Embed{EmbedRecord: record}
+	}
+	return &bsky.FeedPost_Embed{
+		EmbedRecordWithMedia: &bsky.EmbedRecordWithMedia{
+			LexiconTypeID: "app.bsky.embed.recordWithMedia",
+			Record:        record,
+			Media: &bsky.EmbedRecordWithMedia_Media{
+				EmbedImages:   embed.EmbedImages,
+				EmbedExternal: embed.EmbedExternal,
+			},
+		},
+	}
+}
+
+// threadReply returns the reply reference for answering the post at parent.
+func threadReply(parent *atproto.RepoStrongRef, post *bsky.FeedPost) *bsky.FeedPost_ReplyRef {
+	root := parent
+	if post != nil && post.Reply != nil && post.Reply.Root != nil {
+		root = post.Reply.Root
+	}
+	return &bsky.FeedPost_ReplyRef{Root: root, Parent: parent}
+}
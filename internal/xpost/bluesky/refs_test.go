@@ -0,0 +1,158 @@
+package bluesky
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+)
+
+func TestParsePostRef(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    postRef
+		wantErr bool
+	}{
+		{raw: "https://bsky.app/profile/alice.test/post/3kabc", want: postRef{repo: "alice.test", rkey: "3kabc"}},
+		{raw: " https://bsky.app/profile/did:plc:abc/post/3kabc/ ", want: postRef{repo: "did:plc:abc", rkey: "3kabc"}},
+		{raw: "at://did:plc:abc/app.bsky.feed.post/3kabc", want: postRef{repo: "did:plc:abc", rkey: "3kabc"}},
+		{raw: "at://did:plc:abc/app.bsky.feed.like/3kabc", wantErr: true},
+		{raw: "at://did:plc:abc/app.bsky.feed.post", wantErr: true},
+		{raw: "https://bsky.app/profile/alice.test", wantErr: true},
+		{raw: "https://bsky.app/profile/alice.test/feed/3kabc", wantErr: true},
+		{raw: "bsky.app/profile/alice.test/post/3kabc", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePostRef(tt.raw)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parsePostRef(%q) = %+v, %v; want %+v, error %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestThreadReplyInheritsRoot(t *testing.T) {
+	parent := &atproto.RepoStrongRef{Uri: "at://did:plc:a/app.bsky.feed.post/2", Cid: testPostCID}
+	root := &atproto.RepoStrongRef{Uri: "at://did:plc:a/app.bsky.feed.post/1", Cid: testPostCID}
+
+	ref := threadReply(parent, &bsky.FeedPost{})
+	if ref.Root != parent || ref.Parent != parent {
+		t.Errorf("reply to a top-level post: root %v, parent %v; want both the post", ref.Root.Uri, ref.Parent.Uri)
+	}
+	ref = threadReply(parent, &bsky.FeedPost{Reply: &bsky.FeedPost_ReplyRef{Root: root, Parent: root}})
+	if ref.Root != root || ref.Parent != parent {
+		t.Errorf("reply to a reply: root %v, parent %v; want the thread root and the post", ref.Root.Uri, ref.Parent.Uri)
+	}
+}
+
+func TestPostReplyResolvesHandle(t *testing.T) {
+	srv := newFakePDS(t)
+	if _, err := srv.client().Post(context.Background(), xpost.Request{
+		Message: "Thanks!",
+		ReplyTo: "https://bsky.app/profile/alice.test/post/3kabc",
+	}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	reply, _ := srv.records[0]["reply"].(map[string]any)
+	parent, _ := reply["parent"].(map[string]any)
+	root, _ := reply["root"].(map[string]any)
+	want := "at://did:plc:alice-test/app.bsky.feed.post/3kabc"
+	if parent["uri"] != want || root["uri"] != want || parent["cid"] != testPostCID {
+		t.Errorf("reply = %v, want parent and root %s", reply, want)
+	}
+}
+
+func TestPostQuoteEmbedsRecord(t *testing.T) {
+	srv := newFakePDS(t)
+	c := srv.client()
+	if !c.CanQuote("https://bsky.app/profile/alice.test/post/3kabc") || c.CanQuote("https://x.com/jack/status/20") {
+		t.Error("CanQuote does not match bsky.app post URLs only")
+	}
+	if _, err := c.Post(context.Background(), xpost.Request{
+		Message: "Worth a read",
+		Quote:   "https://bsky.app/profile/alice.test/post/3kabc",
+	}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	embed, _ := srv.records[0]["embed"].(map[string]any)
+	record, _ := embed["record"].(map[string]any)
+	if embed["$type"] != "app.bsky.embed.record" {
+		t.Fatalf("embed = %v, want a record embed", embed)
+	}
+	// The handle is resolved to a DID and the CID fetched from the PDS.
+	if record["uri"] != "at://did:plc:alice-test/app.bsky.feed.post/3kabc" || record["cid"] != testPostCID {
+		t.Errorf("quoted record = %v", record)
+	}
+}
+
+func TestPostQuoteWithImages(t *testing.T) {
+	srv := newFakePDS(t)
+	if _, err := srv.client().Post(context.Background(), xpost.Request{
+		Message: "Look at this",
+		Images:  []xpost.ImageAttachment{{Path: writeImage(t, "a.png"), Alt: "a chart"}},
+		Quote:   "https://bsky.app/profile/alice.test/post/3kabc",
+	}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	embed, _ := srv.records[0]["embed"].(map[string]any)
+	if embed["$type"] != "app.bsky.embed.recordWithMedia" {
+		t.Fatalf("embed = %v, want a record with media", embed)
+	}
+	record, _ := embed["record"].(map[string]any)
+	quoted, _ := record["record"].(map[string]any)
+	if record["$type"] != "app.bsky.embed.record" || quoted["uri"] != "at://did:plc:alice-test/app.bsky.feed.post/3kabc" || quoted["cid"] != testPostCID {
+		t.Errorf("record = %v, want the quoted post", record)
+	}
+	media, _ := embed["media"].(map[string]any)
+	images, _ := media["images"].([]any)
+	if media["$type"] != "app.bsky.embed.images" || len(images) != 1 {
+		t.Fatalf("media = %v, want the one image", media)
+	}
+	if image, _ := images[0].(map[string]any); image["alt"] != "a chart" {
+		t.Errorf("image = %v, want its alt text kept", image)
+	}
+}
+
+func TestRepostCreatesRecord(t *testing.T) {
+	srv := newFakePDS(t)
+	if err := srv.client().Repost(context.Background(), "at://did:plc:alice/app.bsky.feed.post/3kabc"); err != nil {
+		t.Fatalf("Repost: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.collections) != 1 || srv.collections[0] != repostCollection {
+		t.Fatalf("created %v, want one %s record", srv.collections, repostCollection)
+	}
+	subject, _ := srv.records[0]["subject"].(map[string]any)
+	if subject["uri"] != "at://did:plc:alice/app.bsky.feed.post/3kabc" || subject["cid"] != testPostCID {
+		t.Errorf("subject = %v", subject)
+	}
+}
+
+func TestReactCreatesLike(t *testing.T) {
+	srv := newFakePDS(t)
+	if err := srv.client().React(context.Background(), "https://bsky.app/profile/alice.test/post/3kabc", "🎉"); err != nil {
+		t.Fatalf("React: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.collections) != 1 || srv.collections[0] != likeCollection {
+		t.Fatalf("created %v, want one %s record", srv.collections, likeCollection)
+	}
+	subject, _ := srv.records[0]["subject"].(map[string]any)
+	if subject["uri"] != "at://did:plc:alice-test/app.bsky.feed.post/3kabc" || subject["cid"] != testPostCID {
+		t.Errorf("subject = %v", subject)
+	}
+}
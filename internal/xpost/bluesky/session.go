@@ -0,0 +1,170 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+const sessionFileName = "bluesky-session.json"
+
+// appPasswordRegex matches the xxxx-xxxx-xxxx-xxxx form of app passwords.
+var appPasswordRegex = regexp.MustCompile(`^[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}$`)
+
+// LooksLikeAppPassword reports whether password has the form Bluesky gives
+// app passwords, as opposed to an account's main password.
+func LooksLikeAppPassword(password string) bool {
+	return appPasswordRegex.MatchString(password)
+}
+
+// Login signs in with cfg, replacing any cached session, and caches the new
+// one so later runs reuse it.
+func Login(ctx context.Context, cfg Config) (*Client, error) {
+	client, err := NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	path, err := sessionPath(cfg.SessionFile)
+	if err != nil {
+		logutil.Debugf("bluesky: not caching the session: %v", err)
+		return client, nil
+	}
+	if err := saveClientSession(path, cfg.Handle, client.client); err != nil {
+		return nil, err
+	}
+	client.sessionFile = path
+	return client, nil
+}
+
+// cachedSession is the session saved between runs so each run does not have
+// to log in again.
+type cachedSession struct {
+	Identifier string `json:"identifier"` // the handle the session was created for
+	PDSURL     string `json:"pds_url"`
+	Handle     string `json:"handle"`
+	Did        string `json:"did"`
+	AccessJwt  string `json:"access_jwt"`
+	RefreshJwt string `json:"refresh_jwt"`
+}
+
+// sessionPath returns the session cache location: file if it is set, or
+// bluesky-session.json under the user's xpost config dir.
+func sessionPath(file string) (string, error) {
+	if file != "" {
+		return file, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locate config dir: %w", err)
+	}
+	return filepath.Join(dir, "xpost", sessionFileName), nil
+}
+
+// loadSession reads the cached session at path. A missing file yields nil.
+func loadSession(path string) (*cachedSession, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read session: %w", err)
+	}
+
+	var s cachedSession
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse session %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// saveSession writes s to path, readable only by the current user.
+func saveSession(path string, s cachedSession) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode session: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create session dir: %w", err)
+	}
+
+	// Write to a temp file first so a failed write never truncates the old session.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".bluesky-session-*.json")
+	if err != nil {
+		return fmt.Errorf("write session: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write session: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write session: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write session: %w", err)
+	}
+	return nil
+}
+
+// resumeSession authenticates client with cached's tokens, first as they are
+// and then by refreshing them. It reports whether either worked.
+func resumeSession(ctx context.Context, client *xrpc.Client, cached *cachedSession) bool {
+	client.Auth = &xrpc.AuthInfo{
+		AccessJwt:  cached.AccessJwt,
+		RefreshJwt: cached.RefreshJwt,
+		Handle:     cached.Handle,
+		Did:        cached.Did,
+	}
+	_, err := atproto.ServerGetSession(ctx, client)
+	if err == nil {
+		logutil.Debugf("bluesky: reusing cached session for %s", cached.Handle)
+		return true
+	}
+	logutil.Debugf("bluesky: cached access token rejected: %v", err)
+
+	// refreshSession authenticates with the refresh token rather than the
+	// access token.
+	refresh := *client
+	refresh.Auth = &xrpc.AuthInfo{AccessJwt: cached.RefreshJwt}
+	session, err := atproto.ServerRefreshSession(ctx, &refresh)
+	if err != nil {
+		logutil.Debugf("bluesky: cached session refresh failed: %v", err)
+		client.Auth = nil
+		return false
+	}
+	logutil.Debugf("bluesky: refreshed cached session for %s", session.Handle)
+	client.Auth = &xrpc.AuthInfo{
+		AccessJwt:  session.AccessJwt,
+		RefreshJwt: session.RefreshJwt,
+		Handle:     session.Handle,
+		Did:        session.Did,
+	}
+	return true
+}
+
+// saveClientSession caches client's current session at path for the handle
+// it was created for.
+func saveClientSession(path, identifier string, client *xrpc.Client) error {
+	return saveSession(path, cachedSession{
+		Identifier: identifier,
+		PDSURL:     client.Host,
+		Handle:     client.Auth.Handle,
+		Did:        client.Auth.Did,
+		AccessJwt:  client.Auth.AccessJwt,
+		RefreshJwt: client.Auth.RefreshJwt,
+	})
+}
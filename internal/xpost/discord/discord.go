@@ -0,0 +1,370 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+const (
+	envWebhookURL = "XPOST_DISCORD_WEBHOOK_URL"
+
+	providerName  = "discord"
+	maxChars      = 2000     // Discord's message content limit
+	maxAltChars   = 4096     // Discord's embed description limit
+	maxImages     = 10       // Discord's attachments (and embeds) per message
+	maxImageBytes = 10 << 20 // Discord's upload limit for servers without boosts
+)
+
+// imageTypes are the MIME types accepted as an explicit media type override.
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// Config contains the webhook messages are sent through.
+type Config struct {
+	WebhookURL string
+
+	// Timeout bounds each request; zero means xpost.DefaultHTTPTimeout.
+	Timeout time.Duration
+}
+
+// Client implements the xpost.Poster interface for a Discord webhook.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+func init() {
+	xpost.Register(providerName, New, xpost.Info{Label: "Discord", Icon: "\uf392", Color: 99})
+	xpost.RegisterValidator(providerName, NewValidator)
+	config.RegisterSection(providerName, config.Section{
+		Options: func(c *config.Config) *config.Options { return &c.Discord.Options },
+		Vars: func(c *config.Config) map[string]*string {
+			return map[string]*string{
+				envWebhookURL: &c.Discord.WebhookURL,
+			}
+		},
+		Account: func(c, acct *config.Config, name string) bool {
+			a, ok := c.Discord.Accounts[name]
+			acct.Discord = a
+			return ok
+		},
+	})
+}
+
+// New constructs a Discord poster from its section of the resolved config.
+func New(ctx context.Context, settings *config.Config) (xpost.Poster, error) {
+	cfg, err := loadConfig(settings.Discord)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeout = settings.HTTPTimeout
+
+	return NewWithConfig(ctx, cfg), nil
+}
+
+// NewWithConfig constructs a Discord client from explicit settings.
+func NewWithConfig(ctx context.Context, cfg Config) *Client {
+	cfg.WebhookURL = strings.TrimRight(cfg.WebhookURL, "/")
+	return &Client{cfg: cfg, http: &http.Client{Timeout: xpost.HTTPTimeout(cfg.Timeout)}}
+}
+
+// NewValidator returns a Discord validator that needs no credentials.
+func NewValidator() xpost.Validator {
+	return &Client{}
+}
+
+// Name identifies the provider.
+func (c *Client) Name() string { return providerName }
+
+// Verify checks the webhook without posting and returns its name.
+func (c *Client) Verify(ctx context.Context) (string, error) {
+	hook, err := c.webhook(ctx)
+	if err != nil {
+		return "", fmt.Errorf("verify webhook: %w", err)
+	}
+	return hook.Name, nil
+}
+
+// Truncate shortens the message so it, including any link, fits within the
+// content limit.
+func (c *Client) Truncate(req xpost.Request) (xpost.Request, string) {
+	limit := maxChars
+	if req.Link != "" {
+		limit -= utf8.RuneCountInString("\n\n" + req.Link)
+	}
+	var removed string
+	req.Message, removed = xpost.TruncateText(req.Message, limit, utf8.RuneCountInString)
+	return req, removed
+}
+
+// TruncateAlt shortens the image description to the embed description limit.
+func (c *Client) TruncateAlt(alt string) (string, string) {
+	return xpost.TruncateText(alt, maxAltChars, utf8.RuneCountInString)
+}
+
+// Capabilities returns Discord's limits.
+func (c *Client) Capabilities(ctx context.Context) (xpost.Capabilities, error) {
+	return xpost.Capabilities{
+		MaxChars:      maxChars,
+		Unit:          "characters",
+		MaxImages:     maxImages,
+		MaxImageBytes: maxImageBytes,
+		MediaTypes:    imageTypes,
+		MaxAltChars:   maxAltChars,
+		Features:      []string{"image descriptions shown in embeds"},
+	}, nil
+}
+
+// Count measures the message content, including any link.
+func (c *Client) Count(req xpost.Request) (int, int, string) {
+	return utf8.RuneCountInString(composeText(req)), maxChars, "characters"
+}
+
+// Validate checks if the request meets Discord's constraints.
+func (c *Client) Validate(req xpost.Request) error {
+	if req.ReplyTo != "" {
+		return xpost.ValidationError{Provider: providerName, Reason: "replies are not supported"}
+	}
+	if len(req.Images) > maxImages {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("too many images: %d (max %d)", len(req.Images), maxImages)}
+	}
+	for _, img := range req.Images {
+		if img.MediaType != "" && !slices.Contains(imageTypes, img.MediaType) {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type %q", img.MediaType)}
+		}
+		if img.IsVideo() {
+			return xpost.ValidationError{Provider: providerName, Reason: "videos are not supported"}
+		}
+		if count := utf8.RuneCountInString(img.Alt); count > maxAltChars {
+			return xpost.ValidationError{
+				Provider: providerName,
+				Reason:   fmt.Sprintf("image description too long: %d characters (max %d)", count, maxAltChars),
+			}
+		}
+	}
+	if strings.TrimSpace(composeText(req)) == "" && len(req.Images) == 0 {
+		return xpost.ValidationError{Provider: providerName, Reason: "a message or an image is required"}
+	}
+	if count, _, _ := c.Count(req); count > maxChars {
+		return xpost.ValidationError{
+			Provider: providerName,
+			Reason:   fmt.Sprintf("message too long: %d characters (max %d)", count, maxChars),
+		}
+	}
+	return nil
+}
+
+// Post sends the message through the webhook with the images attached, each
+// shown in an embed whose description is the image's alt text.
+func (c *Client) Post(ctx context.Context, req xpost.Request) (xpost.PostResult, error) {
+	payload := executeRequest{
+		Content: composeText(req),
+		// Only linkify mentions typed by hand, never ping @everyone or roles.
+		AllowedMentions: allowedMentions{Parse: []string{"users"}},
+	}
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for i, img := range req.Images {
+		name := fmt.Sprintf("%d%s", i, filepath.Ext(img.Path))
+		if err := writeFile(mw, i, name, img); err != nil {
+			return xpost.PostResult{}, err
+		}
+		payload.Attachments = append(payload.Attachments, attachment{ID: i, Filename: name})
+		if alt := strings.TrimSpace(img.Alt); alt != "" {
+			payload.Embeds = append(payload.Embeds, embed{
+				Description: alt,
+				Image:       &embedImage{URL: "attachment://" + name},
+			})
+		}
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return xpost.PostResult{}, fmt.Errorf("send message: %w", err)
+	}
+	if err := mw.WriteField("payload_json", string(payloadJSON)); err != nil {
+		return xpost.PostResult{}, fmt.Errorf("send message: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return xpost.PostResult{}, fmt.Errorf("send message: %w", err)
+	}
+
+	// wait=true makes Discord return the created message.
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.WebhookURL+"?wait=true", body)
+	if err != nil {
+		return xpost.PostResult{}, fmt.Errorf("send message: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var msg message
+	if err := c.send(httpReq, &msg); err != nil {
+		return xpost.PostResult{}, fmt.Errorf("send message: %w", err)
+	}
+	logutil.Debugf("discord message created: id=%s", msg.ID)
+
+	return xpost.PostResult{Provider: providerName, ID: msg.ID, URL: c.messageURL(ctx, msg)}, nil
+}
+
+// Delete removes a message the webhook sent.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.cfg.WebhookURL+"/messages/"+url.PathEscape(id), nil)
+	if err != nil {
+		return fmt.Errorf("delete message: %w", err)
+	}
+	if err := c.send(httpReq, nil); err != nil {
+		return fmt.Errorf("delete message: %w", err)
+	}
+	return nil
+}
+
+type executeRequest struct {
+	Content         string          `json:"content,omitempty"`
+	Embeds          []embed         `json:"embeds,omitempty"`
+	Attachments     []attachment    `json:"attachments,omitempty"`
+	AllowedMentions allowedMentions `json:"allowed_mentions"`
+}
+
+type embed struct {
+	Description string      `json:"description,omitempty"`
+	Image       *embedImage `json:"image,omitempty"`
+}
+
+type embedImage struct {
+	URL string `json:"url"`
+}
+
+type attachment struct {
+	ID       int    `json:"id"`
+	Filename string `json:"filename"`
+}
+
+type allowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+type message struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+}
+
+type webhookInfo struct {
+	Name      string `json:"name"`
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+}
+
+// composeText joins the message and link the way they are sent.
+func composeText(req xpost.Request) string {
+	if req.Link == "" {
+		return req.Message
+	}
+	return strings.TrimSpace(req.Message + "\n\n" + req.Link)
+}
+
+// writeFile adds the image as the files[i] part of the upload.
+func writeFile(mw *multipart.Writer, i int, name string, img xpost.ImageAttachment) error {
+	data, err := os.ReadFile(img.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("image %q not found", img.Path)}
+		}
+		return fmt.Errorf("read image: %w", err)
+	}
+
+	mediaType := img.MediaType
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files[%d]"; filename=%q`, i, name))
+	header.Set("Content-Type", mediaType)
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("attach image: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return fmt.Errorf("attach image: %w", err)
+	}
+	return nil
+}
+
+// webhook fetches the webhook's name and the server and channel it posts to.
+func (c *Client) webhook(ctx context.Context) (webhookInfo, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.WebhookURL, nil)
+	if err != nil {
+		return webhookInfo{}, err
+	}
+	var hook webhookInfo
+	if err := c.send(httpReq, &hook); err != nil {
+		return webhookInfo{}, err
+	}
+	return hook, nil
+}
+
+// messageURL links to msg, or returns "" when the webhook's server cannot be
+// looked up.
+func (c *Client) messageURL(ctx context.Context, msg message) string {
+	hook, err := c.webhook(ctx)
+	if err != nil || hook.GuildID == "" || msg.ID == "" {
+		logutil.Debugf("discord: no link for message %s: %v", msg.ID, err)
+		return ""
+	}
+	return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", hook.GuildID, msg.ChannelID, msg.ID)
+}
+
+func (c *Client) send(httpReq *http.Request, out any) error {
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return xpost.Retryable(err, 0, 0)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+			Code    int    `json:"code"`
+		}
+		err := fmt.Errorf("HTTP %d", resp.StatusCode)
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			err = fmt.Errorf("%s (code %d, HTTP %d)", apiErr.Message, apiErr.Code, resp.StatusCode)
+		}
+		return xpost.Retryable(err, resp.StatusCode, xpost.RetryAfter(resp.Header))
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// loadConfig checks that the Discord section has the credentials needed to
+// post.
+func loadConfig(section config.Discord) (Config, error) {
+	cfg := Config{WebhookURL: strings.TrimSpace(section.WebhookURL)}
+	if cfg.WebhookURL == "" {
+		return Config{}, xpost.MissingEnvError{Provider: providerName, Variables: []string{envWebhookURL}}
+	}
+	return cfg, nil
+}
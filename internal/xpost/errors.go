@@ -1,8 +1,13 @@
 package xpost
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // MissingEnvError is returned when required configuration is missing.
@@ -27,3 +32,77 @@ type ValidationError struct {
 func (e ValidationError) Error() string {
 	return fmt.Sprintf("%s validation failed: %s", e.Provider, e.Reason)
 }
+
+// RetryableError marks a transient failure, such as a rate limit, server
+// error or connection timeout, that may succeed if tried again. Providers
+// wrap errors in it where the status code is known, so retries never guess
+// from messages.
+type RetryableError struct {
+	Err error
+	// RetryAfter is how long the provider asked to wait; zero if it did not
+	// say.
+	RetryAfter time.Duration
+}
+
+func (e RetryableError) Error() string { return e.Err.Error() }
+func (e RetryableError) Unwrap() error { return e.Err }
+
+// PartialError reports that the post was created but a follow-up to it, such
+// as an image description reply, failed. Post returns it with the post's
+// result. It is never retryable: posting again would duplicate the post.
+type PartialError struct {
+	Err error
+}
+
+func (e PartialError) Error() string { return e.Err.Error() }
+func (e PartialError) Unwrap() error { return e.Err }
+
+// Partial wraps err, from a follow-up to a post that was created, in a
+// PartialError. A RetryableError in err is flattened to its message, so no
+// caller retries the post.
+func Partial(err error) error {
+	if errors.As(err, new(RetryableError)) {
+		err = errors.New(err.Error())
+	}
+	return PartialError{Err: err}
+}
+
+// Retryable wraps err in a RetryableError when statusCode is a rate limit or
+// server error, or err is a timeout while connecting. Other errors, including
+// timeouts after the request went out, are returned as they are: the server
+// may already have created the post, and retrying would post it twice.
+func Retryable(err error, statusCode int, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	var netErr net.Error
+	switch {
+	case statusCode == http.StatusTooManyRequests,
+		statusCode >= 500 && statusCode != http.StatusNotImplemented,
+		errors.As(err, &netErr) && netErr.Timeout() && unsent(err):
+		return RetryableError{Err: err, RetryAfter: max(retryAfter, 0)}
+	}
+	return err
+}
+
+// unsent reports whether err failed the request while dialing, before any
+// of it reached the server.
+func unsent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// RetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func RetryAfter(h http.Header) time.Duration {
+	value := strings.TrimSpace(h.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package xpost
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	dialTimeout := &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	readTimeout := &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		retryAfter time.Duration
+		want       bool
+	}{
+		{name: "rate limited", err: errors.New("slow down"), status: http.StatusTooManyRequests, retryAfter: time.Minute, want: true},
+		{name: "server error", err: errors.New("unavailable"), status: http.StatusServiceUnavailable, want: true},
+		{name: "not implemented", err: errors.New("no"), status: http.StatusNotImplemented},
+		{name: "bad request", err: errors.New("invalid"), status: http.StatusBadRequest},
+		{name: "timeout while connecting", err: &url.Error{Op: "Post", URL: "https://example.com", Err: dialTimeout}, want: true},
+		// The post may already exist, so a retry could post it twice.
+		{name: "timeout awaiting the response", err: &url.Error{Op: "Post", URL: "https://example.com", Err: readTimeout}},
+		{name: "client timeout", err: &url.Error{Op: "Post", URL: "https://example.com", Err: os.ErrDeadlineExceeded}},
+		{name: "wrapped dial timeout", err: fmt.Errorf("upload: %w", dialTimeout), want: true},
+	}
+	for _, tt := range tests {
+		err := Retryable(tt.err, tt.status, tt.retryAfter)
+		var retryable RetryableError
+		if got := errors.As(err, &retryable); got != tt.want {
+			t.Errorf("%s: retryable = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: Retryable = %v, want it to wrap %v", tt.name, err, tt.err)
+		}
+		if tt.want && retryable.RetryAfter != tt.retryAfter {
+			t.Errorf("%s: RetryAfter = %s, want %s", tt.name, retryable.RetryAfter, tt.retryAfter)
+		}
+	}
+	if Retryable(nil, http.StatusServiceUnavailable, 0) != nil {
+		t.Error("Retryable(nil) is not nil")
+	}
+}
+
+func TestPartial(t *testing.T) {
+	invalid := ValidationError{Provider: "mastodon", Reason: "too long"}
+	tests := []struct {
+		name string
+		err  error
+		// wraps is an error the partial failure still matches with errors.Is.
+		wraps error
+	}{
+		{name: "retryable", err: fmt.Errorf("reply: %w", Retryable(errors.New("slow down"), http.StatusTooManyRequests, time.Minute))},
+		{name: "permanent", err: fmt.Errorf("reply: %w", invalid), wraps: invalid},
+	}
+	for _, tt := range tests {
+		err := Partial(tt.err)
+		if !errors.As(err, new(PartialError)) || errors.As(err, new(RetryableError)) {
+			t.Errorf("%s: Partial = %#v, want a partial failure that is not retryable", tt.name, err)
+		}
+		if err.Error() != tt.err.Error() {
+			t.Errorf("%s: message = %q, want %q", tt.name, err, tt.err)
+		}
+		if tt.wraps != nil && !errors.Is(err, tt.wraps) {
+			t.Errorf("%s: Partial = %v, want it to wrap %v", tt.name, err, tt.wraps)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package xpost
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// hashtagRegex matches #tags made of letters, digits, and underscores that
+// are not glued to a preceding word (so URL fragments are skipped).
+var hashtagRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_/&])#([\p{L}\p{N}_]*[\p{L}_][\p{L}\p{N}_]*)`)
+
+// Hashtags returns the tags in text without the leading '#', in order of
+// appearance.
+func Hashtags(text string) []string {
+	matches := hashtagRegex.FindAllStringSubmatch(text, -1)
+	tags := make([]string, 0, len(matches))
+	for _, m := range matches {
+		tags = append(tags, m[1])
+	}
+	return tags
+}
+
+// NormalizeHashtag lowercases tag and strips a leading '#' for comparisons.
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// StripHashtags removes the hashtags from text: a run of tags at the end is
+// dropped, and tags inside a sentence keep their word without the '#'.
+func StripHashtags(text string) string {
+	spans := HashtagSpans(text)
+	end, n := len(text), len(spans)
+	for n > 0 && strings.TrimSpace(text[spans[n-1][1]:end]) == "" {
+		end = spans[n-1][0]
+		n--
+	}
+	if n < len(spans) {
+		text = strings.TrimRightFunc(text[:end], unicode.IsSpace)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, span := range spans[:n] {
+		b.WriteString(text[last:span[0]])
+		last = span[0] + 1
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// HashtagSpans returns the byte offsets [start, end) of each #tag in text,
+// including the leading '#', in order of appearance.
+func HashtagSpans(text string) [][2]int {
+	matches := hashtagRegex.FindAllStringSubmatchIndex(text, -1)
+	spans := make([][2]int, 0, len(matches))
+	for _, m := range matches {
+		spans = append(spans, [2]int{m[2] - 1, m[3]})
+	}
+	return spans
+}
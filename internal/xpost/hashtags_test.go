@@ -0,0 +1,34 @@
+package xpost
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "no tags here", want: []string{}},
+		{text: "#Go and #golang_dev", want: []string{"Go", "golang_dev"}},
+		{text: "Ünïcode #日本語 works", want: []string{"日本語"}},
+		{text: "numbers #2024 alone are not tags, #go2 is", want: []string{"go2"}},
+		{text: "see https://example.com/page#anchor and a&#39;b", want: []string{}},
+		{text: "glued word#tag is skipped", want: []string{}},
+		{text: "(#paren) #end.", want: []string{"paren", "end"}},
+	}
+	for _, tt := range tests {
+		if got := Hashtags(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Hashtags(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	for in, want := range map[string]string{"#GoLang": "golang", " Rust ": "rust", "##x": "#x"} {
+		if got := NormalizeHashtag(in); got != want {
+			t.Errorf("NormalizeHashtag(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package lemmy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+const (
+	envInstance = "XPOST_LEMMY_INSTANCE"
+	envUsername = "XPOST_LEMMY_USERNAME"
+	envPassword = "XPOST_LEMMY_PASSWORD"
+
+	providerName  = "lemmy"
+	minTitleChars = 3     // Lemmy rejects titles shorter than this
+	maxTitleChars = 200   // Lemmy's post title limit
+	maxBodyChars  = 10000 // Lemmy's default post body limit
+	maxImages     = 1     // Lemmy posts carry a single image
+)
+
+// imageTypes are the MIME types accepted as an explicit media type override.
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// Config contains the settings needed to reach a Lemmy instance.
+type Config struct {
+	Instance string
+	Username string
+	Password string
+
+	// Timeout bounds each request; zero means xpost.DefaultHTTPTimeout.
+	Timeout time.Duration
+}
+
+// Client implements the xpost.Poster interface for Lemmy.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+func init() {
+	xpost.Register(providerName, New, xpost.Info{
+		Label: "Lemmy",
+		Icon:  "\uf1ea", // newspaper, as Lemmy is link-aggregator style
+		Color: 250,
+	})
+	xpost.RegisterValidator(providerName, NewValidator)
+	config.RegisterSection(providerName, config.Section{
+		Options: func(c *config.Config) *config.Options { return &c.Lemmy.Options },
+		Vars: func(c *config.Config) map[string]*string {
+			return map[string]*string{
+				envInstance: &c.Lemmy.Instance,
+				envUsername: &c.Lemmy.Username,
+				envPassword: &c.Lemmy.Password,
+			}
+		},
+		Account: func(c, acct *config.Config, name string) bool {
+			a, ok := c.Lemmy.Accounts[name]
+			acct.Lemmy = a
+			return ok
+		},
+	})
+}
+
+// New constructs a Lemmy poster from its section of the resolved config.
+func New(ctx context.Context, settings *config.Config) (xpost.Poster, error) {
+	cfg, err := loadConfig(settings.Lemmy)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeout = settings.HTTPTimeout
+
+	return NewWithConfig(ctx, cfg), nil
+}
+
+// NewWithConfig constructs a Lemmy client from explicit settings.
+func NewWithConfig(ctx context.Context, cfg Config) *Client {
+	cfg.Instance = strings.TrimRight(cfg.Instance, "/")
+	return &Client{cfg: cfg, http: &http.Client{Timeout: xpost.HTTPTimeout(cfg.Timeout)}}
+}
+
+// NewValidator returns a Lemmy validator that needs no credentials.
+func NewValidator() xpost.Validator {
+	return &Client{}
+}
+
+// Name identifies the provider.
+func (c *Client) Name() string { return providerName }
+
+// Verify checks the credentials without posting and returns the account name.
+func (c *Client) Verify(ctx context.Context) (string, error) {
+	jwt, err := c.login(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var res siteResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v3/site", jwt, nil, &res); err != nil {
+		return "", fmt.Errorf("get site: %w", err)
+	}
+	if res.MyUser == nil {
+		return "", errors.New("get site: not logged in")
+	}
+	return res.MyUser.LocalUserView.Person.Name, nil
+}
+
+// Capabilities returns Lemmy's default limits.
+func (c *Client) Capabilities(ctx context.Context) (xpost.Capabilities, error) {
+	return xpost.Capabilities{
+		MaxChars:    maxBodyChars,
+		Unit:        "characters",
+		MaxImages:   maxImages,
+		MediaTypes:  imageTypes,
+		MaxAltChars: 0,
+		Features: []string{
+			"communities",
+			fmt.Sprintf("title (first line) of %d-%d characters", minTitleChars, maxTitleChars),
+		},
+	}, nil
+}
+
+// Count measures the post body; the first line becomes the title, which is
+// checked separately.
+func (c *Client) Count(req xpost.Request) (int, int, string) {
+	_, body := splitTitle(req.Message)
+	return len([]rune(body)), maxBodyChars, "body characters"
+}
+
+// Validate checks if the request meets Lemmy's constraints.
+func (c *Client) Validate(req xpost.Request) error {
+	if strings.TrimSpace(req.Community) == "" {
+		return xpost.ValidationError{Provider: providerName, Reason: "a community is required (use --community)"}
+	}
+	if req.ReplyTo != "" {
+		return xpost.ValidationError{Provider: providerName, Reason: "replies are not supported"}
+	}
+	if len(req.Images) > maxImages {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("too many images: %d (max %d)", len(req.Images), maxImages)}
+	}
+	for _, img := range req.Images {
+		if img.MediaType != "" && !slices.Contains(imageTypes, img.MediaType) {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type %q", img.MediaType)}
+		}
+	}
+
+	title, body := splitTitle(req.Message)
+	if count := len([]rune(title)); count < minTitleChars || count > maxTitleChars {
+		return xpost.ValidationError{
+			Provider: providerName,
+			Reason:   fmt.Sprintf("title (first line) must be %d-%d characters, got %d", minTitleChars, maxTitleChars, count),
+		}
+	}
+	if count := len([]rune(body)); count > maxBodyChars {
+		return xpost.ValidationError{
+			Provider: providerName,
+			Reason:   fmt.Sprintf("body too long: %d characters (max %d)", count, maxBodyChars),
+		}
+	}
+	return nil
+}
+
+// Post logs in and submits a new post to the requested community. The first
+// line of the message becomes the title and the remainder the body. A link is
+// used as the post URL; an image is uploaded to pict-rs and used as the URL
+// when there is no link, or embedded in the body otherwise.
+func (c *Client) Post(ctx context.Context, req xpost.Request) (xpost.PostResult, error) {
+	jwt, err := c.login(ctx)
+	if err != nil {
+		return xpost.PostResult{}, err
+	}
+
+	communityID, err := c.resolveCommunity(ctx, jwt, strings.TrimSpace(req.Community))
+	if err != nil {
+		return xpost.PostResult{}, err
+	}
+
+	title, body := splitTitle(req.Message)
+	payload := createPostRequest{
+		Name:        title,
+		CommunityID: communityID,
+		Body:        body,
+		URL:         req.Link,
+	}
+
+	if len(req.Images) > 0 {
+		img := req.Images[0]
+		imageURL, err := c.uploadImage(ctx, jwt, img.Path, img.MediaType)
+		if err != nil {
+			return xpost.PostResult{}, err
+		}
+		if payload.URL == "" {
+			payload.URL = imageURL
+			payload.AltText = img.Alt
+		} else {
+			payload.Body = strings.TrimSpace(payload.Body + "\n\n" + fmt.Sprintf("![%s](%s)", img.Alt, imageURL))
+		}
+	}
+
+	var res createPostResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v3/post", jwt, payload, &res); err != nil {
+		return xpost.PostResult{}, fmt.Errorf("create post: %w", err)
+	}
+	logutil.Debugf("lemmy post created: id=%d ap_id=%s", res.PostView.Post.ID, res.PostView.Post.APID)
+
+	return xpost.PostResult{
+		Provider: providerName,
+		ID:       strconv.FormatInt(res.PostView.Post.ID, 10),
+		URL:      res.PostView.Post.APID,
+	}, nil
+}
+
+// Delete marks the post with the given ID as deleted.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	postID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("delete post: invalid id %q", id)
+	}
+
+	jwt, err := c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.do(ctx, http.MethodPost, "/api/v3/post/delete", jwt, deletePostRequest{PostID: postID, Deleted: true}, nil); err != nil {
+		return fmt.Errorf("delete post: %w", err)
+	}
+	return nil
+}
+
+type loginRequest struct {
+	UsernameOrEmail string `json:"username_or_email"`
+	Password        string `json:"password"`
+}
+
+type loginResponse struct {
+	JWT *string `json:"jwt"`
+}
+
+type communityResponse struct {
+	CommunityView struct {
+		Community struct {
+			ID int64 `json:"id"`
+		} `json:"community"`
+	} `json:"community_view"`
+}
+
+type createPostRequest struct {
+	Name        string `json:"name"`
+	CommunityID int64  `json:"community_id"`
+	Body        string `json:"body,omitempty"`
+	URL         string `json:"url,omitempty"`
+	AltText     string `json:"alt_text,omitempty"`
+}
+
+type deletePostRequest struct {
+	PostID  int64 `json:"post_id"`
+	Deleted bool  `json:"deleted"`
+}
+
+type createPostResponse struct {
+	PostView struct {
+		Post struct {
+			ID   int64  `json:"id"`
+			APID string `json:"ap_id"`
+		} `json:"post"`
+	} `json:"post_view"`
+}
+
+type siteResponse struct {
+	MyUser *struct {
+		LocalUserView struct {
+			Person struct {
+				Name string `json:"name"`
+			} `json:"person"`
+		} `json:"local_user_view"`
+	} `json:"my_user"`
+}
+
+type pictrsResponse struct {
+	Msg   string `json:"msg"`
+	Files []struct {
+		File string `json:"file"`
+	} `json:"files"`
+}
+
+func (c *Client) login(ctx context.Context) (string, error) {
+	var res loginResponse
+	err := c.do(ctx, http.MethodPost, "/api/v3/user/login", "", loginRequest{
+		UsernameOrEmail: c.cfg.Username,
+		Password:        c.cfg.Password,
+	}, &res)
+	if err != nil {
+		return "", fmt.Errorf("login: %w", err)
+	}
+	if res.JWT == nil || *res.JWT == "" {
+		return "", errors.New("login: no token returned (is email verification or registration approval pending?)")
+	}
+	return *res.JWT, nil
+}
+
+func (c *Client) resolveCommunity(ctx context.Context, jwt, name string) (int64, error) {
+	var res communityResponse
+	path := "/api/v3/community?" + url.Values{"name": {strings.TrimPrefix(name, "!")}}.Encode()
+	if err := c.do(ctx, http.MethodGet, path, jwt, nil, &res); err != nil {
+		return 0, fmt.Errorf("resolve community %q: %w", name, err)
+	}
+	if res.CommunityView.Community.ID == 0 {
+		return 0, fmt.Errorf("resolve community %q: not found", name)
+	}
+	return res.CommunityView.Community.ID, nil
+}
+
+func (c *Client) uploadImage(ctx context.Context, jwt, path, mediaType string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("image %q not found", path)}
+		}
+		return "", fmt.Errorf("read image: %w", err)
+	}
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="images[]"; filename=%q`, filepath.Base(path)))
+	header.Set("Content-Type", mediaType)
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.Instance+"/pictrs/image", body)
+	if err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", mw.FormDataContentType())
+	setAuth(httpReq, jwt)
+
+	var res pictrsResponse
+	if err := c.send(httpReq, &res); err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	if res.Msg != "ok" || len(res.Files) == 0 {
+		return "", fmt.Errorf("upload image: unexpected response %q", res.Msg)
+	}
+
+	return c.cfg.Instance + "/pictrs/image/" + res.Files[0].File, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path, jwt string, payload, out any) error {
+	var body io.Reader
+	if payload != nil {
+		buf, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.cfg.Instance+path, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	setAuth(httpReq, jwt)
+
+	return c.send(httpReq, out)
+}
+
+func (c *Client) send(httpReq *http.Request, out any) error {
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return xpost.Retryable(err, 0, 0)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		err := fmt.Errorf("HTTP %d", resp.StatusCode)
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			err = fmt.Errorf("%s (HTTP %d)", apiErr.Error, resp.StatusCode)
+		}
+		return xpost.Retryable(err, resp.StatusCode, xpost.RetryAfter(resp.Header))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// setAuth attaches the session token both as a bearer header (Lemmy 0.19+) and
+// as the jwt cookie that pict-rs uploads are authorized with.
+func setAuth(httpReq *http.Request, jwt string) {
+	if jwt == "" {
+		return
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+jwt)
+	httpReq.AddCookie(&http.Cookie{Name: "jwt", Value: jwt})
+}
+
+// splitTitle uses the first line of the message as the post title and the
+// rest as the body.
+func splitTitle(message string) (string, string) {
+	title, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return strings.TrimSpace(title), strings.TrimSpace(body)
+}
+
+// loadConfig checks that the Lemmy section has the credentials needed to
+// post.
+func loadConfig(section config.Lemmy) (Config, error) {
+	cfg := Config{
+		Instance: strings.TrimSpace(section.Instance),
+		Username: strings.TrimSpace(section.Username),
+		Password: strings.TrimSpace(section.Password),
+	}
+
+	var missing []string
+	if cfg.Instance == "" {
+		missing = append(missing, envInstance)
+	}
+	if cfg.Username == "" {
+		missing = append(missing, envUsername)
+	}
+	if cfg.Password == "" {
+		missing = append(missing, envPassword)
+	}
+
+	if len(missing) > 0 {
+		return Config{}, xpost.MissingEnvError{Provider: providerName, Variables: missing}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,245 @@
+package lemmy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// fakeInstance is a minimal Lemmy API. It issues jwt for alice/secret and
+// records the authorization and body of each request.
+type fakeInstance struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	jwt      *string
+	auth     map[string]string // path -> Authorization header
+	cookies  map[string]string // path -> jwt cookie
+	payloads map[string][]byte // path -> request body
+}
+
+func newFakeInstance(t *testing.T, jwt *string) *fakeInstance {
+	t.Helper()
+	f := &fakeInstance{jwt: jwt, auth: map[string]string{}, cookies: map[string]string{}, payloads: map[string][]byte{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeInstance) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	f.auth[r.URL.Path] = r.Header.Get("Authorization")
+	if c, err := r.Cookie("jwt"); err == nil {
+		f.cookies[r.URL.Path] = c.Value
+	}
+	f.payloads[r.URL.Path] = body
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/api/v3/user/login":
+		var login loginRequest
+		json.Unmarshal(body, &login)
+		if login.UsernameOrEmail != "alice" || login.Password != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"incorrect_login"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(loginResponse{JWT: f.jwt})
+	case "/api/v3/community":
+		if r.URL.Query().Get("name") != "golang" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"couldnt_find_community"}`))
+			return
+		}
+		w.Write([]byte(`{"community_view":{"community":{"id":42}}}`))
+	case "/pictrs/image":
+		w.Write([]byte(`{"msg":"ok","files":[{"file":"abc.png"}]}`))
+	case "/api/v3/post":
+		w.Write([]byte(`{"post_view":{"post":{"id":7,"ap_id":"https://lemmy.example/post/7"}}}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeInstance) client(username, password string) *Client {
+	return NewWithConfig(context.Background(), Config{Instance: f.URL + "/", Username: username, Password: password})
+}
+
+func (f *fakeInstance) createPost(t *testing.T) createPostRequest {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var payload createPostRequest
+	if err := json.Unmarshal(f.payloads["/api/v3/post"], &payload); err != nil {
+		t.Fatalf("decode create-post payload: %v", err)
+	}
+	return payload
+}
+
+func token(s string) *string { return &s }
+
+func TestLoginUsesJWT(t *testing.T) {
+	srv := newFakeInstance(t, token("jwt-123"))
+	c := srv.client("alice", "secret")
+
+	res, err := c.Post(context.Background(), xpost.Request{Message: "Hello Lemmy\nThe body", Community: "!golang"})
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if res.ID != "7" || res.URL != "https://lemmy.example/post/7" {
+		t.Errorf("result = %+v", res)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if got := srv.auth["/api/v3/user/login"]; got != "" {
+		t.Errorf("login sent Authorization %q before having a token", got)
+	}
+	for _, path := range []string{"/api/v3/community", "/api/v3/post"} {
+		if got := srv.auth[path]; got != "Bearer jwt-123" {
+			t.Errorf("%s Authorization = %q, want Bearer jwt-123", path, got)
+		}
+		if got := srv.cookies[path]; got != "jwt-123" {
+			t.Errorf("%s jwt cookie = %q, want jwt-123", path, got)
+		}
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		jwt      *string
+		password string
+		want     string
+	}{
+		{name: "wrong password", jwt: token("jwt-123"), password: "nope", want: "incorrect_login (HTTP 400)"},
+		{name: "no token", jwt: nil, password: "secret", want: "no token returned"},
+		{name: "empty token", jwt: token(""), password: "secret", want: "no token returned"},
+	}
+	for _, tt := range tests {
+		srv := newFakeInstance(t, tt.jwt)
+		_, err := srv.client("alice", tt.password).Post(context.Background(), xpost.Request{Message: "Hello Lemmy", Community: "golang"})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+		srv.mu.Lock()
+		if _, posted := srv.payloads["/api/v3/post"]; posted {
+			t.Errorf("%s: post created without a session", tt.name)
+		}
+		srv.mu.Unlock()
+	}
+}
+
+func TestCreatePostPayload(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "shot.png")
+	if err := os.WriteFile(image, []byte("\x89PNG\r\n\x1a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		req  xpost.Request
+		want createPostRequest
+	}{
+		{
+			name: "title and body",
+			req:  xpost.Request{Message: "  Release 1.2\n\nWhat changed:\n- more  "},
+			want: createPostRequest{Name: "Release 1.2", CommunityID: 42, Body: "What changed:\n- more"},
+		},
+		{
+			name: "link as url",
+			req:  xpost.Request{Message: "Release 1.2", Link: "https://example.com/notes"},
+			want: createPostRequest{Name: "Release 1.2", CommunityID: 42, URL: "https://example.com/notes"},
+		},
+		{
+			name: "image as url",
+			req:  xpost.Request{Message: "Screenshot", Images: []xpost.ImageAttachment{{Path: image, Alt: "The UI"}}},
+			want: createPostRequest{Name: "Screenshot", CommunityID: 42, URL: "{server}/pictrs/image/abc.png", AltText: "The UI"},
+		},
+		{
+			name: "image in body when there is a link",
+			req: xpost.Request{
+				Message: "Screenshot\nBody",
+				Link:    "https://example.com",
+				Images:  []xpost.ImageAttachment{{Path: image, Alt: "The UI"}},
+			},
+			want: createPostRequest{Name: "Screenshot", CommunityID: 42, URL: "https://example.com", Body: "Body\n\n![The UI]({server}/pictrs/image/abc.png)"},
+		},
+	}
+	for _, tt := range tests {
+		srv := newFakeInstance(t, token("jwt-123"))
+		tt.req.Community = "golang"
+		if _, err := srv.client("alice", "secret").Post(context.Background(), tt.req); err != nil {
+			t.Errorf("%s: Post: %v", tt.name, err)
+			continue
+		}
+		want := tt.want
+		want.URL = strings.ReplaceAll(want.URL, "{server}", srv.URL)
+		want.Body = strings.ReplaceAll(want.Body, "{server}", srv.URL)
+		if got := srv.createPost(t); got != want {
+			t.Errorf("%s: payload = %+v, want %+v", tt.name, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := NewValidator()
+	tests := []struct {
+		name    string
+		req     xpost.Request
+		wantErr string
+	}{
+		{name: "ok", req: xpost.Request{Message: "Title\nBody", Community: "golang"}},
+		{name: "no community", req: xpost.Request{Message: "Title"}, wantErr: "community is required"},
+		{name: "short title", req: xpost.Request{Message: "Hi\nBody", Community: "golang"}, wantErr: "title (first line) must be 3-200 characters, got 2"},
+		{name: "reply", req: xpost.Request{Message: "Title", Community: "golang", ReplyTo: "1"}, wantErr: "replies are not supported"},
+	}
+	for _, tt := range tests {
+		err := c.Validate(tt.req)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{name: "default", want: xpost.DefaultHTTPTimeout},
+		{name: "configured", timeout: 2 * time.Minute, want: 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		poster, err := New(context.Background(), &config.Config{
+			Lemmy:       config.Lemmy{Instance: "https://lemmy.example", Username: "me", Password: "secret"},
+			HTTPTimeout: tt.timeout,
+		})
+		if err != nil {
+			t.Fatalf("%s: New: %v", tt.name, err)
+		}
+		if got := poster.(*Client).http.Timeout; got != tt.want {
+			t.Errorf("%s: timeout = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
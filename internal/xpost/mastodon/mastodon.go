@@ -4,10 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
 	"github.com/blacktop/xpost/internal/xpost"
 	mastodonapi "github.com/mattn/go-mastodon"
 )
@@ -18,68 +27,313 @@ const (
 	envClientID     = "XPOST_MASTODON_CLIENT_ID"
 	envClientSecret = "XPOST_MASTODON_CLIENT_SECRET"
 
-	providerName   = "mastodon"
-	requestTimeout = 30 * time.Second
-	maxChars       = 500 // Mastodon's default post character limit
+	providerName  = "mastodon"
+	maxChars      = 500      // Mastodon's default post character limit
+	maxAltChars   = 1500     // Mastodon's image description limit
+	maxImages     = 4        // Mastodon's default attachments per status
+	maxImageBytes = 16 << 20 // Mastodon's default image upload size limit
+	maxVideoBytes = 99 << 20 // Mastodon's default video upload size limit
+
+	videoUploadTimeout = 5 * time.Minute
+	mediaPollInterval  = 2 * time.Second
+	minScheduleLead    = 5 * time.Minute // Mastodon rejects scheduled_at sooner than this
 )
 
+// mediaTypes are the MIME types accepted as an explicit media type override.
+var mediaTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "video/mp4", "video/quicktime"}
+
+// pollLimits are Mastodon's default constraints on polls.
+var pollLimits = xpost.PollLimits{
+	MinOptions:     2,
+	MaxOptions:     4,
+	MaxOptionChars: 50,
+	MinDuration:    5 * time.Minute,
+	MaxDuration:    2629746 * time.Second, // a month
+}
+
+// Visibilities are the accepted values for xpost.Request.Visibility.
+var Visibilities = []string{"public", "unlisted", "private", "direct"}
+
 // Config contains the settings needed to reach a Mastodon server.
 type Config struct {
 	Server       string
 	AccessToken  string
 	ClientID     string
 	ClientSecret string
+
+	// Timeout bounds each request; zero means xpost.DefaultHTTPTimeout.
+	Timeout time.Duration
 }
 
 // Client wraps the Mastodon API client with xpost semantics.
 type Client struct {
 	client *mastodonapi.Client
+	// maxChars is the instance's post character limit, read once at
+	// construction; zero means Mastodon's default.
+	maxChars int
+
+	// instance is the server's /api/v1/instance response, or instanceErr
+	// why it could not be read; fetched once by instanceOnce.
+	instanceOnce sync.Once
+	instance     *mastodonapi.Instance
+	instanceErr  error
+}
+
+func init() {
+	xpost.Register(providerName, New, xpost.Info{
+		Label: "Mastodon",
+		Icon:  "\uedc0",
+		Color: 63,
+		Features: xpost.Features{
+			Visibility:     true,
+			ContentWarning: true,
+			Language:       true,
+			Poll:           true,
+			Schedule:       true,
+			LiveLimit:      true,
+			NoQuote:        true,
+		},
+	})
+	xpost.RegisterValidator(providerName, NewValidator)
+	config.RegisterSection(providerName, config.Section{
+		Options: func(c *config.Config) *config.Options { return &c.Mastodon.Options },
+		Vars: func(c *config.Config) map[string]*string {
+			return map[string]*string{
+				envServer:       &c.Mastodon.Server,
+				envAccessToken:  &c.Mastodon.AccessToken,
+				envClientID:     &c.Mastodon.ClientID,
+				envClientSecret: &c.Mastodon.ClientSecret,
+			}
+		},
+		Account: func(c, acct *config.Config, name string) bool {
+			a, ok := c.Mastodon.Accounts[name]
+			acct.Mastodon = a
+			if ok && len(a.CWHashtags) > 0 {
+				tags := maps.Clone(c.Mastodon.CWHashtags)
+				if tags == nil {
+					tags = map[string]string{}
+				}
+				maps.Copy(tags, a.CWHashtags)
+				c.Mastodon.CWHashtags = tags
+			}
+			return ok
+		},
+	})
 }
 
-// New constructs a Mastodon poster based on environment configuration.
-func New(ctx context.Context) (xpost.Poster, error) {
-	cfg, err := loadConfigFromEnv()
+// New constructs a Mastodon poster from its section of the resolved config.
+func New(ctx context.Context, settings *config.Config) (xpost.Poster, error) {
+	cfg, err := loadConfig(settings.Mastodon)
 	if err != nil {
 		return nil, err
 	}
+	cfg.Timeout = settings.HTTPTimeout
 
+	return NewWithConfig(ctx, cfg), nil
+}
+
+// NewWithConfig constructs a Mastodon client from explicit settings.
+func NewWithConfig(ctx context.Context, cfg Config) *Client {
 	mastodonClient := mastodonapi.NewClient(&mastodonapi.Config{
 		Server:       cfg.Server,
 		AccessToken:  cfg.AccessToken,
 		ClientID:     cfg.ClientID,
 		ClientSecret: cfg.ClientSecret,
 	})
-	mastodonClient.Timeout = requestTimeout
+	mastodonClient.Timeout = xpost.HTTPTimeout(cfg.Timeout)
+
+	c := &Client{client: mastodonClient}
+	caps, err := c.InstanceCapabilities(ctx, xpost.Capabilities{MaxChars: maxChars})
+	if err != nil {
+		logutil.Debugf("mastodon: using the default %d character limit: %v", maxChars, err)
+	}
+	c.maxChars = caps.MaxChars
+	return c
+}
 
-	return &Client{client: mastodonClient}, nil
+// charLimit returns the instance's post character limit.
+func (c *Client) charLimit() int {
+	if c.maxChars > 0 {
+		return c.maxChars
+	}
+	return maxChars
+}
+
+// NewValidator returns a Mastodon validator that needs no credentials.
+func NewValidator() xpost.Validator {
+	return &Client{}
 }
 
 // Name identifies the provider.
 func (c *Client) Name() string { return providerName }
 
-// Validate checks if the request meets Mastodon's constraints.
-func (c *Client) Validate(req xpost.Request) error {
+// Verify checks the credentials without posting and returns the account handle.
+func (c *Client) Verify(ctx context.Context) (string, error) {
+	account, err := c.client.GetAccountCurrentUser(ctx)
+	if err != nil {
+		return "", fmt.Errorf("verify credentials: %w", unwrapMastodonError(err))
+	}
+	return "@" + account.Acct, nil
+}
+
+// Capabilities reads the instance's limits from its configuration, falling
+// back to Mastodon's defaults for anything it does not publish.
+func (c *Client) Capabilities(ctx context.Context) (xpost.Capabilities, error) {
+	return c.InstanceCapabilities(ctx, xpost.Capabilities{
+		MaxChars:      c.charLimit(),
+		Unit:          "characters",
+		MaxImages:     maxImages,
+		MaxImageBytes: maxImageBytes,
+		MediaTypes:    mediaTypes,
+		MaxAltChars:   maxAltChars,
+		Features:      []string{"content warnings", "visibility (" + strings.Join(Visibilities, ", ") + ")", "polls"},
+	})
+}
+
+// InstanceCapabilities overrides defaults with the limits the server reports
+// in /api/v1/instance. Pixelfed serves the same endpoint. The instance is
+// fetched once, when the client is constructed, and reused after that.
+func (c *Client) InstanceCapabilities(ctx context.Context, defaults xpost.Capabilities) (xpost.Capabilities, error) {
+	c.instanceOnce.Do(func() {
+		c.instance, c.instanceErr = c.client.GetInstance(ctx)
+	})
+	instance, err := c.instance, c.instanceErr
+	if err != nil {
+		return defaults, fmt.Errorf("get instance: %w", err)
+	}
+	caps := defaults
+	conf := instance.Configuration
+	if conf == nil {
+		return caps, nil
+	}
+
+	if conf.Statuses != nil {
+		if n, ok := configInt((*conf.Statuses)["max_characters"]); ok {
+			caps.MaxChars, caps.Live = n, true
+		}
+		if n, ok := configInt((*conf.Statuses)["max_media_attachments"]); ok {
+			caps.MaxImages, caps.Live = n, true
+		}
+	}
+	if n, ok := configInt(conf.MediaAttachments["image_size_limit"]); ok {
+		caps.MaxImageBytes, caps.Live = n, true
+	}
+	if n, ok := configInt(conf.MediaAttachments["description_limit"]); ok {
+		caps.MaxAltChars, caps.Live = n, true
+	}
+	if types, ok := conf.MediaAttachments["supported_mime_types"].([]any); ok {
+		var images []string
+		for _, t := range types {
+			if s, ok := t.(string); ok && strings.HasPrefix(s, "image/") {
+				images = append(images, s)
+			}
+		}
+		if len(images) > 0 {
+			caps.MediaTypes, caps.Live = images, true
+		}
+	}
+	return caps, nil
+}
+
+// configInt reads a number from decoded instance configuration JSON.
+func configInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), n > 0
+	case string:
+		i, err := strconv.Atoi(n)
+		return i, err == nil && i > 0
+	}
+	return 0, false
+}
+
+// Truncate shortens the message so the status, including any link, fits
+// within the character limit.
+func (c *Client) Truncate(req xpost.Request) (xpost.Request, string) {
+	// The content warning counts towards the limit too.
+	limit := c.charLimit() - utf8.RuneCountInString(req.ContentWarning)
+	if req.Link != "" {
+		limit -= utf8.RuneCountInString("\n\n" + req.Link)
+	}
+	var removed string
+	req.Message, removed = xpost.TruncateText(req.Message, limit, utf8.RuneCountInString)
+	return req, removed
+}
+
+// TruncateAlt shortens the image description to Mastodon's limit.
+func (c *Client) TruncateAlt(alt string) (string, string) {
+	return xpost.TruncateText(alt, maxAltChars, utf8.RuneCountInString)
+}
+
+// Count measures the status, link and content warning together, as Mastodon
+// does.
+func (c *Client) Count(req xpost.Request) (int, int, string) {
 	text := req.Message
 	if req.Link != "" {
 		text = text + "\n\n" + req.Link
 	}
-	count := len([]rune(text))
-	if count > maxChars {
+	return utf8.RuneCountInString(text) + utf8.RuneCountInString(req.ContentWarning), c.charLimit(), "characters"
+}
+
+// Validate checks if the request meets Mastodon's constraints.
+func (c *Client) Validate(req xpost.Request) error {
+	if len(req.Images) > maxImages {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("too many images: %d (max %d)", len(req.Images), maxImages)}
+	}
+	for _, img := range req.Images {
+		if img.MediaType != "" && !slices.Contains(mediaTypes, img.MediaType) {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type %q", img.MediaType)}
+		}
+		if img.IsVideo() {
+			if len(req.Images) > 1 {
+				return xpost.ValidationError{Provider: providerName, Reason: "a video cannot be combined with other media"}
+			}
+			if err := xpost.CheckVideo(providerName, img, maxVideoBytes, 0); err != nil {
+				return err
+			}
+		}
+		if count := utf8.RuneCountInString(img.Alt); count > maxAltChars {
+			return xpost.ValidationError{
+				Provider: providerName,
+				Reason:   fmt.Sprintf("image description too long: %d characters (max %d)", count, maxAltChars),
+			}
+		}
+	}
+	if req.Visibility != "" && !slices.Contains(Visibilities, req.Visibility) {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported visibility %q", req.Visibility)}
+	}
+	if req.Poll != nil {
+		if len(req.Images) > 0 {
+			return xpost.ValidationError{Provider: providerName, Reason: "a poll cannot be combined with images"}
+		}
+		if err := xpost.CheckPoll(providerName, req.Poll, pollLimits); err != nil {
+			return err
+		}
+	}
+	if !req.ScheduledAt.IsZero() {
+		if time.Until(req.ScheduledAt) < minScheduleLead {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("scheduled time must be at least %s in the future", minScheduleLead)}
+		}
+		if req.AltReply() != "" {
+			return xpost.ValidationError{Provider: providerName, Reason: "image description replies cannot be scheduled"}
+		}
+	}
+	if count, limit, _ := c.Count(req); count > limit {
 		return xpost.ValidationError{
 			Provider: providerName,
-			Reason:   fmt.Sprintf("message too long: %d characters (max %d)", count, maxChars),
+			Reason:   fmt.Sprintf("message too long: %d characters (max %d)", count, limit),
 		}
 	}
 	return nil
 }
 
 // Post publishes a new toot to the configured Mastodon instance.
-func (c *Client) Post(ctx context.Context, req xpost.Request) error {
+func (c *Client) Post(ctx context.Context, req xpost.Request) (xpost.PostResult, error) {
 	var mediaIDs []mastodonapi.ID
-	if req.ImagePath != "" {
-		attachment, err := c.uploadMedia(ctx, req.ImagePath, req.ImageAlt)
+	for _, img := range req.Images {
+		attachment, err := c.uploadMedia(ctx, img.Path, img.Alt, img.MediaType)
 		if err != nil {
-			return err
+			return xpost.PostResult{}, err
 		}
 		mediaIDs = append(mediaIDs, attachment.ID)
 	}
@@ -90,18 +344,192 @@ func (c *Client) Post(ctx context.Context, req xpost.Request) error {
 		status = status + "\n\n" + req.Link
 	}
 
-	_, err := c.client.PostStatus(ctx, &mastodonapi.Toot{
-		Status:   status,
-		MediaIDs: mediaIDs,
-	})
+	toot := &mastodonapi.Toot{
+		Status:      status,
+		MediaIDs:    mediaIDs,
+		SpoilerText: req.ContentWarning,
+		Sensitive:   req.ContentWarning != "",
+		Visibility:  req.Visibility,
+		Language:    req.Language,
+	}
+	if req.ReplyTo != "" {
+		parentID, err := c.resolveStatusID(ctx, req.ReplyTo)
+		if err != nil {
+			return xpost.PostResult{}, fmt.Errorf("resolve reply target: %w", err)
+		}
+		toot.InReplyToID = parentID
+	}
+	if req.Poll != nil {
+		toot.Poll = &mastodonapi.TootPoll{
+			Options:          req.Poll.Options,
+			ExpiresInSeconds: int64(req.Poll.Duration / time.Second),
+		}
+	}
+
+	if !req.ScheduledAt.IsZero() {
+		at := req.ScheduledAt.UTC()
+		toot.ScheduledAt = &at
+	}
+
+	posted, err := c.client.PostStatus(ctx, toot)
+	if err != nil {
+		return xpost.PostResult{}, fmt.Errorf("post status: %w", unwrapMastodonError(err))
+	}
+	if toot.ScheduledAt != nil {
+		// The response is a scheduled status, which has no URL yet.
+		return xpost.PostResult{Provider: providerName, ID: string(posted.ID), Scheduled: true}, nil
+	}
+
+	result := xpost.PostResult{Provider: providerName, ID: string(posted.ID), URL: posted.URL}
+
+	if reply := req.AltReply(); reply != "" && len(mediaIDs) > 0 {
+		// Long descriptions continue across a chain of replies.
+		parentID := posted.ID
+		for _, part := range xpost.SplitText(reply, c.charLimit(), utf8.RuneCountInString) {
+			status, err := c.client.PostStatus(ctx, &mastodonapi.Toot{
+				Status:      part,
+				InReplyToID: parentID,
+				Visibility:  posted.Visibility,
+			})
+			if err != nil {
+				return result, xpost.Partial(fmt.Errorf("post image description reply: %w", unwrapMastodonError(err)))
+			}
+			parentID = status.ID
+		}
+	}
+
+	return result, nil
+}
+
+// Edit updates the status with the given ID. Its images and content warning
+// are kept unless req replaces them; its visibility cannot change.
+func (c *Client) Edit(ctx context.Context, id string, req xpost.Request) (xpost.PostResult, error) {
+	current, err := c.client.GetStatus(ctx, mastodonapi.ID(id))
+	if err != nil {
+		return xpost.PostResult{}, fmt.Errorf("get status: %w", unwrapMastodonError(err))
+	}
+
+	var mediaIDs []mastodonapi.ID
+	for _, attachment := range current.MediaAttachments {
+		mediaIDs = append(mediaIDs, attachment.ID)
+	}
+	if len(req.Images) > 0 {
+		mediaIDs = nil
+		for _, img := range req.Images {
+			attachment, err := c.uploadMedia(ctx, img.Path, img.Alt, img.MediaType)
+			if err != nil {
+				return xpost.PostResult{}, err
+			}
+			mediaIDs = append(mediaIDs, attachment.ID)
+		}
+	}
+
+	status := req.Message
+	if req.Link != "" {
+		status = status + "\n\n" + req.Link
+	}
+	spoiler := current.SpoilerText
+	if req.ContentWarning != "" {
+		spoiler = req.ContentWarning
+	}
+
+	edited, err := c.client.UpdateStatus(ctx, &mastodonapi.Toot{
+		Status:      status,
+		MediaIDs:    mediaIDs,
+		SpoilerText: spoiler,
+		Sensitive:   spoiler != "" || current.Sensitive,
+		Language:    req.Language,
+	}, mastodonapi.ID(id))
 	if err != nil {
-		return fmt.Errorf("post status: %w", err)
+		return xpost.PostResult{}, fmt.Errorf("edit status: %w", unwrapMastodonError(err))
 	}
+	return xpost.PostResult{Provider: providerName, ID: string(edited.ID), URL: edited.URL}, nil
+}
 
+// Delete removes the status with the given ID.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	if err := c.client.DeleteStatus(ctx, mastodonapi.ID(id)); err != nil {
+		return fmt.Errorf("delete status: %w", unwrapMastodonError(err))
+	}
 	return nil
 }
 
-func (c *Client) uploadMedia(ctx context.Context, path, alt string) (*mastodonapi.Attachment, error) {
+// ReadBack fetches the status without credentials to check it is public.
+func (c *Client) ReadBack(ctx context.Context, res xpost.PostResult) (xpost.ReadBack, error) {
+	anon := mastodonapi.NewClient(&mastodonapi.Config{Server: c.client.Config.Server})
+	anon.Timeout = c.client.Timeout
+
+	status, err := anon.GetStatus(ctx, mastodonapi.ID(res.ID))
+	if err != nil {
+		var apiErr *mastodonapi.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return xpost.ReadBack{Detail: "status is not visible to logged-out readers"}, nil
+		}
+		return xpost.ReadBack{}, fmt.Errorf("read back status: %w", err)
+	}
+	if status.Visibility != "public" {
+		return xpost.ReadBack{Visible: true, Detail: fmt.Sprintf("visibility is %s", status.Visibility)}, nil
+	}
+	return xpost.ReadBack{Visible: true}, nil
+}
+
+// Repost reblogs the status at ref.
+func (c *Client) Repost(ctx context.Context, ref string) error {
+	id, err := c.resolveStatusID(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("resolve status: %w", err)
+	}
+	if _, err := c.client.Reblog(ctx, id); err != nil {
+		return fmt.Errorf("reblog status: %w", unwrapMastodonError(err))
+	}
+	return nil
+}
+
+// React favourites the status with the given URL or ID. Mastodon has no
+// emoji reactions, so emoji is ignored.
+func (c *Client) React(ctx context.Context, ref, emoji string) error {
+	id, err := c.resolveStatusID(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("resolve status: %w", err)
+	}
+	if _, err := c.client.Favourite(ctx, id); err != nil {
+		return fmt.Errorf("favourite status: %w", unwrapMastodonError(err))
+	}
+	return nil
+}
+
+// resolveStatusID maps a status URL (or bare ID) to the ID this server knows
+// it by. Links to statuses on other instances are resolved through search,
+// which federates the status in if needed.
+func (c *Client) resolveStatusID(ctx context.Context, ref string) (mastodonapi.ID, error) {
+	ref = strings.TrimSpace(ref)
+	u, err := url.Parse(ref)
+	if err != nil || u.Host == "" {
+		if ref == "" || strings.ContainsAny(ref, "/ ") {
+			return "", fmt.Errorf("invalid status reference %q", ref)
+		}
+		return mastodonapi.ID(ref), nil
+	}
+
+	if server, err := url.Parse(c.client.Config.Server); err == nil && strings.EqualFold(server.Host, u.Host) {
+		// Local statuses live at /@user/<id> or /users/user/statuses/<id>.
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if id := parts[len(parts)-1]; len(parts) >= 2 && id != "" && strings.Trim(id, "0123456789") == "" {
+			return mastodonapi.ID(id), nil
+		}
+	}
+
+	results, err := c.client.Search(ctx, ref, true)
+	if err != nil {
+		return "", fmt.Errorf("search %s: %w", ref, unwrapMastodonError(err))
+	}
+	if len(results.Statuses) == 0 {
+		return "", fmt.Errorf("status %s not found", ref)
+	}
+	return results.Statuses[0].ID, nil
+}
+
+func (c *Client) uploadMedia(ctx context.Context, path, alt, mediaType string) (*mastodonapi.Attachment, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -111,23 +539,84 @@ func (c *Client) uploadMedia(ctx context.Context, path, alt string) (*mastodonap
 	}
 	defer file.Close()
 
-	attachment, err := c.client.UploadMediaFromMedia(ctx, &mastodonapi.Media{
+	// go-mastodon doesn't expose the part's Content-Type and Mastodon sniffs
+	// uploads server-side, so an override can only be checked in Validate.
+	if mediaType != "" {
+		logutil.Debugf("mastodon: media type %s is detected server-side", mediaType)
+	}
+
+	uploader := c.client
+	if (xpost.ImageAttachment{Path: path, MediaType: mediaType}).IsVideo() {
+		// Videos are large enough to outlast the usual request timeout.
+		client := *c.client
+		client.Timeout = max(videoUploadTimeout, c.client.Timeout)
+		uploader = &client
+	}
+	attachment, err := uploader.UploadMediaFromMedia(ctx, &mastodonapi.Media{
 		File:        file,
 		Description: alt,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("upload media: %w", err)
+		return nil, fmt.Errorf("upload media: %w", unwrapMastodonError(err))
+	}
+	if attachment.URL == "" {
+		// Videos are processed after the upload returns and cannot be
+		// attached until that finishes.
+		if err := c.waitForMedia(ctx, attachment.ID); err != nil {
+			return nil, err
+		}
 	}
 
 	return attachment, nil
 }
 
-func loadConfigFromEnv() (Config, error) {
+// waitForMedia polls the attachment until the server has finished
+// processing it.
+func (c *Client) waitForMedia(ctx context.Context, id mastodonapi.ID) error {
+	endpoint, err := url.JoinPath(c.client.Config.Server, "api/v1/media", string(id))
+	if err != nil {
+		return fmt.Errorf("media status: %w", err)
+	}
+
+	for {
+		logutil.Debugf("mastodon: waiting for media %s to be processed", id)
+		timer := time.NewTimer(mediaPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return fmt.Errorf("media status: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+c.client.Config.AccessToken)
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("media status: %w", err)
+		}
+		resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return nil
+		case http.StatusPartialContent: // still processing
+		default:
+			return fmt.Errorf("media status: %s", resp.Status)
+		}
+	}
+}
+
+// loadConfig checks that the Mastodon section has the credentials needed to
+// post.
+func loadConfig(section config.Mastodon) (Config, error) {
 	cfg := Config{
-		Server:       strings.TrimSpace(os.Getenv(envServer)),
-		AccessToken:  strings.TrimSpace(os.Getenv(envAccessToken)),
-		ClientID:     strings.TrimSpace(os.Getenv(envClientID)),
-		ClientSecret: strings.TrimSpace(os.Getenv(envClientSecret)),
+		Server:       strings.TrimSpace(section.Server),
+		AccessToken:  strings.TrimSpace(section.AccessToken),
+		ClientID:     strings.TrimSpace(section.ClientID),
+		ClientSecret: strings.TrimSpace(section.ClientSecret),
 	}
 
 	var missing []string
@@ -144,3 +633,32 @@ func loadConfigFromEnv() (Config, error) {
 
 	return cfg, nil
 }
+
+// apiError shows a Mastodon API error as the server's message while keeping
+// the library error reachable with errors.As.
+type apiError struct{ err *mastodonapi.APIError }
+
+func (e apiError) Error() string { return summarizeMastodonError(e.err) }
+func (e apiError) Unwrap() error { return e.err }
+
+// unwrapMastodonError replaces the library's generic "bad request" wording
+// with the server's error message and HTTP status, and marks transient
+// failures as retryable.
+func unwrapMastodonError(err error) error {
+	var apiErr *mastodonapi.APIError
+	if errors.As(err, &apiErr) && apiErr != nil {
+		return xpost.Retryable(apiError{err: apiErr}, apiErr.StatusCode, 0)
+	}
+	return xpost.Retryable(err, 0, 0)
+}
+
+func summarizeMastodonError(err *mastodonapi.APIError) string {
+	status := fmt.Sprintf("HTTP %d", err.StatusCode)
+	if text := http.StatusText(err.StatusCode); text != "" {
+		status += " " + text
+	}
+	if err.Message == "" {
+		return status
+	}
+	return fmt.Sprintf("%s (%s)", err.Message, status)
+}
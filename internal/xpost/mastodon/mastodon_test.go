@@ -0,0 +1,539 @@
+package mastodon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// fakeServer is a minimal Mastodon API that records the statuses and media
+// it receives.
+type fakeServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []url.Values // form of each status created or edited
+	media    []url.Values // fields of each media upload, plus its file name
+	actions  []string     // paths of reblogs and favourites
+	// fail maps "METHOD path" to the status code returned for it.
+	fail map[string]int
+	// visibility is what fetched statuses report; public when empty.
+	visibility string
+	// instance is the /api/v1/instance response; not found when empty.
+	instance string
+	// instanceHits counts the /api/v1/instance requests.
+	instanceHits int
+	// failReplies is the status code returned for statuses posted in reply;
+	// zero means they succeed.
+	failReplies int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	f := &fakeServer{fail: map[string]int{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if code, ok := f.fail[r.Method+" "+r.URL.Path]; ok {
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Validation failed: simulated"})
+		return
+	}
+
+	if r.URL.Path == "/api/v1/instance" {
+		f.instanceHits++
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v2/media":
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fields := url.Values(r.MultipartForm.Value)
+		if files := r.MultipartForm.File["file"]; len(files) > 0 {
+			fields.Set("filename", files[0].Filename)
+		}
+		f.media = append(f.media, fields)
+		id := fmt.Sprintf("media-%d", len(f.media))
+		json.NewEncoder(w).Encode(map[string]string{"id": id, "url": f.URL + "/media/" + id})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/statuses",
+		r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/api/v1/statuses/"):
+		r.ParseForm()
+		if f.failReplies != 0 && r.PostForm.Get("in_reply_to_id") != "" {
+			w.WriteHeader(f.failReplies)
+			json.NewEncoder(w).Encode(map[string]string{"error": "simulated"})
+			return
+		}
+		f.statuses = append(f.statuses, r.PostForm)
+		id := fmt.Sprint(100 + len(f.statuses))
+		if r.Method == http.MethodPut {
+			id = strings.TrimPrefix(r.URL.Path, "/api/v1/statuses/")
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":         id,
+			"url":        f.URL + "/@me/" + id,
+			"visibility": "public",
+		})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/statuses/"):
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/statuses/")
+		visibility := f.visibility
+		if visibility == "" {
+			visibility = "public"
+		}
+		json.NewEncoder(w).Encode(map[string]any{"id": id, "url": f.URL + "/@me/" + id, "visibility": visibility})
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/v1/statuses/"):
+		// reblog and favourite
+		f.actions = append(f.actions, r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]any{"id": "1"})
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/instance" && f.instance != "":
+		fmt.Fprint(w, f.instance)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v2/search":
+		// Any remote status federates in as 999.
+		json.NewEncoder(w).Encode(map[string]any{"statuses": []map[string]any{{"id": "999"}}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// recorded returns copies of the status forms and media uploads received.
+func (f *fakeServer) recorded() ([]url.Values, []url.Values) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]url.Values(nil), f.statuses...), append([]url.Values(nil), f.media...)
+}
+
+func (f *fakeServer) client(t *testing.T) *Client {
+	t.Helper()
+	return NewWithConfig(context.Background(), Config{Server: f.URL, AccessToken: "token"})
+}
+
+// writeFile creates a file named name with placeholder contents.
+func writeFile(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("not really "+name), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPostAltAsReply(t *testing.T) {
+	srv := newFakeServer(t)
+	c := srv.client(t)
+
+	res, err := c.Post(context.Background(), xpost.Request{
+		Message:    "Sunset",
+		Images:     []xpost.ImageAttachment{{Path: writeFile(t, "a.png"), Alt: "An orange sky over the sea"}},
+		AltAsReply: true,
+		Visibility: "unlisted",
+	})
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	statuses, media := srv.recorded()
+	if len(media) != 1 || len(statuses) != 2 {
+		t.Fatalf("got %d uploads and %d statuses, want 1 and 2", len(media), len(statuses))
+	}
+	if got := statuses[0].Get("media_ids[]"); got != "media-1" {
+		t.Errorf("post media_ids = %q, want media-1", got)
+	}
+	reply := statuses[1]
+	if reply.Get("in_reply_to_id") != res.ID {
+		t.Errorf("reply in_reply_to_id = %q, want the post %q", reply.Get("in_reply_to_id"), res.ID)
+	}
+	if want := "Image description: An orange sky over the sea"; reply.Get("status") != want {
+		t.Errorf("reply status = %q, want %q", reply.Get("status"), want)
+	}
+	if reply.Has("media_ids[]") {
+		t.Error("reply should not carry media")
+	}
+}
+
+func TestPostNoAltReplyWithoutFlag(t *testing.T) {
+	srv := newFakeServer(t)
+	c := srv.client(t)
+
+	if _, err := c.Post(context.Background(), xpost.Request{
+		Message: "Sunset",
+		Images:  []xpost.ImageAttachment{{Path: writeFile(t, "a.png"), Alt: "An orange sky"}},
+	}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if statuses, _ := srv.recorded(); len(statuses) != 1 {
+		t.Errorf("got %d statuses, want only the post", len(statuses))
+	}
+}
+
+func TestValidateMediaType(t *testing.T) {
+	c := NewValidator()
+	for _, tt := range []struct {
+		mediaType string
+		ok        bool
+	}{
+		{mediaType: "image/png", ok: true},
+		{mediaType: "image/tiff"},
+		{mediaType: "application/pdf"},
+	} {
+		err := c.Validate(xpost.Request{Message: "hi", Images: []xpost.ImageAttachment{{Path: "a.bin", MediaType: tt.mediaType}}})
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("Validate(%s) = %v, want ok %v", tt.mediaType, err, tt.ok)
+		}
+	}
+}
+
+func TestResolveStatusID(t *testing.T) {
+	srv := newFakeServer(t)
+	c := srv.client(t)
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "109876", want: "109876"},
+		{ref: srv.URL + "/@me/109876", want: "109876"},
+		{ref: srv.URL + "/users/me/statuses/109876", want: "109876"},
+		{ref: "https://other.example/@bob/1", want: "999"},
+		{ref: "", wantErr: true},
+		{ref: "not an id", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := c.resolveStatusID(context.Background(), tt.ref)
+		if (err != nil) != tt.wantErr || string(got) != tt.want {
+			t.Errorf("resolveStatusID(%q) = %q, %v; want %q, error %v", tt.ref, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPostReplyTo(t *testing.T) {
+	srv := newFakeServer(t)
+	if _, err := srv.client(t).Post(context.Background(), xpost.Request{Message: "Agreed", ReplyTo: "https://other.example/@bob/1"}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if statuses, _ := srv.recorded(); statuses[0].Get("in_reply_to_id") != "999" {
+		t.Errorf("in_reply_to_id = %q, want the federated status 999", statuses[0].Get("in_reply_to_id"))
+	}
+}
+
+func TestRepost(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{ref: "109876", want: "/api/v1/statuses/109876/reblog"},
+		{ref: "https://other.example/@bob/1", want: "/api/v1/statuses/999/reblog"},
+	}
+	for _, tt := range tests {
+		srv := newFakeServer(t)
+		if err := srv.client(t).Repost(context.Background(), tt.ref); err != nil {
+			t.Errorf("Repost(%q): %v", tt.ref, err)
+			continue
+		}
+		srv.mu.Lock()
+		if len(srv.actions) != 1 || srv.actions[0] != tt.want {
+			t.Errorf("Repost(%q) called %v, want %s", tt.ref, srv.actions, tt.want)
+		}
+		srv.mu.Unlock()
+	}
+}
+
+func TestPostKeepsMediaOrder(t *testing.T) {
+	srv := newFakeServer(t)
+	names := []string{"a.png", "b.png", "c.png"}
+	var images []xpost.ImageAttachment
+	for _, name := range names {
+		images = append(images, xpost.ImageAttachment{Path: writeFile(t, name), Alt: name})
+	}
+	if _, err := srv.client(t).Post(context.Background(), xpost.Request{Message: "before and after", Images: images}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	statuses, media := srv.recorded()
+	uploaded := map[string]string{} // media ID to file name
+	for i, m := range media {
+		uploaded[fmt.Sprintf("media-%d", i+1)] = m.Get("filename")
+	}
+	ids := statuses[0]["media_ids[]"]
+	if len(ids) != len(names) {
+		t.Fatalf("media_ids[] = %v, want %d", ids, len(names))
+	}
+	for i, id := range ids {
+		if uploaded[id] != names[i] {
+			t.Errorf("media %d is %s (%s), want %s", i, id, uploaded[id], names[i])
+		}
+	}
+}
+
+func TestReadBack(t *testing.T) {
+	tests := []struct {
+		name       string
+		visibility string
+		fail       int
+		want       xpost.ReadBack
+		wantErr    bool
+	}{
+		{name: "public", want: xpost.ReadBack{Visible: true}},
+		{name: "unlisted", visibility: "unlisted", want: xpost.ReadBack{Visible: true, Detail: "visibility is unlisted"}},
+		{name: "absent", fail: http.StatusNotFound, want: xpost.ReadBack{Detail: "status is not visible to logged-out readers"}},
+		{name: "server error", fail: http.StatusInternalServerError, wantErr: true},
+	}
+	for _, tt := range tests {
+		srv := newFakeServer(t)
+		srv.visibility = tt.visibility
+		if tt.fail != 0 {
+			srv.fail["GET /api/v1/statuses/101"] = tt.fail
+		}
+		got, err := srv.client(t).ReadBack(context.Background(), xpost.PostResult{ID: "101"})
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: ReadBack = %+v, %v; want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestReact(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{ref: "109876", want: "/api/v1/statuses/109876/favourite"},
+		{ref: "https://other.example/@bob/1", want: "/api/v1/statuses/999/favourite"},
+	}
+	for _, tt := range tests {
+		srv := newFakeServer(t)
+		if err := srv.client(t).React(context.Background(), tt.ref, "🎉"); err != nil {
+			t.Errorf("React(%q): %v", tt.ref, err)
+			continue
+		}
+		srv.mu.Lock()
+		if len(srv.actions) != 1 || srv.actions[0] != tt.want {
+			t.Errorf("React(%q) called %v, want %s", tt.ref, srv.actions, tt.want)
+		}
+		srv.mu.Unlock()
+	}
+}
+
+func TestPostAltReplyFails(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.failReplies = http.StatusServiceUnavailable
+	res, err := srv.client(t).Post(context.Background(), xpost.Request{
+		Message:    "Sunset",
+		Images:     []xpost.ImageAttachment{{Path: writeFile(t, "a.png"), Alt: "The sun setting over the sea"}},
+		AltAsReply: true,
+	})
+	// The status exists, so it is returned and must not be retried.
+	if !errors.As(err, new(xpost.PartialError)) || errors.As(err, new(xpost.RetryableError)) {
+		t.Errorf("Post = %v, want a partial failure that is not retryable", err)
+	}
+	if res.ID != "101" || res.URL != srv.URL+"/@me/101" {
+		t.Errorf("Post = %+v, want the status's result", res)
+	}
+}
+
+func TestPostAltOverflowReply(t *testing.T) {
+	srv := newFakeServer(t)
+	full := strings.Repeat("A long description. ", 100)
+	if _, err := srv.client(t).Post(context.Background(), xpost.Request{
+		Message: "Sunset",
+		Images:  []xpost.ImageAttachment{{Path: writeFile(t, "a.png"), Alt: "A long…", FullAlt: full}},
+	}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	statuses, media := srv.recorded()
+	if got := media[0].Get("description"); got != "A long…" {
+		t.Errorf("image description = %q, want the short one", got)
+	}
+	// The full text spans several replies, each within the limit.
+	var reply string
+	for _, status := range statuses[1:] {
+		if n := len([]rune(status.Get("status"))); n > maxChars {
+			t.Errorf("reply of %d characters exceeds the limit", n)
+		}
+		reply += status.Get("status")
+	}
+	if len(statuses) < 3 || !strings.HasPrefix(reply, "Image description: A long description.") {
+		t.Errorf("got %d statuses with replies %q, want the full description across replies", len(statuses), reply)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	// limits are the numeric capabilities, which the instance can override.
+	type limits struct {
+		chars, images, bytes, alt int
+		live                      bool
+	}
+	defaults := limits{chars: maxChars, images: maxImages, bytes: maxImageBytes, alt: maxAltChars}
+	tests := []struct {
+		name     string
+		instance string
+		want     limits
+		types    []string
+		wantErr  bool
+	}{
+		{
+			name: "instance limits",
+			instance: `{"uri":"example.social","configuration":{` +
+				`"statuses":{"max_characters":5000,"max_media_attachments":8},` +
+				`"media_attachments":{"image_size_limit":"16777216","description_limit":1000,"supported_mime_types":["image/png","image/avif","video/mp4"]}}}`,
+			want:  limits{chars: 5000, images: 8, bytes: 16 << 20, alt: 1000, live: true},
+			types: []string{"image/png", "image/avif"},
+		},
+		{
+			name:     "partial configuration",
+			instance: `{"uri":"example.social","configuration":{"statuses":{"max_characters":1000}}}`,
+			want:     limits{chars: 1000, images: maxImages, bytes: maxImageBytes, alt: maxAltChars, live: true},
+			types:    mediaTypes,
+		},
+		{name: "no configuration", instance: `{"uri":"example.social"}`, want: defaults, types: mediaTypes},
+		{name: "lookup fails", want: defaults, types: mediaTypes, wantErr: true},
+	}
+	for _, tt := range tests {
+		srv := newFakeServer(t)
+		srv.instance = tt.instance
+		client := srv.client(t)
+		// The instance read at construction is reused, however often the
+		// capabilities are asked for.
+		for range 2 {
+			caps, err := client.Capabilities(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			got := limits{chars: caps.MaxChars, images: caps.MaxImages, bytes: caps.MaxImageBytes, alt: caps.MaxAltChars, live: caps.Live}
+			if got != tt.want || !slices.Equal(caps.MediaTypes, tt.types) {
+				t.Errorf("%s: Capabilities = %+v, want %+v with types %v", tt.name, caps, tt.want, tt.types)
+			}
+		}
+		srv.mu.Lock()
+		if srv.instanceHits != 1 {
+			t.Errorf("%s: instance fetched %d times, want once", tt.name, srv.instanceHits)
+		}
+		srv.mu.Unlock()
+	}
+}
+
+func TestPostVideoDescription(t *testing.T) {
+	srv := newFakeServer(t)
+	video := xpost.ImageAttachment{Path: writeFile(t, "clip.mp4"), Alt: "A cat chasing a laser dot"}
+	if err := NewValidator().Validate(xpost.Request{Message: "Zoomies", Images: []xpost.ImageAttachment{video}}); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if _, err := srv.client(t).Post(context.Background(), xpost.Request{Message: "Zoomies", Images: []xpost.ImageAttachment{video}}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	_, media := srv.recorded()
+	if len(media) != 1 || media[0].Get("filename") != "clip.mp4" {
+		t.Fatalf("uploads = %v, want the video", media)
+	}
+	if got := media[0].Get("description"); got != video.Alt {
+		t.Errorf("video description = %q, want %q", got, video.Alt)
+	}
+
+	video.Alt = strings.Repeat("a", maxAltChars+1)
+	if err := NewValidator().Validate(xpost.Request{Message: "Zoomies", Images: []xpost.ImageAttachment{video}}); err == nil {
+		t.Error("Validate accepted an over-long video description")
+	}
+}
+
+func TestPostLink(t *testing.T) {
+	tests := []struct {
+		name string
+		req  xpost.Request
+		want string
+	}{
+		{name: "no link", req: xpost.Request{Message: "Hello"}, want: "Hello"},
+		{name: "link", req: xpost.Request{Message: "Hello", Link: "https://example.com"}, want: "Hello\n\nhttps://example.com"},
+	}
+	for _, tt := range tests {
+		srv := newFakeServer(t)
+		if _, err := srv.client(t).Post(context.Background(), tt.req); err != nil {
+			t.Errorf("%s: Post: %v", tt.name, err)
+			continue
+		}
+		if statuses, _ := srv.recorded(); statuses[0].Get("status") != tt.want {
+			t.Errorf("%s: status = %q, want %q", tt.name, statuses[0].Get("status"), tt.want)
+		}
+	}
+}
+
+func TestActionErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		fail      string // "METHOD path" the server fails
+		status    int
+		action    func(*Client) error
+		want      string
+		retryable bool
+	}{
+		{
+			name: "repost", fail: "POST /api/v1/statuses/1/reblog", status: http.StatusUnprocessableEntity,
+			action: func(c *Client) error { return c.Repost(context.Background(), "1") },
+			want:   "reblog status: Validation failed: simulated (HTTP 422 Unprocessable Entity)",
+		},
+		{
+			name: "react", fail: "POST /api/v1/statuses/1/favourite", status: http.StatusServiceUnavailable,
+			action: func(c *Client) error { return c.React(context.Background(), "1", "") },
+			want:   "favourite status: Validation failed: simulated (HTTP 503 Service Unavailable)", retryable: true,
+		},
+		{
+			name: "search", fail: "GET /api/v2/search", status: http.StatusUnauthorized,
+			action: func(c *Client) error { return c.Repost(context.Background(), "https://other.example/@bob/1") },
+			want:   "resolve status: search https://other.example/@bob/1: Validation failed: simulated (HTTP 401 Unauthorized)",
+		},
+	}
+	for _, tt := range tests {
+		srv := newFakeServer(t)
+		srv.fail[tt.fail] = tt.status
+		err := tt.action(srv.client(t))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+		if retryable := errors.As(err, new(xpost.RetryableError)); retryable != tt.retryable {
+			t.Errorf("%s: retryable = %v, want %v", tt.name, retryable, tt.retryable)
+		}
+	}
+}
+
+func TestValidatePoll(t *testing.T) {
+	month := 2629746 * time.Second
+	tests := []struct {
+		name string
+		req  xpost.Request
+		ok   bool
+	}{
+		{name: "shortest", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes", "no"}, Duration: 5 * time.Minute}}, ok: true},
+		{name: "a month", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes", "no"}, Duration: month}}, ok: true},
+		{name: "under five minutes", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes", "no"}, Duration: 4 * time.Minute}}},
+		{name: "over a month", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes", "no"}, Duration: month + time.Second}}},
+		{name: "one option", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes"}, Duration: time.Hour}}},
+		{name: "five options", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"a", "b", "c", "d", "e"}, Duration: time.Hour}}},
+		{name: "empty option", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes", ""}, Duration: time.Hour}}},
+		{name: "option of 51 characters", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes", strings.Repeat("n", 51)}, Duration: time.Hour}}},
+		{name: "with an image", req: xpost.Request{Message: "?", Images: []xpost.ImageAttachment{{Path: "a.png"}}, Poll: &xpost.Poll{Options: []string{"yes", "no"}, Duration: time.Hour}}},
+	}
+	for _, tt := range tests {
+		if err := NewValidator().Validate(tt.req); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
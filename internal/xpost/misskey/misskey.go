@@ -0,0 +1,474 @@
+package misskey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+const (
+	envServer = "XPOST_MISSKEY_SERVER"
+	envToken  = "XPOST_MISSKEY_TOKEN"
+
+	providerName = "misskey"
+	maxChars     = 3000 // Misskey's default note length limit
+	maxCWChars   = 100  // Misskey's content warning limit
+	maxAltChars  = 512  // Misskey's drive file comment (alt text) limit
+	maxImages    = 16   // Misskey's files per note
+	defaultEmoji = "❤"  // the reaction Misskey uses for a plain like
+)
+
+// imageTypes are the MIME types accepted as an explicit media type override.
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "image/avif"}
+
+// visibilities maps xpost's (Mastodon-style) visibility names, and Misskey's
+// own, onto Misskey note visibilities.
+var visibilities = map[string]string{
+	"public":    "public",
+	"unlisted":  "home",
+	"home":      "home",
+	"private":   "followers",
+	"followers": "followers",
+	"direct":    "specified",
+	"specified": "specified",
+}
+
+// Config contains the settings needed to reach a Misskey instance.
+type Config struct {
+	Server string
+	Token  string
+
+	// Timeout bounds each request; zero means xpost.DefaultHTTPTimeout.
+	Timeout time.Duration
+}
+
+// Client implements the xpost.Poster interface for Misskey.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+func init() {
+	xpost.Register(providerName, New, xpost.Info{
+		Label:    "Misskey",
+		Icon:     "\uf075", // speech bubble for Misskey notes
+		Color:    112,
+		Features: xpost.Features{Visibility: true, ContentWarning: true, EmojiReactions: true},
+	})
+	xpost.RegisterValidator(providerName, NewValidator)
+	config.RegisterSection(providerName, config.Section{
+		Options: func(c *config.Config) *config.Options { return &c.Misskey.Options },
+		Vars: func(c *config.Config) map[string]*string {
+			return map[string]*string{
+				envServer: &c.Misskey.Server,
+				envToken:  &c.Misskey.Token,
+			}
+		},
+		Account: func(c, acct *config.Config, name string) bool {
+			a, ok := c.Misskey.Accounts[name]
+			acct.Misskey = a
+			return ok
+		},
+	})
+}
+
+// New constructs a Misskey poster from its section of the resolved config.
+func New(ctx context.Context, settings *config.Config) (xpost.Poster, error) {
+	cfg, err := loadConfig(settings.Misskey)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeout = settings.HTTPTimeout
+
+	return NewWithConfig(ctx, cfg), nil
+}
+
+// NewWithConfig constructs a Misskey client from explicit settings.
+func NewWithConfig(ctx context.Context, cfg Config) *Client {
+	cfg.Server = strings.TrimRight(cfg.Server, "/")
+	return &Client{cfg: cfg, http: &http.Client{Timeout: xpost.HTTPTimeout(cfg.Timeout)}}
+}
+
+// NewValidator returns a Misskey validator that needs no credentials.
+func NewValidator() xpost.Validator {
+	return &Client{}
+}
+
+// Name identifies the provider.
+func (c *Client) Name() string { return providerName }
+
+// Verify checks the access token without posting and returns the account handle.
+func (c *Client) Verify(ctx context.Context) (string, error) {
+	var res struct {
+		Username string `json:"username"`
+	}
+	if err := c.call(ctx, "i", map[string]any{}, &res); err != nil {
+		return "", fmt.Errorf("verify credentials: %w", err)
+	}
+	return "@" + res.Username, nil
+}
+
+// Truncate shortens the message so the note, including any link, fits within
+// the character limit.
+func (c *Client) Truncate(req xpost.Request) (xpost.Request, string) {
+	limit := maxChars
+	if req.Link != "" {
+		limit -= utf8.RuneCountInString("\n\n" + req.Link)
+	}
+	var removed string
+	req.Message, removed = xpost.TruncateText(req.Message, limit, utf8.RuneCountInString)
+	return req, removed
+}
+
+// TruncateAlt shortens the image description to Misskey's limit.
+func (c *Client) TruncateAlt(alt string) (string, string) {
+	return xpost.TruncateText(alt, maxAltChars, utf8.RuneCountInString)
+}
+
+// Capabilities returns Misskey's default limits.
+func (c *Client) Capabilities(ctx context.Context) (xpost.Capabilities, error) {
+	return xpost.Capabilities{
+		MaxChars:    maxChars,
+		Unit:        "characters",
+		MaxImages:   maxImages,
+		MediaTypes:  imageTypes,
+		MaxAltChars: maxAltChars,
+		Features:    []string{"content warnings", "visibility", "emoji reactions"},
+	}, nil
+}
+
+// Count measures the note and link; the content warning has its own limit.
+func (c *Client) Count(req xpost.Request) (int, int, string) {
+	text := req.Message
+	if req.Link != "" {
+		text = text + "\n\n" + req.Link
+	}
+	return utf8.RuneCountInString(text), maxChars, "characters"
+}
+
+// Validate checks if the request meets Misskey's constraints.
+func (c *Client) Validate(req xpost.Request) error {
+	if len(req.Images) > maxImages {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("too many images: %d (max %d)", len(req.Images), maxImages)}
+	}
+	for _, img := range req.Images {
+		if img.MediaType != "" && !slices.Contains(imageTypes, img.MediaType) {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type %q", img.MediaType)}
+		}
+		if count := utf8.RuneCountInString(img.Alt); count > maxAltChars {
+			return xpost.ValidationError{
+				Provider: providerName,
+				Reason:   fmt.Sprintf("image description too long: %d characters (max %d)", count, maxAltChars),
+			}
+		}
+	}
+	if _, ok := visibilities[req.Visibility]; req.Visibility != "" && !ok {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported visibility %q", req.Visibility)}
+	}
+	if count, _, _ := c.Count(req); count > maxChars {
+		return xpost.ValidationError{
+			Provider: providerName,
+			Reason:   fmt.Sprintf("message too long: %d characters (max %d)", count, maxChars),
+		}
+	}
+	if count := utf8.RuneCountInString(req.ContentWarning); count > maxCWChars {
+		return xpost.ValidationError{
+			Provider: providerName,
+			Reason:   fmt.Sprintf("content warning too long: %d characters (max %d)", count, maxCWChars),
+		}
+	}
+	return nil
+}
+
+// Post uploads any images to the drive and creates a note referencing them.
+func (c *Client) Post(ctx context.Context, req xpost.Request) (xpost.PostResult, error) {
+	text := req.Message
+	if req.Link != "" {
+		text = text + "\n\n" + req.Link
+	}
+
+	note := createNoteRequest{
+		Text:       text,
+		CW:         req.ContentWarning,
+		Visibility: visibilities[req.Visibility],
+	}
+
+	for _, img := range req.Images {
+		fileID, err := c.uploadFile(ctx, img.Path, img.Alt, img.MediaType)
+		if err != nil {
+			return xpost.PostResult{}, err
+		}
+		note.FileIDs = append(note.FileIDs, fileID)
+	}
+
+	if req.ReplyTo != "" {
+		parentID, err := c.resolveNoteID(ctx, req.ReplyTo)
+		if err != nil {
+			return xpost.PostResult{}, fmt.Errorf("resolve reply target: %w", err)
+		}
+		note.ReplyID = parentID
+	}
+
+	var res createNoteResponse
+	if err := c.call(ctx, "notes/create", note, &res); err != nil {
+		return xpost.PostResult{}, fmt.Errorf("create note: %w", err)
+	}
+	logutil.Debugf("misskey note created: id=%s", res.CreatedNote.ID)
+
+	result := xpost.PostResult{Provider: providerName, ID: res.CreatedNote.ID, URL: c.noteURL(res.CreatedNote.ID)}
+
+	if reply := req.AltReply(); reply != "" && len(note.FileIDs) > 0 {
+		// Long descriptions continue across a chain of replies.
+		parentID := res.CreatedNote.ID
+		for _, part := range xpost.SplitText(reply, maxChars, utf8.RuneCountInString) {
+			var posted createNoteResponse
+			if err := c.call(ctx, "notes/create", createNoteRequest{
+				Text:       part,
+				Visibility: note.Visibility,
+				ReplyID:    parentID,
+			}, &posted); err != nil {
+				return xpost.PostResult{}, fmt.Errorf("post image description reply: %w", err)
+			}
+			parentID = posted.CreatedNote.ID
+		}
+	}
+
+	return result, nil
+}
+
+// React adds emoji (or a like when empty) to the note with the given URL or ID.
+func (c *Client) React(ctx context.Context, ref, emoji string) error {
+	id, err := c.resolveNoteID(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("resolve note: %w", err)
+	}
+	if emoji == "" {
+		emoji = defaultEmoji
+	}
+	if err := c.call(ctx, "notes/reactions/create", map[string]string{"noteId": id, "reaction": emoji}, nil); err != nil {
+		return fmt.Errorf("create reaction: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the note with the given ID.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	if err := c.call(ctx, "notes/delete", map[string]string{"noteId": id}, nil); err != nil {
+		return fmt.Errorf("delete note: %w", err)
+	}
+	return nil
+}
+
+type createNoteRequest struct {
+	Text       string   `json:"text,omitempty"`
+	CW         string   `json:"cw,omitempty"`
+	Visibility string   `json:"visibility,omitempty"`
+	FileIDs    []string `json:"fileIds,omitempty"`
+	ReplyID    string   `json:"replyId,omitempty"`
+}
+
+type createNoteResponse struct {
+	CreatedNote struct {
+		ID string `json:"id"`
+	} `json:"createdNote"`
+}
+
+type driveFile struct {
+	ID string `json:"id"`
+}
+
+type apShowResponse struct {
+	Type   string `json:"type"`
+	Object struct {
+		ID string `json:"id"`
+	} `json:"object"`
+}
+
+// noteURL returns the permalink of a note on the configured server.
+func (c *Client) noteURL(id string) string {
+	if id == "" {
+		return ""
+	}
+	return c.cfg.Server + "/notes/" + id
+}
+
+// resolveNoteID maps a note URL (or bare ID) to the ID this server knows it
+// by. Links to notes elsewhere are looked up with ap/show, which federates
+// the note in if needed.
+func (c *Client) resolveNoteID(ctx context.Context, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	u, err := url.Parse(ref)
+	if err != nil || u.Host == "" {
+		if ref == "" || strings.ContainsAny(ref, "/ ") {
+			return "", fmt.Errorf("invalid note reference %q", ref)
+		}
+		return ref, nil
+	}
+
+	if server, err := url.Parse(c.cfg.Server); err == nil && strings.EqualFold(server.Host, u.Host) {
+		if id, ok := strings.CutPrefix(u.Path, "/notes/"); ok && id != "" && !strings.Contains(id, "/") {
+			return id, nil
+		}
+	}
+
+	var res apShowResponse
+	if err := c.call(ctx, "ap/show", map[string]string{"uri": ref}, &res); err != nil {
+		return "", fmt.Errorf("look up %s: %w", ref, err)
+	}
+	if res.Type != "Note" || res.Object.ID == "" {
+		return "", fmt.Errorf("%s is not a note", ref)
+	}
+	return res.Object.ID, nil
+}
+
+// uploadFile stores the image in the account's drive, with the description
+// as its comment (Misskey's alt text), and returns the drive file ID.
+func (c *Client) uploadFile(ctx context.Context, path, alt, mediaType string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("image %q not found", path)}
+		}
+		return "", fmt.Errorf("read image: %w", err)
+	}
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	if err := mw.WriteField("i", c.cfg.Token); err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	if alt = strings.TrimSpace(alt); alt != "" {
+		if err := mw.WriteField("comment", alt); err != nil {
+			return "", fmt.Errorf("upload image: %w", err)
+		}
+	}
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filepath.Base(path)))
+	header.Set("Content-Type", mediaType)
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.Server+"/api/drive/files/create", body)
+	if err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var file driveFile
+	if err := c.send(httpReq, &file); err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	if file.ID == "" {
+		return "", errors.New("upload image: no file id returned")
+	}
+	logutil.Debugf("misskey drive file created: id=%s", file.ID)
+	return file.ID, nil
+}
+
+// call POSTs payload to the named API endpoint, authenticating with the
+// access token in the "i" field as every Misskey version accepts.
+func (c *Client) call(ctx context.Context, endpoint string, payload, out any) error {
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(buf, &fields); err != nil {
+		return err
+	}
+	fields["i"] = c.cfg.Token
+	if buf, err = json.Marshal(fields); err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.Server+"/api/"+endpoint, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	return c.send(httpReq, out)
+}
+
+func (c *Client) send(httpReq *http.Request, out any) error {
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return xpost.Retryable(err, 0, 0)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+				Code    string `json:"code"`
+			} `json:"error"`
+		}
+		err := fmt.Errorf("HTTP %d", resp.StatusCode)
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			err = fmt.Errorf("%s (%s, HTTP %d)", apiErr.Error.Message, apiErr.Error.Code, resp.StatusCode)
+		}
+		return xpost.Retryable(err, resp.StatusCode, xpost.RetryAfter(resp.Header))
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// loadConfig checks that the Misskey section has the credentials needed to
+// post.
+func loadConfig(section config.Misskey) (Config, error) {
+	cfg := Config{
+		Server: strings.TrimSpace(section.Server),
+		Token:  strings.TrimSpace(section.Token),
+	}
+
+	var missing []string
+	if cfg.Server == "" {
+		missing = append(missing, envServer)
+	}
+	if cfg.Token == "" {
+		missing = append(missing, envToken)
+	}
+
+	if len(missing) > 0 {
+		return Config{}, xpost.MissingEnvError{Provider: providerName, Variables: missing}
+	}
+
+	return cfg, nil
+}
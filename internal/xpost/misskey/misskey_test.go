@@ -0,0 +1,190 @@
+package misskey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// fakeServer is a minimal Misskey API that records the notes created and the
+// drive files uploaded.
+type fakeServer struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	notes []map[string]any // JSON body of each notes/create call
+	files []url.Values     // fields of each upload, plus its file name and type
+	calls []string         // every endpoint called
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	f := &fakeServer{}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, r.URL.Path)
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/api/drive/files/create":
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fields := url.Values(r.MultipartForm.Value)
+		if files := r.MultipartForm.File["file"]; len(files) > 0 {
+			fields.Set("filename", files[0].Filename)
+			fields.Set("type", files[0].Header.Get("Content-Type"))
+		}
+		f.files = append(f.files, fields)
+		fmt.Fprintf(w, `{"id":"file-%d"}`, len(f.files))
+	case "/api/notes/create":
+		var in map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if in["i"] != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"message":"Credential required.","code":"CREDENTIAL_REQUIRED"}}`)
+			return
+		}
+		f.notes = append(f.notes, in)
+		fmt.Fprintf(w, `{"createdNote":{"id":"note%d"}}`, len(f.notes))
+	case "/api/ap/show":
+		fmt.Fprint(w, `{"type":"Note","object":{"id":"remote1"}}`)
+	case "/api/notes/reactions/create":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"message":"No such endpoint.","code":"NO_SUCH_ENDPOINT"}}`)
+	}
+}
+
+func (f *fakeServer) client() *Client {
+	return NewWithConfig(context.Background(), Config{Server: f.URL + "/", Token: "token"})
+}
+
+// writeFile creates a file named name with placeholder contents.
+func writeFile(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("not really "+name), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPostNotePayload(t *testing.T) {
+	tests := []struct {
+		name string
+		req  xpost.Request
+		want map[string]any
+	}{
+		{
+			name: "plain",
+			req:  xpost.Request{Message: "Hello"},
+			want: map[string]any{"i": "token", "text": "Hello"},
+		},
+		{
+			name: "link, cw and visibility",
+			req:  xpost.Request{Message: "Spoilers ahead", Link: "https://example.com", ContentWarning: "finale", Visibility: "unlisted"},
+			want: map[string]any{"i": "token", "text": "Spoilers ahead\n\nhttps://example.com", "cw": "finale", "visibility": "home"},
+		},
+		{
+			name: "reply to a remote note",
+			req:  xpost.Request{Message: "Agreed", ReplyTo: "https://other.example/notes/abc", Visibility: "private"},
+			want: map[string]any{"i": "token", "text": "Agreed", "replyId": "remote1", "visibility": "followers"},
+		},
+	}
+	for _, tt := range tests {
+		srv := newFakeServer(t)
+		res, err := srv.client().Post(context.Background(), tt.req)
+		if err != nil {
+			t.Errorf("%s: Post: %v", tt.name, err)
+			continue
+		}
+		if res.ID != "note1" || res.URL != srv.URL+"/notes/note1" {
+			t.Errorf("%s: result = %+v", tt.name, res)
+		}
+		if len(srv.notes) != 1 || fmt.Sprint(srv.notes[0]) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: notes = %v, want %v", tt.name, srv.notes, tt.want)
+		}
+	}
+}
+
+func TestPostUploadsToDrive(t *testing.T) {
+	srv := newFakeServer(t)
+	images := []xpost.ImageAttachment{
+		{Path: writeFile(t, "a.png"), Alt: " A cat ", MediaType: "image/png"},
+		{Path: writeFile(t, "b.webp"), MediaType: "image/webp"},
+	}
+	if _, err := srv.client().Post(context.Background(), xpost.Request{Message: "Pets", Images: images}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	if want := []string{"/api/drive/files/create", "/api/drive/files/create", "/api/notes/create"}; !slices.Equal(srv.calls, want) {
+		t.Errorf("calls = %v, want %v", srv.calls, want)
+	}
+	tests := []struct {
+		filename, mediaType, comment string
+	}{
+		{filename: "a.png", mediaType: "image/png", comment: "A cat"},
+		{filename: "b.webp", mediaType: "image/webp"},
+	}
+	for i, tt := range tests {
+		f := srv.files[i]
+		if f.Get("i") != "token" || f.Get("filename") != tt.filename || f.Get("type") != tt.mediaType || f.Get("comment") != tt.comment {
+			t.Errorf("upload %d = %v, want %+v", i, f, tt)
+		}
+	}
+	if ids := srv.notes[0]["fileIds"]; fmt.Sprint(ids) != "[file-1 file-2]" {
+		t.Errorf("fileIds = %v, want both files in order", ids)
+	}
+}
+
+func TestPostAPIError(t *testing.T) {
+	srv := newFakeServer(t)
+	c := NewWithConfig(context.Background(), Config{Server: srv.URL, Token: "wrong"})
+	_, err := c.Post(context.Background(), xpost.Request{Message: "Hello"})
+	if err == nil || err.Error() != "create note: Credential required. (CREDENTIAL_REQUIRED, HTTP 401)" {
+		t.Errorf("Post = %v, want the API's error", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	long := func(n int) string { return string(make([]rune, n)) }
+	tests := []struct {
+		name string
+		req  xpost.Request
+		ok   bool
+	}{
+		{name: "fits", req: xpost.Request{Message: "hi", Visibility: "followers"}, ok: true},
+		{name: "unknown visibility", req: xpost.Request{Message: "hi", Visibility: "friends"}},
+		{name: "too long", req: xpost.Request{Message: long(maxChars + 1)}},
+		{name: "cw too long", req: xpost.Request{Message: "hi", ContentWarning: long(maxCWChars + 1)}},
+		{name: "alt too long", req: xpost.Request{Message: "hi", Images: []xpost.ImageAttachment{{Path: "a.png", Alt: long(maxAltChars + 1)}}}},
+		{name: "unsupported type", req: xpost.Request{Message: "hi", Images: []xpost.ImageAttachment{{Path: "a.bmp", MediaType: "image/bmp"}}}},
+	}
+	for _, tt := range tests {
+		if err := NewValidator().Validate(tt.req); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package pixelfed
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/blacktop/xpost/internal/xpost/mastodon"
+)
+
+const (
+	envServer      = "XPOST_PIXELFED_SERVER"
+	envAccessToken = "XPOST_PIXELFED_ACCESS_TOKEN"
+
+	providerName = "pixelfed"
+	maxChars     = 500  // Pixelfed's default caption limit
+	maxAltChars  = 1000 // Pixelfed's default image description limit
+	maxImages    = 4    // Pixelfed's default photos per post
+
+	maxImageBytes = 15 << 20 // Pixelfed's default photo size limit
+)
+
+// imageTypes are the MIME types accepted as an explicit media type override.
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// Config contains the settings needed to reach a Pixelfed server.
+type Config struct {
+	Server      string
+	AccessToken string
+
+	// Timeout bounds each request; zero means xpost.DefaultHTTPTimeout.
+	Timeout time.Duration
+}
+
+// Client implements the xpost.Poster interface for Pixelfed. Pixelfed speaks
+// the Mastodon API, so posting goes through the Mastodon client.
+type Client struct {
+	mastodon *mastodon.Client
+}
+
+func init() {
+	xpost.Register(providerName, New, xpost.Info{
+		Label:    "Pixelfed",
+		Icon:     "\uf030", // camera, as Pixelfed is image-first
+		Color:    171,
+		Features: xpost.Features{Visibility: true, ContentWarning: true},
+	})
+	xpost.RegisterValidator(providerName, NewValidator)
+	config.RegisterSection(providerName, config.Section{
+		Options: func(c *config.Config) *config.Options { return &c.Pixelfed.Options },
+		Vars: func(c *config.Config) map[string]*string {
+			return map[string]*string{
+				envServer:      &c.Pixelfed.Server,
+				envAccessToken: &c.Pixelfed.AccessToken,
+			}
+		},
+		Account: func(c, acct *config.Config, name string) bool {
+			a, ok := c.Pixelfed.Accounts[name]
+			acct.Pixelfed = a
+			return ok
+		},
+	})
+}
+
+// New constructs a Pixelfed poster from its section of the resolved config.
+func New(ctx context.Context, settings *config.Config) (xpost.Poster, error) {
+	cfg, err := loadConfig(settings.Pixelfed)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeout = settings.HTTPTimeout
+
+	return NewWithConfig(ctx, cfg), nil
+}
+
+// NewWithConfig constructs a Pixelfed client from explicit settings.
+func NewWithConfig(ctx context.Context, cfg Config) *Client {
+	return &Client{mastodon: mastodon.NewWithConfig(ctx, mastodon.Config{
+		Server:      cfg.Server,
+		AccessToken: cfg.AccessToken,
+		Timeout:     cfg.Timeout,
+	})}
+}
+
+// NewValidator returns a Pixelfed validator that needs no credentials.
+func NewValidator() xpost.Validator {
+	return &Client{}
+}
+
+// Name identifies the provider.
+func (c *Client) Name() string { return providerName }
+
+// Verify checks the credentials without posting and returns the account handle.
+func (c *Client) Verify(ctx context.Context) (string, error) {
+	return c.mastodon.Verify(ctx)
+}
+
+// Capabilities reads the server's limits, falling back to Pixelfed's
+// defaults for anything it does not publish.
+func (c *Client) Capabilities(ctx context.Context) (xpost.Capabilities, error) {
+	return c.mastodon.InstanceCapabilities(ctx, xpost.Capabilities{
+		MaxChars:      maxChars,
+		Unit:          "characters",
+		MaxImages:     maxImages,
+		MaxImageBytes: maxImageBytes,
+		MediaTypes:    imageTypes,
+		MaxAltChars:   maxAltChars,
+		Features:      []string{"content warnings", "visibility", "requires an image"},
+	})
+}
+
+// Truncate shortens the caption so it, including any link, fits within the
+// character limit.
+func (c *Client) Truncate(req xpost.Request) (xpost.Request, string) {
+	limit := maxChars - utf8.RuneCountInString(req.ContentWarning)
+	if req.Link != "" {
+		limit -= utf8.RuneCountInString("\n\n" + req.Link)
+	}
+	var removed string
+	req.Message, removed = xpost.TruncateText(req.Message, limit, utf8.RuneCountInString)
+	return req, removed
+}
+
+// TruncateAlt shortens the image description to Pixelfed's limit.
+func (c *Client) TruncateAlt(alt string) (string, string) {
+	return xpost.TruncateText(alt, maxAltChars, utf8.RuneCountInString)
+}
+
+// Count measures the caption, link and content warning together.
+func (c *Client) Count(req xpost.Request) (int, int, string) {
+	text := req.Message
+	if req.Link != "" {
+		text = text + "\n\n" + req.Link
+	}
+	return utf8.RuneCountInString(text) + utf8.RuneCountInString(req.ContentWarning), maxChars, "characters"
+}
+
+// Validate checks if the request meets Pixelfed's constraints. Pixelfed is
+// image-first, so text-only posts are rejected.
+func (c *Client) Validate(req xpost.Request) error {
+	if len(req.Images) == 0 {
+		return xpost.ValidationError{Provider: providerName, Reason: "an image is required (use --image)"}
+	}
+	if len(req.Images) > maxImages {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("too many images: %d (max %d)", len(req.Images), maxImages)}
+	}
+	for _, img := range req.Images {
+		if img.MediaType != "" && !slices.Contains(imageTypes, img.MediaType) {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type %q", img.MediaType)}
+		}
+		if count := utf8.RuneCountInString(img.Alt); count > maxAltChars {
+			return xpost.ValidationError{
+				Provider: providerName,
+				Reason:   fmt.Sprintf("image description too long: %d characters (max %d)", count, maxAltChars),
+			}
+		}
+	}
+	if req.Visibility != "" && !slices.Contains(mastodon.Visibilities, req.Visibility) {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported visibility %q", req.Visibility)}
+	}
+
+	if count, _, _ := c.Count(req); count > maxChars {
+		return xpost.ValidationError{
+			Provider: providerName,
+			Reason:   fmt.Sprintf("caption too long: %d characters (max %d)", count, maxChars),
+		}
+	}
+	return nil
+}
+
+// Post publishes the image and caption through the Mastodon API.
+func (c *Client) Post(ctx context.Context, req xpost.Request) (xpost.PostResult, error) {
+	res, err := c.mastodon.Post(ctx, req)
+	if err != nil {
+		return xpost.PostResult{}, err
+	}
+	res.Provider = providerName
+	return res, nil
+}
+
+// ReadBack checks the post is visible to logged-out readers.
+func (c *Client) ReadBack(ctx context.Context, res xpost.PostResult) (xpost.ReadBack, error) {
+	return c.mastodon.ReadBack(ctx, res)
+}
+
+// Repost shares the post at ref.
+func (c *Client) Repost(ctx context.Context, ref string) error {
+	return c.mastodon.Repost(ctx, ref)
+}
+
+// React likes the post with the given URL or ID.
+func (c *Client) React(ctx context.Context, ref, emoji string) error {
+	return c.mastodon.React(ctx, ref, emoji)
+}
+
+// Delete removes the post with the given ID.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	return c.mastodon.Delete(ctx, id)
+}
+
+// loadConfig checks that the Pixelfed section has the credentials needed to
+// post.
+func loadConfig(section config.Pixelfed) (Config, error) {
+	cfg := Config{
+		Server:      strings.TrimSpace(section.Server),
+		AccessToken: strings.TrimSpace(section.AccessToken),
+	}
+
+	var missing []string
+	if cfg.Server == "" {
+		missing = append(missing, envServer)
+	}
+	if cfg.AccessToken == "" {
+		missing = append(missing, envAccessToken)
+	}
+
+	if len(missing) > 0 {
+		return Config{}, xpost.MissingEnvError{Provider: providerName, Variables: missing}
+	}
+
+	return cfg, nil
+}
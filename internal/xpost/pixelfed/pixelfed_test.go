@@ -0,0 +1,90 @@
+package pixelfed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestValidate(t *testing.T) {
+	c := NewValidator()
+	img := xpost.ImageAttachment{Path: "a.jpg", Alt: "A cat"}
+	tests := []struct {
+		name    string
+		req     xpost.Request
+		wantErr string
+	}{
+		{name: "ok", req: xpost.Request{Message: "Caption", Images: []xpost.ImageAttachment{img}}},
+		{name: "image only", req: xpost.Request{Images: []xpost.ImageAttachment{img}}},
+		{name: "text only", req: xpost.Request{Message: "Caption"}, wantErr: "an image is required"},
+		{name: "too many images", req: xpost.Request{Message: "Caption", Images: []xpost.ImageAttachment{img, img, img, img, img}}, wantErr: "too many images: 5 (max 4)"},
+		{name: "video", req: xpost.Request{Images: []xpost.ImageAttachment{{Path: "a.bin", MediaType: "video/mp4"}}}, wantErr: `unsupported media type "video/mp4"`},
+		{name: "long alt", req: xpost.Request{Images: []xpost.ImageAttachment{{Path: "a.jpg", Alt: strings.Repeat("a", maxAltChars+1)}}}, wantErr: "image description too long"},
+		{name: "visibility", req: xpost.Request{Images: []xpost.ImageAttachment{img}, Visibility: "followers"}, wantErr: `unsupported visibility "followers"`},
+		{name: "caption with cw", req: xpost.Request{Message: strings.Repeat("a", maxChars-2), ContentWarning: "cw!", Images: []xpost.ImageAttachment{img}}, wantErr: "caption too long: 501 characters"},
+	}
+	for _, tt := range tests {
+		err := c.Validate(tt.req)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPostThroughMastodonAPI(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		status string
+		media  string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/media":
+			r.ParseMultipartForm(1 << 20)
+			media = r.FormValue("description")
+			json.NewEncoder(w).Encode(map[string]string{"id": "m1", "url": "https://pixelfed.example/m1.jpg"})
+		case "/api/v1/statuses":
+			r.ParseForm()
+			status = r.PostForm.Get("status") + "|" + r.PostForm.Get("media_ids[]")
+			json.NewEncoder(w).Encode(map[string]string{"id": "7", "url": "https://pixelfed.example/p/me/7"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cat.jpg")
+	if err := os.WriteFile(path, []byte("\xff\xd8\xff"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := NewWithConfig(context.Background(), Config{Server: srv.URL, AccessToken: "token"})
+	res, err := c.Post(context.Background(), xpost.Request{Message: "Caption", Images: []xpost.ImageAttachment{{Path: path, Alt: "A cat"}}})
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if res.Provider != providerName || res.ID != "7" || res.URL != "https://pixelfed.example/p/me/7" {
+		t.Errorf("result = %+v", res)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if status != "Caption|m1" || media != "A cat" {
+		t.Errorf("status = %q, media description = %q", status, media)
+	}
+}
@@ -0,0 +1,51 @@
+package xpost
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Poll is a poll attached to a post.
+type Poll struct {
+	Options  []string
+	Duration time.Duration
+}
+
+// PollLimits are a provider's constraints on polls.
+type PollLimits struct {
+	MinOptions     int
+	MaxOptions     int
+	MaxOptionChars int
+	MinDuration    time.Duration
+	MaxDuration    time.Duration
+}
+
+// CheckPoll validates p against a provider's limits.
+func CheckPoll(provider string, p *Poll, limits PollLimits) error {
+	if n := len(p.Options); n < limits.MinOptions || n > limits.MaxOptions {
+		return ValidationError{
+			Provider: provider,
+			Reason:   fmt.Sprintf("a poll needs %d to %d options, got %d", limits.MinOptions, limits.MaxOptions, n),
+		}
+	}
+	for i, option := range p.Options {
+		if strings.TrimSpace(option) == "" {
+			return ValidationError{Provider: provider, Reason: fmt.Sprintf("poll option %d is empty", i+1)}
+		}
+		if count := utf8.RuneCountInString(option); count > limits.MaxOptionChars {
+			return ValidationError{
+				Provider: provider,
+				Reason:   fmt.Sprintf("poll option %q too long: %d characters (max %d)", option, count, limits.MaxOptionChars),
+			}
+		}
+	}
+	if p.Duration < limits.MinDuration || p.Duration > limits.MaxDuration {
+		return ValidationError{
+			Provider: provider,
+			Reason:   fmt.Sprintf("poll duration %s out of range (%s to %s)", p.Duration, limits.MinDuration, limits.MaxDuration),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package xpost
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckPoll(t *testing.T) {
+	limits := PollLimits{MinOptions: 2, MaxOptions: 4, MaxOptionChars: 10, MinDuration: 5 * time.Minute, MaxDuration: 24 * time.Hour}
+	tests := []struct {
+		name    string
+		poll    Poll
+		wantErr string
+	}{
+		{name: "two options", poll: Poll{Options: []string{"yes", "no"}, Duration: time.Hour}},
+		{name: "four options at the bounds", poll: Poll{Options: []string{"a", "b", "c", strings.Repeat("d", 10)}, Duration: 24 * time.Hour}},
+		{name: "shortest duration", poll: Poll{Options: []string{"yes", "no"}, Duration: 5 * time.Minute}},
+		{name: "no options", poll: Poll{Duration: time.Hour}, wantErr: "a poll needs 2 to 4 options, got 0"},
+		{name: "one option", poll: Poll{Options: []string{"yes"}, Duration: time.Hour}, wantErr: "a poll needs 2 to 4 options, got 1"},
+		{name: "five options", poll: Poll{Options: []string{"a", "b", "c", "d", "e"}, Duration: time.Hour}, wantErr: "got 5"},
+		{name: "empty option", poll: Poll{Options: []string{"yes", " "}, Duration: time.Hour}, wantErr: "poll option 2 is empty"},
+		{name: "long option", poll: Poll{Options: []string{"yes", strings.Repeat("n", 11)}, Duration: time.Hour}, wantErr: "too long: 11 characters (max 10)"},
+		{name: "multibyte option fits", poll: Poll{Options: []string{"はい", "いいえいいえいいえい"}, Duration: time.Hour}},
+		{name: "too short", poll: Poll{Options: []string{"yes", "no"}, Duration: 4 * time.Minute}, wantErr: "poll duration 4m0s out of range (5m0s to 24h0m0s)"},
+		{name: "too long", poll: Poll{Options: []string{"yes", "no"}, Duration: 25 * time.Hour}, wantErr: "out of range"},
+	}
+	for _, tt := range tests {
+		err := CheckPoll("test", &tt.poll, limits)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: CheckPoll = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		var verr ValidationError
+		if !errors.As(err, &verr) || verr.Provider != "test" || !strings.Contains(verr.Reason, tt.wantErr) {
+			t.Errorf("%s: CheckPoll = %v, want a validation error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
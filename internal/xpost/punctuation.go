@@ -0,0 +1,17 @@
+package xpost
+
+import "strings"
+
+// plainPunctuation maps typographic punctuation to ASCII equivalents.
+var plainPunctuation = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`,
+	"–", "-", "—", "--",
+	"…", "...",
+)
+
+// PlainPunctuation replaces curly quotes, en/em dashes, and ellipsis
+// characters in text with their ASCII equivalents.
+func PlainPunctuation(text string) string {
+	return plainPunctuation.Replace(text)
+}
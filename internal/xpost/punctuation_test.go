@@ -0,0 +1,20 @@
+package xpost
+
+import "testing"
+
+func TestPlainPunctuation(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{text: "plain 'ascii' \"text\" - ok...", want: "plain 'ascii' \"text\" - ok..."},
+		{text: "It’s ‘quoted’ and “double”", want: `It's 'quoted' and "double"`},
+		{text: "„Low‟ ‚single‛", want: `"Low" 'single'`},
+		{text: "1–2 pm — late…", want: "1-2 pm -- late..."},
+		{text: "日本語 «guillemets» stay", want: "日本語 «guillemets» stay"},
+	}
+	for _, tt := range tests {
+		if got := PlainPunctuation(tt.text); got != tt.want {
+			t.Errorf("PlainPunctuation(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package xpost
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/blacktop/xpost/internal/config"
+)
+
+// Factory constructs a provider's Poster from its section of cfg, the config
+// file with the environment applied.
+type Factory func(ctx context.Context, cfg *config.Config) (Poster, error)
+
+// Info describes a provider to the command line: how to show its name and
+// which optional request fields it honours.
+type Info struct {
+	// Label is the provider's display name, e.g. "Twitter/X"; empty uses the
+	// registered name.
+	Label string
+	// Icon is a Nerd Font glyph shown before the label.
+	Icon string
+	// Color is the ANSI 256-color code of the label; zero leaves it plain.
+	Color int
+	Features
+}
+
+// Features lists the optional request fields and behaviours a provider
+// supports. Flags for the others are ignored or rejected.
+type Features struct {
+	Visibility     bool // honours Request.Visibility
+	ContentWarning bool // honours Request.ContentWarning
+	LinkPreview    bool // honours Request.LinkPreview
+	Language       bool // honours Request.Language
+	Poll           bool // honours Request.Poll
+	Schedule       bool // honours Request.ScheduledAt
+	// EmojiReactions is set when React applies the emoji itself rather than
+	// recording a plain like.
+	EmojiReactions bool
+	// LiveLimit is set when the server sets the length limit, so it is read
+	// through Prober rather than assumed.
+	LiveLimit bool
+	// NoQuote is set when Request.Quote is rejected instead of falling back
+	// to a link to the quoted post, as the network has no quote posts.
+	NoQuote bool
+}
+
+var (
+	registryMu sync.RWMutex
+	factories  = map[string]Factory{}
+	validators = map[string]func() Validator{}
+	infos      = map[string]Info{}
+)
+
+// Register makes a provider available under name, described by info.
+// Providers call it from their package init; registering a name twice panics.
+func Register(name string, factory Factory, info Info) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("xpost: Register factory is nil for " + name)
+	}
+	if _, dup := factories[name]; dup {
+		panic("xpost: Register called twice for " + name)
+	}
+	factories[name] = factory
+	infos[name] = info
+}
+
+// RegisterValidator makes a credential-free validator available for the
+// provider registered under name.
+func RegisterValidator(name string, factory func() Validator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("xpost: RegisterValidator factory is nil for " + name)
+	}
+	if _, dup := validators[name]; dup {
+		panic("xpost: RegisterValidator called twice for " + name)
+	}
+	validators[name] = factory
+}
+
+// Providers returns the names of the registered providers, sorted.
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return slices.Sorted(maps.Keys(factories))
+}
+
+// Registered reports whether a provider is registered under name.
+func Registered(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := factories[name]
+	return ok
+}
+
+// Describe returns the Info the provider registered under name, and whether
+// one is registered.
+func Describe(name string) (Info, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	info, ok := infos[name]
+	return info, ok
+}
+
+// New constructs the provider registered under name from cfg.
+func New(ctx context.Context, name string, cfg *config.Config) (Poster, error) {
+	registryMu.RLock()
+	factory, ok := factories[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("target %q is not implemented", name)
+	}
+	return factory(ctx, cfg)
+}
+
+// NewValidator returns the credential-free validator registered under name.
+func NewValidator(name string) (Validator, error) {
+	registryMu.RLock()
+	factory, ok := validators[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("target %q is not implemented", name)
+	}
+	return factory(), nil
+}
@@ -0,0 +1,31 @@
+package xpost
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/config"
+)
+
+func TestDescribe(t *testing.T) {
+	info := Info{Label: "Described", Color: 42, Features: Features{Poll: true, NoQuote: true}}
+	Register("described", func(context.Context, *config.Config) (Poster, error) { return nil, nil }, info)
+
+	tests := []struct {
+		name   string
+		want   Info
+		wantOK bool
+	}{
+		{name: "described", want: info, wantOK: true},
+		{name: "missing"},
+	}
+	for _, tt := range tests {
+		got, ok := Describe(tt.name)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("Describe(%q) = %+v, %v, want %+v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if !Registered("described") {
+		t.Error("described is not registered")
+	}
+}
@@ -0,0 +1,440 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+const (
+	envWebhookURL = "XPOST_SLACK_WEBHOOK_URL"
+	envBotToken   = "XPOST_SLACK_BOT_TOKEN"
+	envChannel    = "XPOST_SLACK_CHANNEL"
+
+	providerName    = "slack"
+	maxChars        = 4000 // Slack's recommended message text limit
+	maxSectionChars = 3000 // Slack's section block text limit
+	maxAltChars     = 2000 // Slack's image block alt text limit
+	maxImages       = 10   // images xpost puts in one message
+
+	apiURL = "https://slack.com/api/"
+)
+
+// imageTypes are the MIME types accepted as an explicit media type override.
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// escaper escapes the characters Slack reserves for links and mentions.
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Config contains either an incoming webhook, or a bot token and the channel
+// to post to. The bot token wins when both are set.
+type Config struct {
+	WebhookURL string
+	BotToken   string
+	Channel    string
+
+	// Timeout bounds each request; zero means xpost.DefaultHTTPTimeout.
+	Timeout time.Duration
+}
+
+// poster holds what the webhook and bot clients share.
+type poster struct {
+	cfg  Config
+	http *http.Client
+}
+
+// Webhook posts through an incoming webhook. Webhooks cannot upload files
+// and do not say where the message went.
+type Webhook struct {
+	poster
+}
+
+// Client posts with a bot token through the Web API.
+type Client struct {
+	poster
+}
+
+func init() {
+	xpost.Register(providerName, New, xpost.Info{Label: "Slack", Icon: "\uf198", Color: 162})
+	xpost.RegisterValidator(providerName, NewValidator)
+	config.RegisterSection(providerName, config.Section{
+		Options: func(c *config.Config) *config.Options { return &c.Slack.Options },
+		Vars: func(c *config.Config) map[string]*string {
+			return map[string]*string{
+				envWebhookURL: &c.Slack.WebhookURL,
+				envBotToken:   &c.Slack.BotToken,
+				envChannel:    &c.Slack.Channel,
+			}
+		},
+		Account: func(c, acct *config.Config, name string) bool {
+			a, ok := c.Slack.Accounts[name]
+			acct.Slack = a
+			return ok
+		},
+	})
+}
+
+// New constructs a Slack poster from its section of the resolved config.
+func New(ctx context.Context, settings *config.Config) (xpost.Poster, error) {
+	cfg, err := loadConfig(settings.Slack)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeout = settings.HTTPTimeout
+
+	if cfg.BotToken == "" {
+		return NewWebhook(cfg), nil
+	}
+	return NewWithConfig(ctx, cfg), nil
+}
+
+// NewWebhook constructs a client that posts through cfg.WebhookURL.
+func NewWebhook(cfg Config) *Webhook {
+	return &Webhook{poster{cfg: cfg, http: &http.Client{Timeout: xpost.HTTPTimeout(cfg.Timeout)}}}
+}
+
+// NewWithConfig constructs a bot client from explicit settings.
+func NewWithConfig(ctx context.Context, cfg Config) *Client {
+	return &Client{poster{cfg: cfg, http: &http.Client{Timeout: xpost.HTTPTimeout(cfg.Timeout)}}}
+}
+
+// NewValidator returns a Slack validator that needs no credentials.
+func NewValidator() xpost.Validator {
+	return &Client{}
+}
+
+// Name identifies the provider.
+func (p *poster) Name() string { return providerName }
+
+// Truncate shortens the message so it, including any link, fits within the
+// text limit.
+func (p *poster) Truncate(req xpost.Request) (xpost.Request, string) {
+	limit := maxChars
+	if req.Link != "" {
+		limit -= utf8.RuneCountInString("\n\n" + req.Link)
+	}
+	var removed string
+	req.Message, removed = xpost.TruncateText(req.Message, limit, utf8.RuneCountInString)
+	return req, removed
+}
+
+// TruncateAlt shortens the image description to Slack's limit.
+func (p *poster) TruncateAlt(alt string) (string, string) {
+	return xpost.TruncateText(alt, maxAltChars, utf8.RuneCountInString)
+}
+
+// Capabilities returns Slack's limits.
+func (p *poster) Capabilities(ctx context.Context) (xpost.Capabilities, error) {
+	return xpost.Capabilities{
+		MaxChars:    maxChars,
+		Unit:        "characters",
+		MaxImages:   maxImages,
+		MediaTypes:  imageTypes,
+		MaxAltChars: maxAltChars,
+		Features:    []string{"images need a bot token"},
+	}, nil
+}
+
+// Count measures the message text, including any link.
+func (p *poster) Count(req xpost.Request) (int, int, string) {
+	return utf8.RuneCountInString(composeText(req)), maxChars, "characters"
+}
+
+// Validate checks if the request meets Slack's constraints.
+func (p *poster) Validate(req xpost.Request) error {
+	if req.ReplyTo != "" {
+		return xpost.ValidationError{Provider: providerName, Reason: "replies are not supported"}
+	}
+	if len(req.Images) > 0 && p.cfg.BotToken == "" && p.cfg.WebhookURL != "" {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("images need a bot token (%s and %s); webhooks cannot upload files", envBotToken, envChannel)}
+	}
+	if len(req.Images) > maxImages {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("too many images: %d (max %d)", len(req.Images), maxImages)}
+	}
+	for _, img := range req.Images {
+		if img.MediaType != "" && !slices.Contains(imageTypes, img.MediaType) {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type %q", img.MediaType)}
+		}
+		if img.IsVideo() {
+			return xpost.ValidationError{Provider: providerName, Reason: "videos are not supported"}
+		}
+		if count := utf8.RuneCountInString(img.Alt); count > maxAltChars {
+			return xpost.ValidationError{
+				Provider: providerName,
+				Reason:   fmt.Sprintf("image description too long: %d characters (max %d)", count, maxAltChars),
+			}
+		}
+	}
+	if strings.TrimSpace(composeText(req)) == "" && len(req.Images) == 0 {
+		return xpost.ValidationError{Provider: providerName, Reason: "a message or an image is required"}
+	}
+	if count, _, _ := p.Count(req); count > maxChars {
+		return xpost.ValidationError{
+			Provider: providerName,
+			Reason:   fmt.Sprintf("message too long: %d characters (max %d)", count, maxChars),
+		}
+	}
+	return nil
+}
+
+// Post sends the message through the webhook. Slack answers "ok" on success;
+// there is no message URL to report.
+func (w *Webhook) Post(ctx context.Context, req xpost.Request) (xpost.PostResult, error) {
+	buf, err := json.Marshal(map[string]string{"text": escaper.Replace(composeText(req))})
+	if err != nil {
+		return xpost.PostResult{}, fmt.Errorf("send message: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.WebhookURL, bytes.NewReader(buf))
+	if err != nil {
+		return xpost.PostResult{}, fmt.Errorf("send message: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.http.Do(httpReq)
+	if err != nil {
+		return xpost.PostResult{}, fmt.Errorf("send message: %w", xpost.Retryable(err, 0, 0))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return xpost.PostResult{}, fmt.Errorf("send message: %w", err)
+	}
+	// Webhooks reply with a plain-text error code such as invalid_payload.
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != "ok" {
+		err := fmt.Errorf("%s (HTTP %d)", strings.TrimSpace(string(body)), resp.StatusCode)
+		return xpost.PostResult{}, fmt.Errorf("send message: %w", xpost.Retryable(err, resp.StatusCode, xpost.RetryAfter(resp.Header)))
+	}
+	logutil.Debugf("slack webhook message sent")
+	return xpost.PostResult{Provider: providerName}, nil
+}
+
+// Verify checks the bot token without posting and returns the bot's name.
+func (c *Client) Verify(ctx context.Context) (string, error) {
+	var res struct {
+		User string `json:"user"`
+		Team string `json:"team"`
+	}
+	if err := c.call(ctx, "auth.test", nil, &res); err != nil {
+		return "", fmt.Errorf("verify credentials: %w", err)
+	}
+	return fmt.Sprintf("@%s (%s)", res.User, res.Team), nil
+}
+
+// Post uploads any images and sends the message to the channel with
+// chat.postMessage, showing each image in a block with its alt text.
+func (c *Client) Post(ctx context.Context, req xpost.Request) (xpost.PostResult, error) {
+	text := escaper.Replace(composeText(req))
+	msg := postMessageRequest{Channel: c.cfg.Channel, Text: text}
+
+	if len(req.Images) > 0 {
+		// With blocks, text is only the notification fallback, so it is
+		// repeated in section blocks.
+		if strings.TrimSpace(text) != "" {
+			for _, part := range xpost.SplitText(text, maxSectionChars, utf8.RuneCountInString) {
+				msg.Blocks = append(msg.Blocks, block{Type: "section", Text: &textObject{Type: "mrkdwn", Text: part}})
+			}
+		}
+		for _, img := range req.Images {
+			fileID, err := c.uploadFile(ctx, img)
+			if err != nil {
+				return xpost.PostResult{}, err
+			}
+			alt := strings.TrimSpace(img.Alt)
+			if alt == "" {
+				alt = filepath.Base(img.Path)
+			}
+			msg.Blocks = append(msg.Blocks, block{Type: "image", SlackFile: &slackFile{ID: fileID}, AltText: alt})
+		}
+	}
+
+	var res struct {
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	if err := c.call(ctx, "chat.postMessage", msg, &res); err != nil {
+		return xpost.PostResult{}, fmt.Errorf("post message: %w", err)
+	}
+	logutil.Debugf("slack message posted: channel=%s ts=%s", res.Channel, res.TS)
+
+	result := xpost.PostResult{Provider: providerName, ID: res.TS}
+	var link struct {
+		Permalink string `json:"permalink"`
+	}
+	if err := c.call(ctx, "chat.getPermalink", url.Values{"channel": {res.Channel}, "message_ts": {res.TS}}, &link); err != nil {
+		logutil.Debugf("slack: no link for message %s: %v", res.TS, err)
+	}
+	result.URL = link.Permalink
+	return result, nil
+}
+
+type postMessageRequest struct {
+	Channel string  `json:"channel"`
+	Text    string  `json:"text"`
+	Blocks  []block `json:"blocks,omitempty"`
+}
+
+type block struct {
+	Type      string      `json:"type"`
+	Text      *textObject `json:"text,omitempty"`
+	SlackFile *slackFile  `json:"slack_file,omitempty"`
+	AltText   string      `json:"alt_text,omitempty"`
+}
+
+type textObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackFile struct {
+	ID string `json:"id"`
+}
+
+// composeText joins the message and link the way they are sent.
+func composeText(req xpost.Request) string {
+	if req.Link == "" {
+		return req.Message
+	}
+	return strings.TrimSpace(req.Message + "\n\n" + req.Link)
+}
+
+// uploadFile uploads the image with Slack's external upload flow and returns
+// its file ID. The file is not shared anywhere until a message shows it.
+func (c *Client) uploadFile(ctx context.Context, img xpost.ImageAttachment) (string, error) {
+	data, err := os.ReadFile(img.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("image %q not found", img.Path)}
+		}
+		return "", fmt.Errorf("read image: %w", err)
+	}
+
+	name := filepath.Base(img.Path)
+	form := url.Values{"filename": {name}, "length": {strconv.Itoa(len(data))}}
+	if alt := strings.TrimSpace(img.Alt); alt != "" {
+		form.Set("alt_txt", alt)
+	}
+	var target struct {
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	if err := c.call(ctx, "files.getUploadURLExternal", form, &target); err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, target.UploadURL, bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("upload image: %w", xpost.Retryable(err, 0, 0))
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("upload image: %w", xpost.Retryable(fmt.Errorf("HTTP %d", resp.StatusCode), resp.StatusCode, 0))
+	}
+
+	files, err := json.Marshal([]map[string]string{{"id": target.FileID, "title": name}})
+	if err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	if err := c.call(ctx, "files.completeUploadExternal", url.Values{"files": {string(files)}}, nil); err != nil {
+		return "", fmt.Errorf("upload image: %w", err)
+	}
+	logutil.Debugf("slack file uploaded: id=%s", target.FileID)
+	return target.FileID, nil
+}
+
+// call invokes a Web API method with the bot token. Forms are sent
+// url-encoded and anything else as JSON; Slack reports failures in the
+// response's ok and error fields.
+func (c *Client) call(ctx context.Context, method string, payload, out any) error {
+	var (
+		body        io.Reader
+		contentType string
+	)
+	switch p := payload.(type) {
+	case nil:
+	case url.Values:
+		body, contentType = strings.NewReader(p.Encode()), "application/x-www-form-urlencoded"
+	default:
+		buf, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		body, contentType = bytes.NewReader(buf), "application/json; charset=utf-8"
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+method, body)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.cfg.BotToken)
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return xpost.Retryable(err, 0, 0)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return xpost.Retryable(fmt.Errorf("HTTP %d", resp.StatusCode), resp.StatusCode, xpost.RetryAfter(resp.Header))
+	}
+
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("unexpected response: %w", err)
+	}
+	if !status.OK {
+		return errors.New(status.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// loadConfig checks that the Slack section has the credentials needed to
+// post.
+func loadConfig(section config.Slack) (Config, error) {
+	cfg := Config{
+		WebhookURL: strings.TrimSpace(section.WebhookURL),
+		BotToken:   strings.TrimSpace(section.BotToken),
+		Channel:    strings.TrimSpace(section.Channel),
+	}
+
+	switch {
+	case cfg.BotToken != "" && cfg.Channel == "":
+		return Config{}, xpost.MissingEnvError{Provider: providerName, Variables: []string{envChannel}}
+	case cfg.BotToken == "" && cfg.WebhookURL == "":
+		return Config{}, xpost.MissingEnvError{Provider: providerName, Variables: []string{envWebhookURL}}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,428 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+const (
+	envBotToken = "XPOST_TELEGRAM_BOT_TOKEN"
+	envChatID   = "XPOST_TELEGRAM_CHAT_ID"
+
+	providerName    = "telegram"
+	maxChars        = 4096     // Telegram's message text limit
+	maxCaptionChars = 1024     // Telegram's photo caption limit
+	maxImages       = 10       // Telegram's photos per media group
+	maxImageBytes   = 10 << 20 // Telegram's sendPhoto upload limit
+
+	apiURL = "https://api.telegram.org/bot"
+)
+
+// imageTypes are the MIME types accepted as an explicit media type override.
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// Config contains the bot and the chat or channel it posts to.
+type Config struct {
+	BotToken string
+	// ChatID is a numeric chat ID or a public channel's @username.
+	ChatID string
+
+	// Timeout bounds each request; zero means xpost.DefaultHTTPTimeout.
+	Timeout time.Duration
+}
+
+// Client implements the xpost.Poster interface for Telegram.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+func init() {
+	xpost.Register(providerName, New, xpost.Info{Label: "Telegram", Icon: "\uf2c6", Color: 33})
+	xpost.RegisterValidator(providerName, NewValidator)
+	config.RegisterSection(providerName, config.Section{
+		Options: func(c *config.Config) *config.Options { return &c.Telegram.Options },
+		Vars: func(c *config.Config) map[string]*string {
+			return map[string]*string{
+				envBotToken: &c.Telegram.BotToken,
+				envChatID:   &c.Telegram.ChatID,
+			}
+		},
+		Account: func(c, acct *config.Config, name string) bool {
+			a, ok := c.Telegram.Accounts[name]
+			acct.Telegram = a
+			return ok
+		},
+	})
+}
+
+// New constructs a Telegram poster from its section of the resolved config.
+func New(ctx context.Context, settings *config.Config) (xpost.Poster, error) {
+	cfg, err := loadConfig(settings.Telegram)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeout = settings.HTTPTimeout
+
+	return NewWithConfig(ctx, cfg), nil
+}
+
+// NewWithConfig constructs a Telegram client from explicit settings.
+func NewWithConfig(ctx context.Context, cfg Config) *Client {
+	return &Client{cfg: cfg, http: &http.Client{Timeout: xpost.HTTPTimeout(cfg.Timeout)}}
+}
+
+// NewValidator returns a Telegram validator that needs no credentials.
+func NewValidator() xpost.Validator {
+	return &Client{}
+}
+
+// Name identifies the provider.
+func (c *Client) Name() string { return providerName }
+
+// Verify checks the bot token without posting and returns the bot's handle.
+func (c *Client) Verify(ctx context.Context) (string, error) {
+	var me struct {
+		Username string `json:"username"`
+	}
+	if err := c.call(ctx, "getMe", nil, &me); err != nil {
+		return "", fmt.Errorf("verify credentials: %w", err)
+	}
+	return "@" + me.Username, nil
+}
+
+// charLimit is the caption limit when photos are attached and the message
+// limit otherwise.
+func charLimit(req xpost.Request) int {
+	if len(req.Images) > 0 {
+		return maxCaptionChars
+	}
+	return maxChars
+}
+
+// Truncate shortens the message so it, including any link, fits within the
+// message or caption limit.
+func (c *Client) Truncate(req xpost.Request) (xpost.Request, string) {
+	limit := charLimit(req)
+	if req.Link != "" {
+		limit -= utf8.RuneCountInString("\n\n" + req.Link)
+	}
+	var removed string
+	req.Message, removed = xpost.TruncateText(req.Message, limit, utf8.RuneCountInString)
+	return req, removed
+}
+
+// Capabilities returns Telegram's limits.
+func (c *Client) Capabilities(ctx context.Context) (xpost.Capabilities, error) {
+	return xpost.Capabilities{
+		MaxChars:      maxChars,
+		Unit:          "characters",
+		MaxImages:     maxImages,
+		MaxImageBytes: maxImageBytes,
+		MediaTypes:    imageTypes,
+		Features:      []string{fmt.Sprintf("captions of up to %d characters with images", maxCaptionChars)},
+	}, nil
+}
+
+// Count measures the message, or the caption when photos are attached.
+func (c *Client) Count(req xpost.Request) (int, int, string) {
+	unit := "characters"
+	if len(req.Images) > 0 {
+		unit = "caption characters"
+	}
+	return utf8.RuneCountInString(composeText(req)), charLimit(req), unit
+}
+
+// Validate checks if the request meets Telegram's constraints.
+func (c *Client) Validate(req xpost.Request) error {
+	if len(req.Images) > maxImages {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("too many images: %d (max %d)", len(req.Images), maxImages)}
+	}
+	for _, img := range req.Images {
+		if img.MediaType != "" && !slices.Contains(imageTypes, img.MediaType) {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type %q", img.MediaType)}
+		}
+		if img.IsVideo() {
+			return xpost.ValidationError{Provider: providerName, Reason: "videos are not supported"}
+		}
+	}
+	if req.ReplyTo != "" {
+		if _, err := parseMessageID(req.ReplyTo); err != nil {
+			return xpost.ValidationError{Provider: providerName, Reason: err.Error()}
+		}
+	}
+	if strings.TrimSpace(composeText(req)) == "" && len(req.Images) == 0 {
+		return xpost.ValidationError{Provider: providerName, Reason: "a message or an image is required"}
+	}
+	if count, limit, unit := c.Count(req); count > limit {
+		return xpost.ValidationError{
+			Provider: providerName,
+			Reason:   fmt.Sprintf("message too long: %d %s (max %d)", count, unit, limit),
+		}
+	}
+	return nil
+}
+
+// Post sends the message with sendMessage, or as the caption of the photos
+// with sendPhoto (one image) or sendMediaGroup (several).
+func (c *Client) Post(ctx context.Context, req xpost.Request) (xpost.PostResult, error) {
+	text := composeText(req)
+	params := map[string]string{"chat_id": c.cfg.ChatID}
+	if req.ReplyTo != "" {
+		id, err := parseMessageID(req.ReplyTo)
+		if err != nil {
+			return xpost.PostResult{}, fmt.Errorf("resolve reply target: %w", err)
+		}
+		params["reply_parameters"] = fmt.Sprintf(`{"message_id":%d}`, id)
+	}
+
+	var (
+		msg message
+		err error
+	)
+	switch len(req.Images) {
+	case 0:
+		params["text"] = text
+		err = c.call(ctx, "sendMessage", params, &msg)
+	case 1:
+		params["caption"] = text
+		err = c.upload(ctx, "sendPhoto", params, req.Images, &msg)
+	default:
+		media := make([]inputMedia, len(req.Images))
+		for i := range req.Images {
+			media[i] = inputMedia{Type: "photo", Media: fmt.Sprintf("attach://photo%d", i)}
+		}
+		media[0].Caption = text
+		buf, merr := json.Marshal(media)
+		if merr != nil {
+			return xpost.PostResult{}, fmt.Errorf("send photos: %w", merr)
+		}
+		params["media"] = string(buf)
+		// A media group is answered with one message per photo; the first
+		// carries the caption.
+		var msgs []message
+		if err = c.upload(ctx, "sendMediaGroup", params, req.Images, &msgs); err == nil && len(msgs) > 0 {
+			msg = msgs[0]
+		}
+	}
+	if err != nil {
+		return xpost.PostResult{}, fmt.Errorf("send message: %w", err)
+	}
+	logutil.Debugf("telegram message sent: id=%d", msg.MessageID)
+
+	id := strconv.FormatInt(msg.MessageID, 10)
+	return xpost.PostResult{Provider: providerName, ID: id, URL: messageURL(msg)}, nil
+}
+
+// Delete removes the message with the given ID from the chat.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	if err := c.call(ctx, "deleteMessage", map[string]string{"chat_id": c.cfg.ChatID, "message_id": id}, nil); err != nil {
+		return fmt.Errorf("delete message: %w", err)
+	}
+	return nil
+}
+
+type message struct {
+	MessageID int64 `json:"message_id"`
+	Chat      struct {
+		ID       int64  `json:"id"`
+		Username string `json:"username"`
+	} `json:"chat"`
+}
+
+type inputMedia struct {
+	Type    string `json:"type"`
+	Media   string `json:"media"`
+	Caption string `json:"caption,omitempty"`
+}
+
+// composeText joins the message and link the way they are sent.
+func composeText(req xpost.Request) string {
+	if req.Link == "" {
+		return req.Message
+	}
+	return strings.TrimSpace(req.Message + "\n\n" + req.Link)
+}
+
+// messageURL links to msg: t.me/<username>/<id> in public chats and
+// t.me/c/<id>/<id> in private supergroups and channels, which only members
+// can open. Other chats have no links.
+func messageURL(msg message) string {
+	if msg.Chat.Username != "" {
+		return fmt.Sprintf("https://t.me/%s/%d", msg.Chat.Username, msg.MessageID)
+	}
+	if chat, ok := strings.CutPrefix(strconv.FormatInt(msg.Chat.ID, 10), "-100"); ok {
+		return fmt.Sprintf("https://t.me/c/%s/%d", chat, msg.MessageID)
+	}
+	return ""
+}
+
+// parseMessageID reads a message ID, or the ID at the end of a t.me message
+// link.
+func parseMessageID(ref string) (int64, error) {
+	ref = strings.TrimSpace(ref)
+	if u, err := url.Parse(ref); err == nil && u.Host != "" {
+		ref = u.Path[strings.LastIndex(u.Path, "/")+1:]
+	}
+	id, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid message reference %q: expected a message ID or t.me link", ref)
+	}
+	return id, nil
+}
+
+// upload calls method with params as form fields and the images attached
+// as photo (a single image) or photo0, photo1, ... (a media group).
+func (c *Client) upload(ctx context.Context, method string, params map[string]string, images []xpost.ImageAttachment, out any) error {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for key, value := range params {
+		if err := mw.WriteField(key, value); err != nil {
+			return err
+		}
+	}
+	for i, img := range images {
+		field := "photo"
+		if len(images) > 1 {
+			field = fmt.Sprintf("photo%d", i)
+		}
+		if err := writeImage(mw, field, img); err != nil {
+			return err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+c.cfg.BotToken+"/"+method, body)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", mw.FormDataContentType())
+	return c.send(httpReq, out)
+}
+
+// writeImage adds the image to the form as field.
+func writeImage(mw *multipart.Writer, field string, img xpost.ImageAttachment) error {
+	data, err := os.ReadFile(img.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("image %q not found", img.Path)}
+		}
+		return fmt.Errorf("read image: %w", err)
+	}
+
+	mediaType := img.MediaType
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, field, filepath.Base(img.Path)))
+	header.Set("Content-Type", mediaType)
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("attach image: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return fmt.Errorf("attach image: %w", err)
+	}
+	return nil
+}
+
+// call invokes a Bot API method with params as form fields; nested objects
+// are passed JSON-encoded.
+func (c *Client) call(ctx context.Context, method string, params map[string]string, out any) error {
+	form := url.Values{}
+	for key, value := range params {
+		form.Set(key, value)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+c.cfg.BotToken+"/"+method, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.send(httpReq, out)
+}
+
+// send performs a Bot API request and decodes its result into out. The
+// request URL holds the bot token, so transport errors are reported without
+// it.
+func (c *Client) send(httpReq *http.Request, out any) error {
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return xpost.Retryable(err, 0, 0)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+		ErrorCode   int             `json:"error_code"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf("unexpected response: HTTP %d", resp.StatusCode)
+	}
+	if !res.OK {
+		err := fmt.Errorf("%s (HTTP %d)", res.Description, resp.StatusCode)
+		return xpost.Retryable(err, resp.StatusCode, time.Duration(res.Parameters.RetryAfter)*time.Second)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(res.Result, out)
+}
+
+// loadConfig checks that the Telegram section has the credentials needed to
+// post.
+func loadConfig(section config.Telegram) (Config, error) {
+	cfg := Config{
+		BotToken: strings.TrimSpace(section.BotToken),
+		ChatID:   strings.TrimSpace(section.ChatID),
+	}
+
+	var missing []string
+	if cfg.BotToken == "" {
+		missing = append(missing, envBotToken)
+	}
+	if cfg.ChatID == "" {
+		missing = append(missing, envChatID)
+	}
+
+	if len(missing) > 0 {
+		return Config{}, xpost.MissingEnvError{Provider: providerName, Variables: missing}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,16 @@
+package xpost
+
+import "time"
+
+// DefaultHTTPTimeout is the per-request timeout when none is configured.
+const DefaultHTTPTimeout = 30 * time.Second
+
+// HTTPTimeout returns the per-request timeout for provider HTTP clients: d,
+// or the default when d is zero. It applies to each request separately, so
+// chunked uploads are not cut off as a whole.
+func HTTPTimeout(d time.Duration) time.Duration {
+	if d <= 0 {
+		return DefaultHTTPTimeout
+	}
+	return d
+}
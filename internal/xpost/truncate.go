@@ -0,0 +1,136 @@
+package xpost
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+)
+
+// Ellipsis marks a truncated message.
+const Ellipsis = "…"
+
+// Truncater is implemented by providers that can shorten a message to fit
+// their length limit.
+type Truncater interface {
+	// Truncate returns req with its message shortened so the post fits, along
+	// with the text that was removed ("" when nothing was cut).
+	Truncate(req Request) (Request, string)
+}
+
+// AltTruncater is implemented by providers that cap image descriptions.
+type AltTruncater interface {
+	// TruncateAlt returns an image description shortened to fit, along with
+	// the text that was removed ("" when nothing was cut).
+	TruncateAlt(alt string) (string, string)
+}
+
+// TruncateText cuts text so that, with an ellipsis appended, it measures at
+// most limit according to count. It only cuts between grapheme clusters so
+// emoji sequences and combining marks are never split. It returns the
+// shortened text and the removed suffix.
+func TruncateText(text string, limit int, count func(string) int) (string, string) {
+	if count(text) <= limit {
+		return text, ""
+	}
+
+	budget := limit - count(Ellipsis)
+	used, cut := 0, 0
+	g := uniseg.NewGraphemes(text)
+	for g.Next() {
+		n := count(g.Str())
+		if used+n > budget {
+			break
+		}
+		used += n
+		_, cut = g.Positions()
+	}
+
+	kept := strings.TrimRightFunc(text[:cut], unicode.IsSpace)
+	return kept + Ellipsis, text[len(kept):]
+}
+
+// SplitText breaks text into parts that each measure at most limit according
+// to count, preferring to break at whitespace. Like TruncateText it never
+// splits a grapheme cluster.
+func SplitText(text string, limit int, count func(string) int) []string {
+	return splitParts(text, limit, count, false)
+}
+
+// SplitThread breaks text into posts for a thread, each ending in an "(n/m)"
+// counter and measuring at most limit according to count. It prefers to
+// break after a sentence, then at whitespace; only a word longer than a whole
+// post is split. Text that already fits is returned as is.
+func SplitThread(text string, limit int, count func(string) int) []string {
+	text = strings.TrimSpace(text)
+	if count(text) <= limit {
+		return []string{text}
+	}
+
+	// The counter's width depends on the number of posts, so split again
+	// until the count stops changing how much room it takes.
+	total := 2
+	var parts []string
+	for range 4 {
+		suffix := count(fmt.Sprintf(" (%d/%d)", total, total))
+		parts = splitParts(text, limit-suffix, count, true)
+		if len(strconv.Itoa(len(parts))) == len(strconv.Itoa(total)) {
+			break
+		}
+		total = len(parts)
+	}
+
+	for i := range parts {
+		parts[i] = fmt.Sprintf("%s (%d/%d)", parts[i], i+1, len(parts))
+	}
+	return parts
+}
+
+// splitParts implements SplitText, optionally preferring sentence breaks.
+func splitParts(text string, limit int, count func(string) int, sentences bool) []string {
+	var parts []string
+	text = strings.TrimSpace(text)
+	for text != "" {
+		if count(text) <= limit {
+			parts = append(parts, text)
+			break
+		}
+
+		used, cut, lastSpace, lastSentence := 0, 0, 0, 0
+		prev := ""
+		g := uniseg.NewGraphemes(text)
+		for g.Next() {
+			n := count(g.Str())
+			if used+n > limit {
+				break
+			}
+			used += n
+			_, cut = g.Positions()
+			if strings.TrimSpace(g.Str()) == "" {
+				lastSpace = cut
+				if strings.Contains(g.Str(), "\n") || strings.ContainsAny(prev, ".!?") {
+					lastSentence = cut
+				}
+			}
+			prev = g.Str()
+		}
+		// A sentence break is only worth it if it keeps most of the post.
+		if sentences && lastSentence > cut/2 {
+			cut = lastSentence
+		} else if lastSpace > 0 {
+			cut = lastSpace
+		}
+		if cut == 0 {
+			// A single grapheme wider than limit; emit it alone so the loop
+			// always makes progress.
+			g := uniseg.NewGraphemes(text)
+			g.Next()
+			_, cut = g.Positions()
+		}
+		parts = append(parts, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	return parts
+}
@@ -0,0 +1,97 @@
+package xpost
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		limit       int
+		want        string
+		wantRemoved string
+	}{
+		{name: "fits", text: "hello", limit: 5, want: "hello"},
+		{name: "ascii", text: "hello world", limit: 8, want: "hello w…", wantRemoved: "orld"},
+		{name: "trailing space dropped", text: "hello world", limit: 7, want: "hello…", wantRemoved: " world"},
+		// A family emoji is one grapheme of seven runes: it goes whole or not at all.
+		{name: "zwj sequence kept whole", text: "hi 👨‍👩‍👧‍👦 there", limit: 11, want: "hi 👨‍👩‍👧‍👦…", wantRemoved: " there"},
+		{name: "zwj sequence not split", text: "hi 👨‍👩‍👧‍👦 there", limit: 10, want: "hi…", wantRemoved: " 👨‍👩‍👧‍👦 there"},
+		{name: "combining mark not split", text: "cafe\u0301 au lait", limit: 5, want: "caf…", wantRemoved: "e\u0301 au lait"},
+	}
+	for _, tt := range tests {
+		got, removed := TruncateText(tt.text, tt.limit, utf8.RuneCountInString)
+		if got != tt.want || removed != tt.wantRemoved {
+			t.Errorf("%s: TruncateText = %q, %q; want %q, %q", tt.name, got, removed, tt.want, tt.wantRemoved)
+		}
+		if n := utf8.RuneCountInString(got); n > tt.limit {
+			t.Errorf("%s: %q measures %d, over the limit of %d", tt.name, got, n, tt.limit)
+		}
+	}
+}
+
+func TestSplitThread(t *testing.T) {
+	words := func(n int) string { return strings.TrimSpace(strings.Repeat("ab ", n)) }
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string // the parts, or nil to only check there are n
+		n     int
+	}{
+		{name: "fits", text: " short enough ", limit: 20, want: []string{"short enough"}},
+		{
+			name:  "sentence break",
+			text:  "First sentence here. Then more words follow on",
+			limit: 30,
+			want:  []string{"First sentence here. (1/3)", "Then more words follow (2/3)", "on (3/3)"},
+		},
+		{
+			// The sentence ends too early to be worth breaking at.
+			name:  "word break",
+			text:  "Short one. This sentence is rather longer than that",
+			limit: 30,
+			want:  []string{"Short one. This (1/3)", "sentence is rather (2/3)", "longer than that (3/3)"},
+		},
+		{
+			name:  "long single word",
+			text:  "supercalifragilisticexpialidocious is long",
+			limit: 16,
+			want:  []string{"supercalif (1/5)", "ragilistic (2/5)", "expialidoc (3/5)", "ious is (4/5)", "long (5/5)"},
+		},
+		// Four words fit beside "(n/9)" but only three beside "(n/13)".
+		{name: "nine parts", text: words(36), limit: 18, n: 9},
+		{name: "counter widens past nine", text: words(37), limit: 18, n: 13},
+	}
+	for _, tt := range tests {
+		got := SplitThread(tt.text, tt.limit, utf8.RuneCountInString)
+		if tt.want != nil && !slices.Equal(got, tt.want) {
+			t.Errorf("%s: SplitThread = %q, want %q", tt.name, got, tt.want)
+		}
+		if tt.n > 0 && len(got) != tt.n {
+			t.Errorf("%s: SplitThread made %d parts, want %d: %q", tt.name, len(got), tt.n, got)
+		}
+		var text []string
+		for i, part := range got {
+			if n := utf8.RuneCountInString(part); n > tt.limit {
+				t.Errorf("%s: part %q measures %d, over the limit of %d", tt.name, part, n, tt.limit)
+			}
+			if len(got) > 1 {
+				counter := fmt.Sprintf(" (%d/%d)", i+1, len(got))
+				if !strings.HasSuffix(part, counter) {
+					t.Errorf("%s: part %q does not end in %q", tt.name, part, counter)
+				}
+				part = strings.TrimSuffix(part, counter)
+			}
+			text = append(text, part)
+		}
+		if tt.name != "long single word" && strings.Join(text, " ") != strings.TrimSpace(tt.text) {
+			t.Errorf("%s: parts %q lose text", tt.name, got)
+		}
+	}
+}
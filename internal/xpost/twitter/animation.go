@@ -0,0 +1,63 @@
+package twitter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/gif"
+
+	"github.com/blacktop/xpost/internal/xpost"
+	uploadtypes "github.com/michimani/gotwi/media/upload/types"
+)
+
+// categorize picks the upload category for an image from its contents: only
+// GIFs with more than one frame go up as animated GIFs. X has no category for
+// animated PNG or WebP, so those are rejected rather than losing their motion.
+func categorize(path string, mediaType uploadtypes.MediaType, data []byte) (uploadtypes.MediaCategory, error) {
+	switch mediaType {
+	case uploadtypes.MediaTypeGIF:
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return "", xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("cannot read GIF %s: %v", path, err)}
+		}
+		if len(g.Image) > 1 {
+			return uploadtypes.MediaCategoryTweetGIF, nil
+		}
+	case uploadtypes.MediaTypePNG:
+		if isAnimatedPNG(data) {
+			return "", xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("animated PNG %s is not supported; convert it to a GIF or MP4", path)}
+		}
+	case uploadtypes.MediaTypeWebP:
+		if isAnimatedWebP(data) {
+			return "", xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("animated WebP %s is not supported; convert it to a GIF or MP4", path)}
+		}
+	}
+	return uploadtypes.MediaCategoryTweetImage, nil
+}
+
+// isAnimatedPNG reports whether data is an APNG, which announces its frames
+// in an acTL chunk ahead of the image data.
+func isAnimatedPNG(data []byte) bool {
+	const signatureLen = 8
+	for offset := signatureLen; offset+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		switch string(data[offset+4 : offset+8]) {
+		case "acTL":
+			return true
+		case "IDAT", "IEND":
+			return false
+		}
+		offset += 12 + length // length, type, data and CRC
+	}
+	return false
+}
+
+// isAnimatedWebP reports whether data is a WebP whose extended header sets
+// the animation flag.
+func isAnimatedWebP(data []byte) bool {
+	const animationFlag = 0x02
+	if len(data) < 21 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return false
+	}
+	return string(data[12:16]) == "VP8X" && data[20]&animationFlag != 0
+}
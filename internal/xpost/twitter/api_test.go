@@ -0,0 +1,151 @@
+package twitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/michimani/gotwi"
+)
+
+// fakeAPI is a minimal X API that records the tweets, media uploads, and
+// deletions it receives.
+type fakeAPI struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	tweets  []map[string]any  // JSON body of each tweet created
+	alts    map[string]string // alt text set per media ID
+	uploads int
+	deleted []string
+	// fail maps "METHOD path" to the status code returned for it.
+	fail map[string]int
+	// header is added to every response.
+	header http.Header
+	// failReplies is the status code returned for tweets that reply to
+	// another; zero means they are created.
+	failReplies int
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	t.Helper()
+	f := &fakeAPI{alts: map[string]string{}, fail: map[string]int{}, header: http.Header{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, values := range f.header {
+		w.Header()[key] = values
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if code, ok := f.fail[r.Method+" "+r.URL.Path]; ok {
+		w.WriteHeader(code)
+		fmt.Fprintf(w, `{"title":"Simulated","detail":"simulated failure","type":"about:blank","status":%d}`, code)
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodPost && path == "/2/media/upload/initialize":
+		f.uploads++
+		fmt.Fprintf(w, `{"data":{"id":"media-%d"}}`, f.uploads)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/append"):
+		fmt.Fprint(w, `{"data":{}}`)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/finalize"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/2/media/upload/"), "/finalize")
+		fmt.Fprintf(w, `{"data":{"id":%q}}`, id)
+	case r.Method == http.MethodPost && path == "/1.1/media/metadata/create.json":
+		var in struct {
+			MediaID string `json:"media_id"`
+			AltText struct {
+				Text string `json:"text"`
+			} `json:"alt_text"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		f.alts[in.MediaID] = in.AltText.Text
+		fmt.Fprint(w, `{}`)
+	case r.Method == http.MethodPost && path == "/2/tweets":
+		var in map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := in["reply"]; ok && f.failReplies != 0 {
+			w.WriteHeader(f.failReplies)
+			fmt.Fprintf(w, `{"title":"Simulated","detail":"simulated failure","type":"about:blank","status":%d}`, f.failReplies)
+			return
+		}
+		f.tweets = append(f.tweets, in)
+		fmt.Fprintf(w, `{"data":{"id":"%d","text":""}}`, 100+len(f.tweets))
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/2/tweets/"):
+		f.deleted = append(f.deleted, strings.TrimPrefix(path, "/2/tweets/"))
+		fmt.Fprint(w, `{"data":{"deleted":true}}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"title":"Not Found","detail":"not faked","type":"about:blank","status":404}`)
+	}
+}
+
+// recorded returns copies of the tweets created and the alt texts set.
+func (f *fakeAPI) recorded() ([]map[string]any, map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	alts := make(map[string]string, len(f.alts))
+	for id, alt := range f.alts {
+		alts[id] = alt
+	}
+	return append([]map[string]any(nil), f.tweets...), alts
+}
+
+// toServer sends every request to the fake API whatever its host.
+type toServer struct{ url *url.URL }
+
+func (s toServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = s.url.Scheme, s.url.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// client returns a client whose requests all go to the fake API.
+func (f *fakeAPI) client(t *testing.T) *Client {
+	t.Helper()
+	u, err := url.Parse(f.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := newRateLimits(toServer{u})
+	api, err := gotwi.NewClient(&gotwi.NewClientInput{
+		HTTPClient:           &http.Client{Transport: limits},
+		AuthenticationMethod: gotwi.AuthenMethodOAuth1UserContext,
+		OAuthToken:           "token",
+		OAuthTokenSecret:     "secret",
+		APIKey:               "key",
+		APIKeySecret:         "key secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Client{api: api, limits: limits}
+}
+
+// writePNG creates a tiny PNG named name to upload.
+func writePNG(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pngBytes(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
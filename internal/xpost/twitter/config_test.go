@@ -0,0 +1,55 @@
+package twitter
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/config"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestLoadConfig(t *testing.T) {
+	oauth1 := config.Twitter{ConsumerKey: "key", ConsumerSecret: "secret", AccessToken: "token", AccessTokenSecret: "token secret"}
+	tests := []struct {
+		name    string
+		section config.Twitter
+		want    Config
+		missing []string
+		wantErr bool
+	}{
+		{
+			name:    "oauth1",
+			section: oauth1,
+			want:    Config{APIKey: "key", APISecret: "secret", AccessToken: "token", AccessSecret: "token secret"},
+		},
+		{
+			name:    "oauth1 incomplete",
+			section: config.Twitter{ConsumerKey: "key", AccessToken: " "},
+			missing: []string{envAPISecret, envAccessToken, envAccessSecret},
+		},
+		{
+			name:    "oauth2",
+			section: config.Twitter{Auth: " OAuth2 ", ClientID: "client", TokenFile: "/tmp/token.json", Debug: true},
+			want:    Config{Auth: "oauth2", ClientID: "client", TokenFile: "/tmp/token.json", Debug: true},
+		},
+		{name: "oauth2 without a client", section: config.Twitter{Auth: "oauth2"}, missing: []string{envClientID}},
+		{name: "unknown auth", section: config.Twitter{Auth: "basic"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := loadConfig(tt.section)
+		var missing xpost.MissingEnvError
+		switch {
+		case tt.missing != nil:
+			if !errors.As(err, &missing) || !slices.Equal(missing.Variables, tt.missing) {
+				t.Errorf("%s: loadConfig error = %v, want %v missing", tt.name, err, tt.missing)
+			}
+		case tt.wantErr:
+			if err == nil {
+				t.Errorf("%s: loadConfig succeeded, want an error", tt.name)
+			}
+		case err != nil || got != tt.want:
+			t.Errorf("%s: loadConfig = %+v, %v; want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
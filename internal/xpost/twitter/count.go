@@ -0,0 +1,174 @@
+package twitter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/rivo/uniseg"
+)
+
+// tcoLength is the length every URL counts as once t.co wraps it.
+const tcoLength = 23
+
+// urlRegex matches the URLs that Twitter wraps with t.co: anything with an
+// http(s) scheme, and bare domains such as example.com/foo. The first group
+// is the URL; the leading context keeps e-mail addresses and @mentions out.
+var urlRegex = regexp.MustCompile(`(?i)(?:^|[^\w@./-])((?:https?://[^\s]+)|(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+([a-z]{2,24})(?::\d+)?(?:/[^\s]*)?)`)
+
+// bareTLDs are the top-level domains Twitter links without a scheme. Like
+// twitter-text it needs a known TLD so that "file.txt" stays plain text.
+var bareTLDs = map[string]bool{
+	"ai": true, "app": true, "au": true, "be": true, "biz": true, "blog": true,
+	"br": true, "ca": true, "ch": true, "cn": true, "co": true, "com": true,
+	"de": true, "dev": true, "edu": true, "es": true, "eu": true, "fr": true,
+	"gg": true, "gov": true, "in": true, "info": true, "io": true, "it": true,
+	"jp": true, "ly": true, "me": true, "net": true, "nl": true, "org": true,
+	"ru": true, "se": true, "social": true, "tv": true, "uk": true, "us": true,
+	"xyz": true,
+}
+
+// extractURLs returns the URLs in text as Twitter detects them, without
+// trailing punctuation.
+func extractURLs(text string) []string {
+	var urls []string
+	for _, m := range urlRegex.FindAllStringSubmatchIndex(text, -1) {
+		u := trimURL(text[m[2]:m[3]])
+		if m[4] >= 0 && !bareTLDs[strings.ToLower(text[m[4]:m[5]])] {
+			continue
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// trimURL drops punctuation that ends the sentence rather than the URL,
+// keeping a closing parenthesis that balances one inside it.
+func trimURL(u string) string {
+	for u != "" {
+		last := u[len(u)-1]
+		switch {
+		case strings.IndexByte(".,:;!?'\"", last) >= 0:
+		case last == ')' && strings.Count(u, "(") < strings.Count(u, ")"):
+		default:
+			return u
+		}
+		u = u[:len(u)-1]
+	}
+	return u
+}
+
+// URLCount describes how a single URL counts towards the character limit.
+type URLCount struct {
+	URL     string
+	Actual  int // characters in the URL as written
+	Counted int // characters it counts as after t.co wrapping
+}
+
+// URLBreakdown lists the URLs in text with their actual and counted lengths.
+func URLBreakdown(text string) []URLCount {
+	var counts []URLCount
+	for _, u := range extractURLs(text) {
+		counts = append(counts, URLCount{URL: u, Actual: utf8.RuneCountInString(u), Counted: tcoLength})
+	}
+	return counts
+}
+
+// Count measures the tweet as Twitter does: weighted characters, with URLs
+// shortened to 23 chars.
+func (c *Client) Count(req xpost.Request) (int, int, string) {
+	return countText(composeText(req)), maxChars, "characters"
+}
+
+// countText returns the length of text as Twitter counts it: weightedLength,
+// with every URL counted as tcoLength.
+func countText(text string) int {
+	count := weightedLength(text)
+	for _, u := range URLBreakdown(text) {
+		count += u.Counted - weightedLength(u.URL)
+	}
+	return count
+}
+
+// lightRanges are the code points twitter-text v3 weighs as one character:
+// Latin, Greek, Cyrillic, Hebrew, Arabic, Indic scripts and common
+// punctuation. Everything else, including CJK, counts as two.
+var lightRanges = []struct{ lo, hi rune }{
+	{0x0000, 0x10FF},
+	{0x2000, 0x200D},
+	{0x2010, 0x201F},
+	{0x2032, 0x2037},
+}
+
+// weightedLength returns the length of text as twitter-text v3 weighs it.
+// An emoji, including ZWJ sequences, flags and keycaps, counts as two
+// however many code points it spans.
+func weightedLength(text string) int {
+	n := 0
+	state := -1
+	for text != "" {
+		var cluster string
+		cluster, text, _, state = uniseg.FirstGraphemeClusterInString(text, state)
+		if isEmoji(cluster) {
+			n += 2
+			continue
+		}
+		for _, r := range cluster {
+			n += runeWeight(r)
+		}
+	}
+	return n
+}
+
+func runeWeight(r rune) int {
+	for _, rg := range lightRanges {
+		if r >= rg.lo && r <= rg.hi {
+			return 1
+		}
+	}
+	return 2
+}
+
+// isEmoji reports whether a grapheme cluster is a single emoji.
+func isEmoji(cluster string) bool {
+	first, size := utf8.DecodeRuneInString(cluster)
+	if size == len(cluster) {
+		// A lone code point is weighed by its range.
+		return false
+	}
+	if strings.ContainsAny(cluster, "\u200d\ufe0f\u20e3") {
+		return true
+	}
+	switch {
+	case first >= 0x1F000 && first <= 0x1FAFF, // pictographs, flags, skin tones
+		first >= 0x2600 && first <= 0x27BF: // symbols and dingbats
+		return true
+	}
+	return false
+}
+
+// Preview explains how the tweet's length is counted when it contains URLs.
+func (c *Client) Preview(req xpost.Request) []string {
+	text := composeText(req)
+	urls := URLBreakdown(text)
+	if len(urls) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(urls)+1)
+	for _, u := range urls {
+		lines = append(lines, fmt.Sprintf("URL %s counts as %d (actual %d)", u.URL, u.Counted, u.Actual))
+	}
+	lines = append(lines, fmt.Sprintf("%d characters as written", utf8.RuneCountInString(text)))
+	return lines
+}
+
+// composeText builds the tweet text with the link appended.
+func composeText(req xpost.Request) string {
+	if req.Link == "" {
+		return req.Message
+	}
+	return req.Message + "\n\n" + req.Link
+}
@@ -0,0 +1,127 @@
+package twitter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "no links", want: nil},
+		{text: "see https://example.com/a?b=c.", want: []string{"https://example.com/a?b=c"}},
+		{text: "(https://en.wikipedia.org/wiki/Go_(programming_language))", want: []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"}},
+		{text: "wrapped (https://example.com/x), then", want: []string{"https://example.com/x"}},
+		{text: "two: http://a.io and https://b.dev/path!", want: []string{"http://a.io", "https://b.dev/path"}},
+		{text: "mail me@example.com or @example.com", want: nil},
+		{text: "shortened https://t.co/abc123 mid-sentence", want: []string{"https://t.co/abc123"}},
+		{text: "docs at example.com/foo, then go.dev.", want: []string{"example.com/foo", "go.dev"}},
+		{text: "Really? https://x.com/jack/status/20?!", want: []string{"https://x.com/jack/status/20"}},
+		{text: `quoted "https://example.com/a";`, want: []string{"https://example.com/a"}},
+	}
+	for _, tt := range tests {
+		if got := extractURLs(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("extractURLs(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCountText(t *testing.T) {
+	long := "https://example.com/" + strings.Repeat("a", 60)
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "hello", want: 5},
+		{text: long, want: tcoLength},
+		{text: "go: https://go.dev", want: 4 + tcoLength},
+		{text: "a " + long + " b " + long, want: 5 + 2*tcoLength},
+	}
+	for _, tt := range tests {
+		if got := countText(tt.text); got != tt.want {
+			t.Errorf("countText(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPreviewBreaksDownURLs(t *testing.T) {
+	c := &Client{}
+	if lines := c.Preview(xpost.Request{Message: "no links"}); lines != nil {
+		t.Errorf("Preview without URLs = %q, want nothing", lines)
+	}
+	lines := c.Preview(xpost.Request{Message: "read this", Link: "https://go.dev"})
+	want := []string{"URL https://go.dev counts as 23 (actual 14)", "25 characters as written"}
+	if !slices.Equal(lines, want) {
+		t.Errorf("Preview = %q, want %q", lines, want)
+	}
+}
+
+func TestWeightedLength(t *testing.T) {
+	tests := []struct {
+		name, text string
+		want       int
+	}{
+		{name: "ascii", text: "Hello, world!", want: 13},
+		{name: "latin accents", text: "café", want: 4},
+		{name: "cjk", text: "日本語", want: 6},
+		{name: "hangul", text: "한국", want: 4},
+		{name: "curly quotes and dash", text: "“a”—b", want: 5},
+		{name: "single emoji", text: "👍", want: 2},
+		{name: "skin tone", text: "👍🏽", want: 2},
+		{name: "zwj family", text: "👨‍👩‍👧‍👦", want: 2},
+		{name: "flag", text: "🇯🇵", want: 2},
+		{name: "two flags", text: "🇯🇵🇺🇸", want: 4},
+		{name: "keycap", text: "1️⃣", want: 2},
+		{name: "mixed", text: "Hi 👨‍👩‍👧 日本", want: 3 + 2 + 1 + 4},
+	}
+	for _, tt := range tests {
+		if got := weightedLength(tt.text); got != tt.want {
+			t.Errorf("%s: weightedLength(%q) = %d, want %d", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCountTextURLs(t *testing.T) {
+	tests := []struct {
+		name, text string
+		want       int
+	}{
+		{name: "several urls", text: "https://a.io https://b.dev/x http://c.org", want: 3*tcoLength + 2},
+		{name: "bare domain with known tld", text: "see example.com/docs", want: 4 + tcoLength},
+		{name: "bare domain with unknown tld", text: "open notes.txt", want: 14},
+		{name: "bare domain uppercase tld", text: "Example.COM", want: tcoLength},
+		{name: "url next to cjk", text: "日本 https://example.jp", want: 4 + 1 + tcoLength},
+	}
+	for _, tt := range tests {
+		if got := countText(tt.text); got != tt.want {
+			t.Errorf("%s: countText(%q) = %d, want %d", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestValidateCountsURLs(t *testing.T) {
+	long := "https://example.com/" + strings.Repeat("a", 100)
+	tests := []struct {
+		name    string
+		message string
+		ok      bool
+	}{
+		// The long URL counts as 23, so the tweet fits though it is
+		// longer as written.
+		{name: "long url at the limit", message: strings.Repeat("a", maxChars-tcoLength-1) + " " + long, ok: true},
+		{name: "long url over the limit", message: strings.Repeat("a", maxChars-tcoLength) + " " + long},
+		// A t.co URL shorter than 23 still counts as 23.
+		{name: "short url over the limit", message: strings.Repeat("a", maxChars-tcoLength) + " https://t.co/x"},
+		{name: "bare domain at the limit", message: strings.Repeat("a", maxChars-tcoLength-2) + " example.com.", ok: true},
+	}
+	for _, tt := range tests {
+		if err := (&Client{}).Validate(xpost.Request{Message: tt.message}); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package twitter
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+	"github.com/michimani/gotwi"
+)
+
+const (
+	envAuth         = "XPOST_TWITTER_AUTH"
+	envClientID     = "XPOST_TWITTER_CLIENT_ID"
+	envClientSecret = "XPOST_TWITTER_CLIENT_SECRET"
+	envTokenFile    = "XPOST_TWITTER_TOKEN_FILE"
+	tokenFileName   = "twitter-token.json"
+
+	authorizeEndpoint = "https://x.com/i/oauth2/authorize"
+	tokenEndpoint     = "https://api.x.com/2/oauth2/token"
+
+	// refreshMargin refreshes tokens this long before they expire so one
+	// does not lapse mid-run.
+	refreshMargin = 5 * time.Minute
+)
+
+// oauth2Scopes are the permissions xpost asks for; offline.access grants the
+// refresh token.
+var oauth2Scopes = []string{"tweet.read", "tweet.write", "users.read", "like.write", "media.write", "offline.access"}
+
+// oauth2Token is the OAuth 2.0 token saved between runs.
+type oauth2Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// tokenResponse is the token endpoint's reply.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// newOAuth2Client constructs a Twitter client from the OAuth 2.0 token saved
+// for cfg's app, refreshing it first when it is about to expire.
+func newOAuth2Client(ctx context.Context, cfg Config) (*Client, error) {
+	path, err := tokenPath(cfg.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+	token, err := loadToken(path)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, fmt.Errorf("no OAuth 2.0 token at %s; run `xpost login twitter` first", path)
+	}
+
+	limits := newRateLimits(nil)
+	httpClient := &http.Client{Timeout: xpost.HTTPTimeout(cfg.Timeout), Transport: limits}
+	if time.Until(token.Expiry) < refreshMargin {
+		logutil.Debugf("twitter: refreshing OAuth 2.0 token")
+		form := url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {token.RefreshToken},
+			"client_id":     {cfg.ClientID},
+		}
+		if token, err = requestToken(ctx, httpClient, form, cfg.ClientSecret); err != nil {
+			return nil, fmt.Errorf("refresh OAuth 2.0 token: %w", err)
+		}
+		// X rotates the refresh token, so the new one must be kept.
+		if err := saveToken(path, *token); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := gotwi.NewClientWithAccessToken(&gotwi.NewClientWithAccessTokenInput{
+		HTTPClient:  httpClient,
+		AccessToken: token.AccessToken,
+		Debug:       cfg.Debug || logutil.Verbose(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create X client: %w", err)
+	}
+	return &Client{api: client, limits: limits}, nil
+}
+
+// OAuth2Login is an OAuth 2.0 authorization code flow with PKCE in progress.
+type OAuth2Login struct {
+	// State must come back unchanged on the redirect.
+	State string
+
+	cfg         Config
+	redirectURL string
+	verifier    string
+}
+
+// NewOAuth2Login starts a login for cfg's app that redirects to redirectURL,
+// which must be registered with the app.
+func NewOAuth2Login(cfg Config, redirectURL string) (*OAuth2Login, error) {
+	if cfg.ClientID == "" {
+		return nil, xpost.MissingEnvError{Provider: providerName, Variables: []string{envClientID}}
+	}
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuth2Login{State: state, cfg: cfg, redirectURL: redirectURL, verifier: verifier}, nil
+}
+
+// AuthorizeURL is the page where the user approves xpost.
+func (l *OAuth2Login) AuthorizeURL() string {
+	challenge := sha256.Sum256([]byte(l.verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {l.cfg.ClientID},
+		"redirect_uri":          {l.redirectURL},
+		"scope":                 {strings.Join(oauth2Scopes, " ")},
+		"state":                 {l.State},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	return authorizeEndpoint + "?" + query.Encode()
+}
+
+// Exchange trades the code from the redirect for a token and saves it where
+// later runs read it. It returns the token file's path.
+func (l *OAuth2Login) Exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {l.redirectURL},
+		"code_verifier": {l.verifier},
+		"client_id":     {l.cfg.ClientID},
+	}
+	token, err := requestToken(ctx, &http.Client{Timeout: xpost.HTTPTimeout(l.cfg.Timeout)}, form, l.cfg.ClientSecret)
+	if err != nil {
+		return "", fmt.Errorf("exchange authorization code: %w", err)
+	}
+	path, err := tokenPath(l.cfg.TokenFile)
+	if err != nil {
+		return "", err
+	}
+	return path, saveToken(path, *token)
+}
+
+// requestToken posts form to the token endpoint. Confidential apps also
+// authenticate with their secret.
+func requestToken(ctx context.Context, client *http.Client, form url.Values, secret string) (*oauth2Token, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if secret != "" {
+		req.SetBasicAuth(form.Get("client_id"), secret)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	var res tokenResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	if res.Error != "" {
+		return nil, fmt.Errorf("%s: %s", res.Error, res.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || res.AccessToken == "" {
+		return nil, fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	return &oauth2Token{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(res.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// randomString returns n random bytes, base64url encoded.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// tokenPath returns the token file location: file if it is set, or
+// twitter-token.json under the user's xpost config dir.
+func tokenPath(file string) (string, error) {
+	if file != "" {
+		return file, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locate config dir: %w", err)
+	}
+	return filepath.Join(dir, "xpost", tokenFileName), nil
+}
+
+// loadToken reads the saved token at path. A missing file yields nil.
+func loadToken(path string) (*oauth2Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read token: %w", err)
+	}
+
+	var t oauth2Token
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parse token %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// saveToken writes t to path, readable only by the current user.
+func saveToken(path string, t oauth2Token) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode token: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create token dir: %w", err)
+	}
+
+	// Write to a temp file first so a failed write never loses the refresh token.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".twitter-token-*.json")
+	if err != nil {
+		return fmt.Errorf("write token: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write token: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write token: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write token: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package twitter
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/blacktop/xpost/internal/logutil"
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+// createTweetEndpoint is the endpoint whose quota limits posting.
+const createTweetEndpoint = http.MethodPost + " /2/tweets"
+
+// rateLimit is the quota X last reported for an endpoint.
+type rateLimit struct {
+	remaining int
+	reset     time.Time
+}
+
+// rateLimits is an http.RoundTripper that records the x-rate-limit-remaining
+// and x-rate-limit-reset headers of every response, so a client that has used
+// up an endpoint's quota stops before calling it again.
+type rateLimits struct {
+	next http.RoundTripper
+
+	mu   sync.Mutex
+	seen map[string]rateLimit // keyed by "METHOD /path"
+}
+
+func newRateLimits(next http.RoundTripper) *rateLimits {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &rateLimits{next: next, seen: map[string]rateLimit{}}
+}
+
+// RoundTrip performs the request and records the quota in the response.
+func (l *rateLimits) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := l.next.RoundTrip(req)
+	if err == nil {
+		l.record(req.Method+" "+req.URL.Path, resp.Header)
+	}
+	return resp, err
+}
+
+// record stores the quota in h for endpoint. Responses without both headers
+// are ignored.
+func (l *rateLimits) record(endpoint string, h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("x-rate-limit-remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(h.Get("x-rate-limit-reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	limit := rateLimit{remaining: remaining, reset: time.Unix(reset, 0)}
+	logutil.Debugf("twitter: %s: %d requests left until %s", endpoint, limit.remaining, limit.reset.Format(time.TimeOnly))
+	l.mu.Lock()
+	l.seen[endpoint] = limit
+	l.mu.Unlock()
+}
+
+// check fails when the last response from endpoint said its quota is used up
+// and the window has not reset yet. The error is retryable after the reset.
+func (l *rateLimits) check(endpoint string, now time.Time) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	limit, ok := l.seen[endpoint]
+	l.mu.Unlock()
+	if !ok || limit.remaining > 0 || !now.Before(limit.reset) {
+		return nil
+	}
+
+	wait := limit.reset.Sub(now)
+	return xpost.RetryableError{
+		Err:        fmt.Errorf("rate limit for %s used up; it resets at %s (in %s)", endpoint, limit.reset.Format(time.RFC1123), wait.Round(time.Second)),
+		RetryAfter: wait,
+	}
+}
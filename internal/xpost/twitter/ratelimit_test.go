@@ -0,0 +1,114 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blacktop/xpost/internal/xpost"
+)
+
+func TestRateLimitsRecord(t *testing.T) {
+	reset := time.Unix(1_700_000_000, 0)
+	tests := []struct {
+		name      string
+		remaining string
+		reset     string
+		want      rateLimit
+		recorded  bool
+	}{
+		{name: "both headers", remaining: "3", reset: "1700000000", want: rateLimit{remaining: 3, reset: reset}, recorded: true},
+		{name: "used up", remaining: "0", reset: "1700000000", want: rateLimit{remaining: 0, reset: reset}, recorded: true},
+		{name: "no headers"},
+		{name: "no reset", remaining: "3"},
+		{name: "bad remaining", remaining: "many", reset: "1700000000"},
+		{name: "bad reset", remaining: "3", reset: "soon"},
+	}
+	for _, tt := range tests {
+		l := newRateLimits(nil)
+		h := http.Header{}
+		if tt.remaining != "" {
+			h.Set("x-rate-limit-remaining", tt.remaining)
+		}
+		if tt.reset != "" {
+			h.Set("x-rate-limit-reset", tt.reset)
+		}
+		l.record(createTweetEndpoint, h)
+		got, ok := l.seen[createTweetEndpoint]
+		if ok != tt.recorded || got != tt.want {
+			t.Errorf("%s: recorded %+v (%v), want %+v (%v)", tt.name, got, ok, tt.want, tt.recorded)
+		}
+	}
+}
+
+func TestRateLimitsCheck(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		seen     map[string]rateLimit
+		wantWait time.Duration // 0 when the call is allowed
+	}{
+		{name: "nothing seen"},
+		{name: "quota left", seen: map[string]rateLimit{createTweetEndpoint: {remaining: 1, reset: now.Add(time.Hour)}}},
+		{name: "used up", seen: map[string]rateLimit{createTweetEndpoint: {remaining: 0, reset: now.Add(15 * time.Minute)}}, wantWait: 15 * time.Minute},
+		{name: "window reset", seen: map[string]rateLimit{createTweetEndpoint: {remaining: 0, reset: now}}},
+		{name: "other endpoint used up", seen: map[string]rateLimit{"GET /2/users/me": {remaining: 0, reset: now.Add(time.Hour)}}},
+	}
+	for _, tt := range tests {
+		l := newRateLimits(nil)
+		for endpoint, limit := range tt.seen {
+			l.seen[endpoint] = limit
+		}
+		err := l.check(createTweetEndpoint, now)
+		if tt.wantWait == 0 {
+			if err != nil {
+				t.Errorf("%s: check = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		var retryable xpost.RetryableError
+		if !errors.As(err, &retryable) || retryable.RetryAfter != tt.wantWait {
+			t.Errorf("%s: check = %#v, want retryable after %s", tt.name, err, tt.wantWait)
+		}
+	}
+
+	var l *rateLimits
+	if err := l.check(createTweetEndpoint, now); err != nil {
+		t.Errorf("check on a client without limits = %v, want nil", err)
+	}
+}
+
+func TestPostStopsWhenQuotaUsedUp(t *testing.T) {
+	srv := newFakeAPI(t)
+	srv.header.Set("x-rate-limit-remaining", "0")
+	srv.header.Set("x-rate-limit-reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	c := srv.client(t)
+
+	// The first tweet uses up the quota, so its description reply is held
+	// back, as is the next tweet and its media upload. The first tweet is
+	// still reported, and not retried.
+	images := []xpost.ImageAttachment{{Path: writePNG(t, "a.png"), Alt: "a chart"}}
+	res, err := c.Post(context.Background(), xpost.Request{Message: "first", Images: images, AltAsReply: true})
+	if err == nil || !strings.HasPrefix(err.Error(), "post image description reply: rate limit for POST /2/tweets used up") {
+		t.Errorf("first Post = %v, want the description reply refused", err)
+	}
+	if !errors.As(err, new(xpost.PartialError)) || errors.As(err, new(xpost.RetryableError)) {
+		t.Errorf("first Post = %v, want a partial error that is not retryable", err)
+	}
+	if res.ID != "101" || res.URL != tweetURL("101") {
+		t.Errorf("first Post result = %+v, want tweet 101", res)
+	}
+	_, err = c.Post(context.Background(), xpost.Request{Message: "second", Images: images})
+	if !errors.As(err, new(xpost.RetryableError)) {
+		t.Errorf("second Post = %v, want a retryable rate limit error", err)
+	}
+
+	tweets, _ := srv.recorded()
+	if len(tweets) != 1 || tweets[0]["text"] != "first" || srv.uploads != 1 {
+		t.Errorf("tweets = %v after %d uploads, want only the first", tweets, srv.uploads)
+	}
+}
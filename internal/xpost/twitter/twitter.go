@@ -7,20 +7,31 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/blacktop/xpost/internal/config"
 	"github.com/blacktop/xpost/internal/logutil"
 	"github.com/blacktop/xpost/internal/xpost"
 	"github.com/michimani/gotwi"
 	"github.com/michimani/gotwi/media/upload"
 	uploadtypes "github.com/michimani/gotwi/media/upload/types"
 	"github.com/michimani/gotwi/resources"
+	"github.com/michimani/gotwi/tweet/like"
+	liketypes "github.com/michimani/gotwi/tweet/like/types"
 	"github.com/michimani/gotwi/tweet/managetweet"
 	managetweettypes "github.com/michimani/gotwi/tweet/managetweet/types"
+	"github.com/michimani/gotwi/tweet/retweet"
+	retweettypes "github.com/michimani/gotwi/tweet/retweet/types"
+	"github.com/michimani/gotwi/user/userlookup"
+	userlookuptypes "github.com/michimani/gotwi/user/userlookup/types"
 )
 
 const (
@@ -28,37 +39,120 @@ const (
 	envAPISecret    = "XPOST_TWITTER_CONSUMER_SECRET"
 	envAccessToken  = "XPOST_TWITTER_ACCESS_TOKEN"
 	envAccessSecret = "XPOST_TWITTER_ACCESS_TOKEN_SECRET"
+	envDebug        = "XPOST_TWITTER_DEBUG"
 
 	providerName = "twitter"
-	maxChars     = 280 // Twitter's post character limit
+	maxChars     = 280  // Twitter's post character limit
+	maxAltChars  = 1000 // Twitter's image description limit
+	maxImages    = 4    // Twitter's images per tweet
+
+	maxImageBytes      = 5 << 20           // Twitter's image upload size limit
+	maxVideoBytes      = 512 << 20         // Twitter's video upload size limit
+	maxVideoDuration   = 140 * time.Second // Twitter's video length limit for most accounts
+	appendSegmentBytes = 5 << 20           // chunk size for media upload appends
+	processingTimeout  = 5 * time.Minute   // longest wait for uploaded media to be processed
 
 	metadataEndpoint = "https://upload.twitter.com/1.1/media/metadata/create.json"
+	statusEndpoint   = "https://api.x.com/2/media/upload"
 )
 
-var httpTimeout = 30 * time.Second
+// pollLimits are X's constraints on polls.
+var pollLimits = xpost.PollLimits{
+	MinOptions:     2,
+	MaxOptions:     4,
+	MaxOptionChars: 25,
+	MinDuration:    5 * time.Minute,
+	MaxDuration:    7 * 24 * time.Hour,
+}
 
-// Config captures the credentials required for OAuth 1.0a user-context requests.
+// Config captures the credentials required for OAuth 1.0a user-context
+// requests, or the app whose saved OAuth 2.0 token is used instead.
 type Config struct {
 	APIKey       string
 	APISecret    string
 	AccessToken  string
 	AccessSecret string
+
+	// Auth is "oauth2" to post with the token saved by xpost login twitter.
+	Auth         string
+	ClientID     string
+	ClientSecret string
+	// TokenFile is where the OAuth 2.0 token is saved; empty means the
+	// default location.
+	TokenFile string
+
+	// Debug logs every request and response.
+	Debug bool
+
+	// Timeout bounds each request; zero means xpost.DefaultHTTPTimeout.
+	Timeout time.Duration
 }
 
 // Client implements the Poster interface for X (Twitter).
 type Client struct {
 	api *gotwi.Client
+	// limits holds the rate limits seen in X's responses; nil for validators.
+	limits *rateLimits
 }
 
-// New constructs a Twitter poster using gotwi and OAuth 1.0a credentials.
-func New(ctx context.Context) (xpost.Poster, error) {
-	cfg, err := loadConfigFromEnv()
+func init() {
+	xpost.Register(providerName, New, xpost.Info{
+		Label:    "Twitter/X",
+		Icon:     "\uf099",
+		Color:    39,
+		Features: xpost.Features{Poll: true},
+	})
+	xpost.RegisterValidator(providerName, NewValidator)
+	config.RegisterSection(providerName, config.Section{
+		Options: func(c *config.Config) *config.Options { return &c.Twitter.Options },
+		Vars: func(c *config.Config) map[string]*string {
+			return map[string]*string{
+				envAPIKey:       &c.Twitter.ConsumerKey,
+				envAPISecret:    &c.Twitter.ConsumerSecret,
+				envAccessToken:  &c.Twitter.AccessToken,
+				envAccessSecret: &c.Twitter.AccessTokenSecret,
+				envAuth:         &c.Twitter.Auth,
+				envClientID:     &c.Twitter.ClientID,
+				envClientSecret: &c.Twitter.ClientSecret,
+				envTokenFile:    &c.Twitter.TokenFile,
+			}
+		},
+		Flags: func(c *config.Config) map[string]*bool {
+			return map[string]*bool{envDebug: &c.Twitter.Debug}
+		},
+		Account: func(c, acct *config.Config, name string) bool {
+			a, ok := c.Twitter.Accounts[name]
+			acct.Twitter = a
+			return ok
+		},
+	})
+}
+
+// New constructs a Twitter poster from its section of the resolved config,
+// using gotwi and OAuth 1.0a credentials, or the saved OAuth 2.0 token when
+// the auth is oauth2.
+func New(ctx context.Context, settings *config.Config) (xpost.Poster, error) {
+	cfg, err := loadConfig(settings.Twitter)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeout = settings.HTTPTimeout
+	if cfg.Auth == "oauth2" {
+		return newOAuth2Client(ctx, cfg)
+	}
+
+	client, err := NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
+	return client, nil
+}
 
-	httpClient := &http.Client{Timeout: httpTimeout}
-	debugEnabled := os.Getenv("XPOST_TWITTER_DEBUG") == "1" || logutil.Verbose()
+// NewWithConfig constructs a Twitter client from explicit credentials.
+func NewWithConfig(ctx context.Context, cfg Config) (*Client, error) {
+	limits := newRateLimits(nil)
+	httpClient := &http.Client{Timeout: xpost.HTTPTimeout(cfg.Timeout), Transport: limits}
+	debugEnabled := cfg.Debug || logutil.Verbose()
 
 	client, err := gotwi.NewClient(&gotwi.NewClientInput{
 		HTTPClient:           httpClient,
@@ -77,24 +171,117 @@ func New(ctx context.Context) (xpost.Poster, error) {
 		return nil, fmt.Errorf("twitter client not ready")
 	}
 
-	return &Client{api: client}, nil
+	return &Client{api: client, limits: limits}, nil
+}
+
+// NewValidator returns a Twitter validator that needs no credentials.
+func NewValidator() xpost.Validator {
+	return &Client{}
 }
 
 // Name returns the provider identifier.
 func (c *Client) Name() string { return providerName }
 
+// Verify checks the credentials without posting and returns the account handle.
+func (c *Client) Verify(ctx context.Context) (string, error) {
+	res, err := userlookup.GetMe(ctx, c.api, &userlookuptypes.GetMeInput{})
+	if err != nil {
+		return "", fmt.Errorf("get authenticated user: %w", unwrapGotwiError(err))
+	}
+	if err := partialError(res.Errors); err != nil {
+		return "", fmt.Errorf("get authenticated user: %w", err)
+	}
+	return "@" + gotwi.StringValue(res.Data.Username), nil
+}
+
+// Truncate shortens the message so the tweet, including any link, fits within
+// the character limit.
+func (c *Client) Truncate(req xpost.Request) (xpost.Request, string) {
+	limit := maxChars
+	if req.Link != "" {
+		limit -= countText("\n\n" + req.Link)
+	}
+	var removed string
+	req.Message, removed = xpost.TruncateText(req.Message, limit, countText)
+	return req, removed
+}
+
+// TruncateAlt shortens the image description to Twitter's limit.
+func (c *Client) TruncateAlt(alt string) (string, string) {
+	return xpost.TruncateText(alt, maxAltChars, utf8.RuneCountInString)
+}
+
+// Capabilities returns X's limits. The API does not publish them, so these
+// are the defaults for non-premium accounts.
+func (c *Client) Capabilities(ctx context.Context) (xpost.Capabilities, error) {
+	return xpost.Capabilities{
+		MaxChars:      maxChars,
+		Unit:          "characters",
+		MaxImages:     maxImages,
+		MaxImageBytes: maxImageBytes,
+		MediaTypes:    slices.Sorted(maps.Keys(mediaTypes)),
+		MaxAltChars:   maxAltChars,
+		Features:      []string{"URLs count as 23 characters", "polls"},
+	}, nil
+}
+
+// CanQuote reports whether url is a tweet that can be quoted.
+func (c *Client) CanQuote(url string) bool {
+	_, err := ParseTweetID(url)
+	return err == nil
+}
+
 // Validate checks if the request meets Twitter's constraints.
 func (c *Client) Validate(req xpost.Request) error {
-	text := req.Message
-	if req.Link != "" {
-		text = text + "\n\n" + req.Link
+	if len(req.Images) > maxImages {
+		return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("too many images: %d (max %d)", len(req.Images), maxImages)}
+	}
+	for _, img := range req.Images {
+		if img.MediaType != "" {
+			if _, ok := mediaTypes[img.MediaType]; !ok {
+				return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type %q", img.MediaType)}
+			}
+		}
+		if ext := strings.ToLower(filepath.Ext(img.Path)); img.MediaType == "" && ext != "" {
+			if _, ok := mediaExtensions[ext]; !ok {
+				return xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type %q", ext)}
+			}
+		}
+		if img.IsVideo() {
+			if len(req.Images) > 1 {
+				return xpost.ValidationError{Provider: providerName, Reason: "a video cannot be combined with other media"}
+			}
+			if err := xpost.CheckVideo(providerName, img, maxVideoBytes, maxVideoDuration); err != nil {
+				return err
+			}
+		} else if data, err := os.ReadFile(img.Path); err == nil {
+			// Unreadable files are left for the upload to report.
+			if _, _, err := resolveMediaType(img.Path, data, img.MediaType); err != nil {
+				return err
+			}
+		}
+		if count := utf8.RuneCountInString(img.Alt); count > maxAltChars {
+			return xpost.ValidationError{
+				Provider: providerName,
+				Reason:   fmt.Sprintf("image description too long: %d characters (max %d)", count, maxAltChars),
+			}
+		}
+	}
+	if strings.TrimSpace(composeText(req)) == "" && len(req.Images) == 0 {
+		return xpost.ValidationError{Provider: providerName, Reason: "a message or an image is required"}
 	}
-	// Twitter counts characters (not graphemes), and URLs are shortened to 23 chars
-	// For simplicity, we use len() which counts bytes - this is conservative for ASCII
-	// but may over-count for multi-byte UTF-8 characters. A proper implementation
-	// would use Twitter's text parsing library.
-	count := len([]rune(text))
-	if count > maxChars {
+	if req.Quote != "" && len(req.Images) > 0 {
+		return xpost.ValidationError{Provider: providerName, Reason: "a quote tweet cannot also attach an image"}
+	}
+	if req.Poll != nil {
+		if len(req.Images) > 0 || req.Quote != "" {
+			return xpost.ValidationError{Provider: providerName, Reason: "a poll cannot be combined with images or a quote"}
+		}
+		if err := xpost.CheckPoll(providerName, req.Poll, pollLimits); err != nil {
+			return err
+		}
+	}
+	if count, _, _ := c.Count(req); count > maxChars {
 		return xpost.ValidationError{
 			Provider: providerName,
 			Reason:   fmt.Sprintf("message too long: %d characters (max %d)", count, maxChars),
@@ -104,13 +291,18 @@ func (c *Client) Validate(req xpost.Request) error {
 }
 
 // Post publishes the message (and optional media) to X.
-func (c *Client) Post(ctx context.Context, req xpost.Request) error {
+func (c *Client) Post(ctx context.Context, req xpost.Request) (xpost.PostResult, error) {
+	// Stop before uploading media for a post X would refuse.
+	if err := c.limits.check(createTweetEndpoint, time.Now()); err != nil {
+		return xpost.PostResult{}, err
+	}
+
 	var mediaIDs []string
-	if strings.TrimSpace(req.ImagePath) != "" {
-		logutil.Debugf("uploading media: path=%s", req.ImagePath)
-		mediaID, err := c.uploadMedia(ctx, req.ImagePath, req.ImageAlt)
+	for _, img := range req.Images {
+		logutil.Debugf("uploading media: path=%s", img.Path)
+		mediaID, err := c.uploadMedia(ctx, img.Path, img.Alt, img.MediaType)
 		if err != nil {
-			return err
+			return xpost.PostResult{}, err
 		}
 		mediaIDs = append(mediaIDs, mediaID)
 		logutil.Debugf("media uploaded: media_id=%s", mediaID)
@@ -128,17 +320,174 @@ func (c *Client) Post(ctx context.Context, req xpost.Request) error {
 	if len(mediaIDs) > 0 {
 		input.Media = &managetweettypes.CreateInputMedia{MediaIDs: mediaIDs}
 	}
+	if req.ReplyTo != "" {
+		parentID, err := ParseTweetID(req.ReplyTo)
+		if err != nil {
+			return xpost.PostResult{}, xpost.ValidationError{Provider: providerName, Reason: err.Error()}
+		}
+		input.Reply = &managetweettypes.CreateInputReply{InReplyToTweetID: parentID}
+	}
+	if req.Quote != "" {
+		quotedID, err := ParseTweetID(req.Quote)
+		if err != nil {
+			return xpost.PostResult{}, xpost.ValidationError{Provider: providerName, Reason: err.Error()}
+		}
+		input.QuoteTweetID = gotwi.String(quotedID)
+	}
+	if req.Poll != nil {
+		input.Poll = &managetweettypes.CreateInputPoll{
+			Options:         req.Poll.Options,
+			DurationMinutes: gotwi.Int(int(req.Poll.Duration / time.Minute)),
+		}
+	}
 
 	logutil.Debugf("posting tweet: media_count=%d", len(mediaIDs))
-	if _, err := managetweet.Create(ctx, c.api, input); err != nil {
-		return fmt.Errorf("post tweet: %w", unwrapGotwiError(err))
+	res, err := managetweet.Create(ctx, c.api, input)
+	if err != nil {
+		return xpost.PostResult{}, fmt.Errorf("post tweet: %w", unwrapGotwiError(err))
+	}
+	tweetID := gotwi.StringValue(res.Data.ID)
+	result := xpost.PostResult{Provider: providerName, ID: tweetID, URL: tweetURL(tweetID)}
+	logutil.Debugf("tweet posted successfully: id=%s", tweetID)
+
+	if reply := req.AltReply(); reply != "" && len(mediaIDs) > 0 {
+		// Long descriptions continue across a chain of replies.
+		parentID := tweetID
+		for _, part := range xpost.SplitText(reply, maxChars, countText) {
+			logutil.Debugf("posting image description reply: in_reply_to=%s", parentID)
+			// The tweet is already up, so a used-up quota ends the chain
+			// without inviting a retry that would post it again.
+			if err := c.limits.check(createTweetEndpoint, time.Now()); err != nil {
+				return result, xpost.Partial(fmt.Errorf("post image description reply: %w", err))
+			}
+			res, err := managetweet.Create(ctx, c.api, &managetweettypes.CreateInput{
+				Text:  gotwi.String(part),
+				Reply: &managetweettypes.CreateInputReply{InReplyToTweetID: parentID},
+			})
+			if err != nil {
+				return result, xpost.Partial(fmt.Errorf("post image description reply: %w", unwrapGotwiError(err)))
+			}
+			parentID = gotwi.StringValue(res.Data.ID)
+		}
 	}
-	logutil.Debugf("tweet posted successfully")
 
+	return result, nil
+}
+
+// Repost retweets the tweet at ref as the authenticated user.
+func (c *Client) Repost(ctx context.Context, ref string) error {
+	tweetID, err := ParseTweetID(ref)
+	if err != nil {
+		return err
+	}
+
+	me, err := userlookup.GetMe(ctx, c.api, &userlookuptypes.GetMeInput{})
+	if err != nil {
+		return fmt.Errorf("get authenticated user: %w", unwrapGotwiError(err))
+	}
+
+	res, err := retweet.Create(ctx, c.api, &retweettypes.CreateInput{
+		ID:      gotwi.StringValue(me.Data.ID),
+		TweetID: tweetID,
+	})
+	if err != nil {
+		return fmt.Errorf("retweet: %w", unwrapGotwiError(err))
+	}
+	if !res.Data.Retweeted {
+		return fmt.Errorf("retweet: %s was not retweeted", tweetID)
+	}
 	return nil
 }
 
-func (c *Client) uploadMedia(ctx context.Context, imagePath, altText string) (string, error) {
+// React likes the tweet with the given URL or ID. X has no emoji reactions,
+// so emoji is ignored.
+func (c *Client) React(ctx context.Context, ref, emoji string) error {
+	tweetID, err := ParseTweetID(ref)
+	if err != nil {
+		return err
+	}
+
+	me, err := userlookup.GetMe(ctx, c.api, &userlookuptypes.GetMeInput{})
+	if err != nil {
+		return fmt.Errorf("get authenticated user: %w", unwrapGotwiError(err))
+	}
+
+	res, err := like.Create(ctx, c.api, &liketypes.CreateInput{
+		ID:      gotwi.StringValue(me.Data.ID),
+		TweetID: tweetID,
+	})
+	if err != nil {
+		return fmt.Errorf("like: %w", unwrapGotwiError(err))
+	}
+	if !res.Data.Liked {
+		return fmt.Errorf("like: %s was not liked", tweetID)
+	}
+	return nil
+}
+
+// Delete removes the tweet with the given ID.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	res, err := managetweet.Delete(ctx, c.api, &managetweettypes.DeleteInput{ID: id})
+	if err != nil {
+		return fmt.Errorf("delete tweet: %w", unwrapGotwiError(err))
+	}
+	if res.Data.Deleted == nil || !*res.Data.Deleted {
+		return fmt.Errorf("delete tweet: %s was not deleted", id)
+	}
+	return nil
+}
+
+// ParseTweetID extracts the tweet ID from a twitter.com/x.com status URL, or
+// accepts a bare numeric ID.
+func ParseTweetID(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if isNumeric(raw) {
+		return raw, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid tweet URL %q", raw)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "mobile.")
+	if host != "x.com" && host != "twitter.com" {
+		return "", fmt.Errorf("invalid tweet URL %q: not an x.com or twitter.com link", raw)
+	}
+
+	// /<user>/status/<id>[/photo/1] or /i/web/status/<id>
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "status" || parts[i] == "statuses" {
+			if isNumeric(parts[i+1]) {
+				return parts[i+1], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("invalid tweet URL %q: no status ID found", raw)
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// tweetURL builds a permalink that resolves without knowing the author's handle.
+func tweetURL(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "https://x.com/i/status/" + id
+}
+
+func (c *Client) uploadMedia(ctx context.Context, imagePath, altText, mediaTypeOverride string) (string, error) {
 	data, err := os.ReadFile(imagePath)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -147,7 +496,7 @@ func (c *Client) uploadMedia(ctx context.Context, imagePath, altText string) (st
 		return "", fmt.Errorf("read image: %w", err)
 	}
 
-	mediaType, category, err := resolveMediaType(imagePath, data)
+	mediaType, category, err := resolveMediaType(imagePath, data, mediaTypeOverride)
 	if err != nil {
 		return "", err
 	}
@@ -168,20 +517,23 @@ func (c *Client) uploadMedia(ctx context.Context, imagePath, altText string) (st
 	mediaID := initRes.Data.MediaID
 	logutil.Debugf("initialize complete: media_id=%s", mediaID)
 
-	appendIn := &uploadtypes.AppendInput{
-		MediaID:      mediaID,
-		Media:        bytes.NewReader(data),
-		SegmentIndex: 0,
-	}
-	appendIn.GenerateBoundary()
+	for segment, offset := 0, 0; offset < len(data); segment, offset = segment+1, offset+appendSegmentBytes {
+		chunk := data[offset:min(offset+appendSegmentBytes, len(data))]
+		appendIn := &uploadtypes.AppendInput{
+			MediaID:      mediaID,
+			Media:        bytes.NewReader(chunk),
+			SegmentIndex: segment,
+		}
+		appendIn.GenerateBoundary()
 
-	logutil.Debugf("append upload: media_id=%s segment=0", mediaID)
-	appendRes, err := upload.Append(ctx, c.api, appendIn)
-	if err != nil {
-		return "", fmt.Errorf("append upload: %w", err)
-	}
-	if err := partialError(appendRes.Errors); err != nil {
-		return "", fmt.Errorf("append upload: %w", err)
+		logutil.Debugf("append upload: media_id=%s segment=%d bytes=%d", mediaID, segment, len(chunk))
+		appendRes, err := upload.Append(ctx, c.api, appendIn)
+		if err != nil {
+			return "", fmt.Errorf("append upload: %w", err)
+		}
+		if err := partialError(appendRes.Errors); err != nil {
+			return "", fmt.Errorf("append upload: %w", err)
+		}
 	}
 	logutil.Debugf("append completed")
 
@@ -193,26 +545,40 @@ func (c *Client) uploadMedia(ctx context.Context, imagePath, altText string) (st
 		return "", fmt.Errorf("finalize upload: %w", err)
 	}
 
-	state := finalizeRes.Data.ProcessingInfo.State
-	logutil.Debugf("finalize state=%s media_id=%s", state, mediaID)
-	switch state {
-	case "", resources.ProcessingInfoStateSucceeded:
-		// no-op
-	case resources.ProcessingInfoStateInProgress, resources.ProcessingInfoStatePending:
-		wait := time.Duration(finalizeRes.Data.ProcessingInfo.CheckAfterSecs) * time.Second
+	info := finalizeRes.Data.ProcessingInfo
+	logutil.Debugf("finalize state=%s media_id=%s", info.State, mediaID)
+	deadline := time.Now().Add(processingTimeout)
+	for info.State == resources.ProcessingInfoStateInProgress || info.State == resources.ProcessingInfoStatePending {
+		wait := max(time.Duration(info.CheckAfterSecs)*time.Second, time.Second)
+		if time.Now().Add(wait).After(deadline) {
+			return "", fmt.Errorf("media processing timed out after %s: state=%s progress=%d%%", processingTimeout, info.State, info.ProgressPercent)
+		}
 		timer := time.NewTimer(wait)
 		select {
 		case <-ctx.Done():
 			timer.Stop()
 			return "", ctx.Err()
 		case <-timer.C:
-			// simple re-check by polling finalize again (images usually succeed quickly).
 		}
-	default:
-		return "", fmt.Errorf("media processing failed: state=%s", state)
+
+		statusRes := &statusResponse{}
+		if err := c.api.CallAPI(ctx, statusEndpoint, http.MethodGet, &statusParameters{mediaID: mediaID}, statusRes); err != nil {
+			return "", fmt.Errorf("media status: %w", unwrapGotwiError(err))
+		}
+		if err := partialError(statusRes.Errors); err != nil {
+			return "", fmt.Errorf("media status: %w", err)
+		}
+		info = statusRes.Data.ProcessingInfo
+		logutil.Debugf("media status state=%s progress=%d%% media_id=%s", info.State, info.ProgressPercent, mediaID)
+	}
+	if info.State != "" && info.State != resources.ProcessingInfoStateSucceeded {
+		return "", fmt.Errorf("media processing failed: state=%s", info.State)
 	}
 
-	if alt := strings.TrimSpace(altText); alt != "" {
+	if category == uploadtypes.MediaCategoryTweetVideo {
+		// The metadata endpoint only describes images and GIFs.
+		logutil.Debugf("skipping alt text for video: media_id=%s", mediaID)
+	} else if alt := strings.TrimSpace(altText); alt != "" {
 		logutil.Debugf("setting alt text: media_id=%s", mediaID)
 		if err := c.setAltText(ctx, mediaID, alt); err != nil {
 			return "", err
@@ -238,12 +604,30 @@ func (c *Client) setAltText(ctx context.Context, mediaID, altText string) error
 	return nil
 }
 
-func loadConfigFromEnv() (Config, error) {
+// loadConfig checks that the Twitter section has the credentials needed to
+// post with the auth it selects.
+func loadConfig(section config.Twitter) (Config, error) {
 	cfg := Config{
-		APIKey:       strings.TrimSpace(os.Getenv(envAPIKey)),
-		APISecret:    strings.TrimSpace(os.Getenv(envAPISecret)),
-		AccessToken:  strings.TrimSpace(os.Getenv(envAccessToken)),
-		AccessSecret: strings.TrimSpace(os.Getenv(envAccessSecret)),
+		APIKey:       strings.TrimSpace(section.ConsumerKey),
+		APISecret:    strings.TrimSpace(section.ConsumerSecret),
+		AccessToken:  strings.TrimSpace(section.AccessToken),
+		AccessSecret: strings.TrimSpace(section.AccessTokenSecret),
+		Auth:         strings.ToLower(strings.TrimSpace(section.Auth)),
+		ClientID:     strings.TrimSpace(section.ClientID),
+		ClientSecret: strings.TrimSpace(section.ClientSecret),
+		TokenFile:    strings.TrimSpace(section.TokenFile),
+		Debug:        section.Debug,
+	}
+
+	switch cfg.Auth {
+	case "", "oauth1":
+	case "oauth2":
+		if cfg.ClientID == "" {
+			return Config{}, xpost.MissingEnvError{Provider: providerName, Variables: []string{envClientID}}
+		}
+		return cfg, nil
+	default:
+		return Config{}, fmt.Errorf("invalid %s %q: expected oauth1 or oauth2", envAuth, cfg.Auth)
 	}
 
 	var missing []string
@@ -267,17 +651,55 @@ func loadConfigFromEnv() (Config, error) {
 	return cfg, nil
 }
 
-func resolveMediaType(path string, data []byte) (uploadtypes.MediaType, uploadtypes.MediaCategory, error) {
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".jpg", ".jpeg":
-		return uploadtypes.MediaTypeJPEG, uploadtypes.MediaCategoryTweetImage, nil
-	case ".png":
-		return uploadtypes.MediaTypePNG, uploadtypes.MediaCategoryTweetImage, nil
-	case ".gif":
-		return uploadtypes.MediaTypeGIF, uploadtypes.MediaCategoryTweetGIF, nil
-	case ".webp":
-		return uploadtypes.MediaTypeWebP, uploadtypes.MediaCategoryTweetImage, nil
+// mediaTypes maps the MIME types accepted as an explicit override to their
+// upload type and category.
+var mediaTypes = map[string]struct {
+	mediaType uploadtypes.MediaType
+	category  uploadtypes.MediaCategory
+}{
+	"image/jpeg":      {uploadtypes.MediaTypeJPEG, uploadtypes.MediaCategoryTweetImage},
+	"image/png":       {uploadtypes.MediaTypePNG, uploadtypes.MediaCategoryTweetImage},
+	"image/gif":       {uploadtypes.MediaTypeGIF, uploadtypes.MediaCategoryTweetGIF},
+	"image/webp":      {uploadtypes.MediaTypeWebP, uploadtypes.MediaCategoryTweetImage},
+	"video/mp4":       {uploadtypes.MediaTypeMP4, uploadtypes.MediaCategoryTweetVideo},
+	"video/quicktime": {uploadtypes.MediaTypeQuickTime, uploadtypes.MediaCategoryTweetVideo},
+}
+
+// mediaExtensions maps the file extensions X accepts to their MIME types.
+var mediaExtensions = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+}
+
+// resolveMediaType returns the upload type and category for the media in
+// data, checking image contents for animation.
+func resolveMediaType(path string, data []byte, override string) (uploadtypes.MediaType, uploadtypes.MediaCategory, error) {
+	mediaType, category, err := sniffMediaType(path, data, override)
+	if err != nil || category == uploadtypes.MediaCategoryTweetVideo {
+		return mediaType, category, err
+	}
+	category, err = categorize(path, mediaType, data)
+	return mediaType, category, err
+}
+
+func sniffMediaType(path string, data []byte, override string) (uploadtypes.MediaType, uploadtypes.MediaCategory, error) {
+	if override != "" {
+		mt, ok := mediaTypes[override]
+		if !ok {
+			return "", "", xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type %q", override)}
+		}
+		return mt.mediaType, mt.category, nil
+	}
+
+	if mime, ok := mediaExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+		mt := mediaTypes[mime]
+		return mt.mediaType, mt.category, nil
 	}
 
 	// fallback to simple detection
@@ -291,9 +713,11 @@ func resolveMediaType(path string, data []byte) (uploadtypes.MediaType, uploadty
 		return uploadtypes.MediaTypeGIF, uploadtypes.MediaCategoryTweetGIF, nil
 	case strings.Contains(detected, "webp"):
 		return uploadtypes.MediaTypeWebP, uploadtypes.MediaCategoryTweetImage, nil
+	case strings.Contains(detected, "mp4"):
+		return uploadtypes.MediaTypeMP4, uploadtypes.MediaCategoryTweetVideo, nil
 	}
 
-	return "", "", xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported image type for %q", path)}
+	return "", "", xpost.ValidationError{Provider: providerName, Reason: fmt.Sprintf("unsupported media type for %q", path)}
 }
 
 func partialError(partials []resources.PartialError) error {
@@ -327,9 +751,22 @@ func errorsJoin(messages []string) error {
 func unwrapGotwiError(err error) error {
 	var gwErr *gotwi.GotwiError
 	if errors.As(err, &gwErr) && gwErr != nil {
-		return fmt.Errorf("%s", summarizeGotwiError(gwErr))
+		if !gwErr.OnAPI {
+			// Transport failures keep their cause so connection timeouts
+			// are retryable.
+			return xpost.Retryable(err, 0, 0)
+		}
+		summary := summarizeGotwiError(gwErr)
+		var wait time.Duration
+		if info := gwErr.RateLimitInfo; info != nil && info.ResetAt != nil {
+			wait = time.Until(*info.ResetAt)
+			if gwErr.StatusCode == http.StatusTooManyRequests {
+				summary += fmt.Sprintf(" (rate limit resets at %s)", info.ResetAt.Local().Format(time.RFC1123))
+			}
+		}
+		return xpost.Retryable(errors.New(summary), gwErr.StatusCode, wait)
 	}
-	return err
+	return xpost.Retryable(err, 0, 0)
 }
 
 func summarizeGotwiError(err *gotwi.GotwiError) string {
@@ -403,3 +840,40 @@ func (p *metadataParameters) ParameterMap() map[string]string {
 type metadataResponse struct{}
 
 func (metadataResponse) HasPartialError() bool { return false }
+
+// statusParameters query the processing state of an uploaded media item.
+type statusParameters struct {
+	mediaID     string
+	accessToken string
+}
+
+func (p *statusParameters) SetAccessToken(token string) {
+	p.accessToken = token
+}
+
+func (p *statusParameters) AccessToken() string {
+	return p.accessToken
+}
+
+func (p *statusParameters) ResolveEndpoint(endpointBase string) string {
+	query := url.Values{}
+	for key, value := range p.ParameterMap() {
+		query.Set(key, value)
+	}
+	return endpointBase + "?" + query.Encode()
+}
+
+func (p *statusParameters) Body() (io.Reader, error) {
+	return nil, nil
+}
+
+func (p *statusParameters) ParameterMap() map[string]string {
+	return map[string]string{"command": "STATUS", "media_id": p.mediaID}
+}
+
+type statusResponse struct {
+	Data   resources.UploadedMedia  `json:"data"`
+	Errors []resources.PartialError `json:"errors"`
+}
+
+func (r *statusResponse) HasPartialError() bool { return len(r.Errors) > 0 }
@@ -0,0 +1,253 @@
+package twitter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blacktop/xpost/internal/xpost"
+	uploadtypes "github.com/michimani/gotwi/media/upload/types"
+	"github.com/rivo/uniseg"
+)
+
+// pngBytes encodes a tiny still PNG.
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestResolveMediaTypeOverride(t *testing.T) {
+	data := pngBytes(t)
+	tests := []struct {
+		name         string
+		path         string
+		override     string
+		wantType     uploadtypes.MediaType
+		wantCategory uploadtypes.MediaCategory
+		wantErr      bool
+	}{
+		{name: "extension", path: "shot.png", wantType: uploadtypes.MediaTypePNG, wantCategory: uploadtypes.MediaCategoryTweetImage},
+		{name: "sniffed", path: "shot.dat", wantType: uploadtypes.MediaTypePNG, wantCategory: uploadtypes.MediaCategoryTweetImage},
+		{name: "override beats extension", path: "shot.png", override: "image/jpeg", wantType: uploadtypes.MediaTypeJPEG, wantCategory: uploadtypes.MediaCategoryTweetImage},
+		{name: "override beats contents", path: "shot.dat", override: "image/webp", wantType: uploadtypes.MediaTypeWebP, wantCategory: uploadtypes.MediaCategoryTweetImage},
+		{name: "video override", path: "clip.bin", override: "video/mp4", wantType: uploadtypes.MediaTypeMP4, wantCategory: uploadtypes.MediaCategoryTweetVideo},
+		{name: "unsupported override", path: "shot.png", override: "image/tiff", wantErr: true},
+	}
+	for _, tt := range tests {
+		mediaType, category, err := resolveMediaType(tt.path, data, tt.override)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if mediaType != tt.wantType || category != tt.wantCategory {
+			t.Errorf("%s: got %s/%s, want %s/%s", tt.name, mediaType, category, tt.wantType, tt.wantCategory)
+		}
+	}
+}
+
+func TestParseTweetID(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "1234567890", want: "1234567890"},
+		{raw: "https://x.com/jack/status/20", want: "20"},
+		{raw: "https://twitter.com/jack/status/20/photo/1", want: "20"},
+		{raw: "https://mobile.twitter.com/jack/statuses/20", want: "20"},
+		{raw: "https://x.com/i/web/status/20?s=20", want: "20"},
+		{raw: "https://x.com/jack", wantErr: true},
+		{raw: "https://x.com/jack/status/abc", wantErr: true},
+		{raw: "https://example.com/jack/status/20", wantErr: true},
+		{raw: "not a tweet", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTweetID(tt.raw)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTweetID(%q) = %q, %v; want %q, error %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTruncateKeepsEmojiWhole(t *testing.T) {
+	c := &Client{}
+	// Skin tone modifier, flag, variation selector, ZWJ family, keycap.
+	emoji := "👍🏽🇯🇵❤️👨‍👩‍👧#️⃣"
+	for pad := 268; pad <= 280; pad++ {
+		msg := strings.Repeat("a", pad) + emoji + " tail"
+		req, removed := c.Truncate(xpost.Request{Message: msg})
+		kept := strings.TrimSuffix(req.Message, xpost.Ellipsis)
+		if n := countText(req.Message); n > maxChars {
+			t.Errorf("pad %d: truncated tweet counts %d, over %d", pad, n, maxChars)
+		}
+		if kept+removed != msg {
+			t.Errorf("pad %d: kept %q and removed %q do not rebuild the message", pad, kept, removed)
+		}
+		if !onGraphemeBoundary(msg, len(kept)) {
+			t.Errorf("pad %d: cut inside an emoji sequence: ...%q", pad, kept[max(0, len(kept)-12):])
+		}
+	}
+}
+
+// onGraphemeBoundary reports whether byte offset i of text falls between
+// grapheme clusters.
+func onGraphemeBoundary(text string, i int) bool {
+	g := uniseg.NewGraphemes(text)
+	for g.Next() {
+		if start, end := g.Positions(); start == i || end == i {
+			return true
+		}
+	}
+	return i == 0
+}
+
+func TestPostKeepsMediaOrder(t *testing.T) {
+	srv := newFakeAPI(t)
+	var images []xpost.ImageAttachment
+	for _, name := range []string{"a", "b", "c"} {
+		images = append(images, xpost.ImageAttachment{Path: writePNG(t, name+".png"), Alt: name})
+	}
+	if _, err := srv.client(t).Post(context.Background(), xpost.Request{Message: "before and after", Images: images}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	tweets, alts := srv.recorded()
+	if len(tweets) != 1 {
+		t.Fatalf("created %d tweets, want 1", len(tweets))
+	}
+	media, _ := tweets[0]["media"].(map[string]any)
+	ids, _ := media["media_ids"].([]any)
+	if len(ids) != len(images) {
+		t.Fatalf("media_ids = %v, want %d", ids, len(images))
+	}
+	for i, id := range ids {
+		if alt := alts[id.(string)]; alt != images[i].Alt {
+			t.Errorf("media %d (%v) has alt %q, want %q", i, id, alt, images[i].Alt)
+		}
+	}
+}
+
+func TestPostAltReplyFails(t *testing.T) {
+	srv := newFakeAPI(t)
+	srv.failReplies = http.StatusServiceUnavailable
+	res, err := srv.client(t).Post(context.Background(), xpost.Request{
+		Message:    "Sunset",
+		Images:     []xpost.ImageAttachment{{Path: writePNG(t, "a.png"), Alt: "The sun setting over the sea"}},
+		AltAsReply: true,
+	})
+	// The tweet exists, so it is returned and must not be retried.
+	if !errors.As(err, new(xpost.PartialError)) || errors.As(err, new(xpost.RetryableError)) {
+		t.Errorf("Post = %v, want a partial failure that is not retryable", err)
+	}
+	if res.ID != "101" || res.URL != tweetURL("101") {
+		t.Errorf("Post = %+v, want the tweet's result", res)
+	}
+}
+
+func TestPostLink(t *testing.T) {
+	link := "https://example.com/" + strings.Repeat("long-path/", 10)
+	tests := []struct {
+		name      string
+		req       xpost.Request
+		wantText  string
+		wantCount int
+	}{
+		{name: "no link", req: xpost.Request{Message: "Hello"}, wantText: "Hello", wantCount: 5},
+		// The link counts as a t.co URL whatever its length.
+		{name: "link", req: xpost.Request{Message: "Hello", Link: link}, wantText: "Hello\n\n" + link, wantCount: 5 + 2 + 23},
+	}
+	for _, tt := range tests {
+		srv := newFakeAPI(t)
+		c := srv.client(t)
+		if got, _, _ := c.Count(tt.req); got != tt.wantCount {
+			t.Errorf("%s: Count = %d, want %d", tt.name, got, tt.wantCount)
+		}
+		if _, err := c.Post(context.Background(), tt.req); err != nil {
+			t.Errorf("%s: Post: %v", tt.name, err)
+			continue
+		}
+		if tweets, _ := srv.recorded(); len(tweets) != 1 || tweets[0]["text"] != tt.wantText {
+			t.Errorf("%s: tweets = %v, want text %q", tt.name, tweets, tt.wantText)
+		}
+	}
+}
+
+func TestTruncateLeavesRoomForLink(t *testing.T) {
+	req := xpost.Request{Message: strings.Repeat("word ", 100), Link: "https://example.com/" + strings.Repeat("x", 100)}
+	got, removed := (&Client{}).Truncate(req)
+	if removed == "" {
+		t.Fatal("Truncate removed nothing from an overlong tweet")
+	}
+	if n := countText(composeText(got)); n > maxChars {
+		t.Errorf("truncated tweet counts %d, over %d", n, maxChars)
+	}
+	if got.Link != req.Link {
+		t.Errorf("Link = %q, want it kept", got.Link)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int // status the API fails the deletion with; 0 succeeds
+		wantErr   string
+		retryable bool
+	}{
+		{name: "deleted"},
+		{name: "not found", status: http.StatusNotFound, wantErr: "delete tweet: "},
+		{name: "server error", status: http.StatusServiceUnavailable, wantErr: "delete tweet: ", retryable: true},
+	}
+	for _, tt := range tests {
+		srv := newFakeAPI(t)
+		if tt.status != 0 {
+			srv.fail["DELETE /2/tweets/123"] = tt.status
+		}
+		err := srv.client(t).Delete(context.Background(), "123")
+		if tt.wantErr == "" {
+			if err != nil || len(srv.deleted) != 1 || srv.deleted[0] != "123" {
+				t.Errorf("%s: Delete = %v, deleted %v; want 123 deleted", tt.name, err, srv.deleted)
+			}
+			continue
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "simulated failure") {
+			t.Errorf("%s: Delete = %v, want the API's summarized error", tt.name, err)
+		}
+		if retryable := errors.As(err, new(xpost.RetryableError)); retryable != tt.retryable {
+			t.Errorf("%s: retryable = %v, want %v", tt.name, retryable, tt.retryable)
+		}
+	}
+}
+
+func TestValidatePoll(t *testing.T) {
+	week := 7 * 24 * time.Hour
+	tests := []struct {
+		name string
+		req  xpost.Request
+		ok   bool
+	}{
+		{name: "shortest", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes", "no"}, Duration: 5 * time.Minute}}, ok: true},
+		{name: "a week", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"a", "b", "c", "d"}, Duration: week}}, ok: true},
+		{name: "under five minutes", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes", "no"}, Duration: 4 * time.Minute}}},
+		{name: "over a week", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes", "no"}, Duration: week + time.Minute}}},
+		{name: "one option", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes"}, Duration: time.Hour}}},
+		{name: "five options", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"a", "b", "c", "d", "e"}, Duration: time.Hour}}},
+		{name: "empty option", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes", " "}, Duration: time.Hour}}},
+		{name: "option of 26 characters", req: xpost.Request{Message: "?", Poll: &xpost.Poll{Options: []string{"yes", strings.Repeat("n", 26)}, Duration: time.Hour}}},
+		{name: "with a quote", req: xpost.Request{Message: "?", Quote: "https://x.com/jack/status/20", Poll: &xpost.Poll{Options: []string{"yes", "no"}, Duration: time.Hour}}},
+	}
+	for _, tt := range tests {
+		if err := (&Client{}).Validate(tt.req); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
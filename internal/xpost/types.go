@@ -1,20 +1,215 @@
 package xpost
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ImageAttachment is an image attached to a post.
+type ImageAttachment struct {
+	Path string
+	Alt  string
+	// MediaType forces the MIME type used for Path, bypassing detection.
+	MediaType string
+	// FullAlt is the complete description when Alt was shortened to fit the
+	// provider's cap. It is posted as a reply under the post.
+	FullAlt string
+}
 
 // Request defines the message payload shared across all providers.
 type Request struct {
-	Message   string
-	ImagePath string
-	ImageAlt  string
-	Link      string // Optional URL to append to message with proper formatting
+	Message string
+	Images  []ImageAttachment
+	// Link is an optional URL. Most providers append it after a blank line
+	// (Twitter counts it as 23 characters, however long); Lemmy uses it as
+	// the post's link, and Bluesky can also attach a card for it (see
+	// LinkPreview).
+	Link string
+	// AltAsReply posts the image descriptions as a follow-up reply for
+	// clients that don't surface alt text.
+	AltAsReply bool
+	Community  string // Lemmy community to post to; ignored by other providers
+	// ReplyTo is the URL (or provider-native ID) of a post to reply to.
+	ReplyTo string
+	// Quote is the URL of a post to quote. It is only set for providers whose
+	// Quoter accepts it; others get the URL appended to the message instead.
+	Quote string
+	// ContentWarning hides the post behind this text on providers that support
+	// content warnings (Mastodon, Pixelfed and Misskey); others ignore it.
+	ContentWarning string
+	// Visibility is the Mastodon visibility (public, unlisted, private or
+	// direct); empty uses the account default.
+	Visibility string
+	// ScheduledAt publishes the post at this time instead of now. Only
+	// providers that schedule natively (Mastodon) accept it; zero posts now.
+	ScheduledAt time.Time
+	// LinkPreview attaches a link card for Link (or the first URL in the
+	// message) to posts without images. Only Bluesky needs this; other
+	// networks build previews themselves.
+	LinkPreview bool
+	// Language is the post's BCP 47 language tag (e.g. "en"). Only Mastodon
+	// records it; empty uses the account default.
+	Language string
+	// Poll attaches a poll to the post. Only Twitter and Mastodon support
+	// polls, and neither allows one alongside images.
+	Poll *Poll
 }
 
-// Poster abstracts a social network that can publish content.
-type Poster interface {
+// AltReply returns the text of the image-description reply, or "" when no
+// such reply should be posted. A shortened description always gets one.
+func (r Request) AltReply() string {
+	var parts []string
+	for i, img := range r.Images {
+		alt := strings.TrimSpace(img.Alt)
+		if img.FullAlt != "" {
+			alt = strings.TrimSpace(img.FullAlt)
+		} else if !r.AltAsReply {
+			continue
+		}
+		if alt == "" {
+			continue
+		}
+		if len(r.Images) == 1 {
+			parts = append(parts, "Image description: "+alt)
+		} else {
+			parts = append(parts, fmt.Sprintf("Image %d description: %s", i+1, alt))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// PostResult identifies a published post.
+type PostResult struct {
+	Provider string
+	ID       string // provider-native identifier (tweet ID, status ID, at:// URI)
+	URL      string // public permalink, when the provider exposes one
+	// Scheduled is set when the post was scheduled rather than published;
+	// ID then identifies the scheduled post and URL is empty.
+	Scheduled bool
+}
+
+// Validator checks requests against a social network's constraints. It needs
+// no credentials or network access.
+type Validator interface {
 	Name() string
 	// Validate checks if the request meets platform constraints (character limits, etc.)
 	// without posting. Returns nil if valid.
 	Validate(req Request) error
-	Post(ctx context.Context, req Request) error
+}
+
+// Poster abstracts a social network that can publish content.
+type Poster interface {
+	Validator
+	Post(ctx context.Context, req Request) (PostResult, error)
+}
+
+// Quoter is implemented by providers that can embed a quoted post natively.
+type Quoter interface {
+	// CanQuote reports whether url refers to a post the provider can embed.
+	CanQuote(url string) bool
+}
+
+// Previewer is implemented by providers that can explain how a request will
+// be counted, for --dry-run output.
+type Previewer interface {
+	Preview(req Request) []string
+}
+
+// Counter is implemented by providers that can measure a request the way
+// their length limit is enforced. It needs no credentials or network access.
+type Counter interface {
+	// Count returns the request's length, the limit it must fit and the unit
+	// both are measured in.
+	Count(req Request) (count, limit int, unit string)
+}
+
+// Reposter is implemented by providers that can boost an existing post.
+type Reposter interface {
+	// Repost boosts the post with the given URL (or provider-native ID).
+	Repost(ctx context.Context, ref string) error
+}
+
+// Reacter is implemented by providers that can like or react to an existing
+// post. Networks without emoji reactions record a plain like instead.
+type Reacter interface {
+	// React applies emoji (or a like when emoji is empty) to the post with
+	// the given URL (or provider-native ID).
+	React(ctx context.Context, ref, emoji string) error
+}
+
+// Capabilities describes the limits and features of a provider.
+type Capabilities struct {
+	MaxChars int    // post length limit, measured in Unit
+	Unit     string // what MaxChars counts: "characters" or "graphemes"
+	// MaxImages is how many images the network accepts per post.
+	MaxImages int
+	// MaxImageBytes is the largest image file accepted; 0 when unknown.
+	MaxImageBytes int
+	MediaTypes    []string
+	MaxAltChars   int // image description limit; 0 when alt text is unsupported
+	// Features lists provider-specific options such as content warnings.
+	Features []string
+	// Live is set when the limits were read from the server rather than
+	// taken from built-in defaults.
+	Live bool
+}
+
+// Prober is implemented by providers that can report their Capabilities.
+type Prober interface {
+	// Capabilities returns the provider's limits, fetched from the server
+	// where it publishes them.
+	Capabilities(ctx context.Context) (Capabilities, error)
+}
+
+// Verifier is implemented by providers that can check their credentials
+// without posting.
+type Verifier interface {
+	// Verify makes a lightweight authenticated call and returns the
+	// account's handle.
+	Verify(ctx context.Context) (string, error)
+}
+
+// ReadBack is the result of looking up a published post as an anonymous
+// reader.
+type ReadBack struct {
+	Visible bool
+	// Detail explains why the post may have been filtered when it is not
+	// visible, or notes labels that limit its reach.
+	Detail string
+}
+
+// ReadBacker is implemented by providers that can check whether a published
+// post is publicly visible. It is best-effort: a post can be filtered in ways
+// an anonymous lookup does not reveal.
+type ReadBacker interface {
+	ReadBack(ctx context.Context, res PostResult) (ReadBack, error)
+}
+
+// Closer is implemented by providers that hold resources, such as sessions
+// or connections, that must be released once posting is done.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// Close releases p's resources if it implements Closer.
+func Close(ctx context.Context, p Poster) error {
+	if c, ok := p.(Closer); ok {
+		return c.Close(ctx)
+	}
+	return nil
+}
+
+// Editor is implemented by providers that can change a published post.
+type Editor interface {
+	// Edit replaces the text of the post with the given PostResult.ID and,
+	// when req has images, its media.
+	Edit(ctx context.Context, id string, req Request) (PostResult, error)
+}
+
+// Deleter is implemented by providers that can remove a published post.
+type Deleter interface {
+	// Delete removes the post with the given PostResult.ID.
+	Delete(ctx context.Context, id string) error
 }
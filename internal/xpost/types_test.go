@@ -0,0 +1,65 @@
+package xpost
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAltReply(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			name: "flag unset",
+			req:  Request{Images: []ImageAttachment{{Alt: "A cat"}}},
+		},
+		{
+			name: "single image",
+			req:  Request{AltAsReply: true, Images: []ImageAttachment{{Alt: " A cat "}}},
+			want: "Image description: A cat",
+		},
+		{
+			name: "numbered when several",
+			req:  Request{AltAsReply: true, Images: []ImageAttachment{{Alt: "A cat"}, {Alt: ""}, {Alt: "A dog"}}},
+			want: "Image 1 description: A cat\n\nImage 3 description: A dog",
+		},
+		{
+			name: "shortened description without the flag",
+			req:  Request{Images: []ImageAttachment{{Alt: "A c…", FullAlt: "A cat asleep"}}},
+			want: "Image description: A cat asleep",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.req.AltReply(); got != tt.want {
+			t.Errorf("%s: AltReply() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+type closingPoster struct {
+	Poster
+	err error
+}
+
+func (p closingPoster) Close(context.Context) error { return p.err }
+
+func TestClose(t *testing.T) {
+	failed := errors.New("close failed")
+	tests := []struct {
+		name string
+		p    Poster
+		want error
+	}{
+		{name: "not a Closer", p: closingPoster{}.Poster},
+		{name: "Closer", p: closingPoster{}},
+		{name: "Closer fails", p: closingPoster{err: failed}, want: failed},
+	}
+	for _, tt := range tests {
+		if err := Close(context.Background(), tt.p); err != tt.want {
+			t.Errorf("%s: Close = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package xpost
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// videoExtensions maps the video file extensions xpost uploads to their MIME
+// types.
+var videoExtensions = map[string]string{
+	".mp4": "video/mp4",
+	".m4v": "video/mp4",
+	".mov": "video/quicktime",
+}
+
+// IsVideo reports whether the attachment is an MP4 or QuickTime video, going
+// by its media type override or else its file extension.
+func (a ImageAttachment) IsVideo() bool {
+	if a.MediaType != "" {
+		return strings.HasPrefix(a.MediaType, "video/")
+	}
+	_, ok := videoExtensions[strings.ToLower(filepath.Ext(a.Path))]
+	return ok
+}
+
+// VideoDuration reads the running time from the movie header of the MP4 or
+// QuickTime file at path.
+func VideoDuration(path string) (time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	moov, err := findBox(f, 0, info.Size(), "moov")
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", path, err)
+	}
+	mvhd, err := findBox(f, moov.start, moov.end, "mvhd")
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	header := make([]byte, 32)
+	if _, err := f.ReadAt(header, mvhd.start); err != nil {
+		return 0, fmt.Errorf("read %s: movie header: %w", path, err)
+	}
+	var timescale, duration uint64
+	if header[0] == 1 { // version 1 uses 64-bit times
+		timescale = uint64(binary.BigEndian.Uint32(header[20:24]))
+		duration = binary.BigEndian.Uint64(header[24:32])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(header[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(header[16:20]))
+	}
+	if timescale == 0 {
+		return 0, fmt.Errorf("read %s: movie header has no timescale", path)
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), nil
+}
+
+// box is the payload range of an ISO base media box.
+type box struct {
+	start, end int64
+}
+
+// findBox returns the payload of the first box of the given type between
+// start and end.
+func findBox(r io.ReaderAt, start, end int64, kind string) (box, error) {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return box{}, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerLen := int64(8)
+		switch size {
+		case 0: // the box runs to the end
+			size = end - offset
+		case 1: // a 64-bit size follows the type
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return box{}, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerLen = 16
+		}
+		if size < headerLen || offset+size > end {
+			return box{}, errors.New("malformed video container")
+		}
+		if string(header[4:8]) == kind {
+			return box{start: offset + headerLen, end: offset + size}, nil
+		}
+		offset += size
+	}
+	return box{}, fmt.Errorf("no %s box found", kind)
+}
+
+// CheckVideo validates a video attachment's file size and, when maxDuration
+// is non-zero, its running time. Files that cannot be read are left for the
+// upload to report.
+func CheckVideo(provider string, a ImageAttachment, maxBytes int64, maxDuration time.Duration) error {
+	info, err := os.Stat(a.Path)
+	if err != nil {
+		return nil
+	}
+	if info.Size() > maxBytes {
+		return ValidationError{
+			Provider: provider,
+			Reason:   fmt.Sprintf("video %s too large: %d MB (max %d MB)", a.Path, info.Size()>>20, maxBytes>>20),
+		}
+	}
+	if maxDuration == 0 {
+		return nil
+	}
+	duration, err := VideoDuration(a.Path)
+	if err != nil {
+		return nil
+	}
+	if duration > maxDuration {
+		return ValidationError{
+			Provider: provider,
+			Reason:   fmt.Sprintf("video %s too long: %s (max %s)", a.Path, duration.Round(time.Second), maxDuration),
+		}
+	}
+	return nil
+}